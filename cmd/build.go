@@ -1,12 +1,20 @@
 package cmd
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/olimci/shizuka/internal/build"
 	"github.com/olimci/shizuka/internal/console"
+	"github.com/olimci/shizuka/internal/manifest"
 	"github.com/olimci/shizuka/internal/options"
 	"github.com/urfave/cli/v3"
 )
@@ -35,6 +43,52 @@ var buildCmd = &cli.Command{
 			Name:  "force",
 			Usage: "Overwrite a non-empty output directory",
 		},
+		&cli.Int64Flag{
+			Name:  "max-image-size",
+			Usage: "Warn about static images larger than this many bytes",
+			Validator: func(n int64) error {
+				if n <= 0 {
+					return errors.New("max-image-size must be greater than zero")
+				}
+				return nil
+			},
+		},
+		&cli.BoolFlag{
+			Name:  "include-drafts",
+			Usage: "Build draft pages outside dev mode",
+		},
+		&cli.BoolFlag{
+			Name:  "strict",
+			Usage: "Fail the build on warnings",
+		},
+		&cli.DurationFlag{
+			Name:  "step-timeout",
+			Usage: "Fail the build if a step runs longer than this (e.g. 2m)",
+		},
+		&cli.StringFlag{
+			Name:  "cache",
+			Usage: "Artefact cache file for incremental builds (e.g. .shizuka-cache)",
+		},
+		&cli.StringFlag{
+			Name:  "report",
+			Usage: "Write a JSON report of the build output to this file, outside the output directory",
+		},
+		&cli.BoolFlag{
+			Name:  "profile",
+			Usage: "Print how long each build step took, slowest first",
+		},
+		&cli.StringFlag{
+			Name:  "trace",
+			Usage: "Write a Chrome trace of the build steps to this file (open in chrome://tracing or Perfetto)",
+		},
+		&cli.BoolFlag{
+			Name:  "deterministic",
+			Usage: "Run steps and writes in a fixed order so identical inputs give identical output and logs (slower)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "conflict-priority",
+			Usage: "Owners, highest first, whose claim is kept when two artefacts write one file (e.g. pages:build,static)",
+		},
 	},
 	Action: buildAction,
 }
@@ -62,7 +116,17 @@ func buildAction(ctx context.Context, cmd *cli.Command) error {
 		options.WithConfigPath(cmd.String("config")),
 		options.If(options.WithOutputPath(cmd.String("output")), cmd.IsSet("output")),
 		options.If(options.WithMaxWorkers(cmd.Int("workers")), cmd.IsSet("workers")),
+		options.If(options.WithWriteWorkers(cmd.Int("write-workers")), cmd.IsSet("write-workers")),
 		options.If(options.WithForce(true), cmd.Bool("force")),
+		options.If(options.WithDryRun(true), cmd.Bool("dry-run")),
+		options.If(options.WithStrict(true), cmd.Bool("strict")),
+		options.If(options.WithIncludeDrafts(true), cmd.Bool("include-drafts")),
+		options.If(options.WithStepTimeout(cmd.Duration("step-timeout")), cmd.IsSet("step-timeout")),
+		options.If(options.WithArtefactCache(cmd.String("cache")), cmd.IsSet("cache")),
+		options.If(options.WithReport(cmd.String("report")), cmd.IsSet("report")),
+		options.If(options.WithConflictPriority(cmd.StringSlice("conflict-priority")), cmd.IsSet("conflict-priority")),
+		options.If(options.WithMaxImageSize(cmd.Int64("max-image-size")), cmd.IsSet("max-image-size")),
+		options.If(options.WithDeterministic(true), cmd.Bool("deterministic")),
 
 		// dev stuff
 		options.If(options.WithDev(true), cmd.Bool("dev")),
@@ -70,12 +134,77 @@ func buildAction(ctx context.Context, cmd *cli.Command) error {
 
 	logger.Info("building")
 
-	if err := build.Build(opts...); err != nil {
+	stats, err := build.BuildWithStats(opts...)
+	if stats != nil {
+		if cmd.Bool("profile") {
+			printProfile(os.Stdout, stats)
+		}
+		if cmd.IsSet("trace") {
+			if traceErr := writeTrace(cmd.String("trace"), stats); traceErr != nil {
+				logger.Error("trace failed", "error", traceErr)
+				return handled(traceErr)
+			}
+		}
+	}
+	if err != nil {
 		logger.Error("build failed", "error", err)
 		return handled(err)
 	}
 
-	logger.Info("build complete")
+	changes := stats.Changes
+	logChanges(logger, changes)
+	if cmd.Bool("dry-run") {
+		logger.Info("dry run complete", "would_write", len(changes.Written), "would_edit", len(changes.Edited), "would_delete", len(changes.Deleted), "unchanged", len(changes.Unchanged))
+		return nil
+	}
+	logger.Info("build complete", "summary", stats.Summary(), "written", len(changes.Written), "edited", len(changes.Edited), "deleted", len(changes.Deleted), "unchanged", len(changes.Unchanged))
 
 	return nil
 }
+
+// logChanges logs each output file the build wrote, edited or deleted, at
+// debug level so that the default output stays to one line.
+func logChanges(logger *slog.Logger, changes manifest.Changes) {
+	for _, target := range changes.Written {
+		logger.Debug("written", "file", target)
+	}
+	for _, target := range changes.Edited {
+		logger.Debug("edited", "file", target)
+	}
+	for _, target := range changes.Deleted {
+		logger.Debug("deleted", "file", target)
+	}
+}
+
+// printProfile prints each step's wall-clock time, slowest first, followed by
+// the manifest's final write phase and the whole build.
+func printProfile(w io.Writer, stats *build.BuildStats) {
+	ids := slices.Collect(maps.Keys(stats.Steps))
+	slices.SortFunc(ids, func(a, b string) int {
+		return cmp.Or(cmp.Compare(stats.Steps[b].Duration, stats.Steps[a].Duration), cmp.Compare(a, b))
+	})
+
+	width := len("manifest")
+	for _, id := range ids {
+		width = max(width, len(id))
+	}
+	fmt.Fprintf(w, "%-*s  %10s  %s\n", width, "step", "duration", "artefacts")
+	for _, id := range ids {
+		step := stats.Steps[id]
+		fmt.Fprintf(w, "%-*s  %10s  %d\n", width, id, step.Duration.Round(time.Microsecond), step.Artefacts)
+	}
+	fmt.Fprintf(w, "%-*s  %10s\n", width, "manifest", stats.ManifestDuration.Round(time.Microsecond))
+	fmt.Fprintf(w, "%-*s  %10s\n", width, "total", stats.Duration.Round(time.Microsecond))
+}
+
+func writeTrace(name string, stats *build.BuildStats) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := stats.WriteTrace(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
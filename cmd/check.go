@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/olimci/shizuka/internal/build"
+	"github.com/olimci/shizuka/internal/console"
+	"github.com/olimci/shizuka/internal/options"
+	"github.com/urfave/cli/v3"
+)
+
+var checkCmd = &cli.Command{
+	Name:  "check",
+	Usage: "Check site content for problems without writing output",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"c"},
+			Value:   defaultConfig,
+			Usage:   "Config file path",
+		},
+		&cli.BoolFlag{
+			Name:  "include-drafts",
+			Usage: "Check draft pages too",
+		},
+	},
+	Action: checkAction,
+}
+
+func checkAction(ctx context.Context, cmd *cli.Command) error {
+	con, err := console.Open(os.Stdin, os.Stdout, os.Stderr, console.Options{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "console setup failed:", err)
+		return handled(err)
+	}
+	defer con.Close()
+
+	logger, err := makeLogger(con, cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logger setup failed:", err)
+		return handled(err)
+	}
+
+	err = build.Build(options.Filter(
+		options.WithContext(ctx),
+		options.WithLogger(logger),
+		options.WithConfigPath(cmd.String("config")),
+		options.If(options.WithMaxWorkers(cmd.Int("workers")), cmd.IsSet("workers")),
+		options.If(options.WithIncludeDrafts(true), cmd.Bool("include-drafts")),
+		options.WithCheck(true),
+	)...)
+
+	failure, ok := errors.AsType[*build.Failure](err)
+	if err != nil && !ok {
+		logger.Error("check failed", "error", err)
+		return handled(err)
+	}
+	if problems := printCheckReport(os.Stdout, failure); problems > 0 {
+		return handled(fmt.Errorf("check found %d problem(s)", problems))
+	}
+	return nil
+}
+
+// printCheckReport lists the problems in failure, one per line with where it
+// was found, and returns how many there were.
+func printCheckReport(w io.Writer, failure *build.Failure) int {
+	if failure == nil || len(failure.Errors) == 0 {
+		fmt.Fprintln(w, "no problems found")
+		return 0
+	}
+	for _, problem := range failure.Errors {
+		if loc := problem.Location(); loc != "" {
+			fmt.Fprintf(w, "%s: %s\n", loc, problem.Description())
+		} else {
+			fmt.Fprintln(w, problem.Description())
+		}
+	}
+	fmt.Fprintf(w, "%d problem(s)\n", len(failure.Errors))
+	return len(failure.Errors)
+}
@@ -12,6 +12,10 @@ func TestCompletionSuggestsCommandsAndFlags(t *testing.T) {
 	out := runCLI(t, []string{"shizuka", "--generate-shell-completion"})
 	assertContains(t, out, "build:Build site")
 	assertContains(t, out, "dev:Start development server")
+	assertContains(t, out, "serve:Serve a built site as a static host would")
+	assertContains(t, out, "new:Create a content file from an archetype")
+	assertContains(t, out, "check:Check site content for problems without writing output")
+	assertContains(t, out, "graph:Print the build step graph as Graphviz DOT or Mermaid")
 
 	out = runCLI(t, []string{"shizuka", "--fo", "--generate-shell-completion"})
 	assertContains(t, out, "--format:Output format: auto, plain, pretty, or json")
@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/olimci/shizuka/internal/build"
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/console"
+	"github.com/olimci/shizuka/internal/deploy"
+	"github.com/olimci/shizuka/internal/options"
+	"github.com/urfave/cli/v3"
+)
+
+var deployCmd = &cli.Command{
+	Name:  "deploy",
+	Usage: "Build the site and publish it to a git branch or a configured target",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"c"},
+			Value:   defaultConfig,
+			Usage:   "Config file path",
+		},
+		&cli.StringFlag{
+			Name:  "git-branch",
+			Usage: "Branch to replace with the built site, e.g. gh-pages",
+		},
+		&cli.StringFlag{
+			Name:  "target",
+			Usage: "Deploy target from the config: rsync or s3 (defaults to the only one configured)",
+		},
+		&cli.StringFlag{
+			Name:  "remote",
+			Value: "origin",
+			Usage: "Remote to force-push the git branch to",
+		},
+		&cli.BoolFlag{
+			Name:  "no-push",
+			Usage: "Update the local git branch without pushing",
+		},
+		&cli.StringSliceFlag{
+			Name:  "keep",
+			Usage: "Path on the git branch to carry over when the build does not produce it, e.g. CNAME (repeatable)",
+		},
+		&cli.StringFlag{
+			Name:    "message",
+			Aliases: []string{"m"},
+			Usage:   "Commit message for the git deploy commit",
+		},
+		&cli.BoolFlag{
+			Name:    "yes",
+			Aliases: []string{"y"},
+			Usage:   "Skip the confirmation prompt",
+		},
+	},
+	Action: deployAction,
+}
+
+// gitRunner runs git with extra environment variables and returns its
+// trimmed stdout. It is swapped out in tests.
+type gitRunner interface {
+	Git(ctx context.Context, env []string, args ...string) (string, error)
+}
+
+type execGitRunner struct{}
+
+func (execGitRunner) Git(ctx context.Context, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// deployPlan describes a publish of a built site to a git branch.
+type deployPlan struct {
+	Output  string
+	Branch  string
+	Remote  string
+	Message string
+	// Keep lists paths on the existing branch that are carried over into
+	// the new commit when the output has nothing at that path.
+	Keep   []string
+	DryRun bool
+}
+
+func deployAction(ctx context.Context, cmd *cli.Command) error {
+	con, err := console.Open(os.Stdin, os.Stdout, os.Stderr, console.Options{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "console setup failed:", err)
+		return handled(err)
+	}
+	defer con.Close()
+
+	logger, err := makeLogger(con, cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logger setup failed:", err)
+		return handled(err)
+	}
+
+	var target deploy.Target
+	if !cmd.IsSet("git-branch") {
+		cfg, err := config.Load(cmd.String("config"))
+		if err != nil {
+			logger.Error("deploy failed", "error", err)
+			return handled(err)
+		}
+		target, err = deploy.Select(cfg.Deploy, cmd.String("target"))
+		if err != nil {
+			if errors.Is(err, deploy.ErrNoTarget) && !cmd.IsSet("target") {
+				err = fmt.Errorf("%w: pass --git-branch or add a deploy section to the config", err)
+			}
+			logger.Error("deploy failed", "error", err)
+			return handled(err)
+		}
+	} else if cmd.IsSet("target") {
+		err := errors.New("--git-branch and --target are mutually exclusive")
+		logger.Error("deploy failed", "error", err)
+		return handled(err)
+	}
+
+	output, err := os.MkdirTemp("", "shizuka-deploy-*")
+	if err != nil {
+		logger.Error("deploy failed", "error", err)
+		return handled(err)
+	}
+	defer os.RemoveAll(output)
+
+	logger.Info("building")
+	stats, err := build.BuildWithStats(options.Filter(
+		options.WithContext(ctx),
+		options.WithLogger(logger),
+		options.WithConfigPath(cmd.String("config")),
+		options.WithInternalOutputPath(output),
+		options.WithForce(true),
+		options.If(options.WithMaxWorkers(cmd.Int("workers")), cmd.IsSet("workers")),
+		options.If(options.WithWriteWorkers(cmd.Int("write-workers")), cmd.IsSet("write-workers")),
+	)...)
+	if err != nil {
+		logger.Error("build failed", "error", err)
+		return handled(err)
+	}
+	logger.Info("build complete", "summary", stats.Summary())
+
+	if target != nil {
+		return deployToTarget(ctx, con, cmd, logger, target, output)
+	}
+
+	plan := deployPlan{
+		Output:  output,
+		Branch:  cmd.String("git-branch"),
+		Remote:  cmd.String("remote"),
+		Message: cmd.String("message"),
+		Keep:    cmd.StringSlice("keep"),
+		DryRun:  cmd.Bool("dry-run"),
+	}
+	if cmd.Bool("no-push") {
+		plan.Remote = ""
+	}
+
+	if !plan.DryRun && !cmd.Bool("yes") {
+		ok, err := confirmDeploy(con.In, con.Out, plan)
+		if err != nil {
+			logger.Error("deploy failed", "error", err)
+			return handled(err)
+		}
+		if !ok {
+			logger.Info("deploy cancelled")
+			return nil
+		}
+	}
+
+	if err := deployToBranch(ctx, execGitRunner{}, plan, con.Out); err != nil {
+		logger.Error("deploy failed", "error", err)
+		return handled(err)
+	}
+	return nil
+}
+
+func deployToTarget(ctx context.Context, con *console.Console, cmd *cli.Command, logger *slog.Logger, target deploy.Target, output string) error {
+	dryRun := cmd.Bool("dry-run")
+	if !dryRun && !cmd.Bool("yes") {
+		ok, err := confirm(con.In, con.Out, fmt.Sprintf("This will publish the site with %s.", target.Name()))
+		if err != nil {
+			logger.Error("deploy failed", "error", err)
+			return handled(err)
+		}
+		if !ok {
+			logger.Info("deploy cancelled")
+			return nil
+		}
+	}
+
+	result, err := target.Deploy(ctx, output, deploy.Options{DryRun: dryRun, Out: con.Out})
+	if err != nil {
+		logger.Error("deploy failed", "target", target.Name(), "error", err)
+		return handled(err)
+	}
+	logger.Info("deploy complete", "target", target.Name(), "dry_run", dryRun,
+		"uploaded", result.Uploaded, "deleted", result.Deleted, "unchanged", result.Unchanged)
+	return nil
+}
+
+func confirmDeploy(in io.Reader, out io.Writer, plan deployPlan) (bool, error) {
+	target := "local branch " + plan.Branch
+	if plan.Remote != "" {
+		target = fmt.Sprintf("%s and force-push it to %s", target, plan.Remote)
+	}
+	return confirm(in, out, fmt.Sprintf("This will replace %s.", target))
+}
+
+func confirm(in io.Reader, out io.Writer, prompt string) (bool, error) {
+	fmt.Fprintf(out, "%s Continue? [y/N] ", prompt)
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// deployToBranch commits the output directory as a single parentless commit
+// and points the branch at it, then force-pushes. The commit is staged
+// through a throwaway index so the working tree and current branch are
+// never touched, and the branch only moves once the commit exists. A
+// .gitignore inside the output directory is honoured; dotfiles such as
+// .nojekyll and CNAME are committed like any other file. Paths in plan.Keep
+// are copied from the branch's current commit when the output lacks them,
+// and an empty tree is never committed.
+func deployToBranch(ctx context.Context, git gitRunner, plan deployPlan, out io.Writer) error {
+	if plan.Branch == "" {
+		return errors.New("deploy branch is required")
+	}
+	if _, err := git.Git(ctx, nil, "check-ref-format", "--branch", plan.Branch); err != nil {
+		return fmt.Errorf("invalid branch %q: %w", plan.Branch, err)
+	}
+
+	gitDir, err := git.Git(ctx, nil, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return fmt.Errorf("not a git repository: %w", err)
+	}
+
+	message := plan.Message
+	if message == "" {
+		message = "Deploy site"
+		if head, err := git.Git(ctx, nil, "rev-parse", "--short", "HEAD"); err == nil && head != "" {
+			message = fmt.Sprintf("Deploy site from %s", head)
+		}
+	}
+
+	indexFile := filepath.Join(gitDir, "shizuka-deploy.index")
+	defer os.Remove(indexFile)
+	env := []string{"GIT_INDEX_FILE=" + indexFile}
+
+	output, err := filepath.Abs(plan.Output)
+	if err != nil {
+		return err
+	}
+	// The :/ pathspec names the top of the work tree, which here is the
+	// output directory, wherever in the repository git is run from.
+	if _, err := git.Git(ctx, env, "--work-tree", output, "add", "--all", "--", ":/"); err != nil {
+		return err
+	}
+
+	ref := "refs/heads/" + plan.Branch
+	if err := keepBranchPaths(ctx, git, env, ref, output, plan.Keep); err != nil {
+		return err
+	}
+
+	tree, err := git.Git(ctx, env, "write-tree")
+	if err != nil {
+		return err
+	}
+	if files, err := git.Git(ctx, nil, "ls-tree", "--full-tree", "--name-only", tree); err != nil {
+		return err
+	} else if files == "" {
+		return fmt.Errorf("output %q has nothing to commit; refusing to replace %s with an empty tree", plan.Output, plan.Branch)
+	}
+	commit, err := git.Git(ctx, nil, "commit-tree", tree, "-m", message)
+	if err != nil {
+		return err
+	}
+
+	if plan.DryRun {
+		fmt.Fprintf(out, "dry run: would point %s at %s\n", ref, commit)
+		if plan.Remote != "" {
+			fmt.Fprintf(out, "dry run: would force-push %s to %s\n", plan.Branch, plan.Remote)
+		}
+		return nil
+	}
+
+	if _, err := git.Git(ctx, nil, "update-ref", ref, commit); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s -> %s\n", plan.Branch, commit)
+
+	if plan.Remote == "" {
+		return nil
+	}
+	if _, err := git.Git(ctx, nil, "push", "--force", plan.Remote, ref+":"+ref); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "pushed %s to %s\n", plan.Branch, plan.Remote)
+	return nil
+}
+
+// keepBranchPaths stages the entries under each of keep from ref's current
+// commit into the deploy index, skipping paths the output already has. A
+// branch that does not exist yet has nothing to keep.
+func keepBranchPaths(ctx context.Context, git gitRunner, env []string, ref, output string, keep []string) error {
+	if len(keep) == 0 {
+		return nil
+	}
+	if _, err := git.Git(ctx, nil, "rev-parse", "--verify", "--quiet", ref); err != nil {
+		return nil
+	}
+
+	for _, keepPath := range keep {
+		keepPath = strings.Trim(filepath.ToSlash(keepPath), "/")
+		if keepPath == "" {
+			continue
+		}
+		entries, err := git.Git(ctx, nil, "ls-tree", "-r", "--full-tree", ref, "--", keepPath)
+		if err != nil {
+			return err
+		}
+		for entry := range strings.Lines(entries) {
+			info, name, ok := strings.Cut(strings.TrimSpace(entry), "\t")
+			fields := strings.Fields(info)
+			if !ok || len(fields) != 3 {
+				continue
+			}
+			if _, err := os.Lstat(filepath.Join(output, filepath.FromSlash(name))); err == nil {
+				continue
+			}
+			if _, err := git.Git(ctx, env, "update-index", "--add", "--cacheinfo", fields[0]+","+fields[2]+","+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
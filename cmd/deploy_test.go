@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/console"
+	"github.com/olimci/shizuka/internal/deploy"
+	"github.com/urfave/cli/v3"
+)
+
+type fakeGitRunner struct {
+	calls []string
+}
+
+func (f *fakeGitRunner) Git(_ context.Context, env []string, args ...string) (string, error) {
+	call := strings.Join(args, " ")
+	if len(env) > 0 {
+		call = "[index] " + call
+	}
+	f.calls = append(f.calls, call)
+
+	switch args[0] {
+	case "rev-parse":
+		if args[1] == "--absolute-git-dir" {
+			return "/repo/.git", nil
+		}
+		return "abc123", nil
+	case "write-tree":
+		return "tree1", nil
+	case "ls-tree":
+		return "index.html", nil
+	case "commit-tree":
+		return "commit1", nil
+	}
+	return "", nil
+}
+
+func TestDeployToBranchRunsGitSequence(t *testing.T) {
+	git := &fakeGitRunner{}
+	plan := deployPlan{Output: "/tmp/site", Branch: "gh-pages", Remote: "origin"}
+	if err := deployToBranch(context.Background(), git, plan, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"check-ref-format --branch gh-pages",
+		"rev-parse --absolute-git-dir",
+		"rev-parse --short HEAD",
+		"[index] --work-tree /tmp/site add --all -- :/",
+		"[index] write-tree",
+		"ls-tree --full-tree --name-only tree1",
+		"commit-tree tree1 -m Deploy site from abc123",
+		"update-ref refs/heads/gh-pages commit1",
+		"push --force origin refs/heads/gh-pages:refs/heads/gh-pages",
+	}
+	if strings.Join(git.calls, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("git calls =\n%s\nwant\n%s", strings.Join(git.calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDeployToBranchDryRunLeavesBranchAlone(t *testing.T) {
+	git := &fakeGitRunner{}
+	plan := deployPlan{Output: "/tmp/site", Branch: "gh-pages", Remote: "origin", Message: "ship", DryRun: true}
+	var out strings.Builder
+	if err := deployToBranch(context.Background(), git, plan, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, call := range git.calls {
+		if strings.HasPrefix(call, "update-ref") || strings.HasPrefix(call, "push") {
+			t.Fatalf("dry run ran %q", call)
+		}
+	}
+	if !strings.Contains(out.String(), "would force-push gh-pages to origin") {
+		t.Fatalf("output = %q, want dry-run push note", out.String())
+	}
+}
+
+// gitRepo creates a repository with one commit and a subdirectory, with git
+// isolated from the user's configuration.
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, "site", "content"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "site", "content", "index.md"), []byte("# Home\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "."},
+		{"commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	return repo
+}
+
+func writeOutput(t *testing.T, files map[string]string) string {
+	t.Helper()
+	out := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(out, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return out
+}
+
+func TestDeployToBranchFromSubdirectory(t *testing.T) {
+	repo := gitRepo(t)
+	t.Chdir(filepath.Join(repo, "site"))
+	git := execGitRunner{}
+	ctx := context.Background()
+
+	first := writeOutput(t, map[string]string{"index.html": "v1", "CNAME": "example.com"})
+	if err := deployToBranch(ctx, git, deployPlan{Output: first, Branch: "gh-pages"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	second := writeOutput(t, map[string]string{"index.html": "v2"})
+	if err := deployToBranch(ctx, git, deployPlan{Output: second, Branch: "gh-pages", Keep: []string{"CNAME"}}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := git.Git(ctx, nil, "ls-tree", "-r", "--full-tree", "--name-only", "gh-pages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != "CNAME\nindex.html" {
+		t.Fatalf("gh-pages files = %q, want CNAME and index.html", files)
+	}
+	if index, err := git.Git(ctx, nil, "show", "gh-pages:index.html"); err != nil || index != "v2" {
+		t.Fatalf("gh-pages:index.html = %q (%v), want v2", index, err)
+	}
+
+	before, err := git.Git(ctx, nil, "rev-parse", "gh-pages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = deployToBranch(ctx, git, deployPlan{Output: t.TempDir(), Branch: "gh-pages"}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "empty tree") {
+		t.Fatalf("deployToBranch(empty output) error = %v, want an empty tree refusal", err)
+	}
+	if after, _ := git.Git(ctx, nil, "rev-parse", "gh-pages"); after != before {
+		t.Fatalf("gh-pages moved from %s to %s on an empty deploy", before, after)
+	}
+}
+
+type fakeTarget struct {
+	opts []deploy.Options
+}
+
+func (f *fakeTarget) Name() string { return "fake" }
+
+func (f *fakeTarget) Deploy(_ context.Context, _ string, opts deploy.Options) (deploy.Result, error) {
+	f.opts = append(f.opts, opts)
+	return deploy.Result{}, nil
+}
+
+func TestDeployToTargetPassesDryRun(t *testing.T) {
+	// an empty stdin would answer a confirmation prompt with no
+	null, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	con := &console.Console{In: null, Out: null}
+	logger := slog.New(slog.DiscardHandler)
+
+	target := &fakeTarget{}
+	cmd := &cli.Command{
+		Name:  "deploy",
+		Flags: []cli.Flag{&cli.BoolFlag{Name: "dry-run"}, &cli.BoolFlag{Name: "yes"}},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return deployToTarget(ctx, con, cmd, logger, target, t.TempDir())
+		},
+	}
+	if err := cmd.Run(context.Background(), []string{"deploy", "--dry-run"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(target.opts) != 1 || !target.opts[0].DryRun {
+		t.Fatalf("deploys = %+v, want one dry run", target.opts)
+	}
+}
+
+func TestConfirmDeployDefaultsToNo(t *testing.T) {
+	plan := deployPlan{Branch: "gh-pages", Remote: "origin"}
+	for input, want := range map[string]bool{"y\n": true, "yes\n": true, "\n": false, "": false, "n\n": false} {
+		got, err := confirmDeploy(strings.NewReader(input), io.Discard, plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("confirmDeploy(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
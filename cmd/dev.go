@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/olimci/shizuka/internal/build"
@@ -26,6 +28,11 @@ var devCmd = &cli.Command{
 			Value:   defaultConfig,
 			Usage:   "Config file path",
 		},
+		&cli.StringFlag{
+			Name:  "host",
+			Value: defaultHost,
+			Usage: "Host to listen on; 0.0.0.0 listens on all interfaces",
+		},
 		&cli.IntFlag{
 			Name:    "port",
 			Aliases: []string{"p"},
@@ -40,15 +47,57 @@ var devCmd = &cli.Command{
 			Name:  "no-watch",
 			Usage: "Disable file watching",
 		},
+		&cli.BoolFlag{
+			Name:  "poll",
+			Usage: "Poll for file changes instead of using filesystem notifications, for network mounts and containers",
+		},
+		&cli.DurationFlag{
+			Name:  "poll-interval",
+			Value: time.Second,
+			Usage: "How often to poll for file changes with --poll",
+		},
 		&cli.BoolFlag{
 			Name:  "boring",
 			Usage: "Disable fancy terminal output",
 		},
+		&cli.BoolFlag{
+			Name:  "tls",
+			Usage: "Serve over HTTPS, with a self-signed localhost certificate unless --tls-cert and --tls-key are set",
+		},
+		&cli.StringFlag{
+			Name:  "tls-cert",
+			Usage: "TLS certificate file",
+		},
+		&cli.StringFlag{
+			Name:  "tls-key",
+			Usage: "TLS private key file",
+		},
+		&cli.BoolFlag{
+			Name:  "content-etags",
+			Usage: "Derive ETags from file content hashes instead of size and modification time",
+		},
+		&cli.StringSliceFlag{
+			Name:  "proxy",
+			Usage: "Proxy a path prefix to a backend, e.g. /api=http://localhost:3000; repeatable, longest prefix wins",
+		},
+		&cli.StringFlag{
+			Name:    "auth",
+			Usage:   "Require HTTP Basic credentials, as user:pass",
+			Sources: cli.EnvVars("SHIZUKA_AUTH"),
+		},
+		&cli.StringFlag{
+			Name:  "reload-transport",
+			Value: string(server.ReloadSSE),
+			Usage: "Live reload channel: sse, or ws for a WebSocket that falls back to sse",
+		},
 	},
 	Action: devAction,
 }
 
 func devAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Bool("dry-run") {
+		return errors.New("dev does not support --dry-run")
+	}
 	fancy := !cmd.Bool("boring")
 
 	con, err := console.Open(os.Stdin, os.Stdout, os.Stderr, console.Options{
@@ -75,17 +124,64 @@ func devAction(ctx context.Context, cmd *cli.Command) error {
 		options.WithConfigPath(cmd.String("config")),
 		options.WithLogger(logger),
 		options.If(options.WithMaxWorkers(cmd.Int("workers")), cmd.IsSet("workers")),
+		options.If(options.WithWriteWorkers(cmd.Int("write-workers")), cmd.IsSet("write-workers")),
 		options.If(options.WithDev(true), !cmd.Bool("undev")),
 	)
 
+	var tlsOptions *server.TLSOptions
+	if cmd.Bool("tls") || cmd.IsSet("tls-cert") || cmd.IsSet("tls-key") {
+		tlsOptions = &server.TLSOptions{
+			CertFile: cmd.String("tls-cert"),
+			KeyFile:  cmd.String("tls-key"),
+		}
+	}
+
+	var proxies []server.ProxyRule
+	for _, spec := range cmd.StringSlice("proxy") {
+		rule, err := server.ParseProxyRule(spec)
+		if err != nil {
+			logger.Error("dev server setup failed", "error", err)
+			return handled(err)
+		}
+		proxies = append(proxies, rule)
+	}
+
+	auth, err := authOption(cmd)
+	if err != nil {
+		logger.Error("dev server setup failed", "error", err)
+		return handled(err)
+	}
+
+	reloadTransport, err := server.ParseReloadTransport(cmd.String("reload-transport"))
+	if err != nil {
+		logger.Error("dev server setup failed", "error", err)
+		return handled(err)
+	}
+
+	var watchPoll time.Duration
+	if cmd.Bool("poll") {
+		watchPoll = cmd.Duration("poll-interval")
+		if watchPoll <= 0 {
+			err := errors.New("--poll-interval must be positive")
+			logger.Error("dev server setup failed", "error", err)
+			return handled(err)
+		}
+	}
+
 	srv, err := server.New(server.Options{
-		Addr:          fmt.Sprintf(":%d", cmd.Int("port")),
-		Watch:         !cmd.Bool("no-watch"),
-		WatchDebounce: 200 * time.Millisecond,
-		Reload:        true,
-		Logger:        logger,
-		BuildOptions:  buildOptions,
-		Build:         build.Build,
+		Addr:            net.JoinHostPort(cmd.String("host"), strconv.Itoa(cmd.Int("port"))),
+		Watch:           !cmd.Bool("no-watch"),
+		WatchDebounce:   200 * time.Millisecond,
+		WatchPoll:       watchPoll,
+		Reload:          true,
+		ReloadTransport: reloadTransport,
+		Logger:          logger,
+		TLS:             tlsOptions,
+		Proxies:         proxies,
+		ContentETags:    cmd.Bool("content-etags"),
+		Auth:            auth,
+		BuildOptions:    buildOptions,
+		Build:           build.BuildWithStats,
 	})
 	if err != nil {
 		logger.Error("dev server setup failed", "error", err)
@@ -186,7 +282,7 @@ func logServerEvent(con *console.Console, logger *slog.Logger, ev server.Event,
 		}
 		logger.Info("building", "reason", ev.Reason)
 	case server.EventBuildSucceeded:
-		logger.Info("build complete", "reason", ev.Reason, "duration", ev.Duration)
+		logger.Info("build complete", "reason", ev.Reason, "duration", ev.Duration, "summary", ev.Stats.Summary())
 		printReady(con, ev.URL)
 	case server.EventBuildFailed:
 		logger.Error("build failed", "reason", ev.Reason, "duration", ev.Duration, "error", ev.Err)
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/urfave/cli/v3"
+)
+
+var doctorCmd = &cli.Command{
+	Name:  "doctor",
+	Usage: "Check the environment and site config for problems",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"c"},
+			Value:   defaultConfig,
+			Usage:   "Config file path",
+		},
+	},
+	Action: doctorAction,
+}
+
+type doctorStatus string
+
+const (
+	doctorOK   doctorStatus = "ok"
+	doctorWarn doctorStatus = "warn"
+	doctorFail doctorStatus = "fail"
+)
+
+type doctorCheck struct {
+	Name   string
+	Status doctorStatus
+	Detail string
+	Fix    string
+}
+
+func doctorAction(_ context.Context, cmd *cli.Command) error {
+	checks := runDoctorChecks(cmd.String("config"))
+	failures := printDoctorReport(os.Stdout, checks)
+	if failures > 0 {
+		return handled(fmt.Errorf("doctor found %d problem(s)", failures))
+	}
+	return nil
+}
+
+func runDoctorChecks(configPath string) []doctorCheck {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fix := "fix the reported config error"
+		if errors.Is(err, fs.ErrNotExist) {
+			fix = "create a config file or pass --config"
+		}
+		return []doctorCheck{{Name: "config", Status: doctorFail, Detail: err.Error(), Fix: fix}}
+	}
+
+	checks := []doctorCheck{{Name: "config", Status: doctorOK, Detail: configPath}}
+	for _, content := range cfg.ContentPaths() {
+		checks = append(checks, doctorDirCheck(cfg, "content", content, true))
+	}
+	checks = append(checks,
+		doctorDirCheck(cfg, "templates", cfg.Paths.Templates, true),
+		doctorDirCheck(cfg, "static", cfg.Paths.Static, false),
+		doctorDirCheck(cfg, "data", cfg.Paths.Data, false),
+	)
+
+	if cfg.Content.Git != nil {
+		if gitPath, err := exec.LookPath("git"); err != nil {
+			checks = append(checks, doctorCheck{
+				Name:   "git",
+				Status: doctorWarn,
+				Detail: "git executable not found; content.git metadata will be skipped",
+				Fix:    "install git or remove content.git from the config",
+			})
+		} else {
+			checks = append(checks, doctorCheck{Name: "git", Status: doctorOK, Detail: gitPath})
+		}
+	}
+
+	return checks
+}
+
+func doctorDirCheck(cfg *config.Config, name, rel string, required bool) doctorCheck {
+	dir := filepath.Join(cfg.Root, filepath.FromSlash(rel))
+	info, err := os.Stat(dir)
+	switch {
+	case err == nil && info.IsDir():
+		return doctorCheck{Name: name, Status: doctorOK, Detail: dir}
+	case err == nil:
+		return doctorCheck{
+			Name:   name,
+			Status: doctorFail,
+			Detail: fmt.Sprintf("%s is not a directory", dir),
+			Fix:    fmt.Sprintf("replace it with a directory or change paths.%s", name),
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return doctorCheck{Name: name, Status: doctorFail, Detail: err.Error(), Fix: "check the directory permissions"}
+	case required:
+		return doctorCheck{
+			Name:   name,
+			Status: doctorFail,
+			Detail: fmt.Sprintf("%s does not exist", dir),
+			Fix:    fmt.Sprintf("create %s or change paths.%s", dir, name),
+		}
+	default:
+		return doctorCheck{Name: name, Status: doctorOK, Detail: fmt.Sprintf("%s does not exist (optional)", dir)}
+	}
+}
+
+func printDoctorReport(w io.Writer, checks []doctorCheck) int {
+	failures := 0
+	for _, check := range checks {
+		fmt.Fprintf(w, "%-4s  %-9s  %s\n", check.Status, check.Name, check.Detail)
+		if check.Fix != "" {
+			fmt.Fprintf(w, "%-4s  %-9s  fix: %s\n", "", "", check.Fix)
+		}
+		if check.Status == doctorFail {
+			failures++
+		}
+	}
+	return failures
+}
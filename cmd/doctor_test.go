@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDoctorFlagsMissingContentDir(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "shizuka.jsonc")
+	if err := os.WriteFile(configPath, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "templates"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := runDoctorChecks(configPath)
+
+	var content *doctorCheck
+	for i := range checks {
+		if checks[i].Name == "content" {
+			content = &checks[i]
+		}
+		if checks[i].Name == "templates" && checks[i].Status != doctorOK {
+			t.Fatalf("templates check = %#v, want ok", checks[i])
+		}
+	}
+	if content == nil {
+		t.Fatalf("checks = %#v, want a content check", checks)
+	}
+	if content.Status != doctorFail || content.Fix == "" {
+		t.Fatalf("content check = %#v, want failure with a fix", content)
+	}
+}
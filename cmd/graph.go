@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/olimci/shizuka/internal/build"
+	"github.com/olimci/shizuka/internal/options"
+	"github.com/urfave/cli/v3"
+)
+
+var graphCmd = &cli.Command{
+	Name:  "graph",
+	Usage: "Print the build step graph as Graphviz DOT or Mermaid",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"c"},
+			Value:   defaultConfig,
+			Usage:   "Config file path",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Value: build.GraphDOT,
+			Usage: "Output format: dot or mermaid",
+		},
+		&cli.StringFlag{
+			Name:  "out",
+			Usage: "Write the graph to this file instead of stdout",
+		},
+		&cli.BoolFlag{
+			Name:  "check",
+			Usage: "Include the steps `shizuka check` adds",
+		},
+	},
+	Action: graphAction,
+}
+
+func graphAction(_ context.Context, cmd *cli.Command) error {
+	graph, err := build.Graph(
+		options.WithConfigPath(cmd.String("config")),
+		options.WithCheck(cmd.Bool("check")),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "config:", err)
+		return handled(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if cmd.IsSet("out") {
+		f, err := os.Create(cmd.String("out"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "graph:", err)
+			return handled(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := build.ExportGraph(w, graph, cmd.String("format")); err != nil {
+		fmt.Fprintln(os.Stderr, "graph:", err)
+		return handled(err)
+	}
+
+	// The graph is still written when it would not run, to show why.
+	if err := graph.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "graph:", err)
+		return handled(err)
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/transforms"
+	"github.com/olimci/shizuka/internal/utils/pathutil"
+	"github.com/urfave/cli/v3"
+)
+
+// defaultArchetype is used when the site has no archetype for the section.
+const defaultArchetype = `---
+title: {{ printf "%q" .Title }}
+created: {{ .Date }}
+draft: true
+---
+`
+
+var newCmd = &cli.Command{
+	Name:      "new",
+	Usage:     "Create a content file from an archetype",
+	ArgsUsage: "<section>/<slug>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"c"},
+			Value:   defaultConfig,
+			Usage:   "Config file path",
+		},
+		&cli.StringFlag{
+			Name:  "title",
+			Usage: "Page title; derived from the slug when unset",
+		},
+		&cli.StringFlag{
+			Name:  "date",
+			Usage: "Creation date as YYYY-MM-DD or RFC 3339; today when unset",
+		},
+		&cli.BoolFlag{
+			Name:  "edit",
+			Usage: "Open the new file in $VISUAL or $EDITOR",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "Overwrite an existing file",
+		},
+		&cli.StringFlag{
+			Name:  "source",
+			Usage: "Name of the content source to create the file in; the first source when unset",
+		},
+	},
+	Action: newAction,
+}
+
+// archetypeData is the data an archetype template is executed with.
+type archetypeData struct {
+	Title   string
+	Slug    string
+	Section string
+	Date    string
+	Time    time.Time
+}
+
+type newContentRequest struct {
+	Path   string
+	Source string
+	Title  string
+	Date   time.Time
+	Force  bool
+}
+
+func newAction(_ context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		err := errors.New("new takes one <section>/<slug> argument")
+		fmt.Fprintln(os.Stderr, err)
+		return handled(err)
+	}
+
+	cfg, err := config.Load(cmd.String("config"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "config:", err)
+		return handled(err)
+	}
+
+	date := time.Now()
+	if cmd.IsSet("date") {
+		date, err = parseNewDate(cmd.String("date"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return handled(err)
+		}
+	}
+
+	req := newContentRequest{
+		Path:   cmd.Args().First(),
+		Source: cmd.String("source"),
+		Title:  cmd.String("title"),
+		Date:   date,
+		Force:  cmd.Bool("force"),
+	}
+	if cmd.Bool("dry-run") {
+		file, data, err := planContent(cfg, req)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "new:", err)
+			return handled(err)
+		}
+		fmt.Fprintf(os.Stdout, "dry run: would create %s\n%s", file, data)
+		return nil
+	}
+
+	file, err := newContent(cfg, req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "new:", err)
+		return handled(err)
+	}
+	fmt.Fprintln(os.Stdout, "created", file)
+
+	if cmd.Bool("edit") {
+		if err := openEditor(file); err != nil {
+			fmt.Fprintln(os.Stderr, "edit:", err)
+			return handled(err)
+		}
+	}
+	return nil
+}
+
+// newContent writes the content file named by req.Path from the archetype for
+// its section. The path is relative to the content source named by
+// req.Source, and .md is added when it has no extension. It returns the path
+// of the new file.
+func newContent(cfg *config.Config, req newContentRequest) (string, error) {
+	file, data, err := planContent(cfg, req)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if req.Force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(file, flags, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return "", existsError(file)
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	return file, f.Close()
+}
+
+// planContent returns the path and content newContent would write for req
+// without touching the content directory. Like newContent, it refuses a file
+// that exists unless req.Force is set.
+func planContent(cfg *config.Config, req newContentRequest) (string, []byte, error) {
+	source, err := contentSource(cfg, req.Source)
+	if err != nil {
+		return "", nil, err
+	}
+	rel := strings.Trim(filepath.ToSlash(req.Path), "/")
+	rel, err = pathutil.CleanContentPath(rel)
+	if err != nil || rel == "." {
+		return "", nil, fmt.Errorf("invalid content path %q", req.Path)
+	}
+	if path.Ext(rel) == "" {
+		rel += ".md"
+	}
+
+	section := path.Dir(rel)
+	if section == "." {
+		section = ""
+	}
+	slug := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	title := req.Title
+	if title == "" {
+		title = transforms.TitleFromPath(rel)
+	}
+
+	archetype, err := loadArchetype(cfg, section, path.Ext(rel))
+	if err != nil {
+		return "", nil, err
+	}
+	tmpl, err := template.New("archetype").Parse(archetype)
+	if err != nil {
+		return "", nil, fmt.Errorf("archetype: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, archetypeData{
+		Title:   title,
+		Slug:    slug,
+		Section: section,
+		Date:    req.Date.Format(time.DateOnly),
+		Time:    req.Date,
+	}); err != nil {
+		return "", nil, fmt.Errorf("archetype: %w", err)
+	}
+
+	file := filepath.Join(cfg.Root, filepath.FromSlash(source.Path), filepath.FromSlash(rel))
+	if !req.Force {
+		if _, err := os.Stat(file); err == nil {
+			return "", nil, existsError(file)
+		}
+	}
+	return file, buf.Bytes(), nil
+}
+
+// contentSource returns the content source called name, or the first source
+// when name is empty.
+func contentSource(cfg *config.Config, name string) (config.ConfigContentSource, error) {
+	sources := cfg.ContentSources()
+	if name == "" {
+		return sources[0], nil
+	}
+	for _, source := range sources {
+		if source.Name == name {
+			return source, nil
+		}
+	}
+	return config.ConfigContentSource{}, fmt.Errorf("no content source named %q", name)
+}
+
+func existsError(file string) error {
+	return fmt.Errorf("%s already exists; pass --force to overwrite it", file)
+}
+
+// loadArchetype returns the archetype for section with extension ext: the
+// nearest of archetypes/<section><ext> and those of its parent sections,
+// then archetypes/default<ext>, then the built-in default.
+func loadArchetype(cfg *config.Config, section, ext string) (string, error) {
+	dir := filepath.Join(cfg.Root, filepath.FromSlash(cfg.Paths.Archetypes))
+	var names []string
+	for s := section; s != "" && s != "."; s = path.Dir(s) {
+		names = append(names, s+ext)
+	}
+	names = append(names, "default"+ext)
+
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("archetype: %w", err)
+		}
+		return string(data), nil
+	}
+	return defaultArchetype, nil
+}
+
+func parseNewDate(raw string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, raw, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--date %q: want YYYY-MM-DD or RFC 3339", raw)
+	}
+	return t, nil
+}
+
+func openEditor(file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return errors.New("set $VISUAL or $EDITOR to open the new file")
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], file)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+func TestNewContentUsesNearestArchetype(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "shizuka.jsonc")
+	if err := os.WriteFile(configPath, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "archetypes"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	archetype := "---\ntitle: {{ .Title }}\nsection: {{ .Section }}\nslug: {{ .Slug }}\ncreated: {{ .Date }}\n---\n"
+	if err := os.WriteFile(filepath.Join(root, "archetypes", "posts.md"), []byte(archetype), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)
+
+	file, err := newContent(cfg, newContentRequest{Path: "posts/2024/first-post", Date: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "content", "posts", "2024", "first-post.md"); file != want {
+		t.Fatalf("file = %q, want %q", file, want)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "---\ntitle: First post\nsection: posts/2024\nslug: first-post\ncreated: 2024-03-09\n---\n"
+	if string(data) != want {
+		t.Fatalf("content = %q, want %q", data, want)
+	}
+
+	if _, err := newContent(cfg, newContentRequest{Path: "posts/2024/first-post", Date: date}); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("err = %v, want refusal to overwrite", err)
+	}
+
+	file, err = newContent(cfg, newContentRequest{Path: "notes/idea", Title: "An idea", Date: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\ntitle: \"An idea\"\ncreated: 2024-03-09\ndraft: true\n---\n"; string(data) != want {
+		t.Fatalf("content = %q, want %q", data, want)
+	}
+
+	if _, err := newContent(cfg, newContentRequest{Path: "../escape", Date: date}); err == nil {
+		t.Fatal("want error for a path outside the content directory")
+	}
+}
+
+func TestNewDryRunWritesNothing(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "shizuka.jsonc")
+	if err := os.WriteFile(configPath, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runCLI(t, []string{"shizuka", "--dry-run", "new", "--config", configPath, "--date", "2024-03-09", "posts/first-post"})
+
+	if _, err := os.Stat(filepath.Join(root, "content")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("content stat error = %v, want not exist", err)
+	}
+}
+
+func TestNewContentUsesConfiguredSources(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "shizuka.jsonc")
+	sources := `{"content": {"sources": [{"path": "blog"}, {"name": "docs", "path": "vendor/docs", "url_prefix": "docs"}, {"path": "../manual"}]}}`
+	if err := os.WriteFile(configPath, []byte(sources), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)
+
+	for source, want := range map[string]string{
+		"":       filepath.Join(root, "blog", "hello.md"),
+		"docs":   filepath.Join(root, "vendor", "docs", "hello.md"),
+		"manual": filepath.Join(filepath.Dir(root), "manual", "hello.md"),
+	} {
+		file, err := newContent(cfg, newContentRequest{Path: "hello", Source: source, Date: date})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file != want {
+			t.Fatalf("source %q: file = %q, want %q", source, file, want)
+		}
+	}
+
+	if _, err := newContent(cfg, newContentRequest{Path: "hello", Source: "nope", Date: date}); err == nil {
+		t.Fatal("want error for an unknown source")
+	}
+}
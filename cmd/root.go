@@ -17,6 +17,7 @@ const (
 	defaultConfig = "shizuka.jsonc"
 	defaultOutput = "dist"
 	defaultPort   = 6767
+	defaultHost   = "localhost"
 )
 
 func Execute(ctx context.Context, args []string) error {
@@ -35,10 +36,18 @@ func newRootCommand() *cli.Command {
 			},
 			&cli.StringFlag{
 				Name:      "format",
+				Aliases:   []string{"log-format"},
 				Value:     "auto",
 				Usage:     "Output format: auto, plain, pretty, or json",
 				Validator: logging.ValidateFormat,
 			},
+			// Shared by every command that writes files: build reports the
+			// files it would write and remove, and deploy builds and reports
+			// what it would publish.
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Report intended changes without writing or publishing anything",
+			},
 			&cli.IntFlag{
 				Name:    "workers",
 				Aliases: []string{"w"},
@@ -51,10 +60,27 @@ func newRootCommand() *cli.Command {
 					return nil
 				},
 			},
+			&cli.IntFlag{
+				Name:  "write-workers",
+				Usage: "Maximum number of output files written at once (default: --workers)",
+				Validator: func(workers int) error {
+					if workers <= 0 {
+						return errors.New("write-workers must be greater than zero")
+					}
+					return nil
+				},
+			},
 		},
 		Commands: []*cli.Command{
 			buildCmd,
 			devCmd,
+			serveCmd,
+			newCmd,
+			checkCmd,
+			graphCmd,
+			doctorCmd,
+			deployCmd,
+			templatesCmd,
 		},
 		Version: version.Current().String(),
 	}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/olimci/shizuka/internal/console"
+	"github.com/olimci/shizuka/internal/server"
+	"github.com/urfave/cli/v3"
+)
+
+// serveShutdownTimeout bounds how long serve waits for open requests after an
+// interrupt before closing their connections.
+const serveShutdownTimeout = 5 * time.Second
+
+var serveCmd = &cli.Command{
+	Name:  "serve",
+	Usage: "Serve a built site as a static host would",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "dist",
+			Value: defaultOutput,
+			Usage: "Built site directory",
+		},
+		&cli.StringFlag{
+			Name:  "host",
+			Value: defaultHost,
+			Usage: "Host to listen on; 0.0.0.0 listens on all interfaces",
+		},
+		&cli.IntFlag{
+			Name:    "port",
+			Aliases: []string{"p"},
+			Value:   defaultPort,
+			Usage:   "Port to listen on",
+		},
+		&cli.StringFlag{
+			Name:  "headers-file",
+			Value: "_headers",
+			Usage: "Headers file in the built site",
+		},
+		&cli.StringFlag{
+			Name:  "redirects-file",
+			Value: "_redirects",
+			Usage: "Redirects file in the built site",
+		},
+		&cli.StringFlag{
+			Name:  "index-file",
+			Value: "index.html",
+			Usage: "File served for directory requests",
+		},
+		&cli.StringFlag{
+			Name:    "auth",
+			Usage:   "Require HTTP Basic credentials, as user:pass",
+			Sources: cli.EnvVars("SHIZUKA_AUTH"),
+		},
+	},
+	Action: serveAction,
+}
+
+func serveAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Bool("dry-run") {
+		return errors.New("serve does not support --dry-run")
+	}
+	con, err := console.Open(os.Stdin, os.Stdout, os.Stderr, console.Options{
+		CleanupSignals: true,
+		Context:        ctx,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "console setup failed:", err)
+		return handled(err)
+	}
+	defer con.Close()
+	ctx = con.Context()
+
+	logger, err := makeLogger(con, cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logger setup failed:", err)
+		return handled(err)
+	}
+
+	dist := cmd.String("dist")
+	if info, err := os.Stat(dist); err != nil || !info.IsDir() {
+		if err == nil {
+			err = fmt.Errorf("%q is not a directory", dist)
+		}
+		logger.Error("serve setup failed", "error", err, "hint", "run shizuka build first, or pass --dist")
+		return handled(err)
+	}
+
+	auth, err := authOption(cmd)
+	if err != nil {
+		logger.Error("serve setup failed", "error", err)
+		return handled(err)
+	}
+
+	addr := net.JoinHostPort(cmd.String("host"), strconv.Itoa(cmd.Int("port")))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Error("serve setup failed", "error", err)
+		return handled(err)
+	}
+
+	// No reload script, no proxies: the handler applies the built headers,
+	// redirects and 404 page the way a static host does.
+	var handler http.Handler = server.NewStaticHandler(dist, server.StaticOptions{
+		HeadersFile:   cmd.String("headers-file"),
+		RedirectsFile: cmd.String("redirects-file"),
+		IndexFile:     cmd.String("index-file"),
+	})
+	if auth != nil {
+		handler = server.BasicAuthMiddleware(*auth, handler)
+	}
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(listener)
+	}()
+	logger.Info("serving", "dist", dist, "url", "http://"+listener.Addr().String()+"/")
+
+	select {
+	case err := <-served:
+		logger.Error("server failed", "error", err)
+		return handled(err)
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("shutdown failed", "error", err)
+		return handled(err)
+	}
+	if err := <-served; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("server failed", "error", err)
+		return handled(err)
+	}
+	return nil
+}
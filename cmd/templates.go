@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/olimci/shizuka/internal/build"
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/urfave/cli/v3"
+)
+
+var templatesCmd = &cli.Command{
+	Name:  "templates",
+	Usage: "Work with site templates",
+	Commands: []*cli.Command{
+		{
+			Name:  "lint",
+			Usage: "Parse templates and execute page templates against a sample page",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "config",
+					Aliases: []string{"c"},
+					Value:   defaultConfig,
+					Usage:   "Config file path",
+				},
+			},
+			Action: templatesLintAction,
+		},
+	},
+}
+
+func templatesLintAction(_ context.Context, cmd *cli.Command) error {
+	cfg, err := config.Load(cmd.String("config"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "config:", err)
+		return handled(err)
+	}
+
+	issues, err := build.LintTemplates(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "templates:", err)
+		return handled(err)
+	}
+	for _, issue := range issues {
+		fmt.Fprintln(os.Stdout, issue.Error())
+	}
+	if len(issues) > 0 {
+		return handled(fmt.Errorf("found %d template problem(s)", len(issues)))
+	}
+	fmt.Fprintln(os.Stdout, "templates ok")
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/olimci/shizuka/internal/console"
 	"github.com/olimci/shizuka/internal/logging"
+	"github.com/olimci/shizuka/internal/server"
 	"github.com/urfave/cli/v3"
 )
 
@@ -39,3 +40,17 @@ func makeLogger(con *console.Console, cmd *cli.Command) (*slog.Logger, error) {
 		TimeFormat: time.Kitchen,
 	})), nil
 }
+
+// authOption returns the credentials set with --auth or SHIZUKA_AUTH, or nil
+// when neither is set.
+func authOption(cmd *cli.Command) (*server.BasicAuth, error) {
+	spec := cmd.String("auth")
+	if spec == "" {
+		return nil, nil
+	}
+	auth, err := server.ParseBasicAuth(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &auth, nil
+}
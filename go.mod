@@ -21,7 +21,7 @@ require github.com/dlclark/regexp2/v2 v2.1.1 // indirect
 require (
 	github.com/felixge/httpsnoop v1.0.4
 	github.com/olimci/roundtrip v0.0.0-20260522151306-51e57bd6b51f
-	github.com/tdewolff/parse/v2 v2.8.12 // indirect
+	github.com/tdewolff/parse/v2 v2.8.12
 	golang.org/x/sys v0.45.0
 	golang.org/x/term v0.43.0
 )
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/olimci/shizuka/internal/config"
@@ -22,24 +24,87 @@ var (
 
 type BuildCtx struct {
 	StartTime time.Time
+	// BuildTime is the time written into the output, such as the site's
+	// build time and the date of undated pages. It is StartTime, except in
+	// deterministic builds.
+	BuildTime time.Time
 	Dev       bool
+	// Scope is what the changes behind a cached rebuild invalidate. Builds
+	// without a cache are always ScopeFull.
+	Scope ChangeScope
 }
 
-func Build(opt ...options.Option) (err error) {
+// deterministicBuildTime returns the build time of a deterministic build:
+// SOURCE_DATE_EPOCH when it is set, and the Unix epoch otherwise.
+func deterministicBuildTime() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+	secs, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("SOURCE_DATE_EPOCH %q: want seconds since the Unix epoch", epoch)
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
+
+// Build builds the site described by the options.
+func Build(opt ...options.Option) error {
+	_, err := BuildWithStats(opt...)
+	return err
+}
+
+// BuildWithStats builds the site like Build and reports what the build did.
+// The returned stats are nil if the build fails before the graph runs.
+func BuildWithStats(opt ...options.Option) (*BuildStats, error) {
 	opts := options.DefaultOptions().Apply(opt...)
 	logger := buildLogger(opts.Logger)
 	dagLogger := componentLogger(opts.Logger, "dag")
 
-	cfg, err := config.Load(opts.ConfigPath)
+	if err := checkTemplateFuncs(opts.TemplateFuncs); err != nil {
+		return nil, err
+	}
+
+	cfg, err := loadConfig(opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	logger.Debug("config loaded", "path", opts.ConfigPath, "root", cfg.Root)
 
+	graph := buildGraph(cfg, opts)
+	dagLogger.Debug("build graph assembled", "nodes", graph.Len())
+
+	return build(graph, cfg, opts)
+}
+
+// Graph returns the step graph a build with the options would run, without
+// running it.
+func Graph(opt ...options.Option) (*dag.Graph[Step], error) {
+	opts := options.DefaultOptions().Apply(opt...)
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return buildGraph(cfg, opts), nil
+}
+
+// loadConfig loads the config at opts.ConfigPath and applies the options
+// that override it.
+func loadConfig(opts *options.Options) (*config.Config, error) {
+	cfg, err := config.Load(opts.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
 	if opts.SiteURL != "" {
 		cfg.Site.URL = opts.SiteURL
 	}
+	if opts.MaxImageSize > 0 {
+		cfg.Build.ImageSize = &config.ConfigImageSize{Max: opts.MaxImageSize}
+	}
+	return cfg, nil
+}
 
+func buildGraph(cfg *config.Config, opts *options.Options) *dag.Graph[Step] {
 	graph := dag.New[Step]()
 	staticStep := StepStatic(cfg)
 	_ = graph.Add(staticStep.ID, staticStep.Deps, staticStep)
@@ -47,33 +112,58 @@ func Build(opt ...options.Option) (err error) {
 		_ = graph.Add(step.ID, step.Deps, step)
 	}
 
+	for _, patch := range stepPatches(cfg, opts) {
+		applyStepPatch(graph, patch)
+	}
+	return graph
+}
+
+// stepPatches returns the optional steps enabled by cfg and opts.
+func stepPatches(cfg *config.Config, opts *options.Options) []StepPatch {
+	var patches []StepPatch
 	if cfg.Content.Git != nil {
-		applyStepPatch(graph, StepGit(cfg))
+		patches = append(patches, StepGit(cfg))
+	}
+	if cfg.Content.Related != nil {
+		patches = append(patches, StepRelated(cfg))
+	}
+	if cfg.Build.Images != nil {
+		patches = append(patches, StepImages(cfg))
+	}
+	if cfg.Content.Markdown.Highlighting != nil {
+		patches = append(patches, StepHighlight(cfg))
 	}
 	if cfg.Artefacts.Headers != nil {
-		applyStepPatch(graph, StepHeaders(cfg))
+		patches = append(patches, StepHeaders(cfg))
 	}
 	if cfg.Artefacts.Redirects != nil {
-		applyStepPatch(graph, StepRedirects(cfg))
+		patches = append(patches, StepRedirects(cfg))
 	}
 	if cfg.Artefacts.RSS != nil {
-		applyStepPatch(graph, StepRSS(cfg))
+		patches = append(patches, StepRSS(cfg))
+	}
+	if cfg.Artefacts.JSONFeed != nil {
+		patches = append(patches, StepJSONFeed(cfg))
+	}
+	if cfg.Artefacts.Atom != nil {
+		patches = append(patches, StepAtom(cfg))
 	}
 	if cfg.Artefacts.Sitemap != nil {
-		applyStepPatch(graph, StepSitemap(cfg))
+		patches = append(patches, StepSitemap(cfg))
 	}
 	if cfg.Artefacts.Robots != nil {
-		applyStepPatch(graph, StepRobots(cfg))
+		patches = append(patches, StepRobots(cfg))
 	}
 	if cfg.Artefacts.NotFound != nil {
-		applyStepPatch(graph, StepNotFound(cfg))
+		patches = append(patches, StepNotFound(cfg))
 	}
 	if cfg.Artefacts.Meta != nil {
-		applyStepPatch(graph, StepMeta(cfg))
+		patches = append(patches, StepMeta(cfg))
 	}
-	dagLogger.Debug("build graph assembled", "nodes", graph.Len())
-
-	return build(graph, cfg, opts)
+	if opts.Check {
+		patches = append(patches, StepLint(cfg, opts))
+	}
+	return patches
 }
 
 func applyStepPatch(graph *dag.Graph[Step], patch StepPatch) {
@@ -85,7 +175,7 @@ func applyStepPatch(graph *dag.Graph[Step], patch StepPatch) {
 	}
 }
 
-func build(graph *dag.Graph[Step], cfg *config.Config, options *options.Options) error {
+func build(graph *dag.Graph[Step], cfg *config.Config, options *options.Options) (*BuildStats, error) {
 	startTime := time.Now()
 	logger := buildLogger(options.Logger)
 	dagLogger := componentLogger(options.Logger, "dag")
@@ -97,7 +187,7 @@ func build(graph *dag.Graph[Step], cfg *config.Config, options *options.Options)
 
 	source, err := os.OpenRoot(cfg.Root)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer source.Close()
 
@@ -105,30 +195,65 @@ func build(graph *dag.Graph[Step], cfg *config.Config, options *options.Options)
 	reg := registry.New()
 	cacheReg := options.CacheRegistry
 
+	scope := ScopeFull
 	if cacheReg != nil {
 		registry.Set(cacheReg, ChangedPathsK, options.ChangedPaths)
 		defer registry.Delete(cacheReg, ChangedPathsK)
+		scope = ClassifyChanges(cfg, options.ConfigPath, options.ChangedPaths)
+		logger.Debug("rebuild scope", "scope", scope, "changed_paths", len(options.ChangedPaths))
+		// A full rebuild may fail before pages:templates replaces the parse,
+		// so drop it now rather than let a later, narrower rebuild reuse it.
+		if tmplCache, _ := registry.GetOk(cacheReg, TemplateCacheK); scope == ScopeFull && tmplCache != nil {
+			tmplCache.Parsed = nil
+		}
 	}
 
+	buildTime := startTime
+	if options.Deterministic {
+		buildTime, err = deterministicBuildTime()
+		if err != nil {
+			return nil, err
+		}
+	}
 	registry.Set(reg, BuildCtxK, &BuildCtx{
 		StartTime: startTime,
+		BuildTime: buildTime,
 		Dev:       options.Dev,
+		Scope:     scope,
 	})
 
 	ctx, cancel := context.WithCancel(options.Context)
 	defer cancel()
 
-	var buildErrors = new(errorState)
+	var buildErrors = &errorState{sorted: options.Deterministic}
 	if err := man.Start(ctx, cfg, options, buildErrors.Add, ""); err != nil {
-		return err
+		return nil, err
 	}
+	collector := newStatsCollector()
 
 	manifestLogger.Info("manifest started")
 	pool := pool.New(ctx, options.MaxWorkers)
 	poolLogger.Info("worker pool started", "workers", options.MaxWorkers)
 
-	dagLogger.Info("executing graph", "nodes", graph.Len(), "workers", options.MaxWorkers)
-	runErr := graph.Run(ctx, options.MaxWorkers, func(ctx context.Context, step Step) error {
+	// Step deadlines also bound the pool jobs a step submits, which may
+	// outlive the step, so they are only released once the build is done.
+	var (
+		timeoutsMu sync.Mutex
+		timeouts   []context.CancelFunc
+	)
+	defer func() {
+		for _, cancel := range timeouts {
+			cancel()
+		}
+	}()
+
+	// One step at a time keeps a deterministic build's logs in graph order.
+	stepWorkers := options.MaxWorkers
+	if options.Deterministic {
+		stepWorkers = 1
+	}
+	dagLogger.Info("executing graph", "nodes", graph.Len(), "workers", stepWorkers)
+	runErr := graph.Run(ctx, stepWorkers, func(ctx context.Context, step Step) error {
 		stepStart := time.Now()
 		stepLogger := logger.With("component", "step", "step", step.ID)
 		stepLogger.Info("step running")
@@ -144,19 +269,36 @@ func build(graph *dag.Graph[Step], cfg *config.Config, options *options.Options)
 			stepCache = scopedCache
 		}
 
+		stepCtx, stepPool := ctx, pool
+		if options.StepTimeout > 0 {
+			var cancelStep context.CancelFunc
+			stepCtx, cancelStep = context.WithTimeout(ctx, options.StepTimeout)
+			timeoutsMu.Lock()
+			timeouts = append(timeouts, cancelStep)
+			timeoutsMu.Unlock()
+			stepPool = pool.WithScope(stepCtx)
+		}
+
 		sc := StepContext{
-			Manifest: man,
-			Pool:     pool,
+			Manifest: collector.manifest(step.ID, man),
+			Pool:     stepPool,
 			Registry: stepRegistry,
 			Cache:    stepCache,
 			Logger:   stepLogger,
 			Source:   source,
+			step:     step.ID,
 			errors:   buildErrors,
+			strict:   options.Strict,
 		}
 
-		err := step.Fn(ctx, &sc)
+		err := step.Fn(stepCtx, &sc)
 		dur := time.Since(stepStart).Truncate(time.Microsecond)
+		collector.stepDone(step.ID, stepStart.Sub(startTime), dur)
 		if err != nil {
+			if ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+				stepLogger.Debug("step timed out", "duration", dur, "error", err)
+				return fmt.Errorf("%w (%s): timed out after %s: %w", ErrTaskError, step.ID, options.StepTimeout, context.DeadlineExceeded)
+			}
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 				stepLogger.Debug("step canceled", "duration", dur, "error", err)
 				return nil
@@ -172,7 +314,7 @@ func build(graph *dag.Graph[Step], cfg *config.Config, options *options.Options)
 		_ = pool.Wait()
 		_ = man.Finish(false)
 		logger.Debug("build failed", "duration", time.Since(startTime).Truncate(time.Microsecond), "error", runErr)
-		return runErr
+		return collector.finish(startTime, man.Stats()), runErr
 	}
 	dagLogger.Debug("graph complete", "duration", time.Since(startTime).Truncate(time.Microsecond))
 
@@ -181,26 +323,48 @@ func build(graph *dag.Graph[Step], cfg *config.Config, options *options.Options)
 		cancel()
 		_ = man.Finish(false)
 		logger.Debug("build failed", "duration", time.Since(startTime).Truncate(time.Microsecond), "error", workerErr)
-		return workerErr
+		return collector.finish(startTime, man.Stats()), workerErr
 	}
 	poolLogger.Debug("worker pool drained")
 
+	// Links are checked against every claimed artefact, but before the
+	// manifest commits them, so that a broken link keeps a failing build out
+	// of the output.
+	if cfg.Build.Links != nil || options.Check {
+		_ = man.Drain()
+		links := cfg.Build.Links
+		if links == nil {
+			links = &config.ConfigLinks{}
+		}
+		pages, _ := registry.GetOk(reg, PagesK)
+		checkLinks(pages, man, cfg, links, buildErrors, logger, options.Strict)
+	}
+
 	manifestSuccess := !buildErrors.HasErrors() || options.Dev
+	finishStart := time.Now()
 	manifestErr := man.Finish(manifestSuccess)
-	manifestLogger.Info("manifest complete", "success", manifestSuccess)
+	collector.manifestDone(finishStart.Sub(startTime), time.Since(finishStart))
+	stats := collector.finish(startTime, man.Stats())
+	manifestLogger.Info("manifest complete", "success", manifestSuccess, "written", stats.FilesWritten, "skipped", stats.FilesSkipped, "removed", stats.FilesRemoved, "bytes", stats.BytesWritten)
 	if manifestErr != nil {
 		if buildErrors.HasErrors() {
-			return &Failure{Errors: buildErrors.Slice()}
+			return stats, &Failure{Errors: buildErrors.Slice()}
+		}
+		return stats, manifestErr
+	}
+
+	if cfg.Build.Orphans != nil && manifestSuccess && !options.DryRun {
+		if err := checkOrphans(man, cfg, buildErrors, logger, options.Strict); err != nil {
+			return stats, err
 		}
-		return manifestErr
 	}
 
 	if buildErrors.HasErrors() {
 		failure := &Failure{Errors: buildErrors.Slice()}
 		logger.Debug("build failed", "duration", time.Since(startTime).Truncate(time.Microsecond), "errors", len(failure.Errors))
-		return failure
+		return stats, failure
 	}
 
-	logger.Info("build complete", "duration", time.Since(startTime).Truncate(time.Microsecond))
-	return nil
+	logger.Info("build complete", "duration", time.Since(startTime).Truncate(time.Microsecond), "pages", stats.Pages, "bytes", stats.BytesWritten)
+	return stats, nil
 }
@@ -1,6 +1,7 @@
 package build
 
 import (
+	"html/template"
 	"time"
 
 	"github.com/olimci/shizuka/internal/transforms"
@@ -27,3 +28,11 @@ type gitStepCache struct {
 	SiteExpires time.Time
 	Files       map[string]gitFileCacheEntry
 }
+
+// templateStepCache keeps the page templates parsed by an earlier dev build.
+// The cached set is never executed, so each build clones it and binds its
+// own functions.
+type templateStepCache struct {
+	Glob   string
+	Parsed *template.Template
+}
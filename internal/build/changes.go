@@ -0,0 +1,76 @@
+package build
+
+import (
+	"path/filepath"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+// ChangeScope is how much of an earlier build a rebuild's changed source
+// paths leave reusable, from nothing to all but the changed content.
+type ChangeScope uint8
+
+const (
+	// ScopeFull is an unknown change set, or one that touches the templates
+	// or the config file; nothing from an earlier build is reused.
+	ScopeFull ChangeScope = iota
+	// ScopeSource is a change to source files other than the templates and
+	// the config file, so parsed templates are still valid.
+	ScopeSource
+	// ScopeContent is a change to content files only. Every page is still
+	// rebuilt for now, but this is where a partial build would start.
+	ScopeContent
+)
+
+func (s ChangeScope) String() string {
+	switch s {
+	case ScopeSource:
+		return "source"
+	case ScopeContent:
+		return "content"
+	default:
+		return "full"
+	}
+}
+
+// ClassifyChanges picks the scope of a rebuild from its changed paths, which
+// are absolute as options.CleanChangedPaths leaves them. Nil means the
+// changes are unknown.
+func ClassifyChanges(cfg *config.Config, configPath string, changed []string) ChangeScope {
+	if changed == nil {
+		return ScopeFull
+	}
+
+	root, err := filepath.Abs(cfg.Root)
+	if err != nil {
+		return ScopeFull
+	}
+	configAbs, err := filepath.Abs(configPath)
+	if err != nil {
+		return ScopeFull
+	}
+	within := func(p, dir string) bool {
+		rel, err := filepath.Rel(filepath.Join(root, filepath.FromSlash(dir)), p)
+		return err == nil && filepath.IsLocal(rel)
+	}
+	inContent := func(p string) bool {
+		for _, dir := range cfg.ContentPaths() {
+			if within(p, dir) {
+				return true
+			}
+		}
+		return false
+	}
+
+	scope := ScopeContent
+	for _, p := range changed {
+		p = filepath.Clean(p)
+		switch {
+		case p == configAbs || within(p, cfg.Paths.Templates) || within(p, cfg.PartialsDir()):
+			return ScopeFull
+		case !inContent(p):
+			scope = ScopeSource
+		}
+	}
+	return scope
+}
@@ -0,0 +1,53 @@
+package build
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+func TestClassifyChanges(t *testing.T) {
+	root := t.TempDir()
+	cfg := &config.Config{Root: root}
+	cfg.Paths.Content = "content"
+	cfg.Paths.Templates = "templates"
+	cfg.Paths.PartialsGlob = "includes/**/*.html"
+	configPath := filepath.Join(root, "shizuka.jsonc")
+	at := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+
+	tests := []struct {
+		name    string
+		changed []string
+		want    ChangeScope
+	}{
+		{name: "unknown", changed: nil, want: ScopeFull},
+		{name: "none", changed: []string{}, want: ScopeContent},
+		{name: "content", changed: []string{at("content/a.md"), at("content/blog/b.md")}, want: ScopeContent},
+		{name: "static", changed: []string{at("content/a.md"), at("static/site.css")}, want: ScopeSource},
+		{name: "template", changed: []string{at("content/a.md"), at("templates/html/page.tmpl")}, want: ScopeFull},
+		{name: "partials", changed: []string{at("content/a.md"), at("includes/header.html")}, want: ScopeFull},
+		{name: "config", changed: []string{configPath}, want: ScopeFull},
+		{name: "content prefix", changed: []string{at("content-old/a.md")}, want: ScopeSource},
+	}
+	for _, tt := range tests {
+		if got := ClassifyChanges(cfg, configPath, tt.changed); got != tt.want {
+			t.Fatalf("%s: ClassifyChanges() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyChangesOutsideRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "site")
+	cfg := &config.Config{Root: root}
+	cfg.Paths.Templates = "templates"
+	cfg.Content.Sources = []config.ConfigContentSource{{Path: "content"}, {Path: "../docs"}}
+	configPath := filepath.Join(root, "shizuka.jsonc")
+
+	if got := ClassifyChanges(cfg, configPath, []string{filepath.Join(root, "..", "docs", "guide.md")}); got != ScopeContent {
+		t.Fatalf("ClassifyChanges(../docs) = %s, want %s", got, ScopeContent)
+	}
+	if got := ClassifyChanges(cfg, configPath, []string{filepath.Join(root, "..", "other", "a.md")}); got != ScopeSource {
+		t.Fatalf("ClassifyChanges(../other) = %s, want %s", got, ScopeSource)
+	}
+}
@@ -1,21 +1,31 @@
 package build
 
 import (
-	"strings"
+	"os"
+	"path/filepath"
 
 	"github.com/olimci/shizuka/internal/transforms"
 	"github.com/olimci/shizuka/internal/utils/decodeutil"
 	"github.com/olimci/shizuka/internal/utils/fileutil"
+	"github.com/olimci/shizuka/internal/utils/pathutil"
 )
 
-func isPageSourceExt(ext string) bool {
-	switch strings.ToLower(ext) {
-	case ".md", ".html":
+// openContentSource opens the directory of a content source. A source inside
+// the site root is opened through it; one outside, such as ../docs, gets a
+// root of its own, so its pages still cannot reach past its directory.
+func openContentSource(site *os.Root, dir string) (*os.Root, error) {
+	if pathutil.EscapesRoot(dir) {
+		return os.OpenRoot(filepath.Join(site.Name(), filepath.FromSlash(dir)))
+	}
+	return site.OpenRoot(dir)
+}
+
+func isPageSourceExt(ext string, extensions map[string]string) bool {
+	if _, ok := transforms.ContentHandler(ext, extensions); ok {
 		return true
-	default:
-		_, ok := decodeutil.FormatExt(ext)
-		return ok
 	}
+	_, ok := decodeutil.FormatExt(ext)
+	return ok
 }
 
 func attachPageFileMeta(page *transforms.Page, source string) {
@@ -0,0 +1,46 @@
+package build
+
+import (
+	"bytes"
+	"maps"
+	"sync"
+
+	"github.com/olimci/shizuka/internal/manifest"
+	"github.com/olimci/shizuka/internal/transforms"
+)
+
+// cspCollector gathers inline script and style hashes per route while pages
+// render.
+type cspCollector struct {
+	mu     sync.Mutex
+	hashes map[string]transforms.CSPHashes
+}
+
+func newCSPCollector() *cspCollector {
+	return &cspCollector{hashes: make(map[string]transforms.CSPHashes)}
+}
+
+// record builds the artefact eagerly, so hashes are taken from the bytes that
+// will be written after post-processing, and returns an artefact that writes
+// those bytes.
+func (c *cspCollector) record(artefact manifest.Artefact) (manifest.Artefact, error) {
+	var buf bytes.Buffer
+	if err := artefact.Builder(&buf); err != nil {
+		return manifest.Artefact{}, err
+	}
+
+	hashes := transforms.HashInlineBlocks(buf.Bytes())
+	c.mu.Lock()
+	c.hashes[artefact.Claim.Canon] = hashes
+	c.mu.Unlock()
+
+	out := manifest.TextArtefact(artefact.Claim, buf.String())
+	out.Fingerprint = artefact.Fingerprint
+	return out, nil
+}
+
+func (c *cspCollector) snapshot() map[string]transforms.CSPHashes {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.hashes)
+}
@@ -1,16 +1,23 @@
 package build
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 
 	"github.com/olimci/shizuka/internal/manifest"
+	"github.com/olimci/shizuka/internal/utils/decodeutil"
 )
 
 type BuildError struct {
 	Claim manifest.Claim
 	Err   error
+
+	// Step is the ID of the build step that reported the error, or empty
+	// for errors found outside a step, such as by the manifest.
+	Step string
 }
 
 func wrapError(claim manifest.Claim, err error) *BuildError {
@@ -38,12 +45,9 @@ func (e *BuildError) Error() string {
 		return ""
 	}
 	if loc := e.Location(); loc != "" {
-		return fmt.Sprintf("%s: %v", loc, e.Err)
-	}
-	if e.Err == nil {
-		return ""
+		return fmt.Sprintf("%s: %s", loc, e.Description())
 	}
-	return e.Err.Error()
+	return e.Description()
 }
 
 func (e *BuildError) Unwrap() error {
@@ -74,8 +78,16 @@ func (e *BuildError) Owner() string {
 	return e.Claim.Owner
 }
 
+// Location names where the error occurred: the claim's source, with the line
+// and column when the error carries a position, or else its target or owner.
 func (e *BuildError) Location() string {
 	if src := e.Source(); src != "" {
+		if perr, ok := errors.AsType[*decodeutil.PositionError](e.Err); ok {
+			if perr.Column > 0 {
+				return fmt.Sprintf("%s:%d:%d", src, perr.Line, perr.Column)
+			}
+			return fmt.Sprintf("%s:%d", src, perr.Line)
+		}
 		return src
 	}
 	if target := e.Target(); target != "" {
@@ -84,20 +96,44 @@ func (e *BuildError) Location() string {
 	return e.Owner()
 }
 
+// Description is the error message, prefixed with the step that reported it
+// when there is one.
 func (e *BuildError) Description() string {
 	if e == nil || e.Err == nil {
 		return ""
 	}
+	if e.Step != "" {
+		return fmt.Sprintf("[%s] %v", e.Step, e.Err)
+	}
 	return e.Err.Error()
 }
 
 type errorState struct {
 	mu     sync.Mutex
 	errors []*BuildError
+
+	// sorted orders Slice by location rather than by when steps running in
+	// parallel happened to report.
+	sorted bool
 }
 
 func (s *errorState) Add(claim manifest.Claim, err error) {
+	s.add(wrapError(claim, err))
+}
+
+// AddStep records an error reported by step. An error already attributed to
+// another step keeps that attribution.
+func (s *errorState) AddStep(step string, claim manifest.Claim, err error) {
 	buildErr := wrapError(claim, err)
+	if buildErr != nil && buildErr.Step == "" {
+		out := *buildErr
+		out.Step = step
+		buildErr = &out
+	}
+	s.add(buildErr)
+}
+
+func (s *errorState) add(buildErr *BuildError) {
 	if buildErr == nil {
 		return
 	}
@@ -121,9 +157,21 @@ func (s *errorState) Slice() []*BuildError {
 
 	out := make([]*BuildError, len(s.errors))
 	copy(out, s.errors)
+	if s.sorted {
+		slices.SortStableFunc(out, compareBuildErrors)
+	}
 	return out
 }
 
+func compareBuildErrors(a, b *BuildError) int {
+	return cmp.Or(
+		cmp.Compare(a.Location(), b.Location()),
+		cmp.Compare(a.Owner(), b.Owner()),
+		cmp.Compare(a.Target(), b.Target()),
+		cmp.Compare(a.Description(), b.Description()),
+	)
+}
+
 type Failure struct {
 	Errors []*BuildError
 }
@@ -0,0 +1,80 @@
+package build
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/manifest"
+	"github.com/olimci/shizuka/internal/utils/decodeutil"
+)
+
+func TestBuildErrorLocationIncludesPosition(t *testing.T) {
+	claim := manifest.NewPageClaim("content/post.md", "/post/")
+	parseErr := errors.New("bad value")
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{parseErr, "content/post.md"},
+		{&decodeutil.PositionError{Line: 3, Err: parseErr}, "content/post.md:3"},
+		{&decodeutil.PositionError{Line: 3, Column: 7, Err: parseErr}, "content/post.md:3:7"},
+	}
+	for _, tt := range tests {
+		if got := wrapError(claim, tt.err).Location(); got != tt.want {
+			t.Fatalf("Location() = %q, want %q", got, tt.want)
+		}
+	}
+	if got := wrapError(claim, tests[2].err).Error(); got != "content/post.md:3:7: bad value" {
+		t.Fatalf("Error() = %q", got)
+	}
+}
+
+func TestErrorStateSortsWhenDeterministic(t *testing.T) {
+	state := &errorState{sorted: true}
+	state.Add(manifest.NewPageClaim("content/b.md", "/b/"), errors.New("second"))
+	state.Add(manifest.NewPageClaim("content/a.md", "/a/"), errors.New("first"))
+	state.Add(manifest.NewInternalClaim("feeds", "rss.xml"), errors.New("last"))
+
+	var got []string
+	for _, err := range state.Slice() {
+		got = append(got, err.Location())
+	}
+	if want := []string{"content/a.md", "content/b.md", "rss.xml"}; !slices.Equal(got, want) {
+		t.Fatalf("locations = %v, want %v", got, want)
+	}
+}
+
+func TestErrorStateAttributesStepsWithoutSharing(t *testing.T) {
+	state := new(errorState)
+	shared := wrapError(manifest.NewPageClaim("content/a.md", "/a/"), errors.New("bad"))
+	state.AddStep("pages:build", manifest.Claim{}, shared)
+	state.AddStep("pages:render", manifest.Claim{}, shared)
+	state.Add(manifest.NewInternalClaim("manifest", "a/index.html"), errors.New("write failed"))
+
+	var got []string
+	for _, err := range state.Slice() {
+		got = append(got, err.Step)
+	}
+	if want := []string{"pages:build", "pages:render", ""}; !slices.Equal(got, want) {
+		t.Fatalf("steps = %q, want %q", got, want)
+	}
+	if shared.Step != "" {
+		t.Fatalf("shared error was stamped with %q", shared.Step)
+	}
+}
+
+func TestBuildErrorNamesStep(t *testing.T) {
+	state := new(errorState)
+	state.AddStep("pages:render", manifest.NewPageClaim("content/a.md", "/a/"), errors.New("bad"))
+	state.Add(manifest.Claim{}, errors.New("write failed"))
+
+	var got []string
+	for _, err := range state.Slice() {
+		got = append(got, err.Error())
+	}
+	if want := []string{"content/a.md: [pages:render] bad", "write failed"}; !slices.Equal(got, want) {
+		t.Fatalf("errors = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"text/template/parse"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/manifest"
+	"github.com/olimci/shizuka/internal/transforms"
+	"github.com/olimci/shizuka/internal/utils/pathutil"
+	"github.com/olimci/shizuka/internal/version"
+)
+
+// configFingerprint identifies the config and shizuka version an artefact was
+// built with.
+func configFingerprint(cfg *config.Config) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	return manifest.Fingerprint(version.String(), string(data))
+}
+
+func statFingerprint(name string, info fs.FileInfo) string {
+	return name + ":" + strconv.FormatInt(info.Size(), 10) + ":" + strconv.FormatInt(info.ModTime().UnixNano(), 10)
+}
+
+// treeFingerprint identifies every file under roots. Pages can list, query and
+// include each other, inline static files with readFile and read image sizes
+// with imageSize and img, so a page's output is keyed on the whole content,
+// template, data and static tree rather than its own source alone.
+func treeFingerprint(fsys fs.FS, roots ...string) (string, error) {
+	parts := make([]string, 0, 64)
+	for _, root := range roots {
+		err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			parts = append(parts, statFingerprint(name, info))
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return manifest.Fingerprint(parts...), nil
+}
+
+// siteTreeFingerprint is treeFingerprint over the content, template, data
+// and static trees. Content sources outside the site root are read through
+// roots of their own.
+func siteTreeFingerprint(site *os.Root, cfg *config.Config) (string, error) {
+	roots := []string{cfg.Paths.Templates, cfg.PartialsDir(), cfg.Paths.Data, cfg.Paths.Static}
+	var parts []string
+	for _, dir := range cfg.ContentPaths() {
+		if !pathutil.EscapesRoot(dir) {
+			roots = append(roots, dir)
+			continue
+		}
+		source, err := openContentSource(site, dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		tree, err := treeFingerprint(source.FS(), ".")
+		source.Close()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, dir+":"+tree)
+	}
+	tree, err := treeFingerprint(site.FS(), roots...)
+	if err != nil {
+		return "", err
+	}
+	return manifest.Fingerprint(append(parts, tree)...), nil
+}
+
+// pagesFingerprint identifies the published pages and the git metadata they
+// were built with. Which pages are published and indexed depends on the
+// build time as well as the tree, and git metadata on commits the tree does
+// not show.
+func pagesFingerprint(site *transforms.Site, pages []*transforms.Page) string {
+	parts := make([]string, 0, 2*len(pages)+1)
+	git, err := json.Marshal(site.Git)
+	if err != nil {
+		return ""
+	}
+	parts = append(parts, string(git))
+	for _, page := range pages {
+		git, err := json.Marshal(page.Git)
+		if err != nil {
+			return ""
+		}
+		parts = append(parts, page.SourcePath+":"+page.Path+":"+strconv.FormatBool(page.NoIndex), string(git))
+	}
+	return manifest.Fingerprint(parts...)
+}
+
+// timeFuncs are the template funcs whose result depends on when a page is
+// rendered.
+var timeFuncs = []string{"now", "timeAgo"}
+
+// timeDependent reports whether any template calls a time func or reads the
+// build time, so that its output can change without any input changing.
+func timeDependent(tmpl *template.Template) bool {
+	if tmpl == nil {
+		return false
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && nodeTimeDependent(t.Tree.Root) {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeTimeDependent(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		return slices.ContainsFunc(n.Nodes, nodeTimeDependent)
+	case *parse.ActionNode:
+		return nodeTimeDependent(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		return slices.ContainsFunc(n.Cmds, func(cmd *parse.CommandNode) bool { return nodeTimeDependent(cmd) })
+	case *parse.CommandNode:
+		return slices.ContainsFunc(n.Args, nodeTimeDependent)
+	case *parse.IdentifierNode:
+		return slices.Contains(timeFuncs, n.Ident)
+	case *parse.FieldNode:
+		return slices.Contains(n.Ident, "BuildTime")
+	case *parse.VariableNode:
+		return slices.Contains(n.Ident, "BuildTime")
+	case *parse.ChainNode:
+		return slices.Contains(n.Field, "BuildTime") || nodeTimeDependent(n.Node)
+	case *parse.IfNode:
+		return nodeTimeDependent(n.Pipe) || nodeTimeDependent(n.List) || nodeTimeDependent(n.ElseList)
+	case *parse.RangeNode:
+		return nodeTimeDependent(n.Pipe) || nodeTimeDependent(n.List) || nodeTimeDependent(n.ElseList)
+	case *parse.WithNode:
+		return nodeTimeDependent(n.Pipe) || nodeTimeDependent(n.List) || nodeTimeDependent(n.ElseList)
+	case *parse.TemplateNode:
+		return nodeTimeDependent(n.Pipe)
+	}
+	return false
+}
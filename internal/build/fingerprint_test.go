@@ -0,0 +1,27 @@
+package build
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/utils/tmplutil"
+)
+
+func TestTimeDependent(t *testing.T) {
+	for src, want := range map[string]bool{
+		`{{ .Page.Title }}`: false,
+		`{{ now.Year }}`:    true,
+		`{{ if .Page.Date }}{{ timeAgo .Page.Date }}{{ end }}`:     true,
+		`{{ .Site.BuildTime.Year }}`:                               true,
+		`{{ with .Site }}{{ $s := . }}{{ $s.BuildTime }}{{ end }}`: true,
+		`{{ define "footer" }}{{ now }}{{ end }}ok`:                true,
+	} {
+		tmpl, err := template.New("page").Funcs(tmplutil.DefaultFuncs()).Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := timeDependent(tmpl); got != want {
+			t.Errorf("timeDependent(%q) = %v, want %v", src, got, want)
+		}
+	}
+}
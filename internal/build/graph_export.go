@@ -0,0 +1,103 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/olimci/shizuka/internal/registry"
+	"github.com/olimci/shizuka/internal/utils/dag"
+)
+
+// Graph export formats.
+const (
+	GraphDOT     = "dot"
+	GraphMermaid = "mermaid"
+)
+
+// ExportGraph writes graph in format, GraphDOT or GraphMermaid, with an edge
+// from each step to the steps that depend on it. Each step is labelled with
+// the registry keys it locks: "r" for reads, "r?" for optional reads and "w"
+// for writes, with cache locks prefixed "cache". Dependencies on steps that
+// are not in the graph are drawn as dashed nodes.
+func ExportGraph(w io.Writer, graph *dag.Graph[Step], format string) error {
+	ids := graph.Nodes()
+	var missing []string
+	for _, id := range ids {
+		for _, dep := range graph.Deps(id) {
+			if _, ok := graph.Value(dep); !ok && !slices.Contains(missing, dep) {
+				missing = append(missing, dep)
+			}
+		}
+	}
+
+	var b strings.Builder
+	switch format {
+	case GraphDOT:
+		b.WriteString("digraph build {\n\trankdir=LR;\n\tnode [shape=box, fontname=\"monospace\"];\n")
+		for _, id := range ids {
+			step, _ := graph.Value(id)
+			fmt.Fprintf(&b, "\t%s [label=%s];\n", strconv.Quote(id), strconv.Quote(strings.Join(stepLabel(step), "\n")))
+		}
+		for _, id := range missing {
+			fmt.Fprintf(&b, "\t%s [label=%s, style=dashed];\n", strconv.Quote(id), strconv.Quote(id+"\n(missing)"))
+		}
+		for _, id := range ids {
+			for _, dep := range graph.Deps(id) {
+				fmt.Fprintf(&b, "\t%s -> %s;\n", strconv.Quote(dep), strconv.Quote(id))
+			}
+		}
+		b.WriteString("}\n")
+	case GraphMermaid:
+		// Mermaid node IDs cannot hold the colons step IDs use.
+		nodes := make(map[string]string, len(ids)+len(missing))
+		b.WriteString("flowchart LR\n")
+		for i, id := range ids {
+			nodes[id] = "n" + strconv.Itoa(i)
+			step, _ := graph.Value(id)
+			fmt.Fprintf(&b, "\t%s[\"%s\"]\n", nodes[id], mermaidText(strings.Join(stepLabel(step), "<br/>")))
+		}
+		for i, id := range missing {
+			nodes[id] = "m" + strconv.Itoa(i)
+			fmt.Fprintf(&b, "\t%s[\"%s<br/>(missing)\"]\n\tstyle %s stroke-dasharray: 5 5\n", nodes[id], mermaidText(id), nodes[id])
+		}
+		for _, id := range ids {
+			for _, dep := range graph.Deps(id) {
+				fmt.Fprintf(&b, "\t%s --> %s\n", nodes[dep], nodes[id])
+			}
+		}
+	default:
+		return fmt.Errorf("unknown graph format %q: want %s or %s", format, GraphDOT, GraphMermaid)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func stepLabel(step Step) []string {
+	label := []string{step.ID}
+	for _, lock := range step.RegistryLocks {
+		label = append(label, lockLabel(lock))
+	}
+	for _, lock := range step.CacheLocks {
+		label = append(label, "cache "+lockLabel(lock))
+	}
+	return label
+}
+
+func lockLabel(lock registry.Lock) string {
+	switch {
+	case lock.Writes():
+		return "w " + lock.Key()
+	case lock.Optional():
+		return "r? " + lock.Key()
+	default:
+		return "r " + lock.Key()
+	}
+}
+
+func mermaidText(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
@@ -0,0 +1,50 @@
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/registry"
+	"github.com/olimci/shizuka/internal/utils/dag"
+)
+
+func TestExportGraphLabelsLocksAndMissingSteps(t *testing.T) {
+	graph := dag.New[Step]()
+	for _, step := range []Step{
+		StepFunc("pages:index", nil).Registry(registry.W(PagesK)),
+		StepFunc("pages:resolve", nil, "pages:index", "git").Registry(registry.R(PagesK), registry.RX(SiteGitK)),
+	} {
+		if err := graph.Add(step.ID, step.Deps, step); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var dot strings.Builder
+	if err := ExportGraph(&dot, graph, GraphDOT); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"pages:resolve" [label="pages:resolve\nr pages\nr? sitegit"];`,
+		`"git" [label="git\n(missing)", style=dashed];`,
+		`"pages:index" -> "pages:resolve";`,
+		`"git" -> "pages:resolve";`,
+	} {
+		if !strings.Contains(dot.String(), want) {
+			t.Fatalf("dot output = %s, want it to contain %s", dot.String(), want)
+		}
+	}
+
+	var mermaid strings.Builder
+	if err := ExportGraph(&mermaid, graph, GraphMermaid); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`n0["pages:index<br/>w pages"]`, `m0["git<br/>(missing)"]`, "n0 --> n1", "m0 --> n1"} {
+		if !strings.Contains(mermaid.String(), want) {
+			t.Fatalf("mermaid output = %s, want it to contain %s", mermaid.String(), want)
+		}
+	}
+
+	if err := ExportGraph(&strings.Builder{}, graph, "svg"); err == nil {
+		t.Fatal("want an error for an unknown format")
+	}
+}
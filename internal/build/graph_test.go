@@ -0,0 +1,120 @@
+package build
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/options"
+)
+
+// TestStepRegistryDeclarations checks that the registry locks declared by
+// every step agree with the graph: whatever a step reads is written only by
+// steps that run before it, and the writers of a key run in a fixed order, so
+// no access races a writer or depends on scheduling order.
+func TestStepRegistryDeclarations(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Content.Git = &config.ConfigContentGit{}
+	cfg.Content.Related = &config.ConfigContentRelated{}
+	cfg.Build.Images = &config.ConfigImages{}
+	cfg.Artefacts = config.ConfigArtefacts{
+		Headers:   &config.ConfigHeaders{CSP: &config.ConfigCSP{}},
+		Redirects: &config.ConfigRedirects{},
+		RSS:       &config.ConfigRSS{},
+		JSONFeed:  &config.ConfigJSONFeed{},
+		Atom:      &config.ConfigAtom{},
+		Sitemap:   &config.ConfigSitemap{},
+		Robots:    &config.ConfigRobots{},
+		NotFound:  &config.ConfigNotFound{},
+		Meta:      &config.ConfigMeta{},
+	}
+
+	opts := options.DefaultOptions().Apply(options.WithCheck(true))
+	steps := append([]Step{StepStatic(cfg)}, StepContent(cfg, opts)...)
+	deps := make(map[string][]string)
+	for _, step := range steps {
+		deps[step.ID] = step.Deps
+	}
+	for _, patch := range stepPatches(cfg, opts) {
+		for _, step := range patch.Steps {
+			steps = append(steps, step)
+			deps[step.ID] = step.Deps
+		}
+		for _, dep := range patch.dependencies {
+			deps[dep.id] = append(deps[dep.id], dep.dep)
+		}
+	}
+
+	var ancestors func(id string, seen map[string]bool) map[string]bool
+	ancestors = func(id string, seen map[string]bool) map[string]bool {
+		for _, dep := range deps[id] {
+			if !seen[dep] {
+				seen[dep] = true
+				ancestors(dep, seen)
+			}
+		}
+		return seen
+	}
+
+	writers := make(map[string][]string)
+	for _, step := range steps {
+		for _, lock := range step.RegistryLocks {
+			if lock.Writes() {
+				writers[lock.Key()] = append(writers[lock.Key()], step.ID)
+			}
+		}
+	}
+
+	for _, step := range steps {
+		before := ancestors(step.ID, map[string]bool{})
+		keys := make(map[string]bool)
+		for _, lock := range step.RegistryLocks {
+			keys[lock.Key()] = true
+			if lock.Key() == string(BuildCtxK) {
+				continue // set by build before any step runs
+			}
+			produced := false
+			for _, writer := range writers[lock.Key()] {
+				switch {
+				case writer == step.ID:
+				case before[writer]:
+					produced = true
+				case lock.Writes() && ancestors(writer, map[string]bool{})[step.ID]:
+					// Writers of a key run in a fixed order.
+				default:
+					t.Errorf("step %q locks %q but is not ordered after its writer %q", step.ID, lock.Key(), writer)
+				}
+			}
+			if !produced && !lock.Writes() && !lock.Optional() {
+				t.Errorf("step %q reads %q, which no earlier step writes", step.ID, lock.Key())
+			}
+		}
+		// The site holds the page slice, so reading it reads the pages too.
+		if keys[string(SiteK)] && !keys[string(PagesK)] {
+			t.Errorf("step %q locks %q without %q", step.ID, SiteK, PagesK)
+		}
+	}
+}
+
+// TestLoadConfigAppliesOverrides checks the option overrides Build and Graph
+// share, so the reported graph matches the one a build runs.
+func TestLoadConfigAppliesOverrides(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"shizuka.jsonc": `{"site": {"url": "https://example.com"}}`,
+	})
+
+	cfg, err := loadConfig(options.DefaultOptions().Apply(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithSiteURL("https://preview.example.com"),
+		options.WithMaxImageSize(1024),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Site.URL != "https://preview.example.com" {
+		t.Fatalf("site.url = %q, want the override", cfg.Site.URL)
+	}
+	if cfg.Build.ImageSize == nil || cfg.Build.ImageSize.Max != 1024 {
+		t.Fatalf("build.image_size = %+v, want max 1024", cfg.Build.ImageSize)
+	}
+}
@@ -0,0 +1,111 @@
+package build
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"slices"
+	"strconv"
+
+	"github.com/olimci/shizuka/internal/transforms"
+)
+
+// PageGroup is one heading of an archive built by the group template func.
+type PageGroup struct {
+	Key   string
+	Pages []transforms.PageTmpl
+}
+
+func groupFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"group": groupPages,
+	}
+}
+
+// groupPages groups pages by Year or Month of their creation date, by
+// Section, or by Tags, where a page appears under each of its tags. Date
+// groups come newest first with keys like "2025" and "2025-01"; other groups
+// are sorted by key. Pages without a key, such as undated or untagged pages,
+// are left out, and pages keep their order within a group.
+func groupPages(field string, items any) ([]PageGroup, error) {
+	keys, dates, err := groupKeys(field)
+	if err != nil {
+		return nil, err
+	}
+	slice, err := sliceAny(items)
+	if err != nil {
+		return nil, fmt.Errorf("group items: %w", err)
+	}
+
+	var groups []PageGroup
+	index := make(map[string]int)
+	for _, item := range slice {
+		page, ok := pageTmplOf(item)
+		if !ok {
+			return nil, fmt.Errorf("group items: expected pages, got %T", item)
+		}
+		for _, key := range keys(page) {
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, PageGroup{Key: key})
+			}
+			groups[i].Pages = append(groups[i].Pages, page)
+		}
+	}
+
+	slices.SortFunc(groups, func(a, b PageGroup) int {
+		if dates {
+			return cmp.Compare(b.Key, a.Key)
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return groups, nil
+}
+
+func groupKeys(field string) (keys func(transforms.PageTmpl) []string, dates bool, err error) {
+	switch field {
+	case "Year":
+		return func(p transforms.PageTmpl) []string {
+			if p.Created.IsZero() {
+				return nil
+			}
+			return []string{strconv.Itoa(p.Created.Year())}
+		}, true, nil
+	case "Month":
+		return func(p transforms.PageTmpl) []string {
+			if p.Created.IsZero() {
+				return nil
+			}
+			return []string{p.Created.Format("2006-01")}
+		}, true, nil
+	case "Section":
+		return func(p transforms.PageTmpl) []string {
+			if p.Section == "" {
+				return nil
+			}
+			return []string{p.Section}
+		}, false, nil
+	case "Tags":
+		return func(p transforms.PageTmpl) []string {
+			return slices.Compact(slices.Sorted(slices.Values(p.Tags)))
+		}, false, nil
+	default:
+		return nil, false, fmt.Errorf("group field must be Year, Month, Section or Tags (got %q)", field)
+	}
+}
+
+func pageTmplOf(item any) (transforms.PageTmpl, bool) {
+	switch page := item.(type) {
+	case transforms.PageTmpl:
+		return page, true
+	case *transforms.PageTmpl:
+		if page == nil {
+			return transforms.PageTmpl{}, false
+		}
+		return *page, true
+	default:
+		return transforms.PageTmpl{}, false
+	}
+}
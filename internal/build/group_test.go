@@ -0,0 +1,51 @@
+package build
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olimci/shizuka/internal/transforms"
+)
+
+func TestGroupFunc(t *testing.T) {
+	date := func(year int, month time.Month) time.Time {
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	}
+	pages := []transforms.PageTmpl{
+		{Title: "a", Section: "posts", Tags: []string{"go", "web"}, Created: date(2024, 3)},
+		{Title: "b", Section: "notes", Tags: []string{"go"}, Created: date(2025, 1)},
+		{Title: "c", Section: "posts", Created: date(2024, 11)},
+		{Title: "d", Section: "posts", Tags: []string{"web"}},
+	}
+
+	tmpl := template.Must(template.New("archive").Funcs(groupFuncMap()).Parse(
+		`{{ range group .Field .Pages }}{{ .Key }}:{{ range .Pages }}{{ .Title }}{{ end }} {{ end }}`,
+	))
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{field: "Year", want: "2025:b 2024:ac "},
+		{field: "Month", want: "2025-01:b 2024-11:c 2024-03:a "},
+		{field: "Section", want: "notes:b posts:acd "},
+		{field: "Tags", want: "go:ab web:ad "},
+	}
+	for _, tt := range tests {
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, map[string]any{"Field": tt.field, "Pages": pages}); err != nil {
+			t.Fatalf("group %q: %v", tt.field, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Fatalf("group %q = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+
+	if _, err := groupPages("Weight", pages); err == nil {
+		t.Fatal("group on an unsupported field succeeded, want error")
+	}
+	if groups, err := groupPages("Section", []any{&pages[0]}); err != nil || len(groups) != 1 {
+		t.Fatalf("group over page pointers = %v, %v, want one group", groups, err)
+	}
+}
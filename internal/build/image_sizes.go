@@ -0,0 +1,187 @@
+package build
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	_ "image/gif"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/olimci/shizuka/internal/utils/pathutil"
+)
+
+// ImageSize is the intrinsic size of a static image. Both fields are zero for
+// images whose size is not read, such as SVGs.
+type ImageSize struct {
+	Width  int
+	Height int
+}
+
+// imageSizeCache keeps image sizes across dev rebuilds, keyed by source path
+// and invalidated when the source's size or modification time changes.
+type imageSizeCache struct {
+	mu    sync.Mutex
+	sizes map[string]imageSizeEntry
+}
+
+type imageSizeEntry struct {
+	stat string
+	size ImageSize
+}
+
+// imageSizes reads the sizes of images under the static root for the
+// imageSize and img template funcs, alongside srcset for the variants
+// build.images generated.
+type imageSizes struct {
+	fsys   fs.FS
+	root   string
+	cache  *imageSizeCache
+	images *ImageSet
+}
+
+func newImageSizes(fsys fs.FS, root string, cache *imageSizeCache, images *ImageSet) *imageSizes {
+	if cache == nil {
+		cache = &imageSizeCache{}
+	}
+	return &imageSizes{fsys: fsys, root: root, cache: cache, images: images}
+}
+
+func (s *imageSizes) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"imageSize": s.size,
+		"img":       s.img,
+		"srcset":    s.images.Srcset,
+		"sizes":     s.images.Sizes,
+	}
+}
+
+// size returns the size of the static image at the site path src.
+func (s *imageSizes) size(src string) (ImageSize, error) {
+	rel, err := pathutil.CleanContentPath(strings.TrimPrefix(src, "/"))
+	if err != nil {
+		return ImageSize{}, fmt.Errorf("imageSize: %w", err)
+	}
+	source := path.Join(s.root, rel)
+	info, err := fs.Stat(s.fsys, source)
+	if err != nil {
+		return ImageSize{}, fmt.Errorf("imageSize: %w", err)
+	}
+	stat := statFingerprint(source, info)
+
+	s.cache.mu.Lock()
+	entry, ok := s.cache.sizes[source]
+	s.cache.mu.Unlock()
+	if ok && entry.stat == stat {
+		return entry.size, nil
+	}
+
+	size, err := readImageSize(s.fsys, source)
+	if err != nil {
+		return ImageSize{}, fmt.Errorf("imageSize %q: %w", src, err)
+	}
+
+	s.cache.mu.Lock()
+	defer s.cache.mu.Unlock()
+	if s.cache.sizes == nil {
+		s.cache.sizes = make(map[string]imageSizeEntry)
+	}
+	s.cache.sizes[source] = imageSizeEntry{stat: stat, size: size}
+	return size, nil
+}
+
+// img returns an <img> tag for the static image at src with its intrinsic
+// width and height, and a srcset and the configured sizes when build.images
+// generated variants of it.
+func (s *imageSizes) img(src, alt string) (template.HTML, error) {
+	size, err := s.size(src)
+	if err != nil {
+		return "", err
+	}
+	src = pathutil.EnsureLeadingSlash(src)
+
+	var b strings.Builder
+	b.WriteString(`<img src="` + template.HTMLEscapeString(src) + `" alt="` + template.HTMLEscapeString(alt) + `"`)
+	if size.Width > 0 && size.Height > 0 {
+		b.WriteString(` width="` + strconv.Itoa(size.Width) + `" height="` + strconv.Itoa(size.Height) + `"`)
+	}
+	if srcset := s.images.Srcset(src); srcset != "" {
+		b.WriteString(` srcset="` + template.HTMLEscapeString(srcset) + `"`)
+		if sizes := s.images.Sizes(); sizes != "" {
+			b.WriteString(` sizes="` + template.HTMLEscapeString(sizes) + `"`)
+		}
+	}
+	b.WriteString(">")
+	return template.HTML(b.String()), nil
+}
+
+// readImageSize reads the size from the image header. JPEG, PNG and GIF go
+// through image.DecodeConfig and WebP is read directly; other formats,
+// including SVG, have no size.
+func readImageSize(fsys fs.FS, name string) (ImageSize, error) {
+	ext := strings.ToLower(path.Ext(name))
+	switch ext {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+	default:
+		return ImageSize{}, nil
+	}
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		return ImageSize{}, err
+	}
+	defer file.Close()
+
+	if ext == ".webp" {
+		return webpSize(file)
+	}
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return ImageSize{}, err
+	}
+	return ImageSize{Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+var errBadWebP = errors.New("not a WebP image")
+
+// webpSize reads the canvas size from a WebP header, which stores it in one
+// of three layouts depending on the first chunk: VP8X (extended), VP8L
+// (lossless) or VP8 (lossy).
+func webpSize(r io.Reader) (ImageSize, error) {
+	var header [30]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return ImageSize{}, errBadWebP
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WEBP" {
+		return ImageSize{}, errBadWebP
+	}
+
+	uint24 := func(b []byte) int {
+		return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	}
+	switch string(header[12:16]) {
+	case "VP8X":
+		return ImageSize{Width: uint24(header[24:27]) + 1, Height: uint24(header[27:30]) + 1}, nil
+	case "VP8L":
+		if header[20] != 0x2f {
+			return ImageSize{}, errBadWebP
+		}
+		bits := binary.LittleEndian.Uint32(header[21:25])
+		return ImageSize{Width: int(bits&0x3fff) + 1, Height: int(bits>>14&0x3fff) + 1}, nil
+	case "VP8 ":
+		if header[23] != 0x9d || header[24] != 0x01 || header[25] != 0x2a {
+			return ImageSize{}, errBadWebP
+		}
+		return ImageSize{
+			Width:  int(binary.LittleEndian.Uint16(header[26:28]) & 0x3fff),
+			Height: int(binary.LittleEndian.Uint16(header[28:30]) & 0x3fff),
+		}, nil
+	}
+	return ImageSize{}, errBadWebP
+}
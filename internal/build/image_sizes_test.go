@@ -0,0 +1,86 @@
+package build
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadImageSize(t *testing.T) {
+	var gifData bytes.Buffer
+	if err := gif.Encode(&gifData, image.NewPaletted(image.Rect(0, 0, 30, 20), color.Palette{color.Black, color.White}), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := fstest.MapFS{
+		"static/a.gif":         {Data: gifData.Bytes()},
+		"static/extended.webp": {Data: webp("VP8X", 0, 0, 0, 0, 0x7f, 0x02, 0, 0xdf, 0x01, 0)},
+		// 14-bit width-1 then 14-bit height-1: 99 and 49.
+		"static/lossless.webp": {Data: webp("VP8L", 0x2f, 99, 0x40, 0x0c, 0)},
+		"static/lossy.webp":    {Data: webp("VP8 ", 0, 0, 0, 0x9d, 0x01, 0x2a, 64, 0, 48, 0)},
+		"static/logo.svg":      {Data: []byte("<svg/>")},
+		"static/bad.webp":      {Data: []byte("RIFF")},
+	}
+
+	tests := map[string]ImageSize{
+		"static/a.gif":         {Width: 30, Height: 20},
+		"static/extended.webp": {Width: 640, Height: 480},
+		"static/lossless.webp": {Width: 100, Height: 50},
+		"static/lossy.webp":    {Width: 64, Height: 48},
+		"static/logo.svg":      {},
+	}
+	for name, want := range tests {
+		got, err := readImageSize(fsys, name)
+		if err != nil {
+			t.Fatalf("readImageSize(%s) error = %v", name, err)
+		}
+		if got != want {
+			t.Fatalf("readImageSize(%s) = %+v, want %+v", name, got, want)
+		}
+	}
+	if _, err := readImageSize(fsys, "static/bad.webp"); err == nil {
+		t.Fatal("readImageSize(bad.webp) succeeded, want an error")
+	}
+}
+
+func TestImgTag(t *testing.T) {
+	fsys := fstest.MapFS{
+		"static/img/hero.webp": {Data: webp("VP8X", 0, 0, 0, 0, 0xe7, 0x03, 0, 0xf3, 0x01, 0)},
+		"static/logo.svg":      {Data: []byte("<svg/>")},
+	}
+	images := &ImageSet{images: map[string]imageEntry{
+		"/img/hero.webp": {width: 1000, variants: []imageVariant{{path: "/img/hero-480w.webp", width: 480}}},
+	}}
+	sizes := newImageSizes(fsys, "static", nil, images)
+
+	got, err := sizes.img("img/hero.webp", `A "hero"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<img src="/img/hero.webp" alt="A &#34;hero&#34;" width="1000" height="500" srcset="/img/hero-480w.webp 480w, /img/hero.webp 1000w">`
+	if string(got) != want {
+		t.Fatalf("img() = %s, want %s", got, want)
+	}
+
+	got, err = sizes.img("/logo.svg", "Logo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<img src="/logo.svg" alt="Logo">`; string(got) != want {
+		t.Fatalf("img() = %s, want %s", got, want)
+	}
+
+	if _, err := sizes.img("/missing.png", ""); err == nil {
+		t.Fatal("img() of a missing image succeeded, want an error")
+	}
+}
+
+// webp returns a WebP header whose first chunk is chunk, followed by payload
+// and padding.
+func webp(chunk string, payload ...byte) []byte {
+	data := append([]byte("RIFF\x00\x00\x00\x00WEBP"+chunk+"\x00\x00\x00\x00"), payload...)
+	return append(data, make([]byte, 30)...)
+}
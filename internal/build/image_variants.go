@@ -0,0 +1,315 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/manifest"
+	"github.com/olimci/shizuka/internal/registry"
+	"github.com/olimci/shizuka/internal/utils/pathutil"
+)
+
+// ImageSet records the responsive variants generated for static images, keyed
+// by site path.
+type ImageSet struct {
+	images map[string]imageEntry
+	sizes  string
+}
+
+type imageEntry struct {
+	width    int
+	variants []imageVariant
+}
+
+type imageVariant struct {
+	path  string
+	width int
+}
+
+// Srcset returns a srcset attribute value listing the variants of the image
+// at src followed by the original, or "" when src has no variants.
+func (s *ImageSet) Srcset(src string) string {
+	if s == nil {
+		return ""
+	}
+	entry, ok := s.images[pathutil.EnsureLeadingSlash(src)]
+	if !ok || len(entry.variants) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(entry.variants)+1)
+	for _, variant := range entry.variants {
+		parts = append(parts, variant.path+" "+strconv.Itoa(variant.width)+"w")
+	}
+	parts = append(parts, pathutil.EnsureLeadingSlash(src)+" "+strconv.Itoa(entry.width)+"w")
+	return strings.Join(parts, ", ")
+}
+
+// Sizes returns the configured sizes attribute value for images with
+// variants, or "" when none is set.
+func (s *ImageSet) Sizes() string {
+	if s == nil {
+		return ""
+	}
+	return s.sizes
+}
+
+// imageStepCache keeps encoded variants across dev rebuilds, keyed by source
+// path and invalidated when the source's size or modification time changes.
+type imageStepCache struct {
+	mu      sync.Mutex
+	sources map[string]*imageCacheEntry
+}
+
+type imageCacheEntry struct {
+	stat     string
+	width    int
+	height   int
+	variants map[int][]byte
+}
+
+func (c *imageStepCache) entry(source, stat string) (*imageCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sources == nil {
+		c.sources = make(map[string]*imageCacheEntry)
+	}
+	entry, ok := c.sources[source]
+	if ok && entry.stat == stat {
+		return entry, true
+	}
+	entry = &imageCacheEntry{stat: stat, variants: make(map[int][]byte)}
+	c.sources[source] = entry
+	return entry, false
+}
+
+// forget drops the entry for source, so an image that failed to decode is
+// read and reported again on the next build.
+func (c *imageStepCache) forget(source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sources, source)
+}
+
+func (c *imageStepCache) variant(entry *imageCacheEntry, width int) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := entry.variants[width]
+	return data, ok
+}
+
+func (c *imageStepCache) storeVariant(entry *imageCacheEntry, width int, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.variants[width] = data
+}
+
+func isResizableImageExt(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	default:
+		return false
+	}
+}
+
+// variantPath returns the output path for a width variant of rel encoded
+// with ext, e.g. img/hero.jpg at 480 becomes img/hero-480w.jpg.
+func variantPath(rel string, width int, ext string) string {
+	return strings.TrimSuffix(rel, path.Ext(rel)) + "-" + strconv.Itoa(width) + "w" + ext
+}
+
+// variantExt returns the extension variants of rel are encoded with: the
+// configured format, or the source's own.
+func variantExt(rel, format string) string {
+	switch format {
+	case "jpeg":
+		return ".jpg"
+	case "png":
+		return ".png"
+	default:
+		return path.Ext(rel)
+	}
+}
+
+func StepImages(cfg *config.Config) StepPatch {
+	step := StepFunc("images", func(_ context.Context, sc *StepContext) error {
+		imagesCfg := cfg.Build.Images
+		set := &ImageSet{images: make(map[string]imageEntry), sizes: imagesCfg.Sizes}
+		registry.Set(sc.Registry, ImagesK, set)
+
+		staticRoot := cfg.Paths.Static
+		if _, err := fs.Stat(sc.Source.FS(), staticRoot); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		cache := (*imageStepCache)(nil)
+		if sc.Cache != nil {
+			cache = registry.Get(sc.Cache, ImageCacheK)
+			if cache == nil {
+				cache = &imageStepCache{}
+				registry.Set(sc.Cache, ImageCacheK, cache)
+			}
+		} else {
+			cache = &imageStepCache{}
+		}
+
+		generated := 0
+		err := walkIgnore(sc.Source.FS(), staticRoot, staticExclude(cfg), func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !isResizableImageExt(path.Ext(filePath)) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := pathutil.RelPathWithin(staticRoot, filePath)
+			if err != nil {
+				return err
+			}
+			source := pathutil.JoinSlashRel(staticRoot, rel)
+			stat := statFingerprint(source, info)
+
+			entry, ok := cache.entry(source, stat)
+			if !ok {
+				file, err := sc.Source.FS().Open(source)
+				if err != nil {
+					return err
+				}
+				imgCfg, _, err := image.DecodeConfig(file)
+				file.Close()
+				if err != nil {
+					cache.forget(source)
+					sc.Error(fmt.Errorf("decode image: %w", err), manifest.Claim{Owner: "images", Source: source, Target: rel})
+					return nil
+				}
+				entry.width, entry.height = imgCfg.Width, imgCfg.Height
+			}
+
+			decode := sync.OnceValues(func() (image.Image, error) {
+				file, err := sc.Source.FS().Open(source)
+				if err != nil {
+					return nil, err
+				}
+				defer file.Close()
+				img, _, err := image.Decode(file)
+				return img, err
+			})
+
+			ext := variantExt(rel, imagesCfg.Format)
+			var variants []imageVariant
+			for _, width := range imagesCfg.Widths {
+				if width >= entry.width {
+					continue
+				}
+				target := variantPath(rel, width, ext)
+				claim := manifest.Claim{Owner: "images", Source: source, Target: target, Canon: target}
+				variants = append(variants, imageVariant{path: "/" + target, width: width})
+				generated++
+
+				err := sc.Manifest.Emit(manifest.Artefact{
+					Claim: claim,
+					Builder: func(w io.Writer) error {
+						if data, ok := cache.variant(entry, width); ok {
+							_, err := w.Write(data)
+							return err
+						}
+						img, err := decode()
+						if err != nil {
+							return err
+						}
+						var buf bytes.Buffer
+						if err := encodeImage(&buf, resizeImage(img, width), ext, imagesCfg.Quality); err != nil {
+							return err
+						}
+						data := buf.Bytes()
+						cache.storeVariant(entry, width, data)
+						_, err = w.Write(data)
+						return err
+					},
+				}.Fingerprinted(manifest.Fingerprint(stat, strconv.Itoa(width), ext, strconv.Itoa(imagesCfg.Quality))))
+				if err != nil {
+					return err
+				}
+			}
+			if len(variants) > 0 {
+				set.images["/"+rel] = imageEntry{width: entry.width, variants: variants}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("static source %q: %w", staticRoot, err)
+		}
+		sc.Logger.Info("image variants emitted", "count", generated, "images", len(set.images))
+		return nil
+	}).Registry(registry.W(ImagesK)).Cache(registry.W(ImageCacheK))
+
+	return StepPatchFunc(step).AddDependency("pages:templates", "images")
+}
+
+// resizeImage scales img down to width, keeping the aspect ratio. Each
+// destination pixel is the average of the source pixels it covers.
+func resizeImage(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	height := max(1, int(int64(srcH)*int64(width)/int64(srcW)))
+
+	src := image.NewNRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := range height {
+		y0 := y * srcH / height
+		y1 := max(y0+1, (y+1)*srcH/height)
+		for x := range width {
+			x0 := x * srcW / width
+			x1 := max(x0+1, (x+1)*srcW/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+func encodeImage(w io.Writer, img image.Image, ext string, quality int) error {
+	switch strings.ToLower(ext) {
+	case ".jpg", ".jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case ".png":
+		return png.Encode(w, img)
+	default:
+		return fmt.Errorf("unsupported image format %q", ext)
+	}
+}
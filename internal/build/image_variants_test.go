@@ -0,0 +1,149 @@
+package build
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/options"
+	"github.com/olimci/shizuka/internal/registry"
+)
+
+func TestBuildGeneratesImageVariants(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{"build": {"images": {"widths": [500, 200, 2000]}}}`,
+		"content/index.md":         "---\ntitle: Home\ntemplate: page\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}<img srcset="{{ srcset "/img/a.png" }}">{{ srcset "/img/missing.png" }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	src := image.NewNRGBA(image.Rect(0, 0, 1000, 400))
+	for y := range 400 {
+		for x := range 1000 {
+			src.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "static", "img"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(root, "static", "img", "a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	variant, err := os.Open(filepath.Join(out, "img", "a-200w.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer variant.Close()
+	cfg, err := png.DecodeConfig(variant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 200 || cfg.Height != 80 {
+		t.Fatalf("variant size = %dx%d, want 200x80", cfg.Width, cfg.Height)
+	}
+	if _, err := os.Stat(filepath.Join(out, "img", "a-500w.png")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "img", "a-2000w.png")); !os.IsNotExist(err) {
+		t.Fatalf("upscaled variant stat error = %v, want not exist", err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `srcset="/img/a-200w.png 200w, /img/a-500w.png 500w, /img/a.png 1000w"`
+	if got := string(index); !strings.Contains(got, want) || strings.Contains(got, "missing") {
+		t.Fatalf("index.html = %q, want %s", got, want)
+	}
+}
+
+func TestBuildImageVariantFormatAndSizes(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{"build": {"images": {"widths": [200], "format": "jpeg", "sizes": "(min-width: 40em) 50vw, 100vw"}}}`,
+		"content/index.md":         "---\ntitle: Home\ntemplate: page\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ img "/img/a.png" "A" }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+	if err := os.MkdirAll(filepath.Join(root, "static", "img"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(root, "static", "img", "a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 400, 100))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	variant, err := os.Open(filepath.Join(out, "img", "a-200w.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer variant.Close()
+	if _, err := jpeg.DecodeConfig(variant); err != nil {
+		t.Fatalf("variant is not a JPEG: %v", err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `srcset="/img/a-200w.jpg 200w, /img/a.png 400w" sizes="(min-width: 40em) 50vw, 100vw"`
+	if got := string(index); !strings.Contains(got, want) {
+		t.Fatalf("index.html = %q, want %s", got, want)
+	}
+}
+
+func TestBuildReportsBrokenImagesOnEveryRebuild(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"shizuka.jsonc":            `{"build": {"images": {"widths": [200]}}}`,
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Title }}{{ end }}`,
+		"static/img/a.png":         "not a png",
+	})
+
+	cache := registry.New()
+	out := filepath.Join(root, "dist")
+	for i := range 2 {
+		err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithInternalOutputPath(out),
+			options.WithInternalCache(cache),
+			options.WithForce(true),
+		)
+		failure, ok := errors.AsType[*Failure](err)
+		if !ok || len(failure.Errors) != 1 || !strings.Contains(failure.Errors[0].Error(), "decode image") {
+			t.Fatalf("build %d error = %v, want a decode image failure", i, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package build
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/olimci/shizuka/internal/manifest"
+)
+
+var ErrOversizedImage = errors.New("image exceeds size limit")
+
+var imageExts = map[string]struct{}{
+	".avif": {},
+	".bmp":  {},
+	".gif":  {},
+	".jpeg": {},
+	".jpg":  {},
+	".png":  {},
+	".svg":  {},
+	".tif":  {},
+	".tiff": {},
+	".webp": {},
+}
+
+func isImageExt(ext string) bool {
+	_, ok := imageExts[strings.ToLower(ext)]
+	return ok
+}
+
+// warnOversizedImage warns when the image claimed by claim is larger than
+// limit bytes and reports whether it did.
+func warnOversizedImage(sc *StepContext, claim manifest.Claim, size, limit int64) bool {
+	if limit <= 0 || size <= limit || !isImageExt(path.Ext(claim.Source)) {
+		return false
+	}
+	sc.Warn("image exceeds size limit; consider resizing or recompressing it", ErrOversizedImage, claim,
+		"path", claim.Source,
+		"size", formatBytes(size),
+		"limit", formatBytes(limit),
+	)
+	return true
+}
@@ -0,0 +1,70 @@
+package build
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/manifest"
+	"github.com/olimci/shizuka/internal/options"
+)
+
+func TestWarnOversizedImage(t *testing.T) {
+	var buf bytes.Buffer
+	sc := &StepContext{Logger: slog.New(slog.NewTextHandler(&buf, nil))}
+	claim := func(source string) manifest.Claim { return manifest.Claim{Owner: "static", Source: source} }
+
+	if !warnOversizedImage(sc, claim("static/hero.JPG"), 3<<20, 1<<20) {
+		t.Fatal("oversized image did not warn")
+	}
+	if out := buf.String(); !strings.Contains(out, "path=static/hero.JPG") || !strings.Contains(out, `size="3.0 MB"`) {
+		t.Fatalf("warning = %q, want path and size", out)
+	}
+
+	buf.Reset()
+	if warnOversizedImage(sc, claim("static/icon.png"), 512, 1<<20) {
+		t.Fatal("small image warned")
+	}
+	if warnOversizedImage(sc, claim("static/video.mp4"), 3<<20, 1<<20) {
+		t.Fatal("non-image file warned")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected log output %q", buf.String())
+	}
+}
+
+func TestStrictBuildFailsOnOversizedImage(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/index.md":         "---\ntitle: Home\ntemplate: page\n---\n",
+		"static/hero.png":          strings.Repeat("x", 64),
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Title }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	build := func(strict bool) error {
+		err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(filepath.Join(root, "dist")),
+			options.WithMaxImageSize(16),
+			options.WithForce(true),
+			options.WithStrict(strict),
+		)
+		return err
+	}
+
+	if err := build(false); err != nil {
+		t.Fatalf("Build() error = %v, want warnings to pass", err)
+	}
+	err := build(true)
+	var failure *Failure
+	if !errors.As(err, &failure) || !errors.Is(err, ErrOversizedImage) {
+		t.Fatalf("strict Build() error = %v, want oversized image failure", err)
+	}
+	if failure.Summary() != "1 error" {
+		t.Fatalf("summary = %q, want 1 error", failure.Summary())
+	}
+}
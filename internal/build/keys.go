@@ -11,11 +11,17 @@ import (
 const (
 	PagesK     = registry.K[[]*transforms.Page]("pages")
 	SiteK      = registry.K[*transforms.Site]("site")
+	SiteTmplK  = registry.K[*transforms.SiteTmpl]("site:tmpl")
 	DBK        = registry.K[*structql.DB]("db")
 	TemplatesK = registry.K[*template.Template]("templates")
 	BuildCtxK  = registry.K[*BuildCtx]("buildctx")
 	SiteGitK   = registry.K[*transforms.SiteGitMeta]("sitegit")
+	CSPK       = registry.K[*cspCollector]("csp")
+	ImagesK    = registry.K[*ImageSet]("images")
 
-	GitCacheK     = registry.K[*gitStepCache]("cache:git")
-	ChangedPathsK = registry.K[[]string]("cache:changed_paths")
+	GitCacheK      = registry.K[*gitStepCache]("cache:git")
+	ImageCacheK    = registry.K[*imageStepCache]("cache:images")
+	ImageSizesK    = registry.K[*imageSizeCache]("cache:image_sizes")
+	TemplateCacheK = registry.K[*templateStepCache]("cache:templates")
+	ChangedPathsK  = registry.K[[]string]("cache:changed_paths")
 )
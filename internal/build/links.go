@@ -0,0 +1,82 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/manifest"
+	"github.com/olimci/shizuka/internal/transforms"
+)
+
+var ErrBrokenLink = errors.New("link points at no output file")
+
+// brokenLink is a reference in a page body that resolves to no output file.
+type brokenLink struct {
+	Page *transforms.Page
+	Ref  string
+}
+
+// checkLinks reports links and image sources in rendered page bodies that
+// resolve to no claimed output file, as errors when links.Fail is set or the
+// build is strict, and warnings otherwise. It runs once every step has
+// claimed its outputs, so links to feeds, aliases and static files resolve.
+func checkLinks(pages []*transforms.Page, man *manifest.Manifest, cfg *config.Config, links *config.ConfigLinks, errs *errorState, logger *slog.Logger, strict bool) {
+	targets := make(map[string]struct{})
+	for _, claim := range man.Claims() {
+		targets[claim.Target] = struct{}{}
+	}
+
+	for _, broken := range findBrokenLinks(pages, targets, links, cfg.Site.URL, cfg.Build.IndexFile) {
+		if links.Fail || strict {
+			errs.Add(manifest.NewPageClaim(broken.Page.SourcePath, broken.Page.Path), fmt.Errorf("%w: %s", ErrBrokenLink, broken.Ref))
+		}
+		logger.Warn("broken link", "page", broken.Page.SourcePath, "href", broken.Ref)
+	}
+}
+
+// findBrokenLinks returns the site-relative references in the bodies of the
+// written pages that match none of targets, skipping external and
+// fragment-only references and ignored paths.
+func findBrokenLinks(pages []*transforms.Page, targets map[string]struct{}, links *config.ConfigLinks, siteURL, indexFile string) []brokenLink {
+	var broken []brokenLink
+	for _, page := range pages {
+		if page.Error != nil || page.NoRender || page.Body == "" {
+			continue
+		}
+		for _, ref := range htmlRefs([]byte(page.Body)) {
+			target, ok := resolveRef(page.OutputPath, ref, siteURL)
+			if !ok || linkTargetExists(targets, target, indexFile) {
+				continue
+			}
+			if slices.ContainsFunc(links.Ignore, func(pattern string) bool {
+				ok, _ := doublestar.Match(strings.TrimPrefix(pattern, "/"), target)
+				return ok
+			}) {
+				continue
+			}
+			broken = append(broken, brokenLink{Page: page, Ref: ref})
+		}
+	}
+	return broken
+}
+
+// linkTargetExists reports whether target, as resolved by resolveRef, names
+// an output file, either directly or as a route directory holding indexFile.
+func linkTargetExists(targets map[string]struct{}, target, indexFile string) bool {
+	candidates := []string{target, path.Join(target, indexFile)}
+	if path.Base(target) == "index.html" {
+		candidates = append(candidates, path.Join(path.Dir(target), indexFile))
+	}
+	for _, candidate := range candidates {
+		if _, ok := targets[candidate]; ok {
+			return true
+		}
+	}
+	return false
+}
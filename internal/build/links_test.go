@@ -0,0 +1,47 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/transforms"
+)
+
+func TestFindBrokenLinksResolvesAgainstOutputTargets(t *testing.T) {
+	page := &transforms.Page{
+		SourcePath: "content/posts/a.md",
+		Path:       "/posts/a/",
+		OutputPath: "posts/a/index.htm",
+		Body: `<a href="/">home</a><a href="../b/">b</a><a href="/posts/b">b</a>` +
+			`<a href="#top">top</a><a href="https://example.org/missing">ext</a><a href="mailto:a@example.com">mail</a>` +
+			`<a href="https://example.com/gone/">own host</a><img src="/img/cat.png"><img src="img/local.png">` +
+			`<a href="/feed.xml">feed</a><a href="/api/v1">api</a><a href="/nope/?q=1#x">nope</a>`,
+	}
+	unrendered := &transforms.Page{OutputPath: "x/index.htm", NoRender: true, Body: `<a href="/missing/">x</a>`}
+	targets := map[string]struct{}{
+		"index.htm":         {},
+		"posts/a/index.htm": {},
+		"posts/b/index.htm": {},
+		"img/cat.png":       {},
+		"feed.xml":          {},
+	}
+
+	broken := findBrokenLinks([]*transforms.Page{page, unrendered}, targets, &config.ConfigLinks{Ignore: []string{"/api/**"}}, "https://example.com", "index.htm")
+
+	var refs []string
+	for _, link := range broken {
+		if link.Page != page {
+			t.Fatalf("broken link %q reported for %q", link.Ref, link.Page.OutputPath)
+		}
+		refs = append(refs, link.Ref)
+	}
+	want := []string{"https://example.com/gone/", "img/local.png", "/nope/?q=1#x"}
+	if len(refs) != len(want) {
+		t.Fatalf("broken = %q, want %q", refs, want)
+	}
+	for i := range want {
+		if refs[i] != want[i] {
+			t.Fatalf("broken = %q, want %q", refs, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/manifest"
+	"github.com/olimci/shizuka/internal/options"
+	"github.com/olimci/shizuka/internal/registry"
+)
+
+var (
+	ErrUntitledPage = errors.New("page has no title")
+	ErrExpiresEarly = errors.New("page expires before it is created")
+)
+
+// StepLint reports pages that build but are probably mistakes: pages with no
+// title, and pages that expire before they are created and so never go live.
+// It runs once pages are indexed, before pages:resolve drops the ones that
+// never publish, and locks them for writing so it takes a fixed place among
+// the steps that change them.
+func StepLint(cfg *config.Config, opts *options.Options) StepPatch {
+	includeDrafts := opts.Dev || opts.IncludeDrafts
+	step := StepFunc("pages:lint", func(_ context.Context, sc *StepContext) error {
+		pages := registry.Get(sc.Registry, PagesK)
+
+		problems := 0
+		for _, page := range pages {
+			if page.Error != nil || page.Draft && !includeDrafts {
+				continue
+			}
+			claim := manifest.NewPageClaim(page.SourcePath, page.Path)
+
+			var errs []error
+			// The home page commonly takes the site title instead.
+			if page.Title == "" && !page.NoRender && page.Path != "/" {
+				errs = append(errs, ErrUntitledPage)
+			}
+			if !page.ExpiryDate.IsZero() && !page.ExpiryDate.After(page.Created) {
+				errs = append(errs, fmt.Errorf("%w: expiry_date %s, created %s", ErrExpiresEarly, page.ExpiryDate.Format(time.DateOnly), page.Created.Format(time.DateOnly)))
+			}
+
+			for _, err := range errs {
+				sc.Warn("content problem", err, claim, "page", page.SourcePath)
+			}
+			problems += len(errs)
+		}
+
+		sc.Logger.Info("pages linted", "pages", len(pages), "problems", problems)
+		return nil
+	}, "pages:index").Registry(registry.W(PagesK))
+
+	patch := StepPatchFunc(step).AddDependency("pages:resolve", "pages:lint")
+	if cfg.Content.Git != nil {
+		// Git dates replace the file dates the index step set.
+		patch = patch.AddDependency("pages:lint", "git")
+	}
+	return patch
+}
@@ -0,0 +1,71 @@
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/olimci/shizuka/internal/transforms"
+	gm "github.com/yuin/goldmark"
+)
+
+// markdownFuncMap returns the markdown template funcs, rendering with md so
+// that snippets get the same extensions as page bodies:
+//
+//   - markdown renders a value as a markdown document.
+//   - markdownify renders a short snippet, such as a param, without the
+//     paragraph wrapper when it is a single paragraph.
+//   - plainify strips markdown and HTML down to plain text.
+//
+// Values that are already template.HTML, such as page bodies, have been
+// rendered and pass through markdownify unchanged rather than being parsed
+// as markdown a second time.
+func markdownFuncMap(md gm.Markdown) template.FuncMap {
+	render := func(value any) (template.HTML, error) {
+		var buf bytes.Buffer
+		if err := md.Convert(fmt.Append(nil, value), &buf); err != nil {
+			return "", err
+		}
+		return template.HTML(buf.String()), nil
+	}
+	return template.FuncMap{
+		"markdown": render,
+		"markdownify": func(value any) (template.HTML, error) {
+			if html, ok := value.(template.HTML); ok {
+				return html, nil
+			}
+			html, err := render(value)
+			if err != nil {
+				return "", err
+			}
+			return unwrapParagraph(html), nil
+		},
+		"plainify": func(value any) (string, error) {
+			html, ok := value.(template.HTML)
+			if !ok {
+				var err error
+				if html, err = render(value); err != nil {
+					return "", err
+				}
+			}
+			return transforms.PlainText(string(html)), nil
+		},
+	}
+}
+
+// unwrapParagraph strips the <p> wrapper from a single rendered paragraph, so
+// inline snippets can sit inside headings and links. Longer output, with
+// several blocks, is left as is.
+func unwrapParagraph(html template.HTML) template.HTML {
+	s := strings.TrimSpace(string(html))
+	inner, ok := strings.CutPrefix(s, "<p>")
+	if !ok {
+		return html
+	}
+	inner, ok = strings.CutSuffix(inner, "</p>")
+	if !ok || strings.Contains(inner, "<p>") || strings.Contains(inner, "</p>") {
+		return html
+	}
+	return template.HTML(inner)
+}
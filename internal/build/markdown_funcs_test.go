@@ -0,0 +1,31 @@
+package build
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/markdown"
+)
+
+func TestMarkdownifyAndPlainify(t *testing.T) {
+	md := markdown.Build(config.DefaultConfig().Content.Markdown, markdown.Options{})
+	tmpl := template.Must(template.New("snippet").Funcs(markdownFuncMap(md)).Parse(
+		`<h1>{{ markdownify .Title }}</h1>{{ markdownify .Bio }}{{ markdownify .Body }}|{{ plainify .Title }}|{{ plainify .Body }}`,
+	))
+
+	var buf strings.Builder
+	err := tmpl.Execute(&buf, map[string]any{
+		"Title": "Hello *world*",
+		"Bio":   "First.\n\nSecond.",
+		"Body":  template.HTML("<p>Already *rendered*</p>"),
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := "<h1>Hello <em>world</em></h1><p>First.</p>\n<p>Second.</p>\n<p>Already *rendered*</p>|Hello world|Already *rendered*"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
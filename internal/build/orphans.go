@@ -0,0 +1,99 @@
+package build
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/manifest"
+)
+
+var ErrOrphanedStatic = errors.New("static file is not referenced by any page")
+
+// checkOrphans reports static files in the finished output that nothing
+// references, as errors when cfg.Build.Orphans.Fail is set or the build is
+// strict, and warnings otherwise.
+func checkOrphans(man *manifest.Manifest, cfg *config.Config, errs *errorState, logger *slog.Logger, strict bool) error {
+	claims := make(map[string]manifest.Claim)
+	statics := make([]string, 0)
+	for _, claim := range man.Claims() {
+		if claim.Owner != "static" {
+			continue
+		}
+		claims[claim.Target] = claim
+		statics = append(statics, claim.Target)
+	}
+
+	orphans, err := findOrphans(os.DirFS(man.Output()), statics, cfg.Build.Orphans, cfg.Site.URL)
+	if err != nil {
+		return err
+	}
+	for _, target := range orphans {
+		if cfg.Build.Orphans.Fail || strict {
+			errs.Add(claims[target], ErrOrphanedStatic)
+		}
+		logger.Warn("orphaned static file", "target", target, "source", claims[target].Source)
+	}
+	return nil
+}
+
+// findOrphans returns the static targets in statics that no HTML or CSS file
+// in the output tree references, excluding allowlisted targets.
+func findOrphans(out fs.FS, statics []string, cfg *config.ConfigOrphans, siteURL string) ([]string, error) {
+	referenced := make(map[string]struct{})
+	err := fs.WalkDir(out, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		var refs []string
+		switch path.Ext(name) {
+		case ".html", ".htm":
+			data, err := fs.ReadFile(out, name)
+			if err != nil {
+				return err
+			}
+			refs = htmlRefs(data)
+		case ".css":
+			data, err := fs.ReadFile(out, name)
+			if err != nil {
+				return err
+			}
+			refs = cssRefs(data)
+		default:
+			return nil
+		}
+
+		for _, ref := range refs {
+			if target, ok := resolveRef(name, ref, siteURL); ok {
+				referenced[target] = struct{}{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []string
+	for _, target := range statics {
+		if _, ok := referenced[target]; ok {
+			continue
+		}
+		if slices.ContainsFunc(cfg.Allow, func(pattern string) bool {
+			ok, _ := doublestar.Match(strings.TrimPrefix(pattern, "/"), target)
+			return ok
+		}) {
+			continue
+		}
+		orphans = append(orphans, target)
+	}
+	slices.Sort(orphans)
+	return orphans, nil
+}
@@ -0,0 +1,35 @@
+package build
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+func TestFindOrphansReportsUnreferencedStaticFiles(t *testing.T) {
+	out := fstest.MapFS{
+		"index.html":       {Data: []byte(`<link rel="stylesheet" href="/css/site.css"><img srcset="img/a.png 1x, https://example.com/img/b.png 2x">`)},
+		"posts/index.html": {Data: []byte(`<a href="../files/doc.pdf#page=2">doc</a>`)},
+		"css/site.css":     {Data: []byte(`@font-face { src: url("../fonts/body.woff2"); }`)},
+	}
+	statics := []string{
+		"css/site.css",
+		"img/a.png",
+		"img/b.png",
+		"files/doc.pdf",
+		"fonts/body.woff2",
+		"img/unused.png",
+		"favicon.ico",
+	}
+
+	orphans, err := findOrphans(out, statics, &config.ConfigOrphans{Allow: []string{"favicon.ico"}}, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(orphans, []string{"img/unused.png"}) {
+		t.Fatalf("orphans = %v, want only the unreferenced, non-allowlisted image", orphans)
+	}
+}
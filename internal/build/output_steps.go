@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"maps"
 	"path/filepath"
 	"slices"
 	"strings"
@@ -15,6 +14,7 @@ import (
 
 	"github.com/olimci/shizuka/internal/config"
 	"github.com/olimci/shizuka/internal/manifest"
+	"github.com/olimci/shizuka/internal/markdown"
 	"github.com/olimci/shizuka/internal/registry"
 	"github.com/olimci/shizuka/internal/transforms"
 	"github.com/olimci/shizuka/internal/utils/fileutil"
@@ -37,14 +37,19 @@ func StepStatic(cfg *config.Config) Step {
 		}
 
 		m := NewMinifier(cfg.Build.Minifier)
+		cfgFingerprint := configFingerprint(cfg)
 		emitted := 0
-		err = fs.WalkDir(sc.Source.FS(), staticRoot, func(filePath string, d fs.DirEntry, err error) error {
+		err = walkIgnore(sc.Source.FS(), staticRoot, staticExclude(cfg), func(filePath string, d fs.DirEntry, err error) error {
 			if err != nil {
 				return err
 			}
 			if d.IsDir() {
 				return nil
 			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
 			rel, err := pathutil.RelPathWithin(staticRoot, filePath)
 			if err != nil {
 				return err
@@ -56,8 +61,13 @@ func StepStatic(cfg *config.Config) Step {
 				Target: rel,
 				Canon:  rel,
 			}
+			if cfg.Build.ImageSize != nil {
+				warnOversizedImage(sc, claim, info.Size(), cfg.Build.ImageSize.Max)
+			}
 			emitted++
-			return sc.Manifest.Emit(manifest.StaticArtefact(sc.Source.FS(), claim).Post(m))
+			return sc.Manifest.Emit(manifest.StaticArtefact(sc.Source.FS(), claim).
+				Post(m).
+				Fingerprinted(manifest.Fingerprint(cfgFingerprint, statFingerprint(source, info))))
 		})
 		if err != nil {
 			return fmt.Errorf("static source %q: %w", staticRoot, err)
@@ -195,34 +205,50 @@ func applyGitMetadata(page *transforms.Page, info *transforms.PageGitMeta, backf
 }
 
 func StepHeaders(cfg *config.Config) StepPatch {
-	return StepPatchFunc(StepFunc("headers", func(_ context.Context, sc *StepContext) error {
-		headers := make(map[string]map[string]string, len(cfg.Artefacts.Headers.Values))
-		for path, kvs := range cfg.Artefacts.Headers.Values {
-			headers[path] = maps.Clone(kvs)
+	step := StepFunc("headers", func(_ context.Context, sc *StepContext) error {
+		pages := registry.Get(sc.Registry, PagesK)
+		buildCtx := registry.Get(sc.Registry, BuildCtxK)
+
+		rules := transforms.BuildHeaders(pages, cfg.Artefacts.Headers, buildCtx.Dev)
+		if csp, _ := registry.GetOk(sc.Registry, CSPK); csp != nil {
+			rules = transforms.ApplyCSP(rules, cfg.Artefacts.Headers.CSP.Policy, csp.snapshot())
 		}
-		if len(headers) == 0 {
+		if len(rules) == 0 {
 			return nil
 		}
 
-		return sc.Manifest.Emit(manifest.Artefact{
-			Claim: manifest.NewInternalClaim("headers", cfg.Artefacts.Headers.Path),
-			Builder: func(w io.Writer) error {
-				for pagePath, kvs := range headers {
-					fmt.Fprintf(w, "%s\n", pagePath)
-					for k, v := range kvs {
-						fmt.Fprintf(w, "  %s: %s\n", k, v)
-					}
-					fmt.Fprintln(w)
-				}
-				return nil
-			},
-		})
-	}))
+		return sc.Manifest.Emit(manifest.TextArtefact(
+			manifest.NewInternalClaim("headers", cfg.Artefacts.Headers.Path),
+			transforms.RenderHeaders(rules),
+		))
+	}, "pages:render").Registry(registry.R(PagesK), registry.R(BuildCtxK))
+
+	if cfg.Artefacts.Headers.CSP == nil {
+		return StepPatchFunc(step)
+	}
+	return StepPatchFunc(step.Registry(registry.R(CSPK))).AddDependency("headers", "pages:build")
 }
 
+// ErrAliasConflict is reported for a frontmatter alias whose path already
+// belongs to another page.
+var ErrAliasConflict = errors.New("alias conflicts with an existing page")
+
 func StepRedirects(cfg *config.Config) StepPatch {
 	return StepPatchFunc(StepFunc("redirects", func(_ context.Context, sc *StepContext) error {
-		redirects := slices.Clone(cfg.Artefacts.Redirects.Entries)
+		pages := registry.Get(sc.Registry, PagesK)
+
+		aliases, conflicts := transforms.AliasRedirects(pages)
+		for _, conflict := range conflicts {
+			sc.Warn("alias conflicts with an existing page; not redirecting",
+				fmt.Errorf("%w: %s", ErrAliasConflict, conflict.Alias),
+				manifest.NewPageClaim(conflict.Page.SourcePath, conflict.Page.Path),
+				"alias", conflict.Alias,
+				"page", conflict.Page.SourcePath,
+				"owner", conflict.Owner.SourcePath,
+			)
+		}
+
+		redirects := slices.Concat(cfg.Artefacts.Redirects.Entries, aliases)
 		if len(redirects) == 0 {
 			return nil
 		}
@@ -255,7 +281,7 @@ func StepRedirects(cfg *config.Config) StepPatch {
 				return nil
 			},
 		})
-	}))
+	}, "pages:render").Registry(registry.R(PagesK)))
 }
 
 func StepRSS(cfg *config.Config) StepPatch {
@@ -263,15 +289,57 @@ func StepRSS(cfg *config.Config) StepPatch {
 		site := registry.Get(sc.Registry, SiteK)
 		pages := registry.Get(sc.Registry, PagesK)
 
-		doc, err := transforms.RenderRSS(transforms.BuildRSS(pages, site, cfg.Artefacts.RSS))
+		data := transforms.BuildRSS(pages, site, cfg.Artefacts.RSS)
+		doc, err := transforms.RenderRSS(data)
+		if err != nil {
+			return err
+		}
+		claim := manifest.NewInternalClaim("rss", cfg.Artefacts.RSS.Path)
+		if item, err := validateXML(doc, "item"); err != nil {
+			if item >= 0 {
+				err = fmt.Errorf("%w (in item for %s)", err, data.Items[item].Link)
+			}
+			sc.Error(fmt.Errorf("rss is not well-formed XML: %w", err), claim)
+		}
+		return sc.Manifest.Emit(manifest.TextArtefact(claim, doc))
+	}, "pages:render").Registry(registry.R(SiteK), registry.R(PagesK)))
+}
+
+func StepJSONFeed(cfg *config.Config) StepPatch {
+	return StepPatchFunc(StepFunc("json_feed", func(_ context.Context, sc *StepContext) error {
+		site := registry.Get(sc.Registry, SiteK)
+		pages := registry.Get(sc.Registry, PagesK)
+
+		doc, err := transforms.RenderJSONFeed(transforms.BuildJSONFeed(pages, site, cfg.Artefacts.JSONFeed))
 		if err != nil {
 			return err
 		}
 		return sc.Manifest.Emit(manifest.TextArtefact(
-			manifest.NewInternalClaim("rss", cfg.Artefacts.RSS.Path),
+			manifest.NewInternalClaim("json_feed", cfg.Artefacts.JSONFeed.Path),
 			doc,
 		))
-	}, "pages:resolve").Registry(registry.R(SiteK), registry.R(PagesK)))
+	}, "pages:render").Registry(registry.R(SiteK), registry.R(PagesK)))
+}
+
+func StepAtom(cfg *config.Config) StepPatch {
+	return StepPatchFunc(StepFunc("atom", func(_ context.Context, sc *StepContext) error {
+		site := registry.Get(sc.Registry, SiteK)
+		pages := registry.Get(sc.Registry, PagesK)
+
+		data := transforms.BuildAtom(pages, site, cfg.Artefacts.Atom)
+		doc, err := transforms.RenderAtom(data)
+		if err != nil {
+			return err
+		}
+		claim := manifest.NewInternalClaim("atom", cfg.Artefacts.Atom.Path)
+		if entry, err := validateXML(doc, "entry"); err != nil {
+			if entry >= 0 {
+				err = fmt.Errorf("%w (in entry for %s)", err, data.Entries[entry].Link)
+			}
+			sc.Error(fmt.Errorf("atom feed is not well-formed XML: %w", err), claim)
+		}
+		return sc.Manifest.Emit(manifest.TextArtefact(claim, doc))
+	}, "pages:render").Registry(registry.R(SiteK), registry.R(PagesK)))
 }
 
 func StepSitemap(cfg *config.Config) StepPatch {
@@ -279,15 +347,63 @@ func StepSitemap(cfg *config.Config) StepPatch {
 		site := registry.Get(sc.Registry, SiteK)
 		pages := registry.Get(sc.Registry, PagesK)
 
-		doc, err := transforms.RenderSitemap(transforms.BuildSitemap(pages, site, cfg.Artefacts.Sitemap))
+		data := transforms.BuildSitemap(pages, site, cfg.Artefacts.Sitemap)
+		index, parts, split := transforms.BuildSitemapIndex(data, site, cfg.Artefacts.Sitemap)
+		if !split {
+			return emitSitemap(sc, cfg.Artefacts.Sitemap.Path, data)
+		}
+
+		for _, part := range parts {
+			if err := emitSitemap(sc, part.Path, part.Data); err != nil {
+				return err
+			}
+		}
+		doc, err := transforms.RenderSitemapIndex(index)
 		if err != nil {
 			return err
 		}
-		return sc.Manifest.Emit(manifest.TextArtefact(
-			manifest.NewInternalClaim("sitemap", cfg.Artefacts.Sitemap.Path),
-			doc,
-		))
-	}, "pages:resolve").Registry(registry.R(SiteK), registry.R(PagesK)))
+		claim := manifest.NewInternalClaim("sitemap", cfg.Artefacts.Sitemap.Path)
+		if _, err := validateXML(doc, "sitemap"); err != nil {
+			sc.Error(fmt.Errorf("sitemap index is not well-formed XML: %w", err), claim)
+		}
+		return sc.Manifest.Emit(manifest.TextArtefact(claim, doc))
+	}, "pages:render").Registry(registry.R(SiteK), registry.R(PagesK)))
+}
+
+func emitSitemap(sc *StepContext, target string, data transforms.SitemapTemplateData) error {
+	doc, err := transforms.RenderSitemap(data)
+	if err != nil {
+		return err
+	}
+	claim := manifest.NewInternalClaim("sitemap", target)
+	if item, err := validateXML(doc, "url"); err != nil {
+		if item >= 0 {
+			err = fmt.Errorf("%w (in entry for %s)", err, data.Items[item].Loc)
+		}
+		sc.Error(fmt.Errorf("sitemap is not well-formed XML: %w", err), claim)
+	}
+	return sc.Manifest.Emit(manifest.TextArtefact(claim, doc))
+}
+
+// StepHighlight warns about an unknown highlighting style and, when code is
+// highlighted with classes, emits the stylesheet for them.
+func StepHighlight(cfg *config.Config) StepPatch {
+	hl := cfg.Content.Markdown.Highlighting
+	return StepPatchFunc(StepFunc("highlight", func(_ context.Context, sc *StepContext) error {
+		claim := manifest.NewInternalClaim("highlight", hl.CSS)
+		if _, err := markdown.HighlightStyle(hl); err != nil {
+			sc.Warn("unknown highlighting style", err, claim)
+		}
+		if !hl.Classes {
+			return nil
+		}
+
+		css, err := markdown.HighlightCSS(hl)
+		if err != nil {
+			return err
+		}
+		return sc.Manifest.Emit(manifest.TextArtefact(claim, css))
+	}))
 }
 
 func StepRobots(cfg *config.Config) StepPatch {
@@ -300,12 +416,12 @@ func StepRobots(cfg *config.Config) StepPatch {
 			manifest.NewInternalClaim("robots", cfg.Artefacts.Robots.Path),
 			doc,
 		))
-	}, "pages:resolve").Registry(registry.R(SiteK), registry.R(PagesK)))
+	}, "pages:render").Registry(registry.R(SiteK), registry.R(PagesK)))
 }
 
 func StepNotFound(cfg *config.Config) StepPatch {
 	return StepPatchFunc(StepFunc("not_found", func(_ context.Context, sc *StepContext) error {
-		site := registry.Get(sc.Registry, SiteK)
+		siteTmpl := registry.Get(sc.Registry, SiteTmplK)
 		tmpl := registry.Get(sc.Registry, TemplatesK)
 
 		claim := manifest.NewInternalClaim("not_found", cfg.Artefacts.NotFound.Path)
@@ -317,7 +433,7 @@ func StepNotFound(cfg *config.Config) StepPatch {
 		if pageTemplate := tmpl.Lookup(templateName); pageTemplate != nil {
 			return sc.Pool.Go(func(_ context.Context) error {
 				return emitRenderedTemplate(sc, claim, tmpl, templateName, transforms.PageTemplate{
-					Site: site.Tmpl(),
+					Site: *siteTmpl,
 				}, NewMinifier(cfg.Build.Minifier))
 			})
 		}
@@ -326,7 +442,7 @@ func StepNotFound(cfg *config.Config) StepPatch {
 		}
 
 		return sc.Manifest.Emit(manifest.TextArtefact(claim, "404 Not Found\n"))
-	}, "pages:templates").Registry(registry.R(SiteK), registry.R(TemplatesK)))
+	}, "pages:templates").Registry(registry.R(SiteTmplK), registry.R(PagesK), registry.R(TemplatesK)))
 }
 
 func StepMeta(cfg *config.Config) StepPatch {
@@ -355,5 +471,5 @@ func StepMeta(cfg *config.Config) StepPatch {
 			}
 			return emitDebugTemplate(sc, claim, data, NewMinifier(cfg.Build.Minifier))
 		})
-	}, "pages:resolve").Registry(registry.R(SiteK)))
+	}, "pages:render").Registry(registry.R(SiteK), registry.R(PagesK)))
 }
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"html/template"
 	"io/fs"
+	"log/slog"
 	"maps"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/frontmatter"
 	"github.com/olimci/shizuka/internal/manifest"
 	"github.com/olimci/shizuka/internal/markdown"
 	"github.com/olimci/shizuka/internal/options"
@@ -20,20 +24,68 @@ import (
 	"github.com/olimci/shizuka/internal/utils/pathutil"
 	"github.com/olimci/shizuka/internal/utils/pool"
 	"github.com/olimci/shizuka/internal/utils/tmplutil"
+	"github.com/olimci/shizuka/internal/version"
+	"github.com/olimci/structql"
 )
 
 var (
 	ErrNoTemplate       = errors.New("no template specified")
 	ErrTemplateNotFound = errors.New("template not found")
+
+	// ErrToCWithoutHeadingIDs is reported when content.markdown.toc is set
+	// but headings get no IDs for its entries to link to.
+	ErrToCWithoutHeadingIDs = errors.New("content.markdown.toc is set without parser.auto_heading_id")
 )
 
 func StepContent(cfg *config.Config, opts *options.Options) []Step {
+	includeDrafts := opts.Dev || opts.IncludeDrafts
+
 	build := StepFunc("pages:build", func(_ context.Context, sc *StepContext) error {
 		pages := registry.Get(sc.Registry, PagesK)
 		site := registry.Get(sc.Registry, SiteK)
+		siteTmpl := *registry.Get(sc.Registry, SiteTmplK)
 		tmpl := registry.Get(sc.Registry, TemplatesK)
 		minifier := NewMinifier(cfg.Build.Minifier)
 
+		// Pagination output is never fingerprinted, so paginated pages are
+		// always re-rendered.
+		// Inline hashes are collected from rendered output, so pages are
+		// always rendered when a CSP is generated.
+		var csp *cspCollector
+		if cfg.Artefacts.Headers != nil && cfg.Artefacts.Headers.CSP != nil {
+			csp = newCSPCollector()
+		}
+		renders := pool.NewBatch[struct{}](sc.Pool)
+		render := func(req pageRenderRequest) error {
+			if csp == nil {
+				return sc.Pool.Go(func(_ context.Context) error {
+					return renderPageTemplate(sc, req)
+				})
+			}
+			req.CSP = csp
+			renders.Go(func(_ context.Context) (struct{}, error) {
+				return struct{}{}, renderPageTemplate(sc, req)
+			})
+			return nil
+		}
+
+		// Templates that read the clock render differently on every build,
+		// so their pages are never reused.
+		siteFingerprint := ""
+		if opts.ArtefactCachePath != "" && !opts.Dev && csp == nil && !timeDependent(tmpl) {
+			tree, err := siteTreeFingerprint(sc.Source, cfg)
+			if err != nil {
+				return err
+			}
+			siteFingerprint = manifest.Fingerprint(configFingerprint(cfg), tree, pagesFingerprint(site, pages))
+		}
+		pageFingerprint := func(claim manifest.Claim, templateName string) string {
+			if siteFingerprint == "" {
+				return ""
+			}
+			return manifest.Fingerprint(siteFingerprint, claim.Target, templateName)
+		}
+
 		emitDebug := func(page *transforms.Page, claim manifest.Claim, err error) error {
 			if !opts.Dev {
 				return nil
@@ -42,14 +94,15 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 				return emitDebugTemplate(sc, claim, transforms.PageTemplate{
 					Error: err,
 					Page:  page.Tmpl(),
-					Site:  site.Tmpl(),
+					Site:  siteTmpl,
 				}, minifier)
 			})
 		}
 
-		var built, errored, drafts int
+		var built, variants, errored, unrendered int
+		var missingFallback int
 		for _, page := range pages {
-			claim := manifest.NewPageClaim(page.SourcePath, page.Path)
+			claim := manifest.NewPageClaim(page.SourcePath, page.Path).WithIndexFile(cfg.Build.IndexFile)
 
 			if page.Error != nil {
 				errored++
@@ -59,12 +112,22 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 				continue
 			}
 
-			if page.Draft && !opts.Dev {
-				drafts++
+			if page.NoRender {
+				unrendered++
 				continue
 			}
 
-			if page.Template == "" {
+			templateName, via := pageTemplate(page, tmpl, cfg.Content.DefaultTemplate)
+			if via == "fallback" && tmpl.Lookup(templateName) == nil {
+				// Reported once below rather than for every page.
+				errored++
+				missingFallback++
+				if err := emitDebug(page, claim, fmt.Errorf("%w: fallback %q for a page with no template", ErrTemplateNotFound, templateName)); err != nil {
+					return err
+				}
+				continue
+			}
+			if templateName == "" {
 				errored++
 				sc.Error(ErrNoTemplate, claim)
 				if err := emitDebug(page, claim, ErrNoTemplate); err != nil {
@@ -72,10 +135,11 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 				}
 				continue
 			}
+			sc.Logger.Debug("page template chosen", "page", page.SourcePath, "template", templateName, "via", via)
 
-			if tmpl.Lookup(page.Template) == nil {
+			if tmpl.Lookup(templateName) == nil {
 				errored++
-				err := fmt.Errorf("%w: %q", ErrTemplateNotFound, page.Template)
+				err := fmt.Errorf("%w: %q", ErrTemplateNotFound, templateName)
 				sc.Error(err, claim)
 				if err := emitDebug(page, claim, err); err != nil {
 					return err
@@ -84,23 +148,57 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 			}
 
 			built++
-			if err := sc.Pool.Go(func(_ context.Context) error {
-				return renderPageTemplate(sc, pageRenderRequest{
-					Claim:        claim,
-					TemplateName: page.Template,
+			if err := render(pageRenderRequest{
+				Claim:        claim,
+				TemplateName: templateName,
+				Templates:    tmpl,
+				Page:         page.Tmpl(),
+				Site:         siteTmpl,
+				Minifier:     minifier,
+				Fingerprint:  pageFingerprint(claim, templateName),
+			}); err != nil {
+				return err
+			}
+
+			for _, variant := range transforms.PageVariants(page, cfg.Content.Variants) {
+				variantClaim := manifest.NewPageClaim(page.SourcePath, variant.Path).WithIndexFile(cfg.Build.IndexFile).Own("variant:" + variant.Name)
+				if tmpl.Lookup(variant.Template) == nil {
+					sc.Error(fmt.Errorf("%w: %q (variant %q)", ErrTemplateNotFound, variant.Template, variant.Name), variantClaim)
+					continue
+				}
+
+				variants++
+				if err := render(pageRenderRequest{
+					Claim:        variantClaim,
+					TemplateName: variant.Template,
 					Templates:    tmpl,
 					Page:         page.Tmpl(),
-					Site:         site.Tmpl(),
+					Site:         siteTmpl,
 					Minifier:     minifier,
-				})
-			}); err != nil {
+					Fingerprint:  pageFingerprint(variantClaim, variant.Template),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+
+		if missingFallback > 0 {
+			sc.Error(fmt.Errorf("%w: fallback %q for %d pages with no template", ErrTemplateNotFound, cfg.Content.DefaultTemplate, missingFallback), manifest.Claim{Source: opts.ConfigPath})
+		}
+
+		if csp != nil {
+			if _, err := renders.Wait(); err != nil {
 				return err
 			}
+			registry.Set(sc.Registry, CSPK, csp)
 		}
 
-		sc.Logger.Info("pages built", "built", built, "errored", errored, "drafts_skipped", drafts)
+		sc.Logger.Info("pages built", "built", built, "variants", variants, "errored", errored, "unrendered", unrendered)
 		return nil
-	}, "pages:templates").Registry(registry.R(PagesK), registry.R(SiteK), registry.R(TemplatesK))
+	}, "pages:templates").Registry(registry.R(PagesK), registry.R(SiteK), registry.R(SiteTmplK), registry.R(TemplatesK))
+	if cfg.Artefacts.Headers != nil && cfg.Artefacts.Headers.CSP != nil {
+		build = build.Registry(registry.W(CSPK))
+	}
 
 	resolve := StepFunc("pages:resolve", func(_ context.Context, sc *StepContext) error {
 		pages := registry.Get(sc.Registry, PagesK)
@@ -117,14 +215,34 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 			Params:      maps.Clone(cfg.Site.Params),
 			Dev:         opts.Dev,
 			Git:         *siteGit,
-			BuildTime:   buildCtx.StartTime,
+			BuildTime:   buildCtx.BuildTime,
+			Version:     version.String(),
+		}
+
+		// Dev previews scheduled, expired and draft pages; other builds drop
+		// them before anything reads the page list, so no collection, feed
+		// or sitemap can refer to a page that is not written.
+		if !opts.Dev {
+			pages = publishedPages(pages, buildCtx.StartTime, opts.Dev, includeDrafts, sc.Logger)
+			registry.Set(sc.Registry, PagesK, pages)
 		}
 
+		cascadeSectionParams(pages, func(page *transforms.Page, err error) {
+			sc.Error(err, manifest.NewPageClaim(page.SourcePath, page.Path))
+		})
 		for _, page := range pages {
 			if page.Error != nil {
 				continue
 			}
+			page.ResolveNoIndex(buildCtx.StartTime, cfg.Content.NoIndex)
+			if page.PubDate.IsZero() {
+				page.PubDate = buildCtx.BuildTime
+			}
 
+			// A canonical URL set in frontmatter is kept as written.
+			if page.Canon != "" {
+				continue
+			}
 			canon, err := pathutil.CanonicalPageURL(site.URL, page.Path)
 			if err != nil {
 				sc.Error(fmt.Errorf("canonical URL from site.url %q and page path %q: %w", site.URL, page.Path, err), manifest.NewPageClaim(page.SourcePath, page.Path))
@@ -132,6 +250,9 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 			}
 			page.Canon = canon
 		}
+		transforms.ResolveBreadcrumbs(pages, includeDrafts)
+		transforms.ResolveTranslations(pages, cfg.Content.Languages, includeDrafts)
+		site.SetPages(sitePages(pages, includeDrafts))
 
 		registry.Set(sc.Registry, SiteK, site)
 		return nil
@@ -139,57 +260,107 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 
 	templates := StepFunc("pages:templates", func(_ context.Context, sc *StepContext) error {
 		pages := registry.Get(sc.Registry, PagesK)
-		funcs := tmplutil.DefaultFuncs()
-		md := markdown.Build(cfg.Content.Markdown, markdownOptions(cfg.Content.Markdown, pages, opts.Dev))
-		funcs["markdown"] = func(value any) (template.HTML, error) {
-			var buf strings.Builder
-			if err := md.Convert(fmt.Append(nil, value), &buf); err != nil {
-				return "", err
+		images, _ := registry.GetOk(sc.Registry, ImagesK)
+		var sizeCache *imageSizeCache
+		if sc.Cache != nil {
+			sizeCache = registry.Get(sc.Cache, ImageSizesK)
+			if sizeCache == nil {
+				sizeCache = &imageSizeCache{}
+				registry.Set(sc.Cache, ImageSizesK, sizeCache)
 			}
-			return template.HTML(buf.String()), nil
 		}
-		maps.Copy(funcs, QueryFuncMap(registry.Get(sc.Registry, DBK)))
-		maps.Copy(funcs, paginationFuncMap())
+		sizes := newImageSizes(sc.Source.FS(), cfg.Paths.Static, sizeCache, images)
+		funcs := withUserFuncs(pageTemplateFuncs(cfg, sc.Source.FS(), pages, registry.Get(sc.Registry, DBK), sizes, includeDrafts), opts.TemplateFuncs, sc.Logger)
 
 		templateGlob := path.Join(cfg.Paths.Templates, "html", "**", "*.tmpl")
-		tmpl, err := parseRequiredTemplates(sc.Source.FS(), templateGlob, funcs)
-		if err != nil {
-			return err
+		var tmplCache *templateStepCache
+		if sc.Cache != nil {
+			tmplCache = registry.Get(sc.Cache, TemplateCacheK)
+			if tmplCache == nil {
+				tmplCache = &templateStepCache{}
+				registry.Set(sc.Cache, TemplateCacheK, tmplCache)
+			}
+		}
+
+		// Rebuilds that leave the templates and config alone reuse the
+		// earlier parse; only the functions, which close over this build's
+		// pages, are rebound.
+		scope := registry.Get(sc.Registry, BuildCtxK).Scope
+		reused := tmplCache != nil && tmplCache.Parsed != nil && tmplCache.Glob == templateGlob && scope != ScopeFull
+		var tmpl *template.Template
+		if reused {
+			clone, err := tmplCache.Parsed.Clone()
+			if err != nil {
+				return err
+			}
+			tmpl = clone.Funcs(funcs)
+		} else {
+			parsed, err := parseRequiredTemplates(sc.Source.FS(), templateGlob, funcs)
+			if err == nil {
+				err = parsePartials(sc.Source.FS(), parsed, cfg.Paths.PartialsGlob, funcs)
+			}
+			if tmplCache != nil {
+				tmplCache.Glob, tmplCache.Parsed = templateGlob, nil
+			}
+			if err != nil {
+				return err
+			}
+			tmpl = parsed
+			if tmplCache != nil {
+				tmplCache.Parsed = parsed
+				if tmpl, err = parsed.Clone(); err != nil {
+					return err
+				}
+			}
 		}
 
 		registry.Set(sc.Registry, TemplatesK, tmpl)
+		// Page bodies are rendered by now, so the site's template view is
+		// converted once here for every page and artefact that renders it.
+		siteTmpl := registry.Get(sc.Registry, SiteK).Tmpl()
+		registry.Set(sc.Registry, SiteTmplK, &siteTmpl)
 		var tmplCount int
 		if tmpl != nil {
 			tmplCount = len(tmpl.Templates())
 		}
-		sc.Logger.Info("templates parsed", "count", tmplCount, "glob", templateGlob)
+		if reused {
+			sc.Logger.Info("templates reused", "count", tmplCount, "scope", scope)
+		} else {
+			sc.Logger.Info("templates parsed", "count", tmplCount, "glob", templateGlob)
+		}
 		return nil
-	}, "pages:query").Registry(registry.R(PagesK), registry.R(DBK), registry.W(TemplatesK))
+	}, "pages:query").Registry(registry.R(PagesK), registry.R(SiteK), registry.R(DBK), registry.RX(ImagesK), registry.R(BuildCtxK), registry.W(TemplatesK), registry.W(SiteTmplK)).Cache(registry.W(ImageSizesK), registry.W(TemplateCacheK))
 
 	index := StepFunc("pages:index", func(_ context.Context, sc *StepContext) error {
-		contentRoot := cfg.Paths.Content
-		var pageSources []string
+		type pageSource struct {
+			source *config.ConfigContentSource
+			fsys   fs.FS
+			rel    string
+		}
+		var pageSources []pageSource
 
-		if err := fs.WalkDir(sc.Source.FS(), contentRoot, func(filePath string, d fs.DirEntry, err error) error {
+		sources := cfg.ContentSources()
+		for i := range sources {
+			contentSource := &sources[i]
+			contentRoot, err := openContentSource(sc.Source, contentSource.Path)
 			if err != nil {
-				return err
-			}
-			if d.IsDir() {
-				return nil
+				return fmt.Errorf("content source %q: %w", contentSource.Path, err)
 			}
+			defer contentRoot.Close()
 
-			rel, err := pathutil.RelPathWithin(contentRoot, filePath)
-			if err != nil {
-				return err
-			}
-			if !isPageSourceExt(path.Ext(rel)) {
+			if err := fs.WalkDir(contentRoot.FS(), ".", func(rel string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() || !isPageSourceExt(path.Ext(rel), cfg.Content.Extensions) {
+					return nil
+				}
+
+				pageSources = append(pageSources, pageSource{source: contentSource, fsys: contentRoot.FS(), rel: rel})
 				return nil
+			}); err != nil {
+				return fmt.Errorf("content source %q: %w", contentSource.Path, err)
 			}
-
-			pageSources = append(pageSources, rel)
-			return nil
-		}); err != nil {
-			return fmt.Errorf("content source %q: %w", contentRoot, err)
 		}
 
 		type pageResult struct {
@@ -198,9 +369,13 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 		}
 
 		batch := pool.NewBatch[pageResult](sc.Pool)
-		for i, rel := range pageSources {
+		for i, ps := range pageSources {
 			batch.Go(func(_ context.Context) (pageResult, error) {
-				source := pathutil.JoinSlashRel(contentRoot, rel)
+				source := pathutil.JoinSlashRel(ps.source.Path, ps.rel)
+				// Pages are placed in one tree by their path under the
+				// source's URL prefix.
+				rel := path.Join(ps.source.URLPrefix, ps.rel)
+				lang, rel, translationKey := transforms.PageLanguage(rel, cfg.Content.Languages)
 				routePath, err := pathutil.RoutePathForContentPath(rel)
 				if err != nil {
 					sc.Error(err, manifest.NewPageClaim(source, ""))
@@ -208,8 +383,9 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 				}
 
 				page, err := transforms.BuildPage(
-					sc.Source.FS(),
-					source,
+					ps.fsys,
+					ps.rel,
+					cfg.Content.Extensions,
 					cfg.Content.Defaults.Section,
 					cfg.Content.Defaults.Global,
 					cfg.Content.Defaults.Sections,
@@ -219,7 +395,7 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 						SourcePath:  source,
 						ContentPath: rel,
 						Path:        routePath,
-						OutputPath:  pathutil.OutputPathForRoutePath(routePath),
+						OutputPath:  pathutil.OutputPathForRoutePath(routePath, cfg.Build.IndexFile),
 						Error:       err,
 					}
 					sc.Error(err, manifest.NewPageClaim(source, routePath))
@@ -228,9 +404,33 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 
 				page.SourcePath = source
 				page.ContentPath = rel
+				page.Collection = ps.source.Name
+				page.Lang = lang
+				page.TranslationKey = translationKey
+				if len(ps.source.Params) > 0 {
+					page.Params = frontmatter.MergeParams(ps.source.Params, page.Params)
+				}
+				if page.Title == "" && cfg.Content.TitleFromFilename {
+					page.Title = transforms.TitleFromPath(rel)
+				}
+				if page.URL != "" {
+					override, err := pathutil.RoutePathForURL(page.URL)
+					if err != nil {
+						page.Path = routePath
+						page.OutputPath = pathutil.OutputPathForRoutePath(routePath, cfg.Build.IndexFile)
+						page.Error = fmt.Errorf("invalid url %q: %w", page.URL, err)
+						sc.Error(page.Error, manifest.NewPageClaim(source, routePath))
+						return pageResult{Index: i, Page: page}, nil
+					}
+					routePath = override
+				}
 				page.Path = routePath
-				page.OutputPath = pathutil.OutputPathForRoutePath(routePath)
-				attachPageFileMeta(page, filepath.Join(sc.Source.Name(), filepath.FromSlash(source)))
+				page.OutputPath = pathutil.OutputPathForRoutePath(routePath, cfg.Build.IndexFile)
+				// File times are not content, so a deterministic build
+				// neither exposes them nor dates pages with them.
+				if !opts.Deterministic {
+					attachPageFileMeta(page, filepath.Join(sc.Source.Name(), filepath.FromSlash(source)))
+				}
 				return pageResult{Index: i, Page: page}, nil
 			})
 		}
@@ -258,7 +458,10 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 				if err != nil {
 					page.Error = fmt.Errorf("invalid route path %q: %w", page.Path, err)
 					sc.Error(page.Error, claim)
-				} else if previous, exists := seenPaths[routePath]; exists {
+				} else if previous, exists := seenPaths[routePath]; exists && page.URL != "" {
+					page.Error = fmt.Errorf("url %q of %s collides with %s", page.URL, page.SourcePath, previous)
+					sc.Error(page.Error, manifest.NewPageClaim(page.SourcePath, routePath))
+				} else if exists {
 					page.Error = fmt.Errorf("duplicate route path %q (%s, %s)", routePath, previous, page.SourcePath)
 					sc.Error(page.Error, manifest.NewPageClaim(page.SourcePath, routePath))
 				} else {
@@ -309,12 +512,22 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 		var mdTemplates *template.Template
 		if cfg.Content.Markdown.Components {
 			templateGlob := path.Join(cfg.Paths.Templates, "md", "**", "*.tmpl")
-			tmpl, err := parseOptionalTemplates(sc.Source.FS(), templateGlob, tmplutil.DefaultFuncs())
+			tmpl, err := parseOptionalTemplates(sc.Source.FS(), templateGlob, withUserFuncs(tmplutil.DefaultFuncs(), opts.TemplateFuncs, sc.Logger))
 			if err != nil {
 				return err
 			}
 			mdTemplates = tmpl
 		}
+		shortcodeDir := path.Join(cfg.Paths.Templates, "shortcodes")
+		shortcodeTemplates, err := parseOptionalTemplates(sc.Source.FS(), path.Join(shortcodeDir, "**", "*.tmpl"), withUserFuncs(tmplutil.DefaultFuncs(), opts.TemplateFuncs, sc.Logger))
+		if err != nil {
+			return err
+		}
+		codes := &shortcodes{tmpl: shortcodeTemplates, dir: shortcodeDir}
+
+		if cfg.Content.Markdown.ToC != nil && !cfg.Content.Markdown.Parser.AutoHeadingID {
+			sc.Warn("headings without an explicit id will not be linkable", ErrToCWithoutHeadingIDs, manifest.Claim{Source: opts.ConfigPath})
+		}
 
 		batch := pool.NewBatch[*transforms.Page](sc.Pool)
 		preprocessed := 0
@@ -327,26 +540,41 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 			batch.Go(func(_ context.Context) (*transforms.Page, error) {
 				switch page.Preprocess {
 				case "markdown":
-					rawBody := page.RawBody
+					claim := manifest.NewPageClaim(page.SourcePath, page.Path)
+					rawBody, output := codes.expand(page, page.RawBody, func(err error) { sc.Error(err, claim) })
 					if mdTemplates != nil {
-						rendered, err := renderMarkdownComponentTemplate(mdTemplates, page)
+						rendered, err := renderMarkdownComponents(mdTemplates, page, rawBody, cfg.Content.Markdown.Summary.Divider)
 						if err != nil {
 							return nil, err
 						}
 						rawBody = rendered
 					}
+					rawBody = output.restoreMarkdown(rawBody)
 
 					md := publicMD
 					if page.Draft {
 						md = draftMD
 					}
-					doc, err := markdown.Render(md, page.SourcePath, rawBody)
+					doc, err := markdown.RenderWithOptions(md, page.SourcePath, rawBody, markdown.RenderOptions{
+						SummaryParagraphs: cfg.Content.Markdown.Summary.Paragraphs,
+						SummaryDivider:    cfg.Content.Markdown.Summary.Divider,
+						Placeholders:      output.placeholders(),
+					})
 					if err != nil {
 						return nil, err
 					}
-					page.Body = doc.Body
+					page.Body = restoreShortcodes(doc.Body, output.html)
+					page.Summary = restoreShortcodes(doc.Summary, output.html)
 					page.Sections = doc.Sections
-					page.ToC = doc.ToC
+					for i, section := range page.Sections {
+						page.Sections[i] = restoreShortcodes(section, output.html)
+					}
+					if !page.NoToC {
+						page.ToC = doc.ToC
+						if toc := cfg.Content.Markdown.ToC; toc != nil {
+							page.ToCTree = markdown.NestToC(doc.ToC, toc.MinLevel, toc.MaxLevel)
+						}
+					}
 
 					page.Preprocess = ""
 				default:
@@ -356,11 +584,16 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 			})
 		}
 
-		_, err := batch.Wait()
-		if err == nil {
-			sc.Logger.Info("pages preprocessed", "count", preprocessed)
+		if _, err := batch.Wait(); err != nil {
+			return err
 		}
-		return err
+		for _, page := range pages {
+			if page.Error == nil {
+				page.SummaryText = transforms.PlainSummary(page, transforms.SummaryTextLength)
+			}
+		}
+		sc.Logger.Info("pages preprocessed", "count", preprocessed)
+		return nil
 	}, "pages:resolve").Registry(registry.W(PagesK))
 
 	query := StepFunc("pages:query", func(_ context.Context, sc *StepContext) error {
@@ -383,6 +616,199 @@ func StepContent(cfg *config.Config, opts *options.Options) []Step {
 	return []Step{index, resolve, render, query, templates, build}
 }
 
+// StepRelated links each page to the pages it shares the most tags with,
+// once the published page list is settled and before page bodies render.
+func StepRelated(cfg *config.Config) StepPatch {
+	return StepPatchFunc(StepFunc("pages:related", func(_ context.Context, sc *StepContext) error {
+		pages := registry.Get(sc.Registry, PagesK)
+		transforms.ResolveRelated(pages, cfg.Content.Related)
+
+		linked := 0
+		for _, page := range pages {
+			if len(page.Related) > 0 {
+				linked++
+			}
+		}
+		sc.Logger.Info("related pages resolved", "pages", linked)
+		return nil
+	}, "pages:resolve").Registry(registry.W(PagesK))).AddDependency("pages:render", "pages:related")
+}
+
+// publishedPages drops the pages that are not live at now, either scheduled
+// for later or past their expiry date, unless scheduled is set, and drafts
+// unless drafts is set. It logs the pages it skips.
+func publishedPages(pages []*transforms.Page, now time.Time, scheduled, drafts bool, logger *slog.Logger) []*transforms.Page {
+	kept := make([]*transforms.Page, 0, len(pages))
+	var unpublished, skippedDrafts []string
+	for _, page := range pages {
+		switch {
+		case page.Error != nil:
+		case !drafts && page.Draft:
+			skippedDrafts = append(skippedDrafts, page.SourcePath)
+			continue
+		case !scheduled && !page.Published(now):
+			unpublished = append(unpublished, page.SourcePath)
+			continue
+		}
+		kept = append(kept, page)
+	}
+	if len(skippedDrafts) > 0 {
+		logger.Info("drafts skipped", "count", len(skippedDrafts), "pages", skippedDrafts)
+	}
+	if len(unpublished) > 0 {
+		logger.Info("unpublished pages skipped", "count", len(unpublished), "pages", unpublished)
+	}
+	return kept
+}
+
+// sitePages returns the pages that belong in the site collections: every page
+// that built without error, with drafts included only in dev.
+func sitePages(pages []*transforms.Page, includeDrafts bool) []*transforms.Page {
+	kept := make([]*transforms.Page, 0, len(pages))
+	for _, page := range pages {
+		if page.Error != nil || page.Draft && !includeDrafts {
+			continue
+		}
+		kept = append(kept, page)
+	}
+	return kept
+}
+
+// cascadeSectionParams layers the params set by each section's index page
+// under the params of the pages below it, nearest section first. An index
+// page's `cascade` block is layered with its params, winning over them, and
+// also applies from the site root index, whose plain params do not cascade.
+// Precedence, lowest first: content defaults, then ancestor sections from the
+// root down, then the params a page sets itself, which always win.
+//
+// A cascaded `template` sets the template of the pages below rather than a
+// param, unless they name their own. A template that is not a string or a
+// scalar such as a YAML number is passed to report with its index page.
+func cascadeSectionParams(pages []*transforms.Page, report func(*transforms.Page, error)) {
+	sections := make(map[string]map[string]any)
+	templates := make(map[string]string)
+	for _, page := range pages {
+		if page.Error != nil || !page.IsIndex() {
+			continue
+		}
+		dir := path.Dir(page.ContentPath)
+		params := page.OwnParams
+		if dir == "." {
+			params = nil
+		}
+		if len(params) == 0 && len(page.Cascade) == 0 {
+			continue
+		}
+		merged := frontmatter.MergeParams(params, page.Cascade)
+		if value, ok := merged["template"]; ok {
+			delete(merged, "template")
+			if name, err := templateName(value); err != nil {
+				report(page, fmt.Errorf("cascaded template: %w", err))
+			} else {
+				templates[dir] = name
+			}
+		}
+		sections[dir] = merged
+	}
+	if len(sections) == 0 {
+		return
+	}
+
+	for _, page := range pages {
+		if page.Error != nil {
+			continue
+		}
+		dir := path.Dir(page.ContentPath)
+		if page.IsIndex() {
+			if dir == "." {
+				continue
+			}
+			dir = path.Dir(dir)
+		}
+		inherited := make(map[string]struct{})
+		inheritedTemplate := page.OwnTemplate != ""
+		for {
+			if name, ok := templates[dir]; ok && !inheritedTemplate {
+				page.Template = name
+				inheritedTemplate = true
+			}
+			for key, value := range sections[dir] {
+				if _, ok := page.OwnParams[key]; ok {
+					continue
+				}
+				if _, ok := inherited[key]; ok {
+					continue
+				}
+				if page.Params == nil {
+					page.Params = make(map[string]any)
+				}
+				page.Params[key] = value
+				inherited[key] = struct{}{}
+			}
+			if dir == "." {
+				break
+			}
+			dir = path.Dir(dir)
+		}
+	}
+}
+
+// templateName coerces a template name decoded from frontmatter, such as a
+// YAML number, to a string.
+func templateName(value any) (string, error) {
+	if name, ok := value.(string); ok {
+		return name, nil
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(value), nil
+	}
+	return "", fmt.Errorf("want a string, got %T", value)
+}
+
+// pageTemplate picks the template a page renders with: the one its own
+// frontmatter names, then by its type <section>/<type> and <type>, then the
+// default for its section, then fallback if that is unset or is the built-in
+// default and missing. A template that is named but missing never falls back.
+// via says which of these it was.
+func pageTemplate(page *transforms.Page, tmpl *template.Template, fallback string) (name, via string) {
+	if page.OwnTemplate != "" {
+		return page.OwnTemplate, "frontmatter"
+	}
+	if page.Type != "" {
+		for _, name := range []string{path.Join(page.Section, page.Type), page.Type} {
+			if tmpl.Lookup(name) != nil {
+				return name, "type"
+			}
+		}
+	}
+	builtinMissing := page.Template == config.DefaultPageTemplate && tmpl.Lookup(page.Template) == nil
+	if page.Template != "" && !builtinMissing || fallback == "" {
+		return page.Template, "default"
+	}
+	return fallback, "fallback"
+}
+
+// pageTemplateFuncs returns the functions available to page templates.
+func pageTemplateFuncs(cfg *config.Config, sourceFS fs.FS, pages []*transforms.Page, db *structql.DB, images *imageSizes, includeDrafts bool) template.FuncMap {
+	funcs := tmplutil.DefaultFuncs()
+	md := markdown.Build(cfg.Content.Markdown, markdownOptions(cfg.Content.Markdown, pages, includeDrafts))
+	maps.Copy(funcs, markdownFuncMap(md))
+	maps.Copy(funcs, QueryFuncMap(db))
+	maps.Copy(funcs, paginationFuncMap())
+	maps.Copy(funcs, groupFuncMap())
+	maps.Copy(funcs, whereFuncMap())
+	maps.Copy(funcs, sortFuncMap())
+	maps.Copy(funcs, newSourceFiles(sourceFS).FuncMap())
+	funcs["jsonld"] = transforms.JSONLD
+	funcs["ogTags"] = transforms.OGTagsFunc(pages)
+	maps.Copy(funcs, images.FuncMap())
+	return funcs
+}
+
 func markdownOptions(cfg config.ConfigContentMarkdown, pages []*transforms.Page, includeDrafts bool) markdown.Options {
 	if !cfg.Wikilinks {
 		return markdown.Options{}
@@ -390,7 +816,7 @@ func markdownOptions(cfg config.ConfigContentMarkdown, pages []*transforms.Page,
 
 	routes := make(map[string]struct{}, len(pages))
 	for _, page := range pages {
-		if page.Error != nil || page.Draft && !includeDrafts {
+		if page.Error != nil || page.NoRender || page.Draft && !includeDrafts {
 			continue
 		}
 		routes[page.Path] = struct{}{}
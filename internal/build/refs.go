@@ -0,0 +1,90 @@
+package build
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/tdewolff/parse/v2"
+	htmllex "github.com/tdewolff/parse/v2/html"
+)
+
+var cssURLPattern = regexp.MustCompile(`url\(\s*['"]?([^'")\s]+)['"]?\s*\)|@import\s+['"]([^'"]+)['"]`)
+
+// htmlRefs returns the raw href, src and srcset references in an HTML
+// document.
+func htmlRefs(data []byte) []string {
+	var refs []string
+	lexer := htmllex.NewLexer(parse.NewInputBytes(data))
+	for {
+		tt, _ := lexer.Next()
+		switch tt {
+		case htmllex.ErrorToken:
+			return refs
+		case htmllex.AttributeToken:
+			key := strings.ToLower(string(lexer.AttrKey()))
+			val := html.UnescapeString(string(bytes.Trim(lexer.AttrVal(), `"'`)))
+			switch key {
+			case "href", "src", "poster":
+				refs = append(refs, strings.TrimSpace(val))
+			case "srcset":
+				for candidate := range strings.SplitSeq(val, ",") {
+					if fields := strings.Fields(candidate); len(fields) > 0 {
+						refs = append(refs, fields[0])
+					}
+				}
+			}
+		}
+	}
+}
+
+// cssRefs returns the url() and @import references in a stylesheet.
+func cssRefs(data []byte) []string {
+	var refs []string
+	for _, match := range cssURLPattern.FindAllSubmatch(data, -1) {
+		ref := match[1]
+		if len(ref) == 0 {
+			ref = match[2]
+		}
+		refs = append(refs, string(ref))
+	}
+	return refs
+}
+
+// resolveRef resolves a reference found in the output file from to an output
+// target. References to other hosts, fragments and non-http schemes are not
+// resolved.
+func resolveRef(from, ref, siteURL string) (string, bool) {
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return "", false
+	}
+	if siteURL != "" {
+		if base := strings.TrimSuffix(siteURL, "/"); strings.HasPrefix(ref, base+"/") {
+			ref = strings.TrimPrefix(ref, base)
+		}
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+
+	p := u.Path
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join("/", path.Dir(from), p)
+		if strings.HasSuffix(u.Path, "/") {
+			p += "/"
+		}
+	}
+	if strings.HasSuffix(p, "/") {
+		p += "index.html"
+	}
+	target := strings.TrimPrefix(path.Clean(p), "/")
+	if target == "" || target == "." {
+		return "", false
+	}
+	return target, true
+}
@@ -0,0 +1,1220 @@
+package build
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olimci/shizuka/internal/markdown"
+	"github.com/olimci/shizuka/internal/options"
+	"github.com/olimci/shizuka/internal/registry"
+	"github.com/olimci/shizuka/internal/version"
+)
+
+func TestBuildSkipsUnrenderedPagesButKeepsThemQueryable(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/index.md":         "---\ntitle: Home\ntemplate: page\n---\nhello\n",
+		"content/authors/ada.md":   "---\ntitle: Ada\ntemplate: page\nrender: false\n---\nbio\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ range queryPages "SELECT _page FROM pages WHERE NoRender = true" }}author:{{ .Title }}{{ end }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(out, "authors", "ada", "index.html")); !os.IsNotExist(err) {
+		t.Fatalf("unrendered page output stat error = %v, want not exist", err)
+	}
+	index, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(index), "author:Ada") {
+		t.Fatalf("index.html = %q, want it to reference the unrendered page", index)
+	}
+}
+
+func TestBuildUsesConfiguredIndexFile(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{"build": {"index_file": "index.htm"}}`,
+		"content/index.md":         "---\ntitle: Home\ntemplate: page\n---\n",
+		"content/about.md":         "---\ntitle: About\ntemplate: page\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Title }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	about, err := os.ReadFile(filepath.Join(out, "about", "index.htm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(about) != "About" {
+		t.Fatalf("about/index.htm = %q, want About", about)
+	}
+	if _, err := os.Stat(filepath.Join(out, "index.htm")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "about", "index.html")); !os.IsNotExist(err) {
+		t.Fatalf("about/index.html stat error = %v, want not exist", err)
+	}
+}
+
+func TestBuildRendersPageVariants(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":             `{"content": {"variants": {"amp": {"template": "amp"}, "print": {}}}}`,
+		"content/index.md":          "---\ntitle: Home\ntemplate: page\n---\n",
+		"content/post.md":           "---\ntitle: Post\ntemplate: page\nvariants:\n  print: print\n---\n",
+		"templates/html/page.tmpl":  `{{ define "page" }}{{ .Page.Title }}{{ end }}`,
+		"templates/html/amp.tmpl":   `{{ define "amp" }}amp {{ .Page.Title }}{{ end }}`,
+		"templates/html/print.tmpl": `{{ define "print" }}print {{ .Page.Title }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"post/index.html":       "Post",
+		"post/amp/index.html":   "amp Post",
+		"post/print/index.html": "print Post",
+		"amp/index.html":        "amp Home",
+	} {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+	// Only the post names a print template.
+	if _, err := os.Stat(filepath.Join(out, "print", "index.html")); !os.IsNotExist(err) {
+		t.Fatalf("print/index.html stat error = %v, want not exist", err)
+	}
+}
+
+func TestBuildCascadesSectionIndexParams(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":               `{"content": {"defaults": {"global": {"template": "page", "params": {"layout": "default", "accent": "grey"}}}}}`,
+		"content/index.md":            "---\nparams:\n  hero: true\n---\n",
+		"content/posts/index.md":      "---\nparams:\n  layout: post\n  accent: blue\n---\n",
+		"content/posts/hello.md":      "---\nparams:\n  accent: red\n---\n",
+		"content/posts/2025/index.md": "---\nparams:\n  layout: archive\n---\n",
+		"content/posts/2025/recap.md": "",
+		"content/about.md":            "",
+		"templates/html/page.tmpl":    `{{ define "page" }}{{ .Page.Params.layout }} {{ .Page.Params.accent }}{{ if .Page.Params.hero }} hero{{ end }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tests := map[string]string{
+		"posts/index.html":            "post blue",
+		"posts/hello/index.html":      "post red",
+		"posts/2025/index.html":       "archive blue",
+		"posts/2025/recap/index.html": "archive blue",
+		"about/index.html":            "default grey",
+	}
+	for name, want := range tests {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildCascadesIndexCascadeBlocks(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":                 `{"content": {"defaults": {"global": {"template": "page", "params": {"layout": "default", "accent": "grey", "badge": "none"}}}}}`,
+		"content/index.md":              "---\nparams:\n  hero: true\ncascade:\n  accent: green\n---\n",
+		"content/docs/index.md":         "---\ncascade:\n  layout: docs\n  badge: guide\n---\n",
+		"content/docs/intro.md":         "",
+		"content/docs/api/index.md":     "---\nparams:\n  accent: blue\ncascade:\n  badge: reference\n---\n",
+		"content/docs/api/client.md":    "",
+		"content/docs/api/v2/index.md":  "---\ncascade:\n  layout: api\n---\n",
+		"content/docs/api/v2/server.md": "---\nparams:\n  badge: beta\n---\n",
+		"content/about.md":              "",
+		"templates/html/page.tmpl":      `{{ define "page" }}{{ .Page.Params.layout }} {{ .Page.Params.accent }} {{ .Page.Params.badge }}{{ if .Page.Params.hero }} hero{{ end }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tests := map[string]string{
+		"index.html":                    "default grey none hero",
+		"about/index.html":              "default green none",
+		"docs/index.html":               "default green none",
+		"docs/intro/index.html":         "docs green guide",
+		"docs/api/index.html":           "docs blue guide",
+		"docs/api/client/index.html":    "docs blue reference",
+		"docs/api/v2/index.html":        "docs blue reference",
+		"docs/api/v2/server/index.html": "api blue beta",
+	}
+	for name, want := range tests {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildCascadesTemplates(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/posts/index.md":   "---\ncascade:\n  template: post\n---\n",
+		"content/posts/a.md":       "---\ntitle: A\n---\n",
+		"content/posts/b.md":       "---\ntitle: B\ntemplate: page\n---\n",
+		"content/v/index.md":       "---\ncascade:\n  template: 2024\n---\n",
+		"content/v/c.md":           "---\ntitle: C\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}page:{{ .Page.Title }}:{{ .Page.Params.template }}{{ end }}`,
+		"templates/html/post.tmpl": `{{ define "post" }}post:{{ .Page.Title }}{{ end }}`,
+		"templates/html/2024.tmpl": `{{ define "2024" }}2024:{{ .Page.Title }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	build := func() error {
+		return Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.WithForce(true),
+		)
+	}
+	if err := build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"posts/index.html":   "page::",
+		"posts/a/index.html": "post:A",
+		"posts/b/index.html": "page:B:",
+		"v/c/index.html":     "2024:C",
+	} {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	writeFile(t, root, "content/v/index.md", "---\ncascade:\n  template: [a, b]\n---\n")
+	failure, ok := errors.AsType[*Failure](build())
+	if !ok || len(failure.Errors) != 1 || failure.Errors[0].Source() != "content/v/index.md" || !strings.Contains(failure.Errors[0].Error(), "cascaded template: want a string") {
+		t.Fatalf("Build() error = %v, want the cascaded list refused", failure)
+	}
+}
+
+func TestBuildDerivesTitlesForBareMarkdown(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":               `{"content": {"title_from_filename": true}}`,
+		"content/notes/quick-note.md": "Just a thought.\n",
+		"content/notes/titled.md":     "---\ntitle: Kept\n---\nBody\n",
+		"templates/html/page.tmpl":    `{{ define "page" }}{{ .Page.Title }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"notes/quick-note/index.html": "Quick note",
+		"notes/titled/index.html":     "Kept",
+	} {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildDryRunWritesNothing(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/index.md":         "---\ntitle: Home\n---\nHello\n",
+		"static/style.css":         "body {}",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Title }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	stats, err := BuildWithStats(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+		options.WithDryRun(true),
+	)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if stats.FilesWritten == 0 {
+		t.Fatal("dry run counted no files to write")
+	}
+	if _, err := os.Stat(out); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("output stat error = %v, want not exist", err)
+	}
+}
+
+func TestBuildResolvesBreadcrumbs(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":                 `{}`,
+		"content/index.md":              "---\ntitle: Home\n---\n",
+		"content/docs/index.md":         "---\ntitle: Docs\n---\n",
+		"content/docs/guides/setup.md":  "---\ntitle: Setup\n---\n",
+		"templates/html/page.tmpl":      `{{ define "page" }}{{ range .Page.Breadcrumbs }}[{{ .Title }}{{ with .Path }} {{ . }}{{ end }}]{{ end }}{{ end }}`,
+		"content/docs/x.md":             "---\ntitle: X\n---\n",
+		"content/docs/guides/more/y.md": "---\ntitle: Y\n---\n",
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"index.html":                    "",
+		"docs/index.html":               "[Home /][Docs /docs/]",
+		"docs/x/index.html":             "[Home /][Docs /docs/][X /docs/x/]",
+		"docs/guides/setup/index.html":  "[Home /][Docs /docs/][Guides][Setup /docs/guides/setup/]",
+		"docs/guides/more/y/index.html": "[Home /][Docs /docs/][Guides][More][Y /docs/guides/more/y/]",
+	} {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildSkipsScheduledAndExpiredPagesOutsideDev(t *testing.T) {
+	future := time.Now().AddDate(1, 0, 0).Format(time.DateOnly)
+	past := time.Now().AddDate(-1, 0, 0).Format(time.DateOnly)
+	files := map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/live.md":          "---\ntitle: Live\n---\n",
+		"content/scheduled.md":     "---\ntitle: Scheduled\ncreated: " + future + "\n---\n",
+		"content/expired.md":       "---\ntitle: Expired\nexpiry_date: " + past + "\n---\n",
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ range .Site.Pages }}{{ .Title }},{{ end }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	for _, dev := range []bool{false, true} {
+		out := filepath.Join(root, "dist", strconv.FormatBool(dev))
+		if err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.If(options.WithDev(true), dev),
+		); err != nil {
+			t.Fatalf("Build(dev=%v) error = %v", dev, err)
+		}
+
+		for _, name := range []string{"scheduled", "expired"} {
+			_, err := os.Stat(filepath.Join(out, name, "index.html"))
+			if dev && err != nil {
+				t.Fatalf("dev build did not write %s: %v", name, err)
+			}
+			if !dev && !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("production build wrote %s (stat error = %v)", name, err)
+			}
+		}
+		index, err := os.ReadFile(filepath.Join(out, "index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(string(index), "Scheduled"); got != dev {
+			t.Fatalf("dev=%v: site pages = %q, listing scheduled page = %v", dev, index, got)
+		}
+	}
+}
+
+func TestBuildSkipsDraftsUnlessIncluded(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/live.md":          "---\ntitle: Live\n---\n",
+		"content/draft.md":         "---\ntitle: Draft\ndraft: true\n---\n",
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ range .Site.Pages }}{{ .Title }},{{ end }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	for _, include := range []bool{false, true} {
+		out := filepath.Join(root, "dist", strconv.FormatBool(include))
+		if err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.If(options.WithIncludeDrafts(true), include),
+		); err != nil {
+			t.Fatalf("Build(include drafts=%v) error = %v", include, err)
+		}
+
+		_, err := os.Stat(filepath.Join(out, "draft", "index.html"))
+		if include && err != nil {
+			t.Fatalf("build including drafts did not write the draft: %v", err)
+		}
+		if !include && !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("production build wrote the draft (stat error = %v)", err)
+		}
+		index, err := os.ReadFile(filepath.Join(out, "index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(string(index), "Draft"); got != include {
+			t.Fatalf("include drafts=%v: site pages = %q, listing draft = %v", include, index, got)
+		}
+	}
+}
+
+func TestBuildRedirectsAliasesAndKeepsCanonicalOverride(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{"site": {"url": "https://example.com"}, "artefacts": {"redirects": {"entries": [{"from": "/feed", "to": "/rss.xml"}]}}}`,
+		"content/moved.md":         "---\ntitle: Moved\naliases: [/2019/old-name/]\ncanonical: https://elsewhere.example/moved/\n---\n",
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Canon }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	redirects, err := os.ReadFile(filepath.Join(out, "_redirects"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/feed /rss.xml\n/2019/old-name/ /moved/ 301\n"; string(redirects) != want {
+		t.Fatalf("_redirects = %q, want %q", redirects, want)
+	}
+	moved, err := os.ReadFile(filepath.Join(out, "moved", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://elsewhere.example/moved/"; string(moved) != want {
+		t.Fatalf("moved page canon = %q, want %q", moved, want)
+	}
+}
+
+func TestBuildMergesContentSources(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc": `{"content": {"sources": [
+			{"path": "content"},
+			{"name": "docs", "path": "vendor/docs", "url_prefix": "/docs/", "default_params": {"edit": "docs-repo"}}
+		]}}`,
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"content/docs/index.md":    "---\ntitle: Docs\n---\n",
+		"vendor/docs/intro.md":     "---\ntitle: Intro\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Collection }}:{{ .Page.Params.edit }}:{{ range .Page.Breadcrumbs }}{{ .Title }}/{{ end }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	intro, err := os.ReadFile(filepath.Join(out, "docs", "intro", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "docs:docs-repo:Home/Docs/Intro/"; string(intro) != want {
+		t.Fatalf("docs/intro = %q, want %q", intro, want)
+	}
+
+	// A page at the same route in both sources is a conflict.
+	writeFile(t, root, "content/docs/intro.md", "---\ntitle: Other intro\n---\n")
+	err = Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(filepath.Join(root, "dist-conflict")),
+	)
+	if err == nil || !strings.Contains(err.Error(), `duplicate route path "/docs/intro/"`) {
+		t.Fatalf("Build() error = %v, want a duplicate route conflict", err)
+	}
+
+}
+
+func TestBuildReadsContentSourceOutsideRoot(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"shizuka.jsonc":            `{"content": {"sources": [{"path": "content"}, {"path": "../docs", "url_prefix": "docs"}]}}`,
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Collection }}:{{ .Page.Title }}{{ end }}`,
+	})
+	writeFile(t, root, "../docs/guide.md", "---\ntitle: Guide\n---\n")
+
+	out := filepath.Join(root, "dist")
+	build := func() {
+		t.Helper()
+		if err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.WithArtefactCache(filepath.Join(root, ".shizuka-cache")),
+		); err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+	}
+	guide := func() string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(out, "docs", "guide", "index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	build()
+	if got, want := guide(), "docs:Guide"; got != want {
+		t.Fatalf("docs/guide = %q, want %q", got, want)
+	}
+
+	// An edit outside the root invalidates the cached pages like any other.
+	writeFile(t, root, "../docs/guide.md", "---\ntitle: Guide, revised\n---\n")
+	build()
+	if got, want := guide(), "docs:Guide, revised"; got != want {
+		t.Fatalf("docs/guide after edit = %q, want %q", got, want)
+	}
+}
+
+func TestBuildPlacesPagesAtFrontmatterURL(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{"site": {"url": "https://example.com"}}`,
+		"content/misc/landing.md":  "---\ntitle: Landing\nurl: /\n---\n",
+		"content/misc/contact.md":  "---\ntitle: Contact\nurl: get-in-touch\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Path }} {{ .Page.Canon }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tests := map[string]string{
+		"index.html":              "/ https://example.com/",
+		"get-in-touch/index.html": "/get-in-touch/ https://example.com/get-in-touch/",
+	}
+	for name, want := range tests {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "misc", "landing", "index.html")); !os.IsNotExist(err) {
+		t.Fatalf("misc/landing/index.html stat error = %v, want not exist", err)
+	}
+
+	// A url that lands on another page's path is a conflict.
+	writeFile(t, root, "content/index.md", "---\ntitle: Home\n---\n")
+	err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(filepath.Join(root, "dist-conflict")),
+	)
+	if err == nil || !strings.Contains(err.Error(), `url "/" of content/misc/landing.md collides with content/index.md`) {
+		t.Fatalf("Build() error = %v, want a url conflict", err)
+	}
+}
+
+func TestBuildLinksLanguageVariants(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{"content": {"languages": {"default": "en", "codes": ["ja"]}}}`,
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"content/about.en.md":      "---\ntitle: About\n---\n",
+		"content/about.ja.md":      "---\ntitle: 概要\n---\n",
+		"content/ja/index.md":      "---\ntitle: ホーム\n---\n",
+		"content/contact.md":       "---\ntitle: Contact\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Lang }}:{{ range .Page.Translations }}{{ .Lang }}={{ .Path }};{{ end }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tests := map[string]string{
+		"index.html":          "en:ja=/ja/;",
+		"ja/index.html":       "ja:en=/;",
+		"about/index.html":    "en:ja=/ja/about/;",
+		"ja/about/index.html": "ja:en=/about/;",
+		"contact/index.html":  "en:",
+	}
+	for name, want := range tests {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildListsRelatedPages(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{"content": {"related": {"limit": 1}}}`,
+		"content/posts/a.md":       "---\ntitle: A\ntags: [go, web]\nupdated: 2025-01-01\n---\n",
+		"content/posts/b.md":       "---\ntitle: B\ntags: [go, web]\nupdated: 2025-01-02\n---\n",
+		"content/posts/c.md":       "---\ntitle: C\ntags: [go]\ndraft: true\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ range .Page.Related }}{{ .Title }}{{ end }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+		options.WithIncludeDrafts(true),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tests := map[string]string{
+		"posts/a/index.html": "B",
+		"posts/b/index.html": "A",
+		"posts/c/index.html": "B",
+	}
+	for name, want := range tests {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildCheckReportsContentProblems(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/index.md":         "---\ntemplate: page\n---\n",
+		"content/ok.md":            "---\ntitle: Fine\n---\n",
+		"content/untitled.md":      "---\ndescription: no title\n---\n",
+		"content/never.md":         "---\ntitle: Never\ncreated: 2024-05-01\nexpiry_date: 2024-04-01\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Title }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	opts := []options.Option{
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	}
+	if err := Build(opts...); err != nil {
+		t.Fatalf("Build() error = %v, want lint problems to pass a plain build", err)
+	}
+
+	err := Build(append(opts, options.WithCheck(true))...)
+	failure, ok := errors.AsType[*Failure](err)
+	if !ok {
+		t.Fatalf("Build(check) error = %v, want a failure", err)
+	}
+	got := make(map[string]error)
+	for _, problem := range failure.Errors {
+		got[problem.Source()] = problem.Err
+	}
+	if len(got) != 2 || !errors.Is(got["content/untitled.md"], ErrUntitledPage) || !errors.Is(got["content/never.md"], ErrExpiresEarly) {
+		t.Fatalf("problems = %v, want an untitled page and one that expires early", failure.Errors)
+	}
+	for _, problem := range failure.Errors {
+		if problem.Source() == "content/untitled.md" && problem.Error() != "content/untitled.md: [pages:lint] page has no title" {
+			t.Fatalf("Error() = %q, want the problem prefixed with the step that found it", problem.Error())
+		}
+	}
+}
+
+func TestBuildReportsBrokenLinks(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{"build": {"links": {"fail": true}}}`,
+		"content/index.md":         "---\ntitle: Home\n---\n[about](/about/) [feed](/style.css) ![logo](/logo.png)\n",
+		"content/about.md":         "---\ntitle: About\n---\n[home](../)\n",
+		"static/style.css":         "body {}",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Body }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(filepath.Join(root, "dist")),
+	)
+	failure, ok := errors.AsType[*Failure](err)
+	if !ok {
+		t.Fatalf("Build() error = %v, want a failure", err)
+	}
+	if len(failure.Errors) != 1 || failure.Errors[0].Source() != "content/index.md" || !errors.Is(failure.Errors[0], ErrBrokenLink) || !strings.Contains(failure.Errors[0].Error(), "/logo.png") {
+		t.Fatalf("errors = %v, want only the missing logo in content/index.md", failure.Errors)
+	}
+	if step := failure.Errors[0].Step; step != "" {
+		t.Fatalf("broken link step = %q, want none for the post-build check", step)
+	}
+	if _, err := os.Stat(filepath.Join(root, "dist", "index.html")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("dist/index.html stat error = %v, want a failed link check to write nothing", err)
+	}
+}
+
+func TestBuildReusesTemplatesForContentOnlyRebuilds(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}v1{{ range queryPages "SELECT _page FROM pages ORDER BY Title" }}[{{ .Title }}]{{ end }}{{ end }}`,
+	})
+
+	cache := registry.New()
+	out := filepath.Join(root, "dist")
+	rebuild := func(changed []string) string {
+		t.Helper()
+		if err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithInternalOutputPath(out),
+			options.WithInternalCache(cache),
+			options.WithInternalChanges(changed),
+			options.WithForce(true),
+		); err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		index, err := os.ReadFile(filepath.Join(out, "index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(index)
+	}
+
+	if got := rebuild(nil); got != "v1[Home]" {
+		t.Fatalf("initial index.html = %q", got)
+	}
+	parsed := registry.Get(cache, TemplateCacheK).Parsed
+
+	// The cached parse is reused, but its functions see the new page.
+	added := writeFile(t, root, "content/about.md", "---\ntitle: About\n---\n")
+	if got := rebuild([]string{added}); got != "v1[About][Home]" {
+		t.Fatalf("content rebuild index.html = %q", got)
+	}
+	if registry.Get(cache, TemplateCacheK).Parsed != parsed {
+		t.Fatal("content-only rebuild parsed the templates again")
+	}
+
+	tmpl := writeFile(t, root, "templates/html/page.tmpl", `{{ define "page" }}v2{{ end }}`)
+	if got := rebuild([]string{tmpl}); got != "v2" {
+		t.Fatalf("template rebuild index.html = %q", got)
+	}
+}
+
+func TestBuildDropsTemplatesAfterFailedFullRebuild(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}v1{{ end }}`,
+	})
+
+	cache := registry.New()
+	out := filepath.Join(root, "dist")
+	rebuild := func(changed []string) error {
+		t.Helper()
+		return Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithInternalOutputPath(out),
+			options.WithInternalCache(cache),
+			options.WithInternalChanges(changed),
+			options.WithForce(true),
+		)
+	}
+
+	if err := rebuild(nil); err != nil {
+		t.Fatalf("initial Build() error = %v", err)
+	}
+
+	// The template change makes this a full rebuild, but the broken data
+	// fails it before the templates are parsed again.
+	data := writeFile(t, root, "data/x.json", `{`)
+	tmpl := writeFile(t, root, "templates/html/page.tmpl", `{{ define "page" }}v2{{ end }}`)
+	if err := rebuild([]string{data, tmpl}); err == nil {
+		t.Fatal("Build() with broken data succeeded, want an error")
+	}
+
+	if err := os.Remove(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := rebuild([]string{data}); err != nil {
+		t.Fatalf("Build() after removing the data error = %v", err)
+	}
+	index, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(index) != "v2" {
+		t.Fatalf("index.html = %q, want v2", index)
+	}
+}
+
+func TestBuildUsesCustomTemplateFuncs(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":                  `{}`,
+		"content/index.md":               "---\ntitle: Home\n---\n{{< loud hi >}}\n",
+		"templates/html/page.tmpl":       `{{ define "page" }}{{ shout .Page.Title }}|{{ dateISO "x" }}|{{ .Page.Body }}{{ end }}`,
+		"templates/shortcodes/loud.tmpl": `{{ shout (.Get 0) }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+		options.WithTemplateFuncs(template.FuncMap{"shout": strings.ToUpper}),
+		options.WithTemplateFuncs(template.FuncMap{"dateISO": func(string) string { return "overridden" }}),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	index, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(index); !strings.HasPrefix(got, "HOME|overridden|") || !strings.Contains(got, "HI") {
+		t.Fatalf("index.html = %q, want custom functions in page and shortcode templates", got)
+	}
+
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+		options.WithForce(true),
+		options.WithTemplateFuncs(template.FuncMap{"shout": "not a function"}),
+	); err == nil || !strings.Contains(err.Error(), "template funcs") {
+		t.Fatalf("Build() error = %v, want an invalid template function error", err)
+	}
+}
+
+func TestBuildParsesPartialsByPath(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"shizuka.jsonc":                    `{}`,
+		"content/index.md":                 "---\ntitle: Home\n---\n",
+		"templates/html/page.tmpl":         `{{ define "page" }}{{ template "partials/header" . }}|{{ template "partials/nav/main" . }}{{ end }}{{ define "header" }}page-header{{ end }}`,
+		"templates/partials/header.tmpl":   `<h1>{{ .Page.Title }}</h1>`,
+		"templates/partials/nav/main.tmpl": `nav`,
+	})
+
+	out := filepath.Join(root, "dist")
+	build := func() error {
+		err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.WithForce(true),
+		)
+		return err
+	}
+	if err := build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	index, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(index); got != "<h1>Home</h1>|nav" {
+		t.Fatalf("index.html = %q, want the partials rendered", got)
+	}
+
+	writeFile(t, root, "templates/partials/footer.tmpl", `{{ define "page" }}shadowed{{ end }}`)
+	if err := build(); err == nil || !strings.Contains(err.Error(), `template "page" is already defined`) {
+		t.Fatalf("Build() error = %v, want a partial conflict", err)
+	}
+}
+
+func TestBuildParsesPartialsFromConfiguredGlob(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"shizuka.jsonc":               `{"paths": {"partials_glob": "includes/**/*.html"}}`,
+		"content/index.md":            "---\ntitle: Home\n---\n",
+		"templates/html/page.tmpl":    `{{ define "page" }}{{ template "partials/site/header" . }}{{ end }}`,
+		"includes/site/header.html":   `<h1>{{ .Page.Title }}</h1>`,
+		"includes/site/notes.txt":     `not a partial`,
+		"templates/partials/old.tmpl": `{{ define "page" }}ignored{{ end }}`,
+	})
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	index, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(index); got != "<h1>Home</h1>" {
+		t.Fatalf("index.html = %q, want the partial from the configured glob", got)
+	}
+}
+
+func TestBuildSelectsTemplateByType(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"content/a.md":             "---\ntitle: A\nsection: blog\ntype: post\n---\n",
+		"content/b.md":             "---\ntitle: B\ntype: post\n---\n",
+		"content/c.md":             "---\ntitle: C\ntype: gallery\n---\n",
+		"content/d.md":             "---\ntitle: D\ntype: post\ntemplate: page\n---\n",
+		"content/e.json":           `{"title": "E", "type": "post"}`,
+		"templates/html/page.tmpl": `{{ define "page" }}page:{{ .Page.Title }}{{ end }}`,
+		"templates/html/post.tmpl": `{{ define "post" }}post:{{ .Page.Title }}{{ end }}{{ define "blog/post" }}blog/post:{{ .Page.Title }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	for page, want := range map[string]string{
+		"a": "blog/post:A",
+		"b": "post:B",
+		"c": "page:C",
+		"d": "page:D",
+		"e": "post:E",
+	} {
+		got, err := os.ReadFile(filepath.Join(out, page, "index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s/index.html = %q, want %q", page, got, want)
+		}
+	}
+}
+
+func TestBuildFallsBackToDefaultTemplate(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"shizuka.jsonc":               `{}`,
+		"content/index.md":            "---\ntitle: Home\n---\n",
+		"content/a.md":                "---\ntitle: A\n---\n",
+		"templates/html/default.tmpl": `{{ define "default" }}default:{{ .Page.Title }}{{ end }}`,
+	})
+
+	out := filepath.Join(root, "dist")
+	build := func() error {
+		err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.WithForce(true),
+		)
+		return err
+	}
+
+	// With the default config, pages fall back when the built-in "page"
+	// template does not exist.
+	if err := build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(out, "a", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "default:A" {
+		t.Fatalf("a/index.html = %q, want the fallback template", got)
+	}
+
+	// A named template that is missing does not.
+	writeFile(t, root, "content/b.md", "---\ntitle: B\ntemplate: custom\n---\n")
+	failure, ok := errors.AsType[*Failure](build())
+	if !ok || len(failure.Errors) != 1 || failure.Errors[0].Source() != "content/b.md" || !strings.Contains(failure.Errors[0].Error(), `template not found: "custom"`) {
+		t.Fatalf("Build() error = %v, want only custom not found for content/b.md", failure)
+	}
+	if step := failure.Errors[0].Step; step != "pages:build" {
+		t.Fatalf("error step = %q, want pages:build", step)
+	}
+
+	writeFile(t, root, "shizuka.jsonc", `{"content": {"defaults": {"sections": {"posts": {"template": "missing"}}, "global": {"template": ""}}}}`)
+	writeFile(t, root, "content/b.md", "---\ntitle: B\nsection: posts\n---\n")
+	failure, ok = errors.AsType[*Failure](build())
+	if !ok || len(failure.Errors) != 1 || !strings.Contains(failure.Errors[0].Error(), `template not found: "missing"`) {
+		t.Fatalf("Build() error = %v, want the section default not found", failure)
+	}
+
+	// A missing fallback is one error on the config, not one per page.
+	writeFile(t, root, "shizuka.jsonc", `{"content": {"defaults": {"global": {"template": ""}}}}`)
+	writeFile(t, root, "content/b.md", "---\ntitle: B\n---\n")
+	writeFile(t, root, "templates/html/default.tmpl", `{{ define "other" }}{{ end }}`)
+	failure, ok = errors.AsType[*Failure](build())
+	if !ok || len(failure.Errors) != 1 || !strings.Contains(failure.Errors[0].Error(), `fallback "default" for 3 pages with no template`) {
+		t.Fatalf("Build() error = %v, want a single missing fallback error", failure)
+	}
+	if source := failure.Errors[0].Source(); source != filepath.Join(root, "shizuka.jsonc") {
+		t.Fatalf("error source = %q, want the config", source)
+	}
+
+	// Once it exists, the built-in "page" template is used, not the fallback.
+	writeFile(t, root, "shizuka.jsonc", `{}`)
+	writeFile(t, root, "templates/html/default.tmpl", `{{ define "default" }}default:{{ .Page.Title }}{{ end }}`)
+	writeFile(t, root, "templates/html/page.tmpl", `{{ define "page" }}page:{{ .Page.Title }}{{ end }}`)
+	if err := build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	got, err = os.ReadFile(filepath.Join(out, "a", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "page:A" {
+		t.Fatalf("a/index.html = %q, want the page template", got)
+	}
+}
+
+func TestBuildMarksDraftsOnlyInDev(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"content/draft.md":         "---\ntitle: Draft\ndraft: true\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}<html><body><h1>{{ .Page.Title }}</h1></body></html>{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	for _, dev := range []bool{true, false} {
+		out := filepath.Join(root, "dist", strconv.FormatBool(dev))
+		if err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.WithDev(dev),
+			options.WithIncludeDrafts(true),
+		); err != nil {
+			t.Fatalf("Build(dev=%v) error = %v", dev, err)
+		}
+		for page, want := range map[string]bool{"draft/index.html": dev, "index.html": false} {
+			body, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(page)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(string(body), "shizuka-draft"); got != want {
+				t.Fatalf("dev=%v: %s = %q, banner = %v, want %v", dev, page, body, got, want)
+			}
+		}
+	}
+}
+
+func TestBuildExposesVersionToTemplates(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"templates/html/page.tmpl": `{{ define "page" }}built by {{ .Site.Version }}{{ end }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	index, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "built by " + version.String(); string(index) != want {
+		t.Fatalf("index.html = %q, want %q", index, want)
+	}
+}
+
+func TestBuildEmitsHighlightStylesheetForClasses(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"content/index.md":         "---\ntitle: Home\n---\n```go\nvar x = 1\n```\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Body }}{{ end }}`,
+	})
+
+	out := filepath.Join(root, "dist")
+	build := func(opts ...options.Option) error {
+		err := Build(append([]options.Option{
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.WithForce(true),
+		}, opts...)...)
+		return err
+	}
+
+	writeFile(t, root, "shizuka.jsonc", `{"content": {"markdown": {"highlighting": {"style": "monokai", "classes": true}}}}`)
+	if err := build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	css, err := os.ReadFile(filepath.Join(out, "chroma.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(css), ".chroma") {
+		t.Fatalf("chroma.css = %q, want chroma class rules", css)
+	}
+
+	// Inline styles need no stylesheet.
+	writeFile(t, root, "shizuka.jsonc", `{"content": {"markdown": {"highlighting": {"style": "monokai"}}}}`)
+	if err := build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "chroma.css")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("chroma.css stat error = %v, want not exist", err)
+	}
+
+	// An unknown style is a warning, and an error only in strict builds.
+	writeFile(t, root, "shizuka.jsonc", `{"content": {"markdown": {"highlighting": {"style": "no-such-style"}}}}`)
+	if err := build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if err := build(options.WithStrict(true)); !errors.Is(err, markdown.ErrUnknownHighlightStyle) {
+		t.Fatalf("strict Build() error = %v, want an unknown style", err)
+	}
+}
+
+func TestBuildTableOfContents(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"content/index.md":         "---\ntitle: Home\n---\n## One\n\n### Two\n",
+		"content/flat.md":          "---\ntitle: Flat\ntoc: false\n---\n## One\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ len .Page.ToC }}/{{ range .Page.ToCTree }}{{ .ID }}:{{ len .Children }}{{ end }}{{ end }}`,
+	})
+
+	out := filepath.Join(root, "dist")
+	build := func(opts ...options.Option) error {
+		err := Build(append([]options.Option{
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.WithForce(true),
+		}, opts...)...)
+		return err
+	}
+
+	writeFile(t, root, "shizuka.jsonc", `{"content": {"markdown": {"toc": {}, "parser": {"auto_heading_id": true}}}}`)
+	if err := build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	for name, want := range map[string]string{"index.html": "2/one:1", "flat/index.html": "0/"} {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// Without heading IDs the ToC cannot link, which strict builds reject.
+	writeFile(t, root, "shizuka.jsonc", `{"content": {"markdown": {"toc": {}}}}`)
+	if err := build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if err := build(options.WithStrict(true)); !errors.Is(err, ErrToCWithoutHeadingIDs) {
+		t.Fatalf("strict Build() error = %v, want a ToC without heading IDs", err)
+	}
+}
+
+func TestBuildDeterministicOutputIsReproducible(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"shizuka.jsonc":            `{"site": {"title": "Site", "url": "https://example.com"}, "artefacts": {"rss": {"sections": ["posts"]}, "json_feed": {"sections": ["posts"]}, "sitemap": {}}}`,
+		"content/index.md":         "---\ntitle: Home\n---\n",
+		"content/posts/undated.md": "---\ntitle: Undated\nsection: posts\nrss:\n  include: true\n---\nBody.\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Title }} {{ .Site.BuildTime.Unix }}{{ end }}`,
+	})
+
+	build := func(name string) map[string]string {
+		t.Helper()
+		out := filepath.Join(root, name)
+		if err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.WithDeterministic(true),
+		); err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		files := make(map[string]string)
+		err := filepath.WalkDir(out, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			files[strings.TrimPrefix(p, out)] = string(data)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	first := build("dist1")
+	if !maps.Equal(first, build("dist2")) {
+		t.Fatal("two deterministic builds of the same tree differ")
+	}
+	if rss := first["/rss.xml"]; !strings.Contains(rss, "<pubDate>Thu, 01 Jan 1970 00:00:00 +0000</pubDate>") {
+		t.Fatalf("rss.xml = %s, want the undated post dated at the Unix epoch", rss)
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	if got := build("dist3")["/index.html"]; got != "Home 1700000000" {
+		t.Fatalf("index.html = %q, want the build time from SOURCE_DATE_EPOCH", got)
+	}
+}
@@ -0,0 +1,230 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/olimci/shizuka/internal/markdown"
+	"github.com/olimci/shizuka/internal/transforms"
+)
+
+var ErrUnknownShortcode = errors.New("unknown shortcode")
+
+// shortcodeRe matches {{< name args >}}, whose output is inserted as HTML,
+// and {{% name args %}}, whose output is rendered as markdown with the rest
+// of the page. {{</* ... */>}} writes the shortcode out literally.
+var shortcodeRe = regexp.MustCompile(`(?s)\{\{([<%])\s*(/\*)?(.*?)(\*/)?\s*([>%])\}\}`)
+
+// Shortcode is the data a shortcode template executes with. Args holds the
+// positional arguments and Params the key=value ones, with quotes removed.
+type Shortcode struct {
+	Name   string
+	Args   []string
+	Params map[string]string
+	Page   transforms.PageTmpl
+}
+
+// Get returns the positional argument at an int index or the named param for
+// a string key, or "" when there is none.
+func (s Shortcode) Get(key any) string {
+	switch key := key.(type) {
+	case int:
+		if key >= 0 && key < len(s.Args) {
+			return s.Args[key]
+		}
+	case string:
+		return s.Params[key]
+	}
+	return ""
+}
+
+// shortcodes renders shortcodes in markdown bodies. The shortcode name is
+// the template's path under dir without its extension, so {{< youtube >}}
+// executes templates/shortcodes/youtube.tmpl.
+type shortcodes struct {
+	tmpl *template.Template
+	dir  string
+}
+
+// shortcodeOutput holds the rendered shortcodes of a page, each standing in
+// the markdown body behind a placeholder. HTML output is put back into the
+// rendered HTML, and markdown output into the body once markdown components
+// have run, so that neither is processed by the component templates.
+type shortcodeOutput struct {
+	html     []string
+	markdown []string
+}
+
+// expand renders the shortcodes in raw outside code blocks and spans,
+// replacing each with a placeholder. Shortcodes that cannot be rendered are
+// passed to report and dropped.
+func (s *shortcodes) expand(page *transforms.Page, raw string, report func(error)) (string, shortcodeOutput) {
+	var (
+		out  strings.Builder
+		last int
+		res  shortcodeOutput
+	)
+	data := page.Tmpl()
+	code := markdown.CodeRanges(raw)
+	for _, loc := range shortcodeRe.FindAllStringSubmatchIndex(raw, -1) {
+		if markdown.InCode(code, loc[0]) {
+			continue
+		}
+		out.WriteString(raw[last:loc[0]])
+		last = loc[1]
+
+		token := raw[loc[0]:loc[1]]
+		open, inner, close := raw[loc[2]:loc[3]], strings.TrimSpace(raw[loc[6]:loc[7]]), raw[loc[10]:loc[11]]
+		escaped := loc[4] >= 0 && loc[8] >= 0
+		switch {
+		case open == "<" && close != ">" || open == "%" && close != "%":
+			out.WriteString(token)
+		case escaped:
+			res.markdown = append(res.markdown, "{{"+open+" "+inner+" "+close+"}}")
+			out.WriteString(markdownShortcodePlaceholder(len(res.markdown) - 1))
+		default:
+			rendered, err := s.render(inner, data)
+			switch {
+			case err != nil:
+				report(fmt.Errorf("shortcode %q: %w", token, err))
+			case open == "%":
+				res.markdown = append(res.markdown, rendered)
+				out.WriteString(markdownShortcodePlaceholder(len(res.markdown) - 1))
+			default:
+				res.html = append(res.html, rendered)
+				out.WriteString(shortcodePlaceholder(len(res.html) - 1))
+			}
+		}
+	}
+	out.WriteString(raw[last:])
+	return out.String(), res
+}
+
+// restoreMarkdown puts the output of markdown shortcodes back into raw.
+func (o shortcodeOutput) restoreMarkdown(raw string) string {
+	for i, md := range o.markdown {
+		raw = strings.ReplaceAll(raw, markdownShortcodePlaceholder(i), md)
+	}
+	return raw
+}
+
+// placeholders maps the placeholder of each HTML shortcode to the text of its
+// output, which heading IDs and table of contents entries are made from.
+func (o shortcodeOutput) placeholders() map[string]string {
+	if len(o.html) == 0 {
+		return nil
+	}
+	text := make(map[string]string, len(o.html))
+	for i, block := range o.html {
+		text[shortcodePlaceholder(i)] = transforms.PlainText(block)
+	}
+	return text
+}
+
+func (s *shortcodes) render(inner string, page transforms.PageTmpl) (string, error) {
+	args, err := parseShortcodeArgs(inner)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 || args[0].key != "" {
+		return "", errors.New("missing shortcode name")
+	}
+
+	call := Shortcode{Name: args[0].value, Params: map[string]string{}, Page: page}
+	for _, arg := range args[1:] {
+		if arg.key != "" {
+			call.Params[arg.key] = arg.value
+		} else {
+			call.Args = append(call.Args, arg.value)
+		}
+	}
+
+	name := path.Join(s.dir, call.Name+".tmpl")
+	if s.tmpl == nil || s.tmpl.Lookup(name) == nil {
+		return "", fmt.Errorf("%w %q (no template %s)", ErrUnknownShortcode, call.Name, name)
+	}
+	var buf strings.Builder
+	if err := s.tmpl.ExecuteTemplate(&buf, name, call); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+type shortcodeArg struct {
+	key   string
+	value string
+}
+
+// parseShortcodeArgs splits a shortcode call into whitespace-separated
+// arguments. Values may be quoted with " or `, and key=value arguments are
+// named.
+func parseShortcodeArgs(s string) ([]shortcodeArg, error) {
+	var args []shortcodeArg
+	var arg shortcodeArg
+	var buf strings.Builder
+	var quote rune
+	inArg := false
+	flush := func() {
+		if inArg {
+			arg.value = buf.String()
+			args = append(args, arg)
+		}
+		arg, inArg = shortcodeArg{}, false
+		buf.Reset()
+	}
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			buf.WriteRune(r)
+		case r == '"' || r == '`':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			flush()
+		case r == '=' && arg.key == "" && buf.Len() > 0:
+			arg.key = buf.String()
+			buf.Reset()
+		default:
+			inArg = true
+			buf.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	flush()
+	return args, nil
+}
+
+func shortcodePlaceholder(i int) string {
+	return "SHIZUKASHORTCODE" + strconv.Itoa(i) + "X"
+}
+
+func markdownShortcodePlaceholder(i int) string {
+	return "SHIZUKASHORTCODEMD" + strconv.Itoa(i) + "X"
+}
+
+// restoreShortcodes replaces the HTML placeholders expand left in rendered HTML
+// with the shortcode output, dropping the paragraph markdown wraps around a
+// placeholder on its own line.
+func restoreShortcodes(html template.HTML, blocks []string) template.HTML {
+	if len(blocks) == 0 {
+		return html
+	}
+	out := string(html)
+	for i, block := range blocks {
+		placeholder := shortcodePlaceholder(i)
+		out = strings.ReplaceAll(out, "<p>"+placeholder+"</p>", block)
+		out = strings.ReplaceAll(out, placeholder, block)
+	}
+	return template.HTML(out)
+}
@@ -0,0 +1,133 @@
+package build
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/options"
+)
+
+func TestParseShortcodeArgs(t *testing.T) {
+	args, err := parseShortcodeArgs("figure  src=\"/a b.png\" wide `raw \"text\"` alt=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []shortcodeArg{
+		{value: "figure"},
+		{key: "src", value: "/a b.png"},
+		{value: "wide"},
+		{value: `raw "text"`},
+		{key: "alt", value: "x"},
+	}
+	if !slices.Equal(args, want) {
+		t.Fatalf("args = %#v, want %#v", args, want)
+	}
+
+	if _, err := parseShortcodeArgs(`note "open`); err == nil {
+		t.Fatal("unterminated quote parsed without error")
+	}
+}
+
+func TestBuildRendersShortcodes(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":                     `{}`,
+		"content/index.md":                  "---\ntitle: Home\n---\nIntro {{< badge new >}} here.\n\n{{< youtube id=\"abc\" >}}\n\n{{% note %}}\n\nShow {{</* youtube */>}} literally.\n",
+		"templates/html/page.tmpl":          `{{ define "page" }}{{ .Page.Body }}{{ end }}`,
+		"templates/shortcodes/badge.tmpl":   `<span class="badge">{{ .Get 0 }}</span>`,
+		"templates/shortcodes/youtube.tmpl": "<div class=\"video\">\n\n<iframe src=\"https://www.youtube.com/embed/{{ .Get \"id\" }}\"></iframe>\n\n</div>",
+		"templates/shortcodes/note.tmpl":    `**Note** from {{ .Page.Title }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	if err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(out),
+	); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	body, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<p>Intro <span class=badge>new</span> here.`,
+		`<div class=video><iframe src=https://www.youtube.com/embed/abc></iframe></div>`,
+		`<p><strong>Note</strong> from Home`,
+		`<p>Show {{&lt; youtube >}} literally.`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("body = %q, missing %q", body, want)
+		}
+	}
+}
+
+func TestBuildShortcodesSkipCodeAndComponents(t *testing.T) {
+	files := map[string]string{
+		"shizuka.jsonc":                   `{"content": {"markdown": {"parser": {"auto_heading_id": true}, "toc": {}}}}`,
+		"content/index.md":                "---\ntitle: Home\n---\n## {{< badge new >}} Features\n\nUse `{{< badge x >}}` inline.\n\n```\n{{< badge y >}}\n```\n",
+		"templates/html/page.tmpl":        `{{ define "page" }}{{ range .Page.ToC }}[{{ .ID }}|{{ .Text }}]{{ end }}{{ .Page.Body }}{{ end }}`,
+		"templates/shortcodes/badge.tmpl": `<span class="badge">{{ .Get 0 }}</span>`,
+		"templates/shortcodes/raw.tmpl":   `Literal {{ "{{" }} .Page.Title }}`,
+	}
+	root := writeSite(t, files)
+
+	out := filepath.Join(root, "dist")
+	build := func() string {
+		t.Helper()
+		if err := Build(
+			options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+			options.WithOutputPath(out),
+			options.WithForce(true),
+		); err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		body, err := os.ReadFile(filepath.Join(out, "index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	body := build()
+	for _, want := range []string{
+		// The heading ID and ToC entry come from the shortcode's text.
+		`[new-features|new Features]`,
+		`<h2 id=new-features><span class=badge>new</span> Features</h2>`,
+		// Code is left as written.
+		`<code>{{&lt; badge x >}}</code>`,
+		"<code>{{&lt; badge y &gt;}}\n</code>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("body = %q, missing %q", body, want)
+		}
+	}
+
+	// Markdown shortcode output is not run as a component template.
+	writeFile(t, root, "shizuka.jsonc", `{"content": {"markdown": {"components": true}}}`)
+	writeFile(t, root, "content/index.md", "---\ntitle: Home\n---\n{{% raw %}}\n")
+	if body := build(); !strings.Contains(body, `<p>Literal {{ .Page.Title }}`) {
+		t.Fatalf("body = %q, want the shortcode output left as written", body)
+	}
+}
+
+func TestBuildReportsUnknownShortcodes(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"shizuka.jsonc":            `{}`,
+		"content/index.md":         "---\ntitle: Home\n---\n{{< missing >}}\n",
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Body }}{{ end }}`,
+	})
+
+	err := Build(
+		options.WithConfigPath(filepath.Join(root, "shizuka.jsonc")),
+		options.WithOutputPath(filepath.Join(root, "dist")),
+	)
+	if !errors.Is(err, ErrUnknownShortcode) {
+		t.Fatalf("Build() error = %v, want %v", err, ErrUnknownShortcode)
+	}
+}
@@ -0,0 +1,32 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSite writes files, keyed by slash-separated path, into a new site
+// root and returns it.
+func writeSite(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		writeFile(t, root, name, content)
+	}
+	return root
+}
+
+// writeFile writes content to the slash-separated path name under root,
+// creating its directory, and returns the file's path.
+func writeFile(t *testing.T, root, name, content string) string {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
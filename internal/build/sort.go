@@ -0,0 +1,75 @@
+package build
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+)
+
+func sortFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"sortBy": sortBy,
+	}
+}
+
+// sortBy returns items sorted in ascending order of field, a struct field or
+// dotted map path as accepted by where. Lower weights come first and pages
+// with a zero Weight, which have none set, sort last. Items missing the field
+// sort last, and ties fall back to title order so output is deterministic.
+func sortBy(field string, items any) ([]any, error) {
+	slice, err := sliceAny(items)
+	if err != nil {
+		return nil, fmt.Errorf("sortBy items: %w", err)
+	}
+
+	type keyed struct {
+		item  any
+		key   any
+		ok    bool
+		title string
+	}
+	keys := make([]keyed, len(slice))
+	for i, item := range slice {
+		key, ok, err := whereField(item, field)
+		if err != nil {
+			return nil, fmt.Errorf("sortBy: %w", err)
+		}
+		if field == "Weight" && key == 0 {
+			ok = false
+		}
+		title, _, _ := whereField(item, "Title")
+		s, _ := title.(string)
+		keys[i] = keyed{item: item, key: key, ok: ok, title: s}
+	}
+
+	var sortErr error
+	slices.SortStableFunc(keys, func(a, b keyed) int {
+		switch {
+		case a.ok && !b.ok:
+			return -1
+		case !a.ok && b.ok:
+			return 1
+		case a.ok && b.ok:
+			c, ok := compareValues(a.key, b.key)
+			if !ok {
+				sortErr = fmt.Errorf("sortBy %s: cannot order %T against %T", field, a.key, b.key)
+				return 0
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(strings.ToLower(a.title), strings.ToLower(b.title))
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	out := make([]any, len(keys))
+	for i, k := range keys {
+		out[i] = k.item
+	}
+	return out, nil
+}
@@ -0,0 +1,31 @@
+package build
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/transforms"
+)
+
+func TestSortByWeight(t *testing.T) {
+	pages := []transforms.PageTmpl{
+		{Title: "Unweighted B"},
+		{Title: "Install", Weight: 2},
+		{Title: "Unweighted A"},
+		{Title: "Intro", Weight: 1},
+		{Title: "Configure", Weight: 2},
+	}
+	tmpl := template.Must(template.New("docs").Funcs(sortFuncMap()).Parse(
+		`{{ range sortBy "Weight" . }}{{ .Title }},{{ end }}`,
+	))
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, pages); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := "Intro,Configure,Install,Unweighted A,Unweighted B,"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+package build
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/olimci/shizuka/internal/manifest"
+)
+
+// BuildStats summarises a build for reporting.
+type BuildStats struct {
+	Duration time.Duration
+	Steps    map[string]StepStats
+
+	// ManifestStart and ManifestDuration time the manifest's final phase,
+	// from the end of the step graph, when the build waits for the last
+	// writes and cleans up the output.
+	ManifestStart    time.Duration
+	ManifestDuration time.Duration
+
+	Pages        int
+	FilesWritten int
+	FilesSkipped int
+	FilesRemoved int
+	BytesWritten int64
+
+	// Changes lists the output files the build wrote, edited, left
+	// unchanged and deleted.
+	Changes manifest.Changes
+}
+
+// StepStats records when a step started, relative to the start of the build,
+// its wall-clock time and the artefacts it emitted, including those emitted
+// by work it handed to the worker pool.
+type StepStats struct {
+	Start     time.Duration
+	Duration  time.Duration
+	Artefacts int
+}
+
+// Summary formats the stats as a short human readable line, such as
+// "42 pages, 1.2 MB, 380ms".
+func (s *BuildStats) Summary() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d pages, %s, %s", s.Pages, formatBytes(s.BytesWritten), s.Duration.Truncate(time.Millisecond))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// StepManifest is the manifest view handed to a step. It attributes emitted
+// artefacts to the step for BuildStats, and claims without an owner to the
+// step itself.
+type StepManifest struct {
+	*manifest.Manifest
+	step    string
+	emitted atomic.Int64
+}
+
+func (m *StepManifest) Emit(artefact manifest.Artefact) error {
+	m.emitted.Add(1)
+	artefact.Claim = m.own(artefact.Claim)
+	return m.Manifest.Emit(artefact)
+}
+
+func (m *StepManifest) Reuse(claim manifest.Claim, fingerprint string) bool {
+	if !m.Manifest.Reuse(m.own(claim), fingerprint) {
+		return false
+	}
+	m.emitted.Add(1)
+	return true
+}
+
+func (m *StepManifest) own(claim manifest.Claim) manifest.Claim {
+	if claim.Owner == "" {
+		claim.Owner = m.step
+	}
+	return claim
+}
+
+type statsCollector struct {
+	mu        sync.Mutex
+	starts    map[string]time.Duration
+	durations map[string]time.Duration
+	manifests map[string]*StepManifest
+
+	manifestStart    time.Duration
+	manifestDuration time.Duration
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{
+		starts:    make(map[string]time.Duration),
+		durations: make(map[string]time.Duration),
+		manifests: make(map[string]*StepManifest),
+	}
+}
+
+func (c *statsCollector) manifest(stepID string, man *manifest.Manifest) *StepManifest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sm := &StepManifest{Manifest: man, step: stepID}
+	c.manifests[stepID] = sm
+	return sm
+}
+
+func (c *statsCollector) stepDone(stepID string, start, dur time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.starts[stepID] = start
+	c.durations[stepID] = dur
+}
+
+func (c *statsCollector) manifestDone(start, dur time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.manifestStart = start
+	c.manifestDuration = dur
+}
+
+// finish snapshots the collected stats. Call it once the worker pool has
+// drained so that artefact counts are complete.
+func (c *statsCollector) finish(start time.Time, man manifest.Stats) *BuildStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := &BuildStats{
+		Duration:         time.Since(start),
+		Steps:            make(map[string]StepStats, len(c.durations)),
+		ManifestStart:    c.manifestStart,
+		ManifestDuration: c.manifestDuration,
+		FilesWritten:     man.Written,
+		FilesSkipped:     man.Skipped,
+		FilesRemoved:     man.Removed,
+		BytesWritten:     man.Bytes,
+		Changes:          man.Changes,
+	}
+	for id, sm := range c.manifests {
+		stats.Steps[id] = StepStats{
+			Start:     c.starts[id],
+			Duration:  c.durations[id],
+			Artefacts: int(sm.emitted.Load()),
+		}
+	}
+	stats.Pages = stats.Steps["pages:build"].Artefacts
+	return stats
+}
@@ -0,0 +1,61 @@
+package build
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBuildStatsSummary(t *testing.T) {
+	stats := &BuildStats{
+		Duration:     380*time.Millisecond + 250*time.Microsecond,
+		Pages:        42,
+		BytesWritten: 1258291,
+	}
+	if got, want := stats.Summary(), "42 pages, 1.2 MB, 380ms"; got != want {
+		t.Fatalf("Summary() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildStatsTracePutsOverlappingStepsOnSeparateLanes(t *testing.T) {
+	stats := &BuildStats{
+		Steps: map[string]StepStats{
+			"pages:index": {Start: 0, Duration: 2 * time.Millisecond},
+			"static":      {Start: time.Millisecond, Duration: 3 * time.Millisecond, Artefacts: 4},
+			"pages:build": {Start: 2 * time.Millisecond, Duration: time.Millisecond},
+		},
+		ManifestStart:    4 * time.Millisecond,
+		ManifestDuration: time.Millisecond,
+	}
+
+	var buf bytes.Buffer
+	if err := stats.WriteTrace(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var trace struct {
+		TraceEvents []struct {
+			Name string `json:"name"`
+			Ph   string `json:"ph"`
+			TS   int64  `json:"ts"`
+			Dur  int64  `json:"dur"`
+			TID  int    `json:"tid"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatal(err)
+	}
+
+	lanes := make(map[string]int)
+	for _, event := range trace.TraceEvents {
+		if event.Ph == "X" {
+			lanes[event.Name] = event.TID
+		}
+		if event.Name == "static" && (event.TS != 1000 || event.Dur != 3000) {
+			t.Fatalf("static event = %+v, want ts 1000 dur 3000", event)
+		}
+	}
+	if lanes["pages:index"] != 1 || lanes["static"] != 2 || lanes["pages:build"] != 1 || lanes["manifest"] != 1 {
+		t.Fatalf("lanes = %v, want static on its own lane", lanes)
+	}
+}
@@ -65,7 +65,7 @@ func (p StepPatch) AddDependency(id, dependency string) StepPatch {
 
 // StepContext is the interface for the build step to interact with the build process.
 type StepContext struct {
-	Manifest *manifest.Manifest
+	Manifest *StepManifest
 	Pool     *pool.Pool
 	Registry *registry.Scoped
 	Cache    *registry.Scoped
@@ -74,7 +74,9 @@ type StepContext struct {
 	Source *os.Root
 
 	// unexported to steps
+	step   string
 	errors *errorState
+	strict bool
 }
 
 // Error records an error
@@ -84,7 +86,7 @@ func (sc *StepContext) Error(err error, claim manifest.Claim) {
 	}
 
 	if sc.errors != nil {
-		sc.errors.Add(claim, err)
+		sc.errors.AddStep(sc.step, claim, err)
 	}
 	if sc.Logger != nil {
 		sc.Logger.Warn("build error",
@@ -95,3 +97,14 @@ func (sc *StepContext) Error(err error, claim manifest.Claim) {
 		)
 	}
 }
+
+// Warn logs a problem as a warning with msg, err and the key-value pairs in
+// args. Strict builds also record it as an error.
+func (sc *StepContext) Warn(msg string, err error, claim manifest.Claim, args ...any) {
+	if sc.Logger != nil {
+		sc.Logger.Warn(msg, append(args[:len(args):len(args)], "error", err)...)
+	}
+	if sc.strict {
+		sc.Error(err, claim)
+	}
+}
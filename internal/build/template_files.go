@@ -0,0 +1,116 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/olimci/shizuka/internal/utils/pathutil"
+)
+
+// sourceFiles reads project files for templates. Paths are relative to the
+// source root and may not escape it. Reads are cached for the lifetime of a
+// single build, so a file inlined into every page is read once.
+type sourceFiles struct {
+	fsys  fs.FS
+	mu    sync.Mutex
+	files map[string]sourceFile
+}
+
+type sourceFile struct {
+	data string
+	err  error
+}
+
+func newSourceFiles(fsys fs.FS) *sourceFiles {
+	return &sourceFiles{fsys: fsys, files: make(map[string]sourceFile)}
+}
+
+func (s *sourceFiles) read(name string) (string, error) {
+	clean, err := pathutil.CleanContentPath(name)
+	if err != nil {
+		return "", fmt.Errorf("template %q: %w", name, err)
+	}
+	name = clean
+	return s.load(name)
+}
+
+func (s *sourceFiles) load(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if file, ok := s.files[name]; ok {
+		return file.data, file.err
+	}
+	data, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		err = fmt.Errorf("template %q: %w", name, err)
+	}
+	s.files[name] = sourceFile{data: string(data), err: err}
+	return string(data), err
+}
+
+// exists reports whether name is a regular file. Missing files are not an
+// error, but paths that escape the source root are.
+func (s *sourceFiles) exists(name string) (bool, error) {
+	name, err := pathutil.CleanContentPath(name)
+	if err != nil {
+		return false, fmt.Errorf("fileExists: %w", err)
+	}
+	info, err := fs.Stat(s.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("fileExists: %w", err)
+	}
+	return info.Mode().IsRegular(), nil
+}
+
+// contains reports whether name exists and its content contains substr, so
+// templates can branch on an optional file in one call.
+func (s *sourceFiles) contains(name, substr string) (bool, error) {
+	ok, err := s.exists(name)
+	if err != nil || !ok {
+		return false, err
+	}
+	data, err := s.load(name)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(data, substr), nil
+}
+
+// readTrusted reads name as trusted content. CSS and JavaScript files are
+// typed for their contexts so they can be inlined into <style> and <script>;
+// anything else is treated as markup.
+func (s *sourceFiles) readTrusted(name string) (any, error) {
+	data, err := s.read(name)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".css":
+		return template.CSS(data), nil
+	case ".js", ".mjs":
+		return template.JS(data), nil
+	default:
+		return template.HTML(data), nil
+	}
+}
+
+// FuncMap returns readFile, which inlines a file as escaped text,
+// readFileHTML, which inlines it verbatim for trusted files such as SVGs and
+// critical CSS, and the fileExists and fileContains predicates for optional
+// files.
+func (s *sourceFiles) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"readFile":     s.read,
+		"readFileHTML": s.readTrusted,
+		"fileExists":   s.exists,
+		"fileContains": s.contains,
+	}
+}
@@ -0,0 +1,62 @@
+package build
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadFileFuncs(t *testing.T) {
+	files := newSourceFiles(fstest.MapFS{
+		"static/critical.css": {Data: []byte("body{margin:0}")},
+		"static/icon.svg":     {Data: []byte(`<svg><path d="M0 0"/></svg>`)},
+	})
+	tmpl := template.Must(template.New("page").Funcs(files.FuncMap()).Parse(
+		`<style>{{ readFileHTML "static/critical.css" }}</style>{{ readFileHTML "static/icon.svg" }}|{{ readFile "static/icon.svg" }}`,
+	))
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := `<style>body{margin:0}</style><svg><path d="M0 0"/></svg>|&lt;svg&gt;&lt;path d=&#34;M0 0&#34;/&gt;&lt;/svg&gt;`
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	for _, name := range []string{"../secret.txt", "static/../../secret.txt", "/etc/passwd", "static/missing.css"} {
+		if _, err := files.read(name); err == nil {
+			t.Fatalf("read(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestFileExistsFuncs(t *testing.T) {
+	files := newSourceFiles(fstest.MapFS{
+		"static/custom.css": {Data: []byte(".theme-dark{}")},
+		"static/img/a.png":  {Data: []byte("png")},
+	})
+	tmpl := template.Must(template.New("page").Funcs(files.FuncMap()).Parse(
+		`{{ if fileExists "static/custom.css" }}custom{{ end }}` +
+			`{{ if fileExists "static/missing.css" }}missing{{ end }}` +
+			`{{ if fileExists "static/img" }}dir{{ end }}` +
+			`{{ if fileContains "static/custom.css" "theme-dark" }}dark{{ end }}` +
+			`{{ if fileContains "static/missing.css" "theme-dark" }}missing-dark{{ end }}`,
+	))
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := buf.String(); got != "customdark" {
+		t.Fatalf("output = %q, want %q", got, "customdark")
+	}
+
+	if _, err := files.exists("../shizuka.jsonc"); err == nil {
+		t.Fatal("exists() accepted a path escaping the source root")
+	}
+	if _, err := files.contains("static/../../x", "x"); err == nil {
+		t.Fatal("contains() accepted a path escaping the source root")
+	}
+}
@@ -0,0 +1,162 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/options"
+	"github.com/olimci/shizuka/internal/transforms"
+	"github.com/olimci/shizuka/internal/utils/tmplutil"
+)
+
+// TemplateIssue is a problem LintTemplates found in a template.
+type TemplateIssue struct {
+	Template string
+	Err      error
+}
+
+func (i TemplateIssue) Error() string {
+	return fmt.Sprintf("%s: %v", i.Template, i.Err)
+}
+
+// LintTemplates parses the site's page templates and executes each page
+// template the config refers to against a representative page, without
+// building. Parse errors and undefined functions surface at parse time;
+// missing fields and bad calls surface on execution. Templates that are only
+// used as partials are parsed but not executed, since their data is unknown.
+// Only the template functions of the options are used.
+func LintTemplates(cfg *config.Config, opt ...options.Option) ([]TemplateIssue, error) {
+	opts := options.DefaultOptions().Apply(opt...)
+	if err := checkTemplateFuncs(opts.TemplateFuncs); err != nil {
+		return nil, err
+	}
+
+	source, err := os.OpenRoot(cfg.Root)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	dataTables, err := loadDataTables(source.FS(), cfg.Paths.Data)
+	if err != nil {
+		return nil, err
+	}
+	db, err := buildDB(nil, dataTables)
+	if err != nil {
+		return nil, err
+	}
+
+	templateGlob := path.Join(cfg.Paths.Templates, "html", "**", "*.tmpl")
+	funcs := withUserFuncs(pageTemplateFuncs(cfg, source.FS(), nil, db, newImageSizes(source.FS(), cfg.Paths.Static, nil, nil), false), opts.TemplateFuncs, buildLogger(opts.Logger))
+	tmpl, err := parseRequiredTemplates(source.FS(), templateGlob, funcs)
+	if err == nil {
+		err = parsePartials(source.FS(), tmpl, cfg.Paths.PartialsGlob, funcs)
+	}
+	if err != nil {
+		return []TemplateIssue{{Template: templateGlob, Err: err}}, nil
+	}
+
+	data := lintPageTemplate(cfg)
+	var issues []TemplateIssue
+	for _, name := range lintTemplateNames(cfg, tmpl) {
+		if tmpl.Lookup(name) == nil {
+			issues = append(issues, TemplateIssue{Template: name, Err: ErrTemplateNotFound})
+			continue
+		}
+		err := tmpl.ExecuteTemplate(io.Discard, name, data)
+		var paginationErr *paginationEffect
+		if err == nil || tmplutil.IsDiscard(err) || errors.As(err, &paginationErr) {
+			continue
+		}
+		issues = append(issues, TemplateIssue{Template: name, Err: err})
+	}
+	return issues, nil
+}
+
+// lintTemplateNames returns the page templates named in the config: content
+// defaults, with the fallback standing in for a missing built-in default,
+// variants and the 404 page, which is optional unless configured, and the
+// fallback template if it exists.
+func lintTemplateNames(cfg *config.Config, tmpl *template.Template) []string {
+	names := make(map[string]struct{})
+	add := func(name string) {
+		if name != "" {
+			names[name] = struct{}{}
+		}
+	}
+
+	for _, name := range contentDefaultTemplates(cfg) {
+		// Pages fall back from the built-in default when it is missing, so
+		// then it is the fallback that has to exist.
+		if name == config.DefaultPageTemplate && tmpl.Lookup(name) == nil && cfg.Content.DefaultTemplate != "" {
+			name = cfg.Content.DefaultTemplate
+		}
+		add(name)
+	}
+	if fallback := cfg.Content.DefaultTemplate; fallback != "" && tmpl.Lookup(fallback) != nil {
+		add(fallback)
+	}
+	for _, variant := range cfg.Content.Variants {
+		add(variant.Template)
+	}
+	if cfg.Artefacts.NotFound != nil {
+		add(cfg.Artefacts.NotFound.Template)
+		if cfg.Artefacts.NotFound.Template == "" && tmpl.Lookup("404") != nil {
+			add("404")
+		}
+	}
+	return slices.Sorted(maps.Keys(names))
+}
+
+// contentDefaultTemplates returns the templates the content defaults name.
+func contentDefaultTemplates(cfg *config.Config) []string {
+	names := []string{cfg.Content.Defaults.Global.Template}
+	for _, defaults := range cfg.Content.Defaults.Sections {
+		names = append(names, defaults.Template)
+	}
+	return names
+}
+
+// lintPageTemplate returns template data with every commonly used field
+// populated, so templates take their usual branches.
+func lintPageTemplate(cfg *config.Config) transforms.PageTemplate {
+	now := time.Now()
+	page := transforms.PageTmpl{
+		Path:        "/posts/example/",
+		Canon:       cfg.Site.URL + "/posts/example/",
+		Title:       "Example page",
+		Description: "An example page used to check templates.",
+		Section:     "posts",
+		Slug:        "example",
+		Tags:        []string{"example"},
+		Created:     now,
+		Updated:     now,
+		PubDate:     now,
+		Params:      map[string]any{},
+		Body:        template.HTML("<p>Example body.</p>"),
+		Summary:     template.HTML("<p>Example summary.</p>"),
+		SummaryText: "Example summary.",
+		Sections:    []template.HTML{"<p>Example body.</p>"},
+		Breadcrumbs: []transforms.Crumb{{Title: "Home", Path: "/"}, {Title: "Posts", Path: "/posts/"}, {Title: "Example page", Path: "/posts/example/"}},
+	}
+	return transforms.PageTemplate{
+		Page: page,
+		Site: transforms.SiteTmpl{
+			Title:        cfg.Site.Title,
+			Description:  cfg.Site.Description,
+			URL:          cfg.Site.URL,
+			Params:       maps.Clone(cfg.Site.Params),
+			BuildTime:    now,
+			Pages:        []transforms.PageTmpl{page},
+			RegularPages: []transforms.PageTmpl{page},
+		},
+	}
+}
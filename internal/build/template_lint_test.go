@@ -0,0 +1,70 @@
+package build
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+func TestLintTemplates(t *testing.T) {
+	lint := func(files map[string]string) []TemplateIssue {
+		t.Helper()
+		files["shizuka.jsonc"] = `{"content": {"variants": {"print": {"template": "print"}}}}`
+		root := writeSite(t, files)
+		cfg, err := config.Load(filepath.Join(root, "shizuka.jsonc"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		issues, err := LintTemplates(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return issues
+	}
+
+	issues := lint(map[string]string{
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Title | nope }}{{ end }}`,
+	})
+	if len(issues) != 1 || !strings.Contains(issues[0].Error(), `function "nope" not defined`) {
+		t.Fatalf("issues = %v, want an undefined function error", issues)
+	}
+
+	issues = lint(map[string]string{
+		"templates/html/page.tmpl": `{{ define "page" }}{{ .Page.Title }}{{ range .Site.Pages }}{{ .Author }}{{ end }}{{ end }}`,
+	})
+	if len(issues) != 2 {
+		t.Fatalf("issues = %v, want a missing field and a missing variant template", issues)
+	}
+	if issues[0].Template != "page" || !strings.Contains(issues[0].Err.Error(), "Author") {
+		t.Fatalf("issues[0] = %v, want missing field Author in page", issues[0])
+	}
+	if issues[1].Template != "print" || !errors.Is(issues[1].Err, ErrTemplateNotFound) {
+		t.Fatalf("issues[1] = %v, want print not found", issues[1])
+	}
+
+	issues = lint(map[string]string{
+		"templates/html/page.tmpl":  `{{ define "page" }}{{ .Page.Title }} {{ .Page.SummaryText }}{{ end }}`,
+		"templates/html/print.tmpl": `{{ define "print" }}{{ .Page.Body }}{{ end }}`,
+	})
+	if len(issues) != 0 {
+		t.Fatalf("issues = %v, want none", issues)
+	}
+
+	issues = lint(map[string]string{
+		"templates/html/default.tmpl": `{{ define "default" }}{{ .Page.Nope }}{{ end }}`,
+		"templates/html/print.tmpl":   `{{ define "print" }}{{ .Page.Body }}{{ end }}`,
+	})
+	if len(issues) != 1 || issues[0].Template != "default" || !strings.Contains(issues[0].Err.Error(), "Nope") {
+		t.Fatalf("issues = %v, want only the fallback's missing field, as it covers the missing page", issues)
+	}
+
+	issues = lint(map[string]string{
+		"templates/html/print.tmpl": `{{ define "print" }}{{ .Page.Body }}{{ end }}`,
+	})
+	if len(issues) != 1 || issues[0].Template != "default" || !errors.Is(issues[0].Err, ErrTemplateNotFound) {
+		t.Fatalf("issues = %v, want the fallback not found when page is missing too", issues)
+	}
+}
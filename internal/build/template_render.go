@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"path"
 	"slices"
 	"strings"
 
@@ -22,6 +23,8 @@ type pageRenderRequest struct {
 	Pagination   *transforms.PaginationTmpl
 	Owners       []string
 	Minifier     manifest.PostProcessor
+	Fingerprint  string
+	CSP          *cspCollector
 }
 
 func renderPageTemplate(sc *StepContext, req pageRenderRequest) error {
@@ -30,10 +33,21 @@ func renderPageTemplate(sc *StepContext, req pageRenderRequest) error {
 		return nil
 	}
 
+	if sc.Manifest.Reuse(req.Claim, req.Fingerprint) {
+		return nil
+	}
+
 	owners := append(slices.Clone(req.Owners), req.TemplateName)
 	rendered, err := executePageTemplate(req)
 	if err == nil {
-		return sc.Manifest.Emit(manifest.TextArtefact(req.Claim, rendered).Post(req.Minifier))
+		artefact := manifest.TextArtefact(req.Claim, rendered).Post(req.Minifier).Fingerprinted(req.Fingerprint)
+		if req.CSP != nil {
+			if artefact, err = req.CSP.record(artefact); err != nil {
+				sc.Error(err, req.Claim)
+				return nil
+			}
+		}
+		return sc.Manifest.Emit(artefact)
 	}
 
 	if tmplutil.IsDiscard(err) {
@@ -76,9 +90,27 @@ func executePageTemplate(req pageRenderRequest) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if req.Site.Dev && req.Page.Draft {
+		return injectDraftBanner(buf.String()), nil
+	}
 	return buf.String(), nil
 }
 
+// draftBanner marks draft pages in dev builds. Other builds never get it,
+// even when they include drafts. It is a <style> element rather than inline
+// styles so a generated CSP can hash it.
+const draftBanner = `<style>html{outline:3px dashed #d97706;outline-offset:-3px}#shizuka-draft{position:fixed;top:0;right:0;z-index:2147483647;padding:.25em .75em;background:#d97706;color:#fff;font:600 12px/1.5 system-ui,sans-serif;letter-spacing:.05em;text-transform:uppercase;pointer-events:none}</style><div id="shizuka-draft">Draft</div>`
+
+// injectDraftBanner adds draftBanner to the end of the body of html. Output
+// without a closing body tag, which is not a full document, is left alone.
+func injectDraftBanner(html string) string {
+	idx := strings.LastIndex(strings.ToLower(html), "</body>")
+	if idx == -1 {
+		return html
+	}
+	return html[:idx] + draftBanner + html[idx:]
+}
+
 func renderPaginationEffect(sc *StepContext, req pageRenderRequest, owners []string, effect paginationEffect) error {
 	if req.Templates.Lookup(effect.PageTemplate) == nil {
 		sc.Error(fmt.Errorf("template %q not found", effect.PageTemplate), req.Claim)
@@ -98,6 +130,7 @@ func renderPaginationEffect(sc *StepContext, req pageRenderRequest, owners []str
 			Site:         req.Site,
 			Owners:       owners,
 			Minifier:     req.Minifier,
+			CSP:          req.CSP,
 		}); err != nil {
 			return err
 		}
@@ -110,7 +143,7 @@ func renderPaginationEffect(sc *StepContext, req pageRenderRequest, owners []str
 	}
 
 	for _, page := range pages {
-		claim := manifest.NewPageClaim(req.Claim.Source, page.Route)
+		claim := manifest.NewPageClaim(req.Claim.Source, page.Route).WithIndexFile(path.Base(req.Claim.Target))
 		if req.Claim.Owner != "" {
 			claim = claim.Own(req.Claim.Owner)
 		}
@@ -123,6 +156,7 @@ func renderPaginationEffect(sc *StepContext, req pageRenderRequest, owners []str
 			Pagination:   &page.Data,
 			Owners:       owners,
 			Minifier:     req.Minifier,
+			CSP:          req.CSP,
 		}); err != nil {
 			return err
 		}
@@ -0,0 +1,51 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/options"
+	"github.com/olimci/shizuka/internal/utils/dag"
+)
+
+func TestBuildStepTimeoutStopsStepAndItsJobs(t *testing.T) {
+	root := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Root = root
+
+	jobStopped := make(chan struct{})
+	graph := dag.New[Step]()
+	fast := StepFunc("fast", func(context.Context, *StepContext) error { return nil })
+	slow := StepFunc("slow", func(ctx context.Context, sc *StepContext) error {
+		if err := sc.Pool.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+			close(jobStopped)
+			return ctx.Err()
+		}); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, "fast")
+	_ = graph.Add(fast.ID, fast.Deps, fast)
+	_ = graph.Add(slow.ID, slow.Deps, slow)
+
+	opts := options.DefaultOptions().Apply(
+		options.WithOutputPath(filepath.Join(root, "dist")),
+		options.WithStepTimeout(20*time.Millisecond),
+	)
+	_, err := build(graph, cfg, opts)
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrTaskError) || !strings.Contains(err.Error(), "(slow)") {
+		t.Fatalf("build() error = %v, want a deadline attributed to the slow step", err)
+	}
+	select {
+	case <-jobStopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pool job submitted by the step was not cancelled")
+	}
+}
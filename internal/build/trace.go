@@ -0,0 +1,73 @@
+package build
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"slices"
+	"time"
+)
+
+// traceEvent is an event in the Chrome trace event format, as read by
+// chrome://tracing and Perfetto. Times are in microseconds.
+type traceEvent struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	TS   int64          `json:"ts"`
+	Dur  int64          `json:"dur,omitempty"`
+	PID  int            `json:"pid"`
+	TID  int            `json:"tid"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// WriteTrace writes the step timings as a Chrome trace, with steps that ran
+// at the same time on separate lanes so the parallel schedule shows.
+func (s *BuildStats) WriteTrace(w io.Writer) error {
+	ids := make([]string, 0, len(s.Steps))
+	for id := range s.Steps {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		return cmp.Or(cmp.Compare(s.Steps[a].Start, s.Steps[b].Start), cmp.Compare(a, b))
+	})
+
+	events := []traceEvent{{Name: "process_name", Ph: "M", PID: 1, Args: map[string]any{"name": "shizuka build"}}}
+	var lanes []time.Duration // when each lane is next free
+	for _, id := range ids {
+		step := s.Steps[id]
+		lane := slices.IndexFunc(lanes, func(free time.Duration) bool { return free <= step.Start })
+		if lane < 0 {
+			lane = len(lanes)
+			lanes = append(lanes, 0)
+		}
+		lanes[lane] = step.Start + step.Duration
+		events = append(events, traceEvent{
+			Name: id,
+			Cat:  "step",
+			Ph:   "X",
+			TS:   step.Start.Microseconds(),
+			Dur:  step.Duration.Microseconds(),
+			PID:  1,
+			TID:  lane + 1,
+			Args: map[string]any{"artefacts": step.Artefacts},
+		})
+	}
+	events = append(events, traceEvent{
+		Name: "manifest",
+		Cat:  "manifest",
+		Ph:   "X",
+		TS:   s.ManifestStart.Microseconds(),
+		Dur:  s.ManifestDuration.Microseconds(),
+		PID:  1,
+		TID:  1,
+		Args: map[string]any{"written": s.FilesWritten, "removed": s.FilesRemoved},
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"})
+}
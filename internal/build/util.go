@@ -6,15 +6,19 @@ import (
 	"io"
 	"io/fs"
 	"log/slog"
+	"maps"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/olimci/shizuka/internal/build/embed"
 	"github.com/olimci/shizuka/internal/config"
 	"github.com/olimci/shizuka/internal/manifest"
+	"github.com/olimci/shizuka/internal/markdown"
 	"github.com/olimci/shizuka/internal/transforms"
+	"github.com/olimci/shizuka/internal/utils/pathutil"
 	"github.com/olimci/shizuka/internal/utils/tmplutil"
 	"github.com/tdewolff/minify/v2"
 	mincss "github.com/tdewolff/minify/v2/css"
@@ -53,8 +57,13 @@ func NewMinifier(cfg *config.ConfigMinifier) manifest.PostProcessor {
 	m.AddFunc("application/javascript", minjs.Minify)
 
 	return func(claim manifest.Claim, next manifest.ArtefactBuilder) manifest.ArtefactBuilder {
-		mime, ex := mimes[filepath.Ext(claim.Target)]
-		if !ex {
+		ext := filepath.Ext(claim.Target)
+		mime, ex := mimes[ext]
+		if !ex || !cfg.Minifies(ext) {
+			return next
+		}
+		// Bundles shipped as .min.css or .min.js are already minified.
+		if strings.HasSuffix(claim.Target, ".min"+ext) {
 			return next
 		}
 
@@ -108,6 +117,76 @@ func matchGitignorePattern(pattern, target string) bool {
 	return doublestar.MatchUnvalidated(pattern, target) || doublestar.MatchUnvalidated("**/"+pattern, target)
 }
 
+// ignored reports whether target is excluded by the gitignore-style patterns.
+// As in gitignore, the last matching pattern wins and a pattern starting with
+// ! re-includes what an earlier one excluded.
+func ignored(patterns []string, target string) bool {
+	excluded := false
+	for _, pattern := range patterns {
+		negated, ok := strings.CutPrefix(pattern, "!")
+		if matchGitignorePattern(negated, target) {
+			excluded = !ok
+		}
+	}
+	return excluded
+}
+
+// staticExclude returns the build.static.exclude patterns, if any.
+func staticExclude(cfg *config.Config) []string {
+	if cfg.Build.Static == nil {
+		return nil
+	}
+	return cfg.Build.Static.Exclude
+}
+
+// walkIgnore walks root like fs.WalkDir, skipping files and directories that
+// the patterns, relative to root, exclude. Files under an excluded directory
+// are never visited, so they cannot be re-included.
+func walkIgnore(fsys fs.FS, root string, patterns []string, fn fs.WalkDirFunc) error {
+	if len(patterns) == 0 {
+		return fs.WalkDir(fsys, root, fn)
+	}
+	return fs.WalkDir(fsys, root, func(filePath string, d fs.DirEntry, err error) error {
+		if err == nil && filePath != root {
+			rel, relErr := pathutil.RelPathWithin(root, filePath)
+			if relErr != nil {
+				return relErr
+			}
+			if ignored(patterns, rel) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+		}
+		return fn(filePath, d, err)
+	})
+}
+
+// withUserFuncs adds the functions from options.WithTemplateFuncs to funcs,
+// noting each one that replaces a built-in.
+func withUserFuncs(funcs, user template.FuncMap, logger *slog.Logger) template.FuncMap {
+	for _, name := range slices.Sorted(maps.Keys(user)) {
+		if _, ok := funcs[name]; ok {
+			logger.Debug("template function replaces built-in", "name", name)
+		}
+		funcs[name] = user[name]
+	}
+	return funcs
+}
+
+// checkTemplateFuncs reports a function html/template would reject, which it
+// would otherwise panic over in the middle of a build.
+func checkTemplateFuncs(funcs template.FuncMap) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("template funcs: %v", r)
+		}
+	}()
+	template.New("").Funcs(funcs)
+	return nil
+}
+
 func parseRequiredTemplates(sourceFS fs.FS, pattern string, funcs template.FuncMap) (*template.Template, error) {
 	files, err := doublestar.Glob(sourceFS, pattern, doublestar.WithFailOnIOErrors())
 	if err != nil {
@@ -156,12 +235,71 @@ func parseTemplateFiles(sourceFS fs.FS, files []string, funcs template.FuncMap)
 	return tmpl, nil
 }
 
-func renderMarkdownComponentTemplate(tmpl *template.Template, page *transforms.Page) (string, error) {
+// parsePartials adds the templates matching glob to tmpl, each named
+// partials/ followed by its path under the glob's base directory without the
+// extension, so with templates/partials/**/*.tmpl,
+// templates/partials/nav/main.tmpl is called as {{ template "partials/nav/main" . }}.
+// A partial, or a template one defines, may not share a name with a template
+// already in tmpl.
+func parsePartials(sourceFS fs.FS, tmpl *template.Template, glob string, funcs template.FuncMap) error {
+	files, err := doublestar.Glob(sourceFS, glob, doublestar.WithFailOnIOErrors(), doublestar.WithFilesOnly())
+	if err != nil {
+		return fmt.Errorf("partials glob %q: %w", glob, err)
+	}
+	dir, _ := doublestar.SplitPattern(glob)
+
+	for _, file := range files {
+		rel := path.Clean(file)
+		name := rel
+		if dir != "." {
+			name = strings.TrimPrefix(rel, dir+"/")
+		}
+		name = "partials/" + strings.TrimSuffix(name, path.Ext(name))
+		content, err := fs.ReadFile(sourceFS, rel)
+		if err != nil {
+			return fmt.Errorf("partial %q: %w", rel, err)
+		}
+		partial, err := template.New(name).Funcs(funcs).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("partial %q: %w", rel, err)
+		}
+		for _, t := range partial.Templates() {
+			if tmpl.Lookup(t.Name()) != nil {
+				return fmt.Errorf("partial %q: template %q is already defined by the page templates", rel, t.Name())
+			}
+			if _, err := tmpl.AddParseTree(t.Name(), t.Tree); err != nil {
+				return fmt.Errorf("partial %q: %w", rel, err)
+			}
+		}
+	}
+	return nil
+}
+
+// renderMarkdownComponents renders markdown component templates on either side
+// of the summary divider, since html/template strips the divider comment.
+func renderMarkdownComponents(tmpl *template.Template, page *transforms.Page, rawBody, divider string) (string, error) {
+	summary, body, ok := markdown.SplitSummary(rawBody, divider)
+	if !ok {
+		return renderMarkdownComponentTemplate(tmpl, page, rawBody)
+	}
+
+	before, err := renderMarkdownComponentTemplate(tmpl, page, summary)
+	if err != nil {
+		return "", err
+	}
+	after, err := renderMarkdownComponentTemplate(tmpl, page, body[len(summary):])
+	if err != nil {
+		return "", err
+	}
+	return before + divider + after, nil
+}
+
+func renderMarkdownComponentTemplate(tmpl *template.Template, page *transforms.Page, rawBody string) (string, error) {
 	tmpl, err := tmpl.Clone()
 	if err != nil {
 		return "", err
 	}
-	if _, err := tmpl.New(page.SourcePath).Parse(page.RawBody); err != nil {
+	if _, err := tmpl.New(page.SourcePath).Parse(rawBody); err != nil {
 		return "", fmt.Errorf("markdown template %q: %w", page.SourcePath, err)
 	}
 
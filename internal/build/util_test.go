@@ -0,0 +1,76 @@
+package build
+
+import (
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/manifest"
+)
+
+func TestWalkIgnoreHonorsNegatedPatterns(t *testing.T) {
+	fsys := fstest.MapFS{
+		"static/.DS_Store":       {Data: []byte("x")},
+		"static/app.js":          {Data: []byte("x")},
+		"static/app.js.swp":      {Data: []byte("x")},
+		"static/keep.me":         {Data: []byte("x")},
+		"static/drop.me":         {Data: []byte("x")},
+		"static/drafts/a.css":    {Data: []byte("x")},
+		"static/img/.DS_Store":   {Data: []byte("x")},
+		"static/img/logo.png":    {Data: []byte("x")},
+		"static/drafts/keep.css": {Data: []byte("x")},
+	}
+	patterns := []string{".DS_Store", "*.swp", "*.me", "!keep.me", "drafts/", "!drafts/keep.css"}
+
+	var got []string
+	err := walkIgnore(fsys, "static", patterns, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			got = append(got, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkIgnore() error = %v", err)
+	}
+
+	want := []string{"static/app.js", "static/img/logo.png", "static/keep.me"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("walked %v, want %v", got, want)
+	}
+}
+
+func TestMinifierSkipsDisabledTypesAndMinFiles(t *testing.T) {
+	off := false
+	m := NewMinifier(&config.ConfigMinifier{JS: &off})
+
+	tests := []struct {
+		target string
+		input  string
+		want   string
+	}{
+		{target: "site.css", input: "a {  color: red;  }\n", want: "a{color:red}"},
+		{target: "vendor.min.css", input: "a {  color: red;  }\n", want: "a {  color: red;  }\n"},
+		{target: "app.js", input: "var  a = 1 ;\n", want: "var  a = 1 ;\n"},
+		{target: "notes.txt", input: "a  b\n", want: "a  b\n"},
+	}
+	for _, tt := range tests {
+		var buf strings.Builder
+		build := m(manifest.NewInternalClaim("static", tt.target), func(w io.Writer) error {
+			_, err := io.WriteString(w, tt.input)
+			return err
+		})
+		if err := build(&buf); err != nil {
+			t.Fatalf("%s: %v", tt.target, err)
+		}
+		if buf.String() != tt.want {
+			t.Fatalf("%s minified to %q, want %q", tt.target, buf.String(), tt.want)
+		}
+	}
+}
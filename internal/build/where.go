@@ -0,0 +1,210 @@
+package build
+
+import (
+	"fmt"
+	"html/template"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+func whereFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"where": where,
+	}
+}
+
+// where filters items on a field compared against value. The field is a
+// struct field such as "Section" or a dotted path into a map such as
+// "Params.author". The operator is one of eq, ne, gt, lt, in (the field is
+// one of the values in a list) and contains (a list field holds value, or a
+// string field contains it as a substring). Numbers compare numerically
+// whatever their Go type, and times compare against times or against dates
+// written as strings. Items without the field never match.
+func where(items any, field, op string, value any) ([]any, error) {
+	match, ok := whereOps[op]
+	if !ok {
+		return nil, fmt.Errorf("where: unknown operator %q", op)
+	}
+	slice, err := sliceAny(items)
+	if err != nil {
+		return nil, fmt.Errorf("where items: %w", err)
+	}
+
+	out := make([]any, 0, len(slice))
+	for _, item := range slice {
+		got, ok, err := whereField(item, field)
+		if err != nil {
+			return nil, fmt.Errorf("where: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		matched, err := match(got, value)
+		if err != nil {
+			return nil, fmt.Errorf("where %s %s: %w", field, op, err)
+		}
+		if matched {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+var whereOps = map[string]func(got, want any) (bool, error){
+	"eq": func(got, want any) (bool, error) {
+		c, ok := compareValues(got, want)
+		return ok && c == 0, nil
+	},
+	"ne": func(got, want any) (bool, error) {
+		c, ok := compareValues(got, want)
+		return !ok || c != 0, nil
+	},
+	"gt": func(got, want any) (bool, error) {
+		c, ok := compareValues(got, want)
+		if !ok {
+			return false, fmt.Errorf("cannot order %T against %T", got, want)
+		}
+		return c > 0, nil
+	},
+	"lt": func(got, want any) (bool, error) {
+		c, ok := compareValues(got, want)
+		if !ok {
+			return false, fmt.Errorf("cannot order %T against %T", got, want)
+		}
+		return c < 0, nil
+	},
+	"in": func(got, want any) (bool, error) {
+		list, err := sliceAny(want)
+		if err != nil {
+			return false, fmt.Errorf("in expects a list: %w", err)
+		}
+		return slices.ContainsFunc(list, func(v any) bool {
+			c, ok := compareValues(got, v)
+			return ok && c == 0
+		}), nil
+	},
+	"contains": func(got, want any) (bool, error) {
+		if s, ok := got.(string); ok {
+			sub, ok := want.(string)
+			return ok && strings.Contains(s, sub), nil
+		}
+		list, err := sliceAny(got)
+		if err != nil {
+			return false, fmt.Errorf("contains expects a list or string field: %w", err)
+		}
+		return slices.ContainsFunc(list, func(v any) bool {
+			c, ok := compareValues(v, want)
+			return ok && c == 0
+		}), nil
+	},
+}
+
+// whereField reads a dotted field path from item, reporting false when a map
+// along the path has no such key.
+func whereField(item any, field string) (any, bool, error) {
+	value := item
+	for part := range strings.SplitSeq(field, ".") {
+		rv := reflect.ValueOf(value)
+		for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return nil, false, nil
+			}
+			rv = rv.Elem()
+		}
+		if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+			next := rv.MapIndex(reflect.ValueOf(part).Convert(rv.Type().Key()))
+			if !next.IsValid() {
+				return nil, false, nil
+			}
+			value = next.Interface()
+			continue
+		}
+		next, err := paginationGroupValue(rv.Interface(), part)
+		if err != nil {
+			return nil, false, err
+		}
+		value = next
+	}
+	return value, true, nil
+}
+
+// compareValues orders a against b, reporting false when they are not of
+// comparable kinds.
+func compareValues(a, b any) (int, bool) {
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	}
+	// Strings are only read as dates when compared against a time.
+	_, aTime := a.(time.Time)
+	_, bTime := b.(time.Time)
+	if aTime || bTime {
+		at, aok := toTime(a)
+		bt, bok := toTime(b)
+		if !aok || !bok {
+			return 0, false
+		}
+		return at.Compare(bt), true
+	}
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, b), true
+	case bool:
+		b, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if a == b {
+			return 0, true
+		}
+		if b {
+			return -1, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// toTime accepts times and dates written as RFC 3339 or YYYY-MM-DD strings.
+func toTime(v any) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
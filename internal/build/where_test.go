@@ -0,0 +1,60 @@
+package build
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olimci/shizuka/internal/transforms"
+)
+
+func TestWhereFunc(t *testing.T) {
+	pages := []transforms.PageTmpl{
+		{Title: "a", Section: "posts", Tags: []string{"go"}, Params: map[string]any{
+			"author": "jane", "rating": 4, "featured": true, "event": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{Title: "b", Section: "notes", Params: map[string]any{
+			"author": "john", "rating": 2.5, "featured": false, "event": time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{Title: "c", Section: "posts", Tags: []string{"web"}, Params: map[string]any{}},
+	}
+
+	tmpl := template.Must(template.New("list").Funcs(whereFuncMap()).Parse(
+		`{{ range where .Pages .Field .Op .Value }}{{ .Title }}{{ end }}`,
+	))
+	tests := []struct {
+		field string
+		op    string
+		value any
+		want  string
+	}{
+		{field: "Params.author", op: "eq", value: "jane", want: "a"},
+		{field: "Params.author", op: "ne", value: "jane", want: "b"},
+		{field: "Params.rating", op: "gt", value: 3, want: "a"},
+		{field: "Params.rating", op: "lt", value: int64(3), want: "b"},
+		{field: "Params.featured", op: "eq", value: true, want: "a"},
+		{field: "Params.event", op: "gt", value: "2024-01-01", want: "a"},
+		{field: "Params.author", op: "in", value: []string{"john", "jill"}, want: "b"},
+		{field: "Params.author", op: "contains", value: "oh", want: "b"},
+		{field: "Tags", op: "contains", value: "web", want: "c"},
+		{field: "Section", op: "eq", value: "posts", want: "ac"},
+	}
+	for _, tt := range tests {
+		var buf strings.Builder
+		data := map[string]any{"Pages": pages, "Field": tt.field, "Op": tt.op, "Value": tt.value}
+		if err := tmpl.Execute(&buf, data); err != nil {
+			t.Fatalf("where %s %s %v: %v", tt.field, tt.op, tt.value, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Fatalf("where %s %s %v = %q, want %q", tt.field, tt.op, tt.value, got, tt.want)
+		}
+	}
+
+	if _, err := where(pages, "Params.author", "like", "j"); err == nil {
+		t.Fatal("where with an unknown operator succeeded, want error")
+	}
+	if _, err := where(pages, "Params.author", "gt", 1); err == nil {
+		t.Fatal("where ordering a string against a number succeeded, want error")
+	}
+}
@@ -0,0 +1,39 @@
+package build
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// validateXML checks that doc is well-formed. When it is not, the returned
+// index is the position of the itemTag element the error occurred in, so the
+// caller can name the page that produced it, or -1 outside any item.
+func validateXML(doc, itemTag string) (int, error) {
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	item, inItem := -1, false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return -1, nil
+		}
+		if err != nil {
+			if !inItem {
+				return -1, err
+			}
+			return item, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if tok.Name.Local == itemTag {
+				item++
+				inItem = true
+			}
+		case xml.EndElement:
+			if tok.Name.Local == itemTag {
+				inItem = false
+			}
+		}
+	}
+}
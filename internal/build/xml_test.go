@@ -0,0 +1,46 @@
+package build
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olimci/shizuka/internal/transforms"
+)
+
+func TestValidateXMLEscapedFeed(t *testing.T) {
+	doc, err := transforms.RenderRSS(transforms.RSSTemplateData{
+		Title:     "Tom & Jerry's <blog>",
+		Link:      "https://example.com",
+		BuildDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC1123Z),
+		Items: []transforms.RSSItem{{
+			Title:       "Fish & <chips>",
+			Link:        "https://example.com/fish/",
+			Description: "a < b && c > d",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := validateXML(doc, "item"); err != nil {
+		t.Fatalf("validateXML() error = %v\n%s", err, doc)
+	}
+	if !strings.Contains(doc, "<title>Fish &amp; &lt;chips&gt;</title>") {
+		t.Fatalf("title was not escaped:\n%s", doc)
+	}
+}
+
+func TestValidateXMLNamesOffendingItem(t *testing.T) {
+	doc := `<rss><channel><item><title>ok</title></item><item><title>Fish & chips</title></item></channel></rss>`
+	item, err := validateXML(doc, "item")
+	if err == nil {
+		t.Fatal("validateXML() accepted malformed XML")
+	}
+	if item != 1 {
+		t.Fatalf("item = %d, want 1", item)
+	}
+
+	if item, err := validateXML(`<rss><channel><title>a & b</title></channel></rss>`, "item"); err == nil || item != -1 {
+		t.Fatalf("validateXML() = %d, %v; want -1 and an error", item, err)
+	}
+}
@@ -3,10 +3,16 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/olimci/roundtrip/json"
 	"github.com/olimci/shizuka/internal/frontmatter"
+	"github.com/olimci/shizuka/internal/utils/pathutil"
 	"github.com/olimci/shizuka/internal/utils/urlutil"
 	"github.com/olimci/shizuka/internal/version"
 )
@@ -51,6 +57,7 @@ type Config struct {
 	Build     ConfigBuild     `json:"build"`
 	Content   ConfigContent   `json:"content"`
 	Artefacts ConfigArtefacts `json:"artefacts"`
+	Deploy    ConfigDeploy    `json:"deploy"`
 
 	Root string `toml:"-" yaml:"-" json:"-"`
 }
@@ -69,21 +76,229 @@ type ConfigPaths struct {
 	Data      string `json:"data"`
 	Static    string `json:"static"`
 	Templates string `json:"templates"`
+
+	// Archetypes holds the templates `shizuka new` writes new content from.
+	Archetypes string `json:"archetypes"`
+
+	// PartialsGlob matches the partial templates, each named partials/
+	// followed by its path under the glob's base directory. It defaults to
+	// every .tmpl file under the partials directory of Templates.
+	PartialsGlob string `json:"partials_glob"`
 }
 
 type ConfigBuild struct {
-	Minifier *ConfigMinifier `json:"minifier"`
+	Minifier  *ConfigMinifier  `json:"minifier"`
+	Orphans   *ConfigOrphans   `json:"orphans"`
+	Links     *ConfigLinks     `json:"links"`
+	ImageSize *ConfigImageSize `json:"image_size"`
+	Images    *ConfigImages    `json:"images"`
+	Watch     *ConfigWatch     `json:"watch"`
+	Static    *ConfigStatic    `json:"static"`
+
+	// IndexFile names the file each page is written to inside its route
+	// directory. It defaults to index.html; some hosts expect index.htm.
+	IndexFile string `json:"index_file"`
+}
+
+// ConfigWatch configures the dev server's file watcher. Paths matching an
+// Ignore pattern, relative to the config root, are neither watched nor
+// trigger rebuilds.
+type ConfigWatch struct {
+	Ignore []string `json:"ignore"`
+}
+
+// ConfigStatic configures the copy of the static directory. Exclude holds
+// gitignore-style patterns, relative to the static root, for files and
+// directories to leave out; a pattern starting with ! re-includes a file an
+// earlier pattern excluded.
+type ConfigStatic struct {
+	Exclude []string `json:"exclude"`
 }
 
+// ConfigImages enables resized variants of static JPEG and PNG images at
+// each of Widths narrower than the original. Format re-encodes the variants
+// as "jpeg" or "png" instead of the source format, and Quality applies to
+// JPEG output. Sizes is the sizes attribute the img func emits alongside the
+// srcset.
+type ConfigImages struct {
+	Widths  []int  `json:"widths"`
+	Format  string `json:"format"`
+	Quality int    `json:"quality"`
+	Sizes   string `json:"sizes"`
+}
+
+// ConfigImageSize enables a warning for static images larger than Max bytes.
+type ConfigImageSize struct {
+	Max int64 `json:"max"`
+}
+
+// ConfigOrphans enables the check for static files that no rendered page or
+// stylesheet references. Allow holds glob patterns for files that are expected
+// to be requested directly, such as favicons.
+type ConfigOrphans struct {
+	Allow []string `json:"allow"`
+	Fail  bool     `json:"fail"`
+}
+
+// ConfigLinks enables the check for links and image sources in page bodies
+// that point at no output file. Ignore holds glob patterns for site paths
+// that exist outside the build, such as paths a proxy serves.
+type ConfigLinks struct {
+	Ignore []string `json:"ignore"`
+	Fail   bool     `json:"fail"`
+}
+
+// ConfigDeploy configures the targets `shizuka deploy --target` can publish
+// the built site to.
+type ConfigDeploy struct {
+	Rsync *ConfigDeployRsync `json:"rsync"`
+	S3    *ConfigDeployS3    `json:"s3"`
+}
+
+// ConfigDeployRsync deploys by running the rsync binary against Destination,
+// e.g. user@host:/var/www/site. Flags are passed through before the paths.
+type ConfigDeployRsync struct {
+	Destination string   `json:"destination"`
+	Delete      bool     `json:"delete"`
+	Flags       []string `json:"flags"`
+}
+
+// ConfigDeployS3 deploys to an S3-compatible bucket, uploading only files
+// whose content hash differs from the remote object. Credentials are read
+// from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// environment variables.
+type ConfigDeployS3 struct {
+	Bucket    string `json:"bucket"`
+	Region    string `json:"region"`
+	Endpoint  string `json:"endpoint"`
+	Prefix    string `json:"prefix"`
+	PathStyle bool   `json:"path_style"`
+	Delete    bool   `json:"delete"`
+}
+
+// ConfigMinifier minifies HTML, CSS and JS output. Each type can be turned
+// off with HTML, CSS or JS set to false; unset types are minified.
 type ConfigMinifier struct {
 	Whitelist []string `json:"whitelist"`
 	Blacklist []string `json:"blacklist"`
+
+	HTML *bool `json:"html"`
+	CSS  *bool `json:"css"`
+	JS   *bool `json:"js"`
+}
+
+// Minifies reports whether files with the extension ext are minified.
+func (c *ConfigMinifier) Minifies(ext string) bool {
+	var enabled *bool
+	switch ext {
+	case ".html":
+		enabled = c.HTML
+	case ".css":
+		enabled = c.CSS
+	case ".js":
+		enabled = c.JS
+	default:
+		return false
+	}
+	return enabled == nil || *enabled
 }
 
 type ConfigContent struct {
-	Defaults ConfigContentDefaults `json:"defaults"`
-	Markdown ConfigContentMarkdown `json:"markdown"`
-	Git      *ConfigContentGit     `json:"git"`
+	Defaults ConfigContentDefaults           `json:"defaults"`
+	Markdown ConfigContentMarkdown           `json:"markdown"`
+	Git      *ConfigContentGit               `json:"git"`
+	Variants map[string]ConfigContentVariant `json:"variants"`
+	NoIndex  ConfigContentNoIndex            `json:"noindex"`
+
+	// Extensions maps extra content file extensions to the handler that
+	// processes them, on top of DefaultContentExtensions.
+	Extensions map[string]string `json:"extensions"`
+
+	// TitleFromFilename gives pages without a title, such as markdown notes
+	// with no frontmatter, one derived from their file or directory name.
+	TitleFromFilename bool `json:"title_from_filename"`
+
+	// DefaultTemplate renders pages whose frontmatter, type and content
+	// defaults name no template, or only DefaultPageTemplate when no such
+	// template exists. A template that is named but missing is an error
+	// rather than falling back. Empty turns the fallback off.
+	DefaultTemplate string `json:"default_template"`
+
+	// Sources are the content directories merged into the page tree. When
+	// unset, paths.content is the only source.
+	Sources []ConfigContentSource `json:"sources"`
+
+	// Languages turns on multilingual content. When unset, language
+	// suffixes and directories are ordinary path segments.
+	Languages *ConfigContentLanguages `json:"languages"`
+
+	// Related turns on the related pages computed for each page from the
+	// tags it shares with others.
+	Related *ConfigContentRelated `json:"related"`
+}
+
+// ConfigContentRelated scores pages against each other: TagWeight for every
+// tag they share, plus SectionWeight when they also share a section. Only
+// pages sharing at least one tag are related. Each page keeps its Limit
+// highest-scoring pages, the newest first among equal scores. Validate
+// defaults Limit to 5 and TagWeight to 1.
+type ConfigContentRelated struct {
+	Limit         int     `json:"limit"`
+	TagWeight     float64 `json:"tag_weight"`
+	SectionWeight float64 `json:"section_weight"`
+}
+
+// ConfigContentLanguages lists the languages content is written in. A page is
+// in a language by its file suffix (about.ja.md) or by sitting under a
+// top-level directory named for it (ja/about.md), and in Default otherwise.
+// Pages in other languages are routed under /CODE/, while Default pages keep
+// their unprefixed paths. Validate adds Default to Codes when missing.
+type ConfigContentLanguages struct {
+	Default string   `json:"default"`
+	Codes   []string `json:"codes"`
+}
+
+// Has reports whether code is one of the configured languages.
+func (c *ConfigContentLanguages) Has(code string) bool {
+	return c != nil && slices.Contains(c.Codes, code)
+}
+
+// ConfigContentSource is a named content directory whose pages are routed
+// under URLPrefix, so a source at docs with prefix "/docs" turns
+// docs/intro.md into /docs/intro/. Path is relative to the site root and may
+// lie outside it, as ../docs does. Params are defaults for every page in the
+// source, below section defaults and page params.
+// Validate normalises URLPrefix to a path without leading or trailing
+// slashes, "" for the site root.
+type ConfigContentSource struct {
+	Name      string         `json:"name"`
+	Path      string         `json:"path"`
+	URLPrefix string         `json:"url_prefix"`
+	Params    map[string]any `json:"default_params"`
+}
+
+// Content handlers for files with frontmatter. Markdown bodies are rendered
+// to HTML; passthrough bodies are used as-is.
+const (
+	ContentHandlerMarkdown    = "markdown"
+	ContentHandlerPassthrough = "passthrough"
+)
+
+// DefaultContentExtensions returns the built-in extension to handler map.
+// Data formats (.toml, .yaml, .yml, .json) are always content pages.
+func DefaultContentExtensions() map[string]string {
+	return map[string]string{
+		".md":   ContentHandlerMarkdown,
+		".html": ContentHandlerPassthrough,
+	}
+}
+
+// ConfigContentNoIndex selects which pages are marked noindex in addition to
+// those that set `noindex` in frontmatter. Production builds leave out drafts
+// and future pages altogether, so these matter for dev builds.
+type ConfigContentNoIndex struct {
+	Drafts bool `json:"drafts"`
+	Future bool `json:"future"`
 }
 
 type ConfigContentDefaults struct {
@@ -105,6 +320,14 @@ type ConfigContentMarkdown struct {
 	Wikilinks      bool                        `json:"wikilinks"`
 	Highlighting   *ConfigMarkdownHighlighting `json:"highlighting"`
 	Components     bool                        `json:"components"`
+	Summary        ConfigMarkdownSummary       `json:"summary"`
+	ToC            *ConfigMarkdownToC          `json:"toc"`
+}
+
+// ConfigContentVariant enables an alternate rendering of pages, emitted under
+// the page route at /<page>/<name>/.
+type ConfigContentVariant struct {
+	Template string `json:"template"`
 }
 
 type ConfigContentGit struct {
@@ -115,32 +338,91 @@ type ConfigArtefacts struct {
 	Headers   *ConfigHeaders   `json:"headers"`
 	Redirects *ConfigRedirects `json:"redirects"`
 	RSS       *ConfigRSS       `json:"rss"`
+	JSONFeed  *ConfigJSONFeed  `json:"json_feed"`
+	Atom      *ConfigAtom      `json:"atom"`
 	Sitemap   *ConfigSitemap   `json:"sitemap"`
 	Robots    *ConfigRobots    `json:"robots"`
 	NotFound  *ConfigNotFound  `json:"not_found"`
 	Meta      *ConfigMeta      `json:"meta"`
 }
 
+// ConfigHeaders configures the `_headers` artefact. NoIndex adds an
+// `X-Robots-Tag: noindex` header for pages marked noindex. Values are keyed by route
+// path and merged with the `headers` declared in page frontmatter. When both
+// set the same header, Precedence decides which value is kept ("page", the
+// default, or "config"), except for headers listed in Merge, whose values are
+// all kept. Each kept value is written as its own line; hosts that read
+// `_headers` join repeated keys for a path into one comma-separated header.
 type ConfigHeaders struct {
-	Path   string                       `json:"path"`
-	Values map[string]map[string]string `json:"values"`
+	Path       string                       `json:"path"`
+	Values     map[string]map[string]string `json:"values"`
+	Precedence string                       `json:"precedence"`
+	Merge      []string                     `json:"merge"`
+	CSP        *ConfigCSP                   `json:"csp"`
+	NoIndex    bool                         `json:"noindex"`
+}
+
+// ConfigCSP adds a Content-Security-Policy header to every page with inline
+// scripts or styles, allowing them by SHA-256 hash on top of Policy.
+type ConfigCSP struct {
+	Policy string `json:"policy"`
 }
 
+const (
+	HeadersPrecedencePage   = "page"
+	HeadersPrecedenceConfig = "config"
+)
+
 type ConfigRedirects struct {
 	Path    string     `json:"path"`
 	Entries []Redirect `json:"entries"`
 }
 
+// ConfigRSS configures the RSS feed. Items are ordered newest first by
+// OrderBy: "updated" (the default) uses the last update, falling back to the
+// creation date, and "date" uses the creation date. When MaxAge is set (a Go
+// duration such as "2160h"), items older than that at build time are dropped.
+// Hub is a WebSub hub URL advertised in the feed.
 type ConfigRSS struct {
 	Path          string   `json:"path"`
 	Sections      []string `json:"sections"`
 	Limit         int      `json:"limit"`
 	IncludeDrafts bool     `json:"include_drafts"`
+	OrderBy       string   `json:"order_by"`
+	MaxAge        string   `json:"max_age"`
+	Hub           string   `json:"hub"`
+
+	MaxAgeDuration time.Duration `json:"-"`
+}
+
+const (
+	RSSOrderDate    = "date"
+	RSSOrderUpdated = "updated"
+)
+
+type ConfigJSONFeed struct {
+	Path          string   `json:"path"`
+	Sections      []string `json:"sections"`
+	Limit         int      `json:"limit"`
+	IncludeDrafts bool     `json:"include_drafts"`
+}
+
+// ConfigAtom configures the Atom 1.0 feed. The feed author is read from the
+// "author" site param.
+type ConfigAtom struct {
+	Path          string   `json:"path"`
+	Sections      []string `json:"sections"`
+	Limit         int      `json:"limit"`
+	IncludeDrafts bool     `json:"include_drafts"`
 }
 
+// ConfigSitemap configures the sitemap. A site with more than MaxURLs
+// entries (45000 by default, under the 50000 search engines accept) is split
+// into numbered sitemaps next to Path, with Path holding an index of them.
 type ConfigSitemap struct {
 	Path          string `json:"path"`
 	IncludeDrafts bool   `json:"include_drafts"`
+	MaxURLs       int    `json:"max_urls"`
 }
 
 type ConfigRobots struct {
@@ -183,10 +465,38 @@ type ConfigMarkdownRenderer struct {
 	XHTML      bool `json:"xhtml"`
 }
 
+// ConfigMarkdownSummary selects a page's summary: the markdown before
+// Divider when the body contains it, otherwise the first Paragraphs
+// paragraphs.
+type ConfigMarkdownSummary struct {
+	Paragraphs int    `json:"paragraphs"`
+	Divider    string `json:"divider"`
+}
+
+// ConfigMarkdownToC enables the nested table of contents built from the
+// headings between MinLevel and MaxLevel (h2 to h4 by default). Its links
+// need heading IDs, so it expects parser.auto_heading_id.
+type ConfigMarkdownToC struct {
+	MinLevel int `json:"min_level"`
+	MaxLevel int `json:"max_level"`
+}
+
+// DefaultSummaryDivider is the summary divider used by Hugo and Jekyll.
+const DefaultSummaryDivider = "<!--more-->"
+
+// DefaultPageTemplate is the template the built-in content defaults give
+// every page. Unlike a template the site names, it is not required to exist:
+// pages fall back to ConfigContent.DefaultTemplate when it does not.
+const DefaultPageTemplate = "page"
+
+// ConfigMarkdownHighlighting turns on Chroma highlighting of fenced code
+// blocks. With Classes, code is marked up with CSS classes instead of inline
+// styles, and the stylesheet for Style is emitted at CSS.
 type ConfigMarkdownHighlighting struct {
 	Style       string `json:"style"`
 	LineNumbers bool   `json:"line_numbers"`
 	Classes     bool   `json:"classes"`
+	CSS         string `json:"css"`
 }
 
 // DefaultConfig constructs a new Config with default values.
@@ -199,6 +509,10 @@ func DefaultConfig() *Config {
 		DefinitionList: true,
 		Footnotes:      true,
 		Typographer:    true,
+		Summary: ConfigMarkdownSummary{
+			Paragraphs: 1,
+			Divider:    DefaultSummaryDivider,
+		},
 	}
 
 	return &Config{
@@ -211,23 +525,30 @@ func DefaultConfig() *Config {
 			Params:      map[string]any{},
 		},
 		Paths: ConfigPaths{
-			Output:    "dist",
-			Content:   "content",
-			Data:      "data",
-			Static:    "static",
-			Templates: "templates",
+			Output:     "dist",
+			Content:    "content",
+			Data:       "data",
+			Static:     "static",
+			Templates:  "templates",
+			Archetypes: "archetypes",
 		},
 		Build: ConfigBuild{
-			Minifier: &ConfigMinifier{},
+			Minifier:  &ConfigMinifier{},
+			IndexFile: "index.html",
 		},
 		Content: ConfigContent{
 			Defaults: ConfigContentDefaults{
 				Section: "pages",
 				Global: frontmatter.Defaults{
-					Template: "page",
+					Template: DefaultPageTemplate,
 				},
 			},
-			Markdown: defaultMarkdown,
+			DefaultTemplate: "default",
+			Markdown:        defaultMarkdown,
+			NoIndex: ConfigContentNoIndex{
+				Drafts: true,
+				Future: true,
+			},
 		},
 	}
 }
@@ -257,6 +578,93 @@ func (c *Config) Validate() error {
 		c.Content.Defaults.Sections = map[string]frontmatter.Defaults{}
 	}
 
+	for name := range c.Content.Variants {
+		if _, err := pathutil.ValidateRoutePath("/" + name + "/"); err != nil || strings.Contains(name, "/") {
+			return fmt.Errorf("content.variants: invalid variant name %q", name)
+		}
+	}
+
+	extensions := DefaultContentExtensions()
+	for ext, handler := range c.Content.Extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if ext == "." || strings.ContainsAny(ext[1:], "./") {
+			return fmt.Errorf("content.extensions: invalid extension %q", ext)
+		}
+		switch handler {
+		case ContentHandlerMarkdown, ContentHandlerPassthrough:
+		default:
+			return fmt.Errorf("content.extensions: unknown handler %q for %q", handler, ext)
+		}
+		extensions[ext] = handler
+	}
+	c.Content.Extensions = extensions
+
+	if c.Content.Markdown.Summary.Paragraphs < 0 {
+		return fmt.Errorf("content.markdown.summary.paragraphs must not be negative")
+	}
+	if strings.TrimSpace(c.Content.Markdown.Summary.Divider) == "" {
+		c.Content.Markdown.Summary.Divider = DefaultSummaryDivider
+	}
+	if toc := c.Content.Markdown.ToC; toc != nil {
+		if toc.MinLevel == 0 {
+			toc.MinLevel = 2
+		}
+		if toc.MaxLevel == 0 {
+			toc.MaxLevel = 4
+		}
+		if toc.MinLevel < 1 || toc.MaxLevel > 6 || toc.MinLevel > toc.MaxLevel {
+			return fmt.Errorf("content.markdown.toc levels must satisfy 1 <= min_level <= max_level <= 6 (got %d and %d)", toc.MinLevel, toc.MaxLevel)
+		}
+	}
+
+	if hl := c.Content.Markdown.Highlighting; hl != nil {
+		if hl.CSS == "" {
+			hl.CSS = "chroma.css"
+		}
+		path, err := c.resolvePath("content.markdown.highlighting.css", hl.CSS)
+		if err != nil {
+			return err
+		}
+		hl.CSS = path
+	}
+
+	if langs := c.Content.Languages; langs != nil {
+		if langs.Default == "" {
+			return fmt.Errorf("content.languages.default must be set")
+		}
+		if !slices.Contains(langs.Codes, langs.Default) {
+			langs.Codes = append([]string{langs.Default}, langs.Codes...)
+		}
+		seen := make(map[string]struct{}, len(langs.Codes))
+		for _, code := range langs.Codes {
+			if code == "" || strings.ContainsAny(code, "./") {
+				return fmt.Errorf("content.languages: invalid language code %q", code)
+			}
+			if _, err := pathutil.ValidateRoutePath("/" + code + "/"); err != nil {
+				return fmt.Errorf("content.languages: invalid language code %q", code)
+			}
+			if _, ok := seen[code]; ok {
+				return fmt.Errorf("content.languages: duplicate language code %q", code)
+			}
+			seen[code] = struct{}{}
+		}
+	}
+
+	if related := c.Content.Related; related != nil {
+		if related.Limit == 0 {
+			related.Limit = 5
+		}
+		if related.TagWeight == 0 {
+			related.TagWeight = 1
+		}
+		if related.Limit < 0 || related.TagWeight < 0 || related.SectionWeight < 0 {
+			return fmt.Errorf("content.related: limit and weights must not be negative")
+		}
+	}
+
 	if c.Build.Minifier != nil {
 		patterns, err := cleanPatterns("build.minifier.whitelist", c.Build.Minifier.Whitelist)
 		if err != nil {
@@ -271,6 +679,80 @@ func (c *Config) Validate() error {
 		c.Build.Minifier.Blacklist = patterns
 	}
 
+	if c.Build.ImageSize != nil {
+		if c.Build.ImageSize.Max == 0 {
+			c.Build.ImageSize.Max = 1 << 20
+		}
+		if c.Build.ImageSize.Max < 0 {
+			return fmt.Errorf("build.image_size.max must be positive")
+		}
+	}
+
+	if c.Build.IndexFile == "" {
+		c.Build.IndexFile = "index.html"
+	}
+	if strings.ContainsAny(c.Build.IndexFile, `/\`) || c.Build.IndexFile == "." || c.Build.IndexFile == ".." {
+		return fmt.Errorf("build.index_file must be a file name (got %q)", c.Build.IndexFile)
+	}
+
+	if c.Build.Images != nil {
+		if c.Build.Images.Widths == nil {
+			c.Build.Images.Widths = []int{480, 960, 1440}
+		}
+		for _, width := range c.Build.Images.Widths {
+			if width <= 0 {
+				return fmt.Errorf("build.images.widths must be positive (got %d)", width)
+			}
+		}
+		c.Build.Images.Widths = slices.Compact(slices.Sorted(slices.Values(c.Build.Images.Widths)))
+		switch c.Build.Images.Format {
+		case "", "jpeg", "png":
+		default:
+			return fmt.Errorf("build.images.format must be \"jpeg\" or \"png\" (got %q)", c.Build.Images.Format)
+		}
+		if c.Build.Images.Quality == 0 {
+			c.Build.Images.Quality = 80
+		}
+		if c.Build.Images.Quality < 1 || c.Build.Images.Quality > 100 {
+			return fmt.Errorf("build.images.quality must be between 1 and 100")
+		}
+	}
+
+	if c.Build.Watch != nil {
+		patterns, err := cleanPatterns("build.watch.ignore", c.Build.Watch.Ignore)
+		if err != nil {
+			return err
+		}
+		c.Build.Watch.Ignore = patterns
+	}
+
+	if c.Build.Static != nil {
+		patterns, err := cleanIgnorePatterns("build.static.exclude", c.Build.Static.Exclude)
+		if err != nil {
+			return err
+		}
+		c.Build.Static.Exclude = patterns
+	}
+
+	if c.Build.Orphans != nil {
+		if c.Build.Orphans.Allow == nil {
+			c.Build.Orphans.Allow = []string{"favicon.ico", "robots.txt", "CNAME", ".well-known/**"}
+		}
+		patterns, err := cleanPatterns("build.orphans.allow", c.Build.Orphans.Allow)
+		if err != nil {
+			return err
+		}
+		c.Build.Orphans.Allow = patterns
+	}
+
+	if c.Build.Links != nil {
+		patterns, err := cleanPatterns("build.links.ignore", c.Build.Links.Ignore)
+		if err != nil {
+			return err
+		}
+		c.Build.Links.Ignore = patterns
+	}
+
 	if c.Artefacts.Headers != nil {
 		if c.Artefacts.Headers.Values == nil {
 			c.Artefacts.Headers.Values = map[string]map[string]string{}
@@ -278,6 +760,19 @@ func (c *Config) Validate() error {
 		if c.Artefacts.Headers.Path == "" {
 			c.Artefacts.Headers.Path = "_headers"
 		}
+		switch c.Artefacts.Headers.Precedence {
+		case "":
+			c.Artefacts.Headers.Precedence = HeadersPrecedencePage
+		case HeadersPrecedencePage, HeadersPrecedenceConfig:
+		default:
+			return fmt.Errorf("artefacts.headers.precedence must be %q or %q", HeadersPrecedencePage, HeadersPrecedenceConfig)
+		}
+		if c.Artefacts.Headers.Merge == nil {
+			c.Artefacts.Headers.Merge = []string{"Link"}
+		}
+		if c.Artefacts.Headers.CSP != nil && c.Artefacts.Headers.CSP.Policy == "" {
+			c.Artefacts.Headers.CSP.Policy = "default-src 'self'"
+		}
 		path, err := c.resolvePath("artefacts.headers.path", c.Artefacts.Headers.Path)
 		if err != nil {
 			return err
@@ -305,6 +800,48 @@ func (c *Config) Validate() error {
 			return err
 		}
 		c.Artefacts.RSS.Path = path
+
+		switch c.Artefacts.RSS.OrderBy {
+		case "":
+			c.Artefacts.RSS.OrderBy = RSSOrderUpdated
+		case RSSOrderDate, RSSOrderUpdated:
+		default:
+			return fmt.Errorf("artefacts.rss.order_by must be %q or %q", RSSOrderDate, RSSOrderUpdated)
+		}
+		if c.Artefacts.RSS.MaxAge != "" {
+			maxAge, err := time.ParseDuration(c.Artefacts.RSS.MaxAge)
+			if err != nil || maxAge <= 0 {
+				return fmt.Errorf("artefacts.rss.max_age must be a positive duration (got %q)", c.Artefacts.RSS.MaxAge)
+			}
+			c.Artefacts.RSS.MaxAgeDuration = maxAge
+		}
+		if c.Artefacts.RSS.Hub != "" {
+			hub, err := urlutil.ValidURL(c.Artefacts.RSS.Hub)
+			if err != nil {
+				return fmt.Errorf("artefacts.rss.hub: %w", err)
+			}
+			c.Artefacts.RSS.Hub = hub
+		}
+	}
+	if c.Artefacts.JSONFeed != nil && c.Artefacts.JSONFeed.Path == "" {
+		c.Artefacts.JSONFeed.Path = "feed.json"
+	}
+	if c.Artefacts.JSONFeed != nil {
+		path, err := c.resolvePath("artefacts.json_feed.path", c.Artefacts.JSONFeed.Path)
+		if err != nil {
+			return err
+		}
+		c.Artefacts.JSONFeed.Path = path
+	}
+	if c.Artefacts.Atom != nil && c.Artefacts.Atom.Path == "" {
+		c.Artefacts.Atom.Path = "atom.xml"
+	}
+	if c.Artefacts.Atom != nil {
+		path, err := c.resolvePath("artefacts.atom.path", c.Artefacts.Atom.Path)
+		if err != nil {
+			return err
+		}
+		c.Artefacts.Atom.Path = path
 	}
 	if c.Artefacts.Sitemap != nil && c.Artefacts.Sitemap.Path == "" {
 		c.Artefacts.Sitemap.Path = "sitemap.xml"
@@ -315,6 +852,12 @@ func (c *Config) Validate() error {
 			return err
 		}
 		c.Artefacts.Sitemap.Path = path
+		if c.Artefacts.Sitemap.MaxURLs == 0 {
+			c.Artefacts.Sitemap.MaxURLs = 45000
+		}
+		if c.Artefacts.Sitemap.MaxURLs < 0 {
+			return fmt.Errorf("artefacts.sitemap.max_urls must not be negative")
+		}
 	}
 	if c.Artefacts.Robots != nil {
 		if c.Artefacts.Robots.Path == "" {
@@ -353,6 +896,27 @@ func (c *Config) Validate() error {
 		c.Artefacts.Meta.Path = path
 	}
 
+	if c.Deploy.Rsync != nil && c.Deploy.Rsync.Destination == "" {
+		return fmt.Errorf("deploy.rsync.destination is required")
+	}
+	if s3 := c.Deploy.S3; s3 != nil {
+		if s3.Bucket == "" {
+			return fmt.Errorf("deploy.s3.bucket is required")
+		}
+		if s3.Region == "" {
+			s3.Region = "us-east-1"
+		}
+		if s3.Endpoint == "" {
+			s3.Endpoint = "https://s3." + s3.Region + ".amazonaws.com"
+		}
+		endpoint, err := urlutil.ValidURL(s3.Endpoint)
+		if err != nil {
+			return fmt.Errorf("deploy.s3.endpoint: %w", err)
+		}
+		s3.Endpoint = strings.TrimSuffix(endpoint, "/")
+		s3.Prefix = strings.Trim(s3.Prefix, "/")
+	}
+
 	outputPath, err := c.resolvePath("paths.output", c.Paths.Output)
 	if err != nil {
 		return err
@@ -371,6 +935,10 @@ func (c *Config) Validate() error {
 	}
 	c.Paths.Content = contentPath
 
+	if err := c.validateContentSources(); err != nil {
+		return err
+	}
+
 	dataPath, err := c.resolvePath("paths.data", c.Paths.Data)
 	if err != nil {
 		return err
@@ -383,14 +951,117 @@ func (c *Config) Validate() error {
 	}
 	c.Paths.Templates = templatePath
 
+	archetypePath, err := c.resolvePath("paths.archetypes", c.Paths.Archetypes)
+	if err != nil {
+		return err
+	}
+	c.Paths.Archetypes = archetypePath
+
+	if c.Paths.PartialsGlob == "" {
+		c.Paths.PartialsGlob = path.Join(c.Paths.Templates, "partials", "**", "*.tmpl")
+	}
+	partialsGlob, err := pathutil.CleanContentGlob(c.Paths.PartialsGlob)
+	if err != nil {
+		return fmt.Errorf("paths.partials_glob: %w", err)
+	}
+	if !doublestar.ValidatePattern(partialsGlob) {
+		return fmt.Errorf("paths.partials_glob: invalid glob %q", partialsGlob)
+	}
+	c.Paths.PartialsGlob = partialsGlob
+
+	return nil
+}
+
+// PartialsDir returns the base directory of the partials glob, which partial
+// names are relative to, or the default partials directory for a config that
+// has not been validated.
+func (c *Config) PartialsDir() string {
+	if c.Paths.PartialsGlob == "" {
+		return path.Join(c.Paths.Templates, "partials")
+	}
+	base, _ := doublestar.SplitPattern(c.Paths.PartialsGlob)
+	return base
+}
+
+func (c *Config) validateContentSources() error {
+	c.Content.Sources = c.ContentSources()
+
+	names := make(map[string]struct{}, len(c.Content.Sources))
+	for i := range c.Content.Sources {
+		source := &c.Content.Sources[i]
+		label := fmt.Sprintf("content.sources[%d]", i)
+
+		sourcePath, err := c.resolveSourcePath(label+".path", source.Path)
+		if err != nil {
+			return err
+		}
+		source.Path = sourcePath
+
+		if source.Name == "" {
+			source.Name = path.Base(sourcePath)
+		}
+		if _, ok := names[source.Name]; ok {
+			return fmt.Errorf("%s.name: duplicate source name %q", label, source.Name)
+		}
+		names[source.Name] = struct{}{}
+
+		prefix := strings.Trim(source.URLPrefix, "/")
+		if prefix != "" && (path.Clean(prefix) != prefix || pathutil.EscapesRoot(prefix)) {
+			return fmt.Errorf("%s.url_prefix must be a clean path (got %q)", label, source.URLPrefix)
+		}
+		source.URLPrefix = prefix
+	}
 	return nil
 }
 
+// ContentSources returns the content sources, or paths.content alone for a
+// config that has not been validated.
+func (c *Config) ContentSources() []ConfigContentSource {
+	if len(c.Content.Sources) == 0 {
+		return []ConfigContentSource{{Name: path.Base(c.Paths.Content), Path: c.Paths.Content}}
+	}
+	return c.Content.Sources
+}
+
+// ContentPaths returns the directory of every content source.
+func (c *Config) ContentPaths() []string {
+	sources := c.ContentSources()
+	paths := make([]string, 0, len(sources))
+	for _, source := range sources {
+		paths = append(paths, source.Path)
+	}
+	return paths
+}
+
 func (c *Config) WatchedPaths() (paths []string, globs []string, err error) {
-	return []string{
-		filepath.Join(c.root(), filepath.FromSlash(c.Paths.Static)),
-		filepath.Join(c.root(), filepath.FromSlash(c.Paths.Content)),
+	for _, p := range append([]string{c.Paths.Static}, c.ContentPaths()...) {
+		paths = append(paths, filepath.Join(c.root(), filepath.FromSlash(p)))
+	}
+	paths = append(paths,
 		filepath.Join(c.root(), filepath.FromSlash(c.Paths.Data)),
 		filepath.Join(c.root(), filepath.FromSlash(c.Paths.Templates)),
-	}, nil, nil
+	)
+	// Partials kept outside the templates directory are watched on their own.
+	partials := c.PartialsDir()
+	if _, err := pathutil.RelPathWithin(c.Paths.Templates, partials); err != nil && partials != c.Paths.Templates {
+		paths = append(paths, filepath.Join(c.root(), filepath.FromSlash(partials)))
+	}
+	return paths, nil, nil
+}
+
+// WatchIgnored reports whether p matches one of the build.watch.ignore
+// patterns. Paths outside the config root are never ignored.
+func (c *Config) WatchIgnored(p string) bool {
+	if c.Build.Watch == nil || len(c.Build.Watch.Ignore) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(c.root(), p)
+	if err != nil || !filepath.IsLocal(rel) {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	return slices.ContainsFunc(c.Build.Watch.Ignore, func(pattern string) bool {
+		ok, _ := doublestar.Match(strings.TrimPrefix(pattern, "/"), rel)
+		return ok
+	})
 }
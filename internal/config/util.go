@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -25,6 +26,24 @@ func cleanPatterns(label string, patterns []string) ([]string, error) {
 	return cleaned, nil
 }
 
+// cleanIgnorePatterns cleans gitignore-style patterns, which may be negated
+// with a leading !.
+func cleanIgnorePatterns(label string, patterns []string) ([]string, error) {
+	cleaned := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		negated, ok := strings.CutPrefix(pattern, "!")
+		c, err := cleanPatterns(label, []string{negated})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			c[0] = "!" + c[0]
+		}
+		cleaned = append(cleaned, c[0])
+	}
+	return cleaned, nil
+}
+
 func (c *Config) resolvePath(label, raw string) (string, error) {
 	resolved, err := pathutil.CleanContentPath(raw)
 	if err != nil {
@@ -33,6 +52,18 @@ func (c *Config) resolvePath(label, raw string) (string, error) {
 	return resolved, nil
 }
 
+// resolveSourcePath is resolvePath for a content source, which may also lie
+// outside the site root.
+func (c *Config) resolveSourcePath(label, raw string) (string, error) {
+	if p := filepath.ToSlash(raw); !filepath.IsAbs(raw) && pathutil.EscapesRoot(p) {
+		if cleaned := path.Clean(p); cleaned != p {
+			return "", fmt.Errorf("%s: path must be clean (got %q, want %q)", label, p, cleaned)
+		}
+		return p, nil
+	}
+	return c.resolvePath(label, raw)
+}
+
 func (c *Config) root() string {
 	if c.Root == "" {
 		return "."
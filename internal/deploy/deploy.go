@@ -0,0 +1,159 @@
+// Package deploy publishes a built site to remote hosts.
+package deploy
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+var ErrNoTarget = errors.New("no deploy target configured")
+
+// Target publishes the contents of a local directory.
+type Target interface {
+	Name() string
+	Deploy(ctx context.Context, dir string, opts Options) (Result, error)
+}
+
+// Options apply to every target. Out receives progress lines.
+type Options struct {
+	DryRun bool
+	Out    io.Writer
+}
+
+// Result counts the files a deploy touched. Targets that delegate to an
+// external tool may leave it zero.
+type Result struct {
+	Uploaded  int
+	Deleted   int
+	Unchanged int
+}
+
+// File is a deployable file, keyed by its slash-separated path and the hex
+// MD5 of its content. MD5 matches the ETag S3 reports for single-part
+// uploads, so remote listings can be compared without downloading.
+type File struct {
+	Path string
+	Hash string
+	Size int64
+}
+
+// Plan is the set of changes that brings a remote in line with the local
+// files.
+type Plan struct {
+	Upload    []File
+	Delete    []string
+	Unchanged int
+}
+
+// Targets returns the targets enabled in cfg, keyed by name.
+func Targets(cfg config.ConfigDeploy) map[string]Target {
+	targets := make(map[string]Target)
+	if cfg.Rsync != nil {
+		targets["rsync"] = NewRsync(*cfg.Rsync)
+	}
+	if cfg.S3 != nil {
+		targets["s3"] = NewS3(*cfg.S3, CredentialsFromEnv())
+	}
+	return targets
+}
+
+// Select returns the named target, or the only configured one when name is
+// empty.
+func Select(cfg config.ConfigDeploy, name string) (Target, error) {
+	targets := Targets(cfg)
+	if name != "" {
+		target, ok := targets[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: deploy.%s", ErrNoTarget, name)
+		}
+		return target, nil
+	}
+
+	switch len(targets) {
+	case 0:
+		return nil, ErrNoTarget
+	case 1:
+		for _, target := range targets {
+			return target, nil
+		}
+	}
+	names := slices.Sorted(maps.Keys(targets))
+	return nil, fmt.Errorf("several deploy targets configured (%s); pick one with --target", strings.Join(names, ", "))
+}
+
+// LocalFiles hashes every regular file under dir.
+func LocalFiles(dir string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		hash, size, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, File{Path: filepath.ToSlash(rel), Hash: hash, Size: size})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(files, func(a, b File) int { return strings.Compare(a.Path, b.Path) })
+	return files, nil
+}
+
+func hashFile(p string) (string, int64, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// Diff compares local files against a remote listing of path to hash. Remote
+// paths missing locally are deleted only when prune is set.
+func Diff(local []File, remote map[string]string, prune bool) Plan {
+	var plan Plan
+	seen := make(map[string]struct{}, len(local))
+	for _, file := range local {
+		seen[file.Path] = struct{}{}
+		if hash, ok := remote[file.Path]; ok && strings.EqualFold(hash, file.Hash) {
+			plan.Unchanged++
+			continue
+		}
+		plan.Upload = append(plan.Upload, file)
+	}
+
+	if prune {
+		for p := range remote {
+			if _, ok := seen[p]; !ok {
+				plan.Delete = append(plan.Delete, p)
+			}
+		}
+		slices.Sort(plan.Delete)
+	}
+	return plan
+}
@@ -0,0 +1,59 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+// CommandRunner runs an external command, streaming its output to out.
+type CommandRunner func(ctx context.Context, out io.Writer, name string, args ...string) error
+
+func execCommand(ctx context.Context, out io.Writer, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Rsync deploys by running the rsync binary, which does its own change
+// detection.
+type Rsync struct {
+	cfg config.ConfigDeployRsync
+	run CommandRunner
+}
+
+func NewRsync(cfg config.ConfigDeployRsync) *Rsync {
+	return &Rsync{cfg: cfg, run: execCommand}
+}
+
+func (r *Rsync) Name() string { return "rsync" }
+
+func (r *Rsync) Deploy(ctx context.Context, dir string, opts Options) (Result, error) {
+	out := opts.Out
+	if out == nil {
+		out = io.Discard
+	}
+	return Result{}, r.run(ctx, out, "rsync", r.args(dir, opts.DryRun)...)
+}
+
+func (r *Rsync) args(dir string, dryRun bool) []string {
+	args := []string{"--recursive", "--links", "--times", "--compress", "--checksum", "--itemize-changes"}
+	if r.cfg.Delete {
+		args = append(args, "--delete")
+	}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	args = append(args, r.cfg.Flags...)
+	// The trailing separator makes rsync copy the directory's contents
+	// rather than the directory itself.
+	return append(args, filepath.Clean(dir)+string(filepath.Separator), r.cfg.Destination)
+}
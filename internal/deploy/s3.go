@@ -0,0 +1,328 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+var ErrNoCredentials = errors.New("missing S3 credentials: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+func CredentialsFromEnv() Credentials {
+	return Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// objectStore is the part of the S3 API the target uses. List returns object
+// keys under prefix mapped to their ETags.
+type objectStore interface {
+	List(ctx context.Context, prefix string) (map[string]string, error)
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	Delete(ctx context.Context, key string) error
+}
+
+// S3 deploys to an S3-compatible bucket, uploading only changed files.
+type S3 struct {
+	cfg   config.ConfigDeployS3
+	creds Credentials
+	store objectStore
+}
+
+func NewS3(cfg config.ConfigDeployS3, creds Credentials) *S3 {
+	return &S3{
+		cfg:   cfg,
+		creds: creds,
+		store: &s3Client{cfg: cfg, creds: creds, http: http.DefaultClient, now: time.Now},
+	}
+}
+
+func (s *S3) Name() string { return "s3" }
+
+func (s *S3) Deploy(ctx context.Context, dir string, opts Options) (Result, error) {
+	out := opts.Out
+	if out == nil {
+		out = io.Discard
+	}
+	if _, ok := s.store.(*s3Client); ok && (s.creds.AccessKeyID == "" || s.creds.SecretAccessKey == "") {
+		return Result{}, ErrNoCredentials
+	}
+
+	local, err := LocalFiles(dir)
+	if err != nil {
+		return Result{}, err
+	}
+
+	prefix := ""
+	if s.cfg.Prefix != "" {
+		prefix = s.cfg.Prefix + "/"
+	}
+	objects, err := s.store.List(ctx, prefix)
+	if err != nil {
+		return Result{}, err
+	}
+	remote := make(map[string]string, len(objects))
+	for key, etag := range objects {
+		remote[strings.TrimPrefix(key, prefix)] = strings.Trim(etag, `"`)
+	}
+
+	plan := Diff(local, remote, s.cfg.Delete)
+	result := Result{Uploaded: len(plan.Upload), Deleted: len(plan.Delete), Unchanged: plan.Unchanged}
+	for _, file := range plan.Upload {
+		fmt.Fprintf(out, "upload %s\n", file.Path)
+	}
+	for _, p := range plan.Delete {
+		fmt.Fprintf(out, "delete %s\n", p)
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for _, file := range plan.Upload {
+		body, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			return Result{}, err
+		}
+		contentType := mime.TypeByExtension(path.Ext(file.Path))
+		if contentType == "" {
+			contentType = http.DetectContentType(body)
+		}
+		if err := s.store.Put(ctx, prefix+file.Path, body, contentType); err != nil {
+			return Result{}, err
+		}
+	}
+	for _, p := range plan.Delete {
+		if err := s.store.Delete(ctx, prefix+p); err != nil {
+			return Result{}, err
+		}
+	}
+	return result, nil
+}
+
+// s3Client talks to the S3 REST API directly, signing requests with AWS
+// Signature Version 4.
+type s3Client struct {
+	cfg   config.ConfigDeployS3
+	creds Credentials
+	http  *http.Client
+	now   func() time.Time
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		ETag string `xml:"ETag"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (c *s3Client) List(ctx context.Context, prefix string) (map[string]string, error) {
+	objects := make(map[string]string)
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		body, err := c.do(ctx, http.MethodGet, "", query, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		if err := xml.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("s3 list %s: %w", c.cfg.Bucket, err)
+		}
+		for _, object := range page.Contents {
+			objects[object.Key] = object.ETag
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (c *s3Client) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	_, err := c.do(ctx, http.MethodPut, key, nil, body, contentType)
+	return err
+}
+
+func (c *s3Client) Delete(ctx context.Context, key string) error {
+	_, err := c.do(ctx, http.MethodDelete, key, nil, nil, "")
+	return err
+}
+
+func (c *s3Client) do(ctx context.Context, method, key string, query url.Values, body []byte, contentType string) ([]byte, error) {
+	u, err := c.objectURL(key, query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	signV4(req, body, c.creds, c.cfg.Region, c.now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("s3 %s %q: %s: %s", method, key, resp.Status, bytes.TrimSpace(respBody))
+	}
+	return respBody, nil
+}
+
+func (c *s3Client) objectURL(key string, query url.Values) (*url.URL, error) {
+	u, err := url.Parse(c.cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.PathStyle {
+		u.Path = "/" + c.cfg.Bucket + "/" + key
+	} else {
+		u.Host = c.cfg.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = awsEscape(u.Path, true)
+	u.RawQuery = canonicalQuery(query)
+	return u, nil
+}
+
+// signV4 adds AWS Signature Version 4 headers to req. Every header already
+// set on req is signed, along with host and the x-amz-* headers added here.
+func signV4(req *http.Request, body []byte, creds Credentials, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.Path, true),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query sorted by key with AWS URI encoding, which is
+// both the wire form and the canonical form used for signing.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := slices.Clone(query[key])
+		slices.Sort(values)
+		for _, value := range values {
+			parts = append(parts, awsEscape(key, false)+"="+awsEscape(value, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything except unreserved characters, and
+// slashes when keepSlash is set.
+func awsEscape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
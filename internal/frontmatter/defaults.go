@@ -1,6 +1,7 @@
 package frontmatter
 
 import (
+	"maps"
 	"slices"
 	"time"
 )
@@ -19,9 +20,13 @@ type Defaults struct {
 
 	Template string `toml:"template" yaml:"template" json:"template"`
 
-	Featured bool `toml:"featured" yaml:"featured" json:"featured"`
-	Draft    bool `toml:"draft" yaml:"draft" json:"draft"`
-	Weight   int  `toml:"weight" yaml:"weight" json:"weight"`
+	Featured bool  `toml:"featured" yaml:"featured" json:"featured"`
+	Draft    bool  `toml:"draft" yaml:"draft" json:"draft"`
+	NoIndex  bool  `toml:"noindex" yaml:"noindex" json:"noindex"`
+	Weight   int   `toml:"weight" yaml:"weight" json:"weight"`
+	Render   *bool `toml:"render" yaml:"render" json:"render"`
+
+	Params map[string]any `toml:"params" yaml:"params" json:"params"`
 }
 
 func (d Defaults) Frontmatter() Frontmatter {
@@ -38,7 +43,16 @@ func (d Defaults) Frontmatter() Frontmatter {
 		Template:    d.Template,
 		Featured:    d.Featured,
 		Draft:       d.Draft,
+		NoIndex:     d.NoIndex,
 		Weight:      d.Weight,
-		Params:      map[string]any{},
+		Render:      d.Render,
+		Params:      cloneParams(d.Params),
+	}
+}
+
+func cloneParams(params map[string]any) map[string]any {
+	if params == nil {
+		return map[string]any{}
 	}
+	return maps.Clone(params)
 }
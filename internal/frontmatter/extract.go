@@ -1,6 +1,7 @@
 package frontmatter
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 
@@ -21,11 +22,11 @@ func ExtractWithDefaults(doc []byte, defaultSection string, defaults Defaults, b
 
 	switch fmType, start, end, bodyStart := detect(b); fmType {
 	case "yaml":
-		return extract(decodeutil.FormatYAML, b[start:end], b[bodyStart:], doc, defaultSection, defaults, bySection)
+		return extract(decodeutil.FormatYAML, b[start:end], b[bodyStart:], doc, lineOf(b, start), defaultSection, defaults, bySection)
 	case "toml":
-		return extract(decodeutil.FormatTOML, b[start:end], b[bodyStart:], doc, defaultSection, defaults, bySection)
+		return extract(decodeutil.FormatTOML, b[start:end], b[bodyStart:], doc, lineOf(b, start), defaultSection, defaults, bySection)
 	case "json":
-		return extract(decodeutil.FormatJSONC, b[start:end], b[bodyStart:], doc, defaultSection, defaults, bySection)
+		return extract(decodeutil.FormatJSONC, b[start:end], b[bodyStart:], doc, lineOf(b, start), defaultSection, defaults, bySection)
 	case "":
 		fm := FrontmatterFor(defaultSection, defaults, bySection)
 		return &fm, doc, nil
@@ -39,9 +40,17 @@ func DecodeWithDefaults(format decodeutil.Format, data []byte, defaultSection st
 	if err != nil {
 		return Frontmatter{}, err
 	}
+	defaultParams, defaultTemplate := fm.Params, fm.Template
+	fm.Params, fm.Template = nil, ""
 	if err := decodeutil.Unmarshal(format, data, &fm); err != nil {
 		return Frontmatter{}, err
 	}
+	fm.OwnParams = fm.Params
+	fm.Params = MergeParams(defaultParams, fm.Params)
+	fm.OwnTemplate = fm.Template
+	if fm.Template == "" {
+		fm.Template = defaultTemplate
+	}
 	return fm, nil
 }
 
@@ -80,10 +89,17 @@ func FrontmatterFor(section string, defaults Defaults, bySection map[string]Defa
 	return fm
 }
 
-func extract(format decodeutil.Format, data, body, fallback []byte, defaultSection string, defaults Defaults, bySection map[string]Defaults) (*Frontmatter, []byte, error) {
+// extract decodes frontmatter that starts startLine lines into the document,
+// so error positions are reported relative to the whole file.
+func extract(format decodeutil.Format, data, body, fallback []byte, startLine int, defaultSection string, defaults Defaults, bySection map[string]Defaults) (*Frontmatter, []byte, error) {
 	fm, err := DecodeWithDefaults(format, data, defaultSection, defaults, bySection)
 	if err != nil {
-		return nil, fallback, fmt.Errorf("%w (%s): %w", ErrParse, format, err)
+		return nil, fallback, fmt.Errorf("%w (%s): %w", ErrParse, format, decodeutil.OffsetLines(err, startLine))
 	}
 	return &fm, body, nil
 }
+
+// lineOf returns the number of lines before offset in b.
+func lineOf(b []byte, offset int) int {
+	return bytes.Count(b[:offset], []byte("\n"))
+}
@@ -5,6 +5,8 @@ import (
 	"slices"
 	"testing"
 	"time"
+
+	"github.com/olimci/shizuka/internal/utils/decodeutil"
 )
 
 func TestExtractWithDefaultsAppliesSectionDefaultsBeforeDocumentFields(t *testing.T) {
@@ -115,3 +117,28 @@ func TestExtractWithoutFrontmatterUsesDefaultSection(t *testing.T) {
 		t.Fatalf("frontmatter = %#v, want default section and fields", fm)
 	}
 }
+
+func TestExtractReportsErrorPositionInDocument(t *testing.T) {
+	tests := map[string]struct {
+		doc          string
+		line, column int
+	}{
+		"yaml": {"---\ntitle: ok\nweight: heavy\n---\nBody", 3, 0},
+		"toml": {"+++\ntitle = 'ok'\nweight = = 3\n+++\nBody", 3, 10},
+		"json": {"{\n  \"title\": \"ok\",\n  \"weight\" 3\n}\nBody", 3, 12},
+	}
+
+	for name, tt := range tests {
+		_, _, err := Extract([]byte(tt.doc))
+		if !errors.Is(err, ErrParse) {
+			t.Fatalf("%s: err = %v, want ErrParse", name, err)
+		}
+		perr, ok := errors.AsType[*decodeutil.PositionError](err)
+		if !ok {
+			t.Fatalf("%s: err = %v, want a position", name, err)
+		}
+		if perr.Line != tt.line || perr.Column != tt.column {
+			t.Fatalf("%s: position = %d:%d, want %d:%d", name, perr.Line, perr.Column, tt.line, tt.column)
+		}
+	}
+}
@@ -7,26 +7,69 @@ import (
 )
 
 type Frontmatter struct {
-	Title       string   `toml:"title" yaml:"title" json:"title"`
-	Description string   `toml:"description" yaml:"description" json:"description"`
-	Section     string   `toml:"section" yaml:"section" json:"section"`
-	Slug        string   `toml:"slug" yaml:"slug" json:"slug"`
-	Tags        []string `toml:"tags" yaml:"tags" json:"tags"`
+	Title       string `toml:"title" yaml:"title" json:"title"`
+	Description string `toml:"description" yaml:"description" json:"description"`
+	Section     string `toml:"section" yaml:"section" json:"section"`
+	Slug        string `toml:"slug" yaml:"slug" json:"slug"`
+
+	// URL places the page at a site path of its own choosing, such as "/",
+	// instead of the path derived from its source location.
+	URL  string   `toml:"url" yaml:"url" json:"url"`
+	Tags []string `toml:"tags" yaml:"tags" json:"tags"`
+
+	// Aliases are old paths that redirect to the page, and Canonical
+	// replaces the canonical URL otherwise derived from site.url.
+	Aliases   []string `toml:"aliases" yaml:"aliases" json:"aliases"`
+	Canonical string   `toml:"canonical" yaml:"canonical" json:"canonical"`
 
 	Created time.Time `toml:"created" yaml:"created" json:"created"`
 	Updated time.Time `toml:"updated" yaml:"updated" json:"updated"`
 
+	// ExpiryDate retires a page: once it has passed, production builds
+	// leave the page out, as they do pages created in the future.
+	ExpiryDate time.Time `toml:"expiry_date" yaml:"expiry_date" json:"expiry_date"`
+
 	RSS     RSSMeta     `toml:"rss" yaml:"rss" json:"rss"`
 	Sitemap SitemapMeta `toml:"sitemap" yaml:"sitemap" json:"sitemap"`
 	Robots  RobotsMeta  `toml:"robots" yaml:"robots" json:"robots"`
 
-	Params map[string]any `toml:"params" yaml:"params" json:"params"`
+	Params  map[string]any    `toml:"params" yaml:"params" json:"params"`
+	Headers map[string]string `toml:"headers" yaml:"headers" json:"headers"`
+
+	// OwnParams holds the params set by the document itself, before defaults
+	// are merged into Params.
+	OwnParams map[string]any `toml:"-" yaml:"-" json:"-"`
 
-	Template string `toml:"template" yaml:"template" json:"template"`
+	// Cascade holds params an index page passes down to every page below
+	// it. It is ignored on other pages.
+	Cascade map[string]any `toml:"cascade" yaml:"cascade" json:"cascade"`
+
+	Template string            `toml:"template" yaml:"template" json:"template"`
+	Variants map[string]string `toml:"variants" yaml:"variants" json:"variants"`
+
+	// Type picks a template by convention, <section>/<type> then <type>,
+	// for documents that do not set a template of their own.
+	Type string `toml:"type" yaml:"type" json:"type"`
+
+	// OwnTemplate holds the template set by the document itself, before
+	// defaults are applied.
+	OwnTemplate string `toml:"-" yaml:"-" json:"-"`
 
 	Featured bool `toml:"featured" yaml:"featured" json:"featured"`
 	Draft    bool `toml:"draft" yaml:"draft" json:"draft"`
-	Weight   int  `toml:"weight" yaml:"weight" json:"weight"`
+	NoIndex  bool `toml:"noindex" yaml:"noindex" json:"noindex"`
+
+	// Weight orders pages manually, as with the sortBy "Weight" template
+	// func: lower weights come first, unweighted (zero) pages last, and ties
+	// fall back to title order.
+	Weight int `toml:"weight" yaml:"weight" json:"weight"`
+
+	// Render defaults to true when unset; false keeps the page as data only.
+	Render *bool `toml:"render" yaml:"render" json:"render"`
+
+	// ToC defaults to true when unset; false leaves the page without a
+	// nested table of contents.
+	ToC *bool `toml:"toc" yaml:"toc" json:"toc"`
 }
 
 type RSSMeta struct {
@@ -49,6 +92,11 @@ type RobotsMeta struct {
 func (fm *Frontmatter) Clone() *Frontmatter {
 	clone := *fm
 	clone.Tags = slices.Clone(fm.Tags)
+	clone.Aliases = slices.Clone(fm.Aliases)
 	clone.Params = maps.Clone(fm.Params)
+	clone.OwnParams = maps.Clone(fm.OwnParams)
+	clone.Cascade = maps.Clone(fm.Cascade)
+	clone.Variants = maps.Clone(fm.Variants)
+	clone.Headers = maps.Clone(fm.Headers)
 	return &clone
 }
@@ -0,0 +1,72 @@
+package frontmatter
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// MergeParams overlays document params onto default params. Overrides are
+// coerced to the type of the default they replace so that, for example, a
+// string "3" overriding an int default stays usable in numeric comparisons.
+func MergeParams(defaults, overrides map[string]any) map[string]any {
+	out := cloneParams(defaults)
+	for key, value := range overrides {
+		if existing, ok := out[key]; ok {
+			value = coerceLike(existing, value)
+		}
+		out[key] = value
+	}
+	return out
+}
+
+// coerceLike converts a string value to the type of existing. Values that are
+// not strings, or that fail to parse, are returned unchanged.
+func coerceLike(existing, value any) any {
+	s, ok := value.(string)
+	if !ok || existing == nil {
+		return value
+	}
+
+	if _, ok := existing.(time.Time); ok {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t
+		}
+		if t, err := time.Parse(time.DateOnly, s); err == nil {
+			return t
+		}
+		return value
+	}
+
+	rv := reflect.ValueOf(existing)
+	out := reflect.New(rv.Type()).Elem()
+	switch rv.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return value
+		}
+		out.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return value
+		}
+		out.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return value
+		}
+		out.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return value
+		}
+		out.SetFloat(f)
+	default:
+		return value
+	}
+	return out.Interface()
+}
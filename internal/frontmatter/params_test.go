@@ -0,0 +1,37 @@
+package frontmatter
+
+import "testing"
+
+func TestCoerceLikeConvertsStringToExistingType(t *testing.T) {
+	if got := coerceLike(int64(10), "3"); got != int64(3) {
+		t.Fatalf("coerceLike(int64, %q) = %#v, want int64(3)", "3", got)
+	}
+	if got := coerceLike(1.5, "2.5"); got != 2.5 {
+		t.Fatalf("coerceLike(float64, %q) = %#v, want 2.5", "2.5", got)
+	}
+	if got := coerceLike(true, "false"); got != false {
+		t.Fatalf("coerceLike(bool, %q) = %#v, want false", "false", got)
+	}
+	if got := coerceLike(10, "ten"); got != "ten" {
+		t.Fatalf("coerceLike(int, %q) = %#v, want unchanged string", "ten", got)
+	}
+}
+
+func TestExtractWithDefaultsCoercesParamOverrides(t *testing.T) {
+	defaults := Defaults{Params: map[string]any{"count": 10, "name": "default"}}
+
+	fm, _, err := ExtractWithDefaults([]byte("---\nparams:\n  count: \"3\"\n---\nHello"), "", defaults, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fm.Params["count"]; got != 3 {
+		t.Fatalf("count = %#v, want int 3", got)
+	}
+	if got := fm.Params["name"]; got != "default" {
+		t.Fatalf("name = %#v, want default preserved", got)
+	}
+	if defaults.Params["count"] != 10 {
+		t.Fatalf("defaults mutated: %#v", defaults.Params)
+	}
+}
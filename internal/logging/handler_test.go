@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestJSONFormatEncodesErrorsAsMessages(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(NewHandler(Options{Out: &out, Err: &out, Format: FormatJSON}))
+
+	logger.With("step", "pages:build").Warn("build error",
+		"error", fmt.Errorf("render: %w", errors.New("template missing")),
+		"claim_source", "content/index.md",
+	)
+
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("output %q is not JSON: %v", out.String(), err)
+	}
+	if line["error"] != "render: template missing" {
+		t.Fatalf("error = %#v, want the error message", line["error"])
+	}
+	if line["step"] != "pages:build" || line["claim_source"] != "content/index.md" || line["level"] != "WARN" {
+		t.Fatalf("line = %#v, want step, source and level", line)
+	}
+}
+
+func TestJSONFormatFallsBackToPlainText(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(NewHandler(Options{Out: &out, Err: &out, Format: FormatJSON}))
+
+	logger.Info("unencodable", "value", func() {})
+
+	if got := out.String(); !strings.Contains(got, "unencodable") || json.Valid(out.Bytes()) {
+		t.Fatalf("output = %q, want a plain-text line", got)
+	}
+}
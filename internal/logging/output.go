@@ -87,7 +87,12 @@ func (h *Handler) formatForOutput(r slog.Record, output output) ([]byte, error)
 
 	switch format {
 	case FormatJSON:
-		return h.formatJSON(r)
+		// A record whose attrs cannot be encoded still gets logged, as plain
+		// text, rather than failing the caller.
+		if line, err := h.formatJSON(r); err == nil {
+			return line, nil
+		}
+		return h.format(r, false)
 
 	case FormatPlain:
 		return h.format(r, false)
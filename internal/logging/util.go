@@ -300,6 +300,10 @@ func jsonValue(v slog.Value) any {
 	case slog.KindLogValuer:
 		return jsonValue(v.Resolve())
 	case slog.KindAny:
+		// Most error types have no exported fields and would encode as {}.
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
 		return v.Any()
 	default:
 		return fmt.Sprint(v.Any())
@@ -15,6 +15,10 @@ type PostProcessor func(claim Claim, next ArtefactBuilder) ArtefactBuilder
 type Artefact struct {
 	Claim   Claim
 	Builder ArtefactBuilder
+
+	// Fingerprint identifies the inputs the artefact was built from. It is
+	// only consulted when an artefact cache is enabled.
+	Fingerprint string
 }
 
 func (a Artefact) Post(pp PostProcessor) Artefact {
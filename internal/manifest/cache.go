@@ -0,0 +1,113 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const artefactCacheVersion = 2
+
+// Stats summarises the artefacts handled by a manifest. Written and Bytes
+// count the files a build changed; Skipped counts those it left as they were,
+// whether reused from the artefact cache or rebuilt with the same content.
+type Stats struct {
+	Written int
+	Skipped int
+	Removed int
+	Bytes   int64
+
+	Changes Changes
+}
+
+type artefactCacheFile struct {
+	Version int               `json:"version"`
+	Output  string            `json:"output"`
+	Entries map[string]string `json:"entries"`
+}
+
+// Fingerprint hashes the given parts into a stable artefact fingerprint.
+func Fingerprint(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Fingerprinted attaches a fingerprint to the artefact. When an artefact cache
+// is enabled and the previous build wrote the same target with the same
+// fingerprint, the builder is not run.
+func (a Artefact) Fingerprinted(fingerprint string) Artefact {
+	a.Fingerprint = fingerprint
+	return a
+}
+
+// loadArtefactCache returns the fingerprints cached at path for the output
+// directory out, which must be absolute. It returns nil when there is no
+// cache for out: a cache written for another output says nothing about what
+// is in this one.
+func loadArtefactCache(path, out string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("artefact cache %q: %w", path, err)
+	}
+
+	var file artefactCacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != artefactCacheVersion || file.Entries == nil {
+		// a stale or corrupt cache only costs a full rebuild
+		return nil, nil
+	}
+	if file.Output != out {
+		return nil, nil
+	}
+	return file.Entries, nil
+}
+
+func saveArtefactCache(path, out string, entries map[string]string) error {
+	data, err := json.Marshal(artefactCacheFile{
+		Version: artefactCacheVersion,
+		Output:  out,
+		Entries: entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("artefact cache %q: %w", path, err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data, creating its directory
+// if needed, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".shizuka-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
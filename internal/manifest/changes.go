@@ -0,0 +1,47 @@
+package manifest
+
+import "slices"
+
+// Changes lists the output files a build touched, by target path. Written
+// files are new, Edited files existed with different content, Unchanged files
+// were already up to date, and Deleted files were stale and removed. A dry run
+// reports what a real build would do.
+type Changes struct {
+	Written   []string
+	Edited    []string
+	Unchanged []string
+	Deleted   []string
+}
+
+// Changed returns the written, edited and deleted targets.
+func (c Changes) Changed() []string {
+	return slices.Concat(c.Written, c.Edited, c.Deleted)
+}
+
+// record files a written target under Written, Edited or Unchanged.
+func (c *Changes) record(target string, existed, changed bool) {
+	switch {
+	case !existed:
+		c.Written = append(c.Written, target)
+	case changed:
+		c.Edited = append(c.Edited, target)
+	default:
+		c.Unchanged = append(c.Unchanged, target)
+	}
+}
+
+// forget removes target from every list.
+func (c *Changes) forget(target string) {
+	for _, list := range []*[]string{&c.Written, &c.Edited, &c.Unchanged, &c.Deleted} {
+		*list = slices.DeleteFunc(*list, func(t string) bool { return t == target })
+	}
+}
+
+func (c Changes) sorted() Changes {
+	return Changes{
+		Written:   slices.Sorted(slices.Values(c.Written)),
+		Edited:    slices.Sorted(slices.Values(c.Edited)),
+		Unchanged: slices.Sorted(slices.Values(c.Unchanged)),
+		Deleted:   slices.Sorted(slices.Values(c.Deleted)),
+	}
+}
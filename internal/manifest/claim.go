@@ -19,6 +19,16 @@ func NewPageClaim(source, routePath string) Claim {
 	}
 }
 
+// WithIndexFile points a page claim at name instead of index.html, for hosts
+// that expect a different directory index.
+func (c Claim) WithIndexFile(name string) Claim {
+	if name == "" || path.Base(c.Target) != "index.html" {
+		return c
+	}
+	c.Target = path.Join(path.Dir(c.Target), name)
+	return c
+}
+
 func NewInternalClaim(owner, target string) Claim {
 	return Claim{
 		Owner:  owner,
@@ -1,13 +1,17 @@
 package manifest
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"sync"
 
 	"github.com/olimci/shizuka/internal/config"
@@ -44,6 +48,15 @@ type Manifest struct {
 
 	claims  map[string][]Claim
 	outputs map[string]struct{}
+
+	cachePath    string
+	cached       map[string]string
+	fingerprints map[string]string
+	writes       map[string]writeRecord
+	targetLocks  map[string]*sync.Mutex
+	stats        Stats
+
+	reportPath string
 }
 
 // Start opens the output tree and starts accepting artefacts.
@@ -67,19 +80,44 @@ func (m *Manifest) Start(ctx context.Context, cfg *config.Config, opts *options.
 	if err := validateOutputPath(cfg, opts, out); err != nil {
 		return err
 	}
-	if err := os.MkdirAll(out, 0o755); err != nil {
-		return fmt.Errorf("directory %q: %w", out, err)
+	if err := validateReportPath(out, opts.ReportPath); err != nil {
+		return err
+	}
+	if !opts.DryRun {
+		if err := mkdirOutput(out, opts.DirMode); err != nil {
+			return fmt.Errorf("directory %q: %w", out, err)
+		}
 	}
-	if info, err := os.Stat(out); err != nil {
+	info, err := os.Stat(out)
+	switch {
+	case opts.DryRun && errors.Is(err, fs.ErrNotExist):
+		// a dry run into a missing output would write everything
+	case err != nil:
 		return fmt.Errorf("directory %q: %w", out, err)
-	} else if !info.IsDir() {
+	case !info.IsDir():
 		return fmt.Errorf("path %q is not a directory", out)
 	}
-	outRoot, err := os.OpenRoot(out)
-	if err != nil {
-		return err
+	var cached map[string]string
+	if opts.ArtefactCachePath != "" {
+		outAbs, err := filepath.Abs(out)
+		if err != nil {
+			return fmt.Errorf("output path %q: %w", out, err)
+		}
+		cached, err = loadArtefactCache(opts.ArtefactCachePath, outAbs)
+		if err != nil {
+			return err
+		}
 	}
-	if !opts.Force {
+	var outRoot *os.Root
+	if info != nil {
+		outRoot, err = os.OpenRoot(out)
+		if err != nil {
+			return err
+		}
+	}
+	// an output the cache was written for is ours to reconcile, and a dry
+	// run leaves whatever is there alone
+	if outRoot != nil && !opts.Force && !opts.DryRun && cached == nil {
 		empty, err := rootEmpty(outRoot)
 		if err != nil {
 			_ = outRoot.Close()
@@ -95,7 +133,9 @@ func (m *Manifest) Start(ctx context.Context, cfg *config.Config, opts *options.
 	defer m.mu.Unlock()
 
 	if m.started {
-		_ = outRoot.Close()
+		if outRoot != nil {
+			_ = outRoot.Close()
+		}
 		return ErrStarted
 	}
 
@@ -103,13 +143,20 @@ func (m *Manifest) Start(ctx context.Context, cfg *config.Config, opts *options.
 
 	m.ctx = runCtx
 	m.cancel = cancel
-	m.pool = pool.New(runCtx, opts.MaxWorkers)
+	m.pool = pool.New(runCtx, writeWorkers(opts))
 	m.out = out
 	m.outRoot = outRoot
 	m.options = opts
 	m.report = report
 	m.claims = make(map[string][]Claim)
 	m.outputs = make(map[string]struct{})
+	m.cachePath = opts.ArtefactCachePath
+	m.cached = cached
+	m.fingerprints = make(map[string]string)
+	m.writes = make(map[string]writeRecord)
+	m.targetLocks = make(map[string]*sync.Mutex)
+	m.stats = Stats{}
+	m.reportPath = opts.ReportPath
 	m.started = true
 	return nil
 }
@@ -132,14 +179,89 @@ func (m *Manifest) Emit(artefact Artefact) error {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		accepted, err := m.accept(artefact)
-		if err != nil {
+		accepted, keep, err := m.accept(artefact)
+		if err != nil || !keep {
 			return err
 		}
 		return m.write(accepted)
 	})
 }
 
+// Reuse claims target for an artefact whose fingerprint matches the one
+// recorded by the previous build, leaving the existing output in place. It
+// reports false when the caller must build and emit the artefact itself.
+func (m *Manifest) Reuse(claim Claim, fingerprint string) bool {
+	m.mu.Lock()
+	enabled := m.started && !m.closed && m.cachePath != ""
+	m.mu.Unlock()
+	if !enabled || fingerprint == "" {
+		return false
+	}
+
+	target, err := normalizeTarget(claim.Target)
+	if err != nil || !m.cacheHit(target, fingerprint) {
+		return false
+	}
+	// A contested target may already hold another claim's output.
+	m.mu.Lock()
+	_, claimed := m.claims[target]
+	m.mu.Unlock()
+	if claimed {
+		return false
+	}
+	claim.Target = target
+	if _, keep, err := m.accept(Artefact{Claim: claim, Fingerprint: fingerprint}); err != nil || !keep {
+		return true
+	}
+	m.skipped(target, fingerprint)
+	return true
+}
+
+// Output returns the resolved output directory.
+func (m *Manifest) Output() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.out
+}
+
+// Claims returns the accepted claims, one per output target, sorted by
+// target.
+func (m *Manifest) Claims() []Claim {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Claim, 0, len(m.outputs))
+	for _, target := range slices.Sorted(maps.Keys(m.outputs)) {
+		out = append(out, m.claims[target][0])
+	}
+	return out
+}
+
+// Stats reports how many artefacts were written and skipped so far.
+func (m *Manifest) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.stats
+	stats.Changes = stats.Changes.sorted()
+	return stats
+}
+
+// Drain closes the manifest and waits for accepted artefacts to be claimed
+// and written, so that Claims is complete before Finish decides whether the
+// output is committed. Finish reports the error Drain returns again.
+func (m *Manifest) Drain() error {
+	m.mu.Lock()
+	if !m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	pool := m.pool
+	m.mu.Unlock()
+
+	return pool.Wait()
+}
+
 // Finish closes the manifest, waits for accepted artefacts to drain, and
 // reconciles the output tree.
 func (m *Manifest) Finish(success bool) error {
@@ -158,12 +280,21 @@ func (m *Manifest) Finish(success bool) error {
 	cancel := m.cancel
 	outRoot := m.outRoot
 	dev := m.options != nil && m.options.Dev
+	dryRun := m.options != nil && m.options.DryRun
 	m.mu.Unlock()
 
 	runErr := pool.Wait()
 
 	var err error
 	switch {
+	case dryRun:
+		// Count what a real build would remove, but leave the output and
+		// the artefact cache alone.
+		if runErr == nil && success {
+			err = m.cleanup(m.outputSnapshot())
+		} else {
+			err = runErr
+		}
 	case !success && !dev:
 		err = m.cleanup(nil)
 	case !success:
@@ -178,50 +309,135 @@ func (m *Manifest) Finish(success bool) error {
 		err = runErr
 	default:
 		err = m.cleanup(m.outputSnapshot())
+		if err == nil && m.cachePath != "" {
+			var out string
+			if out, err = filepath.Abs(m.out); err == nil {
+				err = saveArtefactCache(m.cachePath, out, m.fingerprintSnapshot())
+			}
+		}
+		if err == nil && m.reportPath != "" {
+			err = saveReport(m.reportPath, m.buildReport())
+		}
 	}
 
 	cancel()
-	_ = outRoot.Close()
+	if outRoot != nil {
+		_ = outRoot.Close()
+	}
 	return err
 }
 
-func (m *Manifest) accept(artefact Artefact) (Artefact, error) {
+// accept claims the artefact's target. It reports false, with no error, for
+// an artefact that lost its target to a higher-priority claim and must not be
+// written.
+func (m *Manifest) accept(artefact Artefact) (Artefact, bool, error) {
 	target, err := normalizeTarget(artefact.Claim.Target)
 	if err != nil {
-		return Artefact{}, m.recordError(artefact.Claim, err)
+		return Artefact{}, false, m.recordError(artefact.Claim, err)
 	}
 	artefact.Claim.Target = target
 
 	m.mu.Lock()
 	if !m.started {
 		m.mu.Unlock()
-		return Artefact{}, ErrClosed
+		return Artefact{}, false, ErrClosed
 	}
 	if err := m.ctx.Err(); err != nil {
 		m.mu.Unlock()
-		return Artefact{}, err
+		return Artefact{}, false, err
 	}
 
 	m.claims[target] = append(m.claims[target], artefact.Claim)
-	if len(m.claims[target]) > 1 {
-		err := conflictError(target, m.claims[target])
+	if claims := m.claims[target]; len(claims) > 1 {
+		winner, ok := conflictWinner(m.options.ConflictPriority, claims)
+		if !ok {
+			err := conflictError(target, claims)
+			m.mu.Unlock()
+			return Artefact{}, false, m.recordError(NewInternalClaim("manifest", target), err)
+		}
+		keep := winner == len(claims)-1
+		claims[0], claims[winner] = claims[winner], claims[0]
+		resolved := slices.Clone(claims)
 		m.mu.Unlock()
-		return Artefact{}, m.recordError(NewInternalClaim("manifest", target), err)
+
+		if err := m.conflictResolved(target, resolved); err != nil {
+			return Artefact{}, false, err
+		}
+		return artefact, keep, nil
 	}
 	m.outputs[target] = struct{}{}
 	m.mu.Unlock()
 
-	return artefact, nil
+	return artefact, true, nil
+}
+
+// conflictResolved warns that claims[0] was kept over the other claims on
+// target. Strict builds treat it as an error.
+func (m *Manifest) conflictResolved(target string, claims []Claim) error {
+	dropped := make([]string, 0, len(claims)-1)
+	for _, claim := range claims[1:] {
+		dropped = append(dropped, claim.DisplayOwner())
+	}
+	if logger := m.options.Logger; logger != nil {
+		logger.Warn("artefact conflict resolved by priority", "target", target, "kept", claims[0].DisplayOwner(), "dropped", dropped)
+	}
+	if m.options.Strict {
+		return m.recordError(NewInternalClaim("manifest", target), conflictError(target, claims))
+	}
+	return nil
+}
+
+// lockTarget serialises writes to target once conflict priorities are set,
+// so that a claim replaced while its write is in flight cannot overwrite the
+// claim that replaced it.
+func (m *Manifest) lockTarget(target string) func() {
+	if len(m.options.ConflictPriority) == 0 {
+		return func() {}
+	}
+	m.mu.Lock()
+	lock, ok := m.targetLocks[target]
+	if !ok {
+		lock = new(sync.Mutex)
+		m.targetLocks[target] = lock
+	}
+	m.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// current reports whether claim still holds its target, and whether an
+// earlier claim's output was written there first.
+func (m *Manifest) current(claim Claim) (current, replaced bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, replaced = m.writes[claim.Target]
+	return m.claims[claim.Target][0] == claim, replaced
 }
 
 func (m *Manifest) write(artefact Artefact) error {
+	unlock := m.lockTarget(artefact.Claim.Target)
+	defer unlock()
+	current, replaced := m.current(artefact.Claim)
+	if !current {
+		return nil
+	}
+	if replaced {
+		// the output on disk is the replaced claim's, not a cached build
+		artefact.Fingerprint = ""
+	}
+
+	if m.options.DryRun {
+		return m.dryWrite(artefact)
+	}
+
 	target := artefact.Claim.Target
 	dir := path.Dir(target)
 	if dir == "." {
 		dir = ""
 	}
 	if dir != "" {
-		if err := m.outRoot.MkdirAll(dir, 0o755); err != nil {
+		if err := fileutil.MkdirAll(m.outRoot, dir, m.options.DirMode); err != nil {
 			return m.recordError(artefact.Claim, err)
 		}
 	}
@@ -229,22 +445,138 @@ func (m *Manifest) write(artefact Artefact) error {
 		return m.recordError(artefact.Claim, err)
 	}
 
+	if artefact.Fingerprint != "" && m.cacheHit(target, artefact.Fingerprint) {
+		m.skipped(target, artefact.Fingerprint)
+		return nil
+	}
+
 	exists, err := rootFileExists(m.outRoot, target)
 	if err != nil {
 		return m.recordError(artefact.Claim, err)
 	}
 
-	_, err = fileutil.AtomicWrite(m.outRoot, target, artefact.Builder, fileutil.AtomicOptions{
+	var written int64
+	builder := func(w io.Writer) error {
+		cw := &countingWriter{w: w}
+		err := artefact.Builder(cw)
+		written = cw.n
+		return err
+	}
+	changed, err := fileutil.AtomicWrite(m.outRoot, target, builder, fileutil.AtomicOptions{
 		Sync:            m.options.SyncWrites,
 		CompareExisting: exists,
+		Mode:            m.options.FileMode,
 	})
 	if err == nil {
+		m.mu.Lock()
+		m.recordWrite(target, writeRecord{size: written, existed: exists}, changed)
+		if artefact.Fingerprint != "" {
+			m.fingerprints[target] = artefact.Fingerprint
+		}
+		m.mu.Unlock()
 		return nil
 	}
 
 	return m.recordError(artefact.Claim, err)
 }
 
+// dryWrite builds artefact without writing it, so that build errors still
+// surface, and records the change writing it would make.
+func (m *Manifest) dryWrite(artefact Artefact) error {
+	if artefact.Fingerprint != "" && m.cacheHit(artefact.Claim.Target, artefact.Fingerprint) {
+		m.skipped(artefact.Claim.Target, artefact.Fingerprint)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := artefact.Builder(&buf); err != nil {
+		return m.recordError(artefact.Claim, err)
+	}
+	exists, changed := false, true
+	if m.outRoot != nil {
+		existing, err := m.outRoot.ReadFile(artefact.Claim.Target)
+		exists = err == nil
+		changed = !exists || !bytes.Equal(existing, buf.Bytes())
+	}
+	m.mu.Lock()
+	m.recordWrite(artefact.Claim.Target, writeRecord{size: int64(buf.Len()), existed: exists}, changed)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Manifest) cacheHit(target, fingerprint string) bool {
+	m.mu.Lock()
+	prev, ok := m.cached[target]
+	m.mu.Unlock()
+	if !ok || prev != fingerprint || m.outRoot == nil {
+		return false
+	}
+	exists, err := rootFileExists(m.outRoot, target)
+	return err == nil && exists
+}
+
+func (m *Manifest) skipped(target, fingerprint string) {
+	var size int64
+	if info, err := m.outRoot.Stat(target); err == nil {
+		size = info.Size()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordWrite(target, writeRecord{size: size, existed: true, skipped: true}, false)
+	m.fingerprints[target] = fingerprint
+}
+
+// writeRecord is what writing or skipping a target added to the stats.
+type writeRecord struct {
+	size    int64
+	existed bool
+	skipped bool
+}
+
+// recordWrite adds the write of target to the stats, first taking back the
+// write of a claim that has since lost the target to this one. Only writes
+// that changed the file count as written. The caller must hold m.mu.
+func (m *Manifest) recordWrite(target string, rec writeRecord, changed bool) {
+	if prev, ok := m.writes[target]; ok {
+		if prev.skipped {
+			m.stats.Skipped--
+		} else {
+			m.stats.Written--
+			m.stats.Bytes -= prev.size
+		}
+		m.stats.Changes.forget(target)
+		rec.existed, changed = prev.existed, true
+	}
+	// a write that left the file as it was counts as skipped
+	if !changed {
+		rec.skipped = true
+	}
+	m.writes[target] = rec
+
+	if rec.skipped {
+		m.stats.Skipped++
+		m.stats.Changes.Unchanged = append(m.stats.Changes.Unchanged, target)
+		return
+	}
+	m.stats.Written++
+	m.stats.Bytes += rec.size
+	m.stats.Changes.record(target, rec.existed, changed)
+}
+
+func (m *Manifest) fingerprintSnapshot() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[string]string, len(m.fingerprints))
+	for target, fingerprint := range m.fingerprints {
+		if _, ok := m.outputs[target]; ok {
+			out[target] = fingerprint
+		}
+	}
+	return out
+}
+
 func (m *Manifest) outputSnapshot() map[string]struct{} {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -256,10 +588,16 @@ func (m *Manifest) outputSnapshot() map[string]struct{} {
 	return out
 }
 
+// cleanup removes files and directories in the output that are not in
+// wantFiles. A dry run only counts the files it would remove.
 func (m *Manifest) cleanup(wantFiles map[string]struct{}) error {
+	if m.outRoot == nil {
+		return nil
+	}
 	if wantFiles == nil {
 		wantFiles = map[string]struct{}{}
 	}
+	dryRun := m.options.DryRun
 
 	var gotFiles []string
 	var gotDirs []string
@@ -283,11 +621,25 @@ func (m *Manifest) cleanup(wantFiles map[string]struct{}) error {
 		if _, ok := wantFiles[rel]; ok {
 			continue
 		}
+		if dryRun {
+			m.mu.Lock()
+			m.stats.Removed++
+			m.stats.Changes.Deleted = append(m.stats.Changes.Deleted, rel)
+			m.mu.Unlock()
+			continue
+		}
 		if err := m.outRoot.Remove(rel); err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return fmt.Errorf("output %q: %w", filepath.Clean(filepath.Join(m.out, rel)), err)
 		}
+		m.mu.Lock()
+		m.stats.Removed++
+		m.stats.Changes.Deleted = append(m.stats.Changes.Deleted, rel)
+		m.mu.Unlock()
 	}
 
+	if dryRun {
+		return nil
+	}
 	for _, rel := range gotDirs {
 		if _, ok := wantDirs[rel]; ok {
 			continue
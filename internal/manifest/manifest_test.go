@@ -2,9 +2,13 @@ package manifest
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -66,6 +70,210 @@ func TestManifestForceReconcilesOutput(t *testing.T) {
 	}
 }
 
+func TestManifestReportsChanges(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "dist")
+	opts := options.DefaultOptions().Apply(options.WithForce(true))
+
+	run := func(files map[string]string) Changes {
+		t.Helper()
+		man := New()
+		if err := man.Start(context.Background(), manifestTestConfig(root), opts, nil, out); err != nil {
+			t.Fatal(err)
+		}
+		for target, content := range files {
+			if err := man.Emit(TextArtefact(NewInternalClaim("test", target), content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := man.Finish(true); err != nil {
+			t.Fatal(err)
+		}
+		return man.Stats().Changes
+	}
+
+	files := map[string]string{"index.html": "home", "about/index.html": "about"}
+	if changes := run(files); len(changes.Written) != 2 || len(changes.Unchanged) != 0 {
+		t.Fatalf("first build changes = %+v, want two written", changes)
+	}
+	if changes := run(files); len(changes.Written) != 0 || len(changes.Edited) != 0 || len(changes.Unchanged) != 2 {
+		t.Fatalf("unchanged rebuild changes = %+v, want nothing written or edited", changes)
+	}
+
+	changes := run(map[string]string{"index.html": "home v2", "new.html": "new"})
+	want := Changes{Written: []string{"new.html"}, Edited: []string{"index.html"}, Deleted: []string{"about/index.html"}}
+	if !slices.Equal(changes.Written, want.Written) || !slices.Equal(changes.Edited, want.Edited) ||
+		!slices.Equal(changes.Deleted, want.Deleted) || len(changes.Unchanged) != 0 {
+		t.Fatalf("changed build changes = %+v, want %+v", changes, want)
+	}
+}
+
+func TestManifestDryRunLeavesOutputAlone(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "dist")
+	opts := options.DefaultOptions().Apply(options.WithDryRun(true))
+
+	run := func() Stats {
+		t.Helper()
+		man := New()
+		if err := man.Start(context.Background(), manifestTestConfig(root), opts, nil, out); err != nil {
+			t.Fatal(err)
+		}
+		if err := man.Emit(TextArtefact(NewInternalClaim("test", "index.html"), "ok")); err != nil {
+			t.Fatal(err)
+		}
+		if err := man.Finish(true); err != nil {
+			t.Fatal(err)
+		}
+		return man.Stats()
+	}
+
+	if stats := run(); stats.Written != 1 || stats.Bytes != 2 {
+		t.Fatalf("stats = %+v, want one counted write", stats)
+	}
+	if _, err := os.Stat(out); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("output stat error = %v, want not exist", err)
+	}
+
+	staleDir := filepath.Join(out, "old", "nested")
+	if err := os.MkdirAll(staleDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(out, "stale.txt")
+	if err := os.WriteFile(stale, []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if stats := run(); stats.Written != 1 || stats.Removed != 1 {
+		t.Fatalf("stats = %+v, want one write and one removal counted", stats)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatalf("stale file removed by dry run: %v", err)
+	}
+	if _, err := os.Stat(staleDir); err != nil {
+		t.Fatalf("stale directory removed by dry run: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "index.html")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("index.html stat error = %v, want not exist", err)
+	}
+}
+
+func TestManifestArtefactCacheSkipsUnchangedArtefacts(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "dist")
+	opts := options.DefaultOptions().Apply(options.WithArtefactCache(filepath.Join(root, ".shizuka-cache")))
+
+	run := func(fingerprint string) (Stats, int) {
+		t.Helper()
+		calls := 0
+		man := New()
+		if err := man.Start(context.Background(), manifestTestConfig(root), opts, nil, out); err != nil {
+			t.Fatal(err)
+		}
+		artefact := Artefact{
+			Claim: NewInternalClaim("test", "index.html"),
+			Builder: func(w io.Writer) error {
+				calls++
+				_, err := w.Write([]byte("ok"))
+				return err
+			},
+		}
+		if err := man.Emit(artefact.Fingerprinted(fingerprint)); err != nil {
+			t.Fatal(err)
+		}
+		if err := man.Finish(true); err != nil {
+			t.Fatal(err)
+		}
+		return man.Stats(), calls
+	}
+
+	if stats, calls := run("a"); calls != 1 || stats.Written != 1 || stats.Skipped != 0 {
+		t.Fatalf("first build stats = %+v calls = %d, want one write", stats, calls)
+	}
+	if stats, calls := run("a"); calls != 0 || stats.Skipped != 1 {
+		t.Fatalf("second build stats = %+v calls = %d, want skipped builder", stats, calls)
+	}
+	if _, err := os.Stat(filepath.Join(out, "index.html")); err != nil {
+		t.Fatalf("skipped artefact removed from output: %v", err)
+	}
+	// The rebuilt artefact has the same content, so nothing is written.
+	if stats, calls := run("b"); calls != 1 || stats.Written != 0 || stats.Bytes != 0 || stats.Skipped != 1 {
+		t.Fatalf("changed build stats = %+v calls = %d, want an unchanged rebuild", stats, calls)
+	}
+}
+
+func TestManifestArtefactCacheGuardsOtherOutputs(t *testing.T) {
+	root := t.TempDir()
+	opts := options.DefaultOptions().Apply(options.WithArtefactCache(filepath.Join(root, ".shizuka-cache")))
+
+	man := New()
+	if err := man.Start(context.Background(), manifestTestConfig(root), opts, nil, filepath.Join(root, "dist")); err != nil {
+		t.Fatal(err)
+	}
+	if err := man.Emit(TextArtefact(NewInternalClaim("test", "index.html"), "ok").Fingerprinted("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := man.Finish(true); err != nil {
+		t.Fatal(err)
+	}
+
+	other := filepath.Join(root, "public")
+	if err := os.MkdirAll(other, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(other, "keep.txt"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := New().Start(context.Background(), manifestTestConfig(root), opts, nil, other)
+	if err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Fatalf("Start() error = %v, want non-empty output refusal", err)
+	}
+}
+
+func TestManifestResolvesConflictsByOwnerPriority(t *testing.T) {
+	root := t.TempDir()
+	priority := []string{"pages:build", "static"}
+
+	run := func(name string, claims ...Claim) (string, Stats, error) {
+		t.Helper()
+		out := filepath.Join(root, name)
+		opts := options.DefaultOptions().Apply(options.WithConflictPriority(priority))
+		man := New()
+		if err := man.Start(context.Background(), manifestTestConfig(root), opts, nil, out); err != nil {
+			t.Fatal(err)
+		}
+		for _, claim := range claims {
+			if err := man.Emit(TextArtefact(claim, claim.Owner)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := man.Finish(true)
+		got, _ := os.ReadFile(filepath.Join(out, "index.html"))
+		return string(got), man.Stats(), err
+	}
+
+	page := Claim{Owner: "pages:build", Source: "content/index.md", Target: "index.html"}
+	static := Claim{Owner: "static", Source: "static/index.html", Target: "index.html"}
+	other := Claim{Owner: "other", Target: "index.html"}
+	another := Claim{Owner: "another", Target: "index.html"}
+
+	for i, claims := range [][]Claim{{static, page}, {page, static}, {other, page, static}} {
+		got, stats, err := run(fmt.Sprintf("dist-%d", i), claims...)
+		if err != nil {
+			t.Fatalf("claims %d: Finish() error = %v", i, err)
+		}
+		if got != "pages:build" {
+			t.Fatalf("claims %d: index.html = %q, want the pages:build artefact", i, got)
+		}
+		if stats.Written != 1 || !slices.Equal(stats.Changes.Written, []string{"index.html"}) {
+			t.Fatalf("claims %d: stats = %+v, want one write", i, stats)
+		}
+	}
+
+	if _, _, err := run("dist-unlisted", other, another); !errors.Is(err, ErrConflicts) {
+		t.Fatalf("unlisted owners error = %v, want ErrConflicts", err)
+	}
+}
+
 func manifestTestConfig(root string) *config.Config {
 	return &config.Config{
 		Root: root,
@@ -78,3 +286,104 @@ func manifestTestConfig(root string) *config.Config {
 		},
 	}
 }
+
+func TestManifestWritesReport(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "dist")
+	reportPath := filepath.Join(root, "meta", "build-report.json")
+	opts := options.DefaultOptions().Apply(options.WithForce(true), options.WithReport(reportPath))
+
+	run := func(files map[string]string) Report {
+		t.Helper()
+		man := New()
+		if err := man.Start(context.Background(), manifestTestConfig(root), opts, nil, out); err != nil {
+			t.Fatal(err)
+		}
+		for target, text := range files {
+			claim := Claim{Owner: "test", Source: "src/" + target, Target: target}
+			if err := man.Emit(TextArtefact(claim, text)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := man.Finish(true); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(reportPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report Report
+		if err := json.Unmarshal(data, &report); err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	run(map[string]string{"index.html": "home", "old.html": "old"})
+	report := run(map[string]string{"index.html": "home", "about/index.html": "about!"})
+
+	want := []ReportArtefact{
+		{Target: "about/index.html", Owner: "test", Source: "src/about/index.html", Size: 6},
+		{Target: "index.html", Owner: "test", Source: "src/index.html", Size: 4},
+	}
+	if !slices.Equal(report.Artefacts, want) {
+		t.Fatalf("artefacts = %#v, want %#v", report.Artefacts, want)
+	}
+	if !slices.Equal(report.Removed, []string{"old.html"}) {
+		t.Fatalf("removed = %v, want [old.html]", report.Removed)
+	}
+
+	// A later build would remove a report inside the output as a stray file.
+	inside := options.DefaultOptions().Apply(options.WithReport(filepath.Join(out, "meta", "build-report.json")))
+	err := New().Start(context.Background(), manifestTestConfig(root), inside, nil, out)
+	if err == nil || !strings.Contains(err.Error(), "must be outside output path") {
+		t.Fatalf("Start() error = %v, want a report inside the output refused", err)
+	}
+}
+
+func TestManifestWritesWithConfiguredModes(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "build", "dist")
+	opts := options.DefaultOptions().Apply(
+		options.WithForce(true),
+		options.WithFileMode(0o664),
+		options.WithDirMode(0o775),
+	)
+
+	man := New()
+	if err := man.Start(context.Background(), manifestTestConfig(root), opts, nil, out); err != nil {
+		t.Fatal(err)
+	}
+	if err := man.Emit(TextArtefact(NewInternalClaim("test", "docs/index.html"), "docs")); err != nil {
+		t.Fatal(err)
+	}
+	if err := man.Finish(true); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]os.FileMode{
+		filepath.Join(root, "build"):             0o775,
+		out:                                      0o775,
+		filepath.Join(out, "docs"):               0o775,
+		filepath.Join(out, "docs", "index.html"): 0o664,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s mode = %o, want %o", path, got, want)
+		}
+	}
+}
+
+func TestWriteWorkersDefaultsToStepWorkers(t *testing.T) {
+	opts := options.DefaultOptions().Apply(options.WithMaxWorkers(8))
+	if got := writeWorkers(opts); got != 8 {
+		t.Fatalf("writeWorkers = %d, want the 8 step workers", got)
+	}
+	if got := writeWorkers(opts.Apply(options.WithWriteWorkers(2))); got != 2 {
+		t.Fatalf("writeWorkers = %d, want 2", got)
+	}
+}
@@ -0,0 +1,86 @@
+package manifest
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+const reportVersion = 1
+
+// Report describes the output of a successful build: every file in it, and
+// the stale files the build removed.
+type Report struct {
+	Version   int              `json:"version"`
+	Artefacts []ReportArtefact `json:"artefacts"`
+	Removed   []string         `json:"removed"`
+}
+
+// ReportArtefact is one output file, by target path relative to the output
+// directory, with the claim that produced it.
+type ReportArtefact struct {
+	Target string `json:"target"`
+	Owner  string `json:"owner,omitempty"`
+	Source string `json:"source,omitempty"`
+	Size   int64  `json:"size"`
+}
+
+// validateReportPath refuses a report inside out, where the next build would
+// remove it as a stray file.
+func validateReportPath(out, report string) error {
+	if report == "" {
+		return nil
+	}
+	outAbs, err := filepath.Abs(out)
+	if err != nil {
+		return fmt.Errorf("output path %q: %w", out, err)
+	}
+	reportAbs, err := filepath.Abs(report)
+	if err != nil {
+		return fmt.Errorf("report path %q: %w", report, err)
+	}
+	if pathsIntersect(outAbs, reportAbs) {
+		return fmt.Errorf("report path %q must be outside output path %q", report, out)
+	}
+	return nil
+}
+
+func (m *Manifest) buildReport() Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := Report{
+		Version:   reportVersion,
+		Artefacts: make([]ReportArtefact, 0, len(m.outputs)),
+		Removed:   slices.Sorted(slices.Values(m.stats.Changes.Deleted)),
+	}
+	for target := range m.outputs {
+		claim := m.claims[target][0]
+		report.Artefacts = append(report.Artefacts, ReportArtefact{
+			Target: target,
+			Owner:  claim.Owner,
+			Source: claim.Source,
+			Size:   m.writes[target].size,
+		})
+	}
+	slices.SortFunc(report.Artefacts, func(a, b ReportArtefact) int {
+		return cmp.Compare(a.Target, b.Target)
+	})
+	if report.Removed == nil {
+		report.Removed = []string{}
+	}
+	return report
+}
+
+func saveReport(path string, report Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("build report %q: %w", path, err)
+	}
+	return nil
+}
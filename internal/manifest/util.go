@@ -8,6 +8,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/olimci/shizuka/internal/config"
@@ -68,10 +69,9 @@ func manifestSourcePaths(cfg *config.Config) ([]string, error) {
 		return nil, fmt.Errorf("config root %q: %w", cfg.Root, err)
 	}
 
-	paths := []string{
-		filepath.Join(rootAbs, filepath.FromSlash(cfg.Paths.Static)),
-		filepath.Join(rootAbs, filepath.FromSlash(cfg.Paths.Content)),
-		filepath.Join(rootAbs, filepath.FromSlash(cfg.Paths.Templates)),
+	var paths []string
+	for _, p := range append([]string{cfg.Paths.Static, cfg.Paths.Templates}, cfg.ContentPaths()...) {
+		paths = append(paths, filepath.Join(rootAbs, filepath.FromSlash(p)))
 	}
 	for i, p := range paths {
 		abs, err := filepath.Abs(p)
@@ -93,6 +93,49 @@ func pathsIntersect(a, b string) bool {
 	return err == nil && (rel == "." || !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && rel != "..")
 }
 
+// writeWorkers is the number of artefacts written at once, which defaults to
+// the step workers. A deterministic build writes one at a time.
+func writeWorkers(opts *options.Options) int {
+	switch {
+	case opts.Deterministic:
+		return 1
+	case opts.WriteWorkers > 0:
+		return opts.WriteWorkers
+	default:
+		return opts.MaxWorkers
+	}
+}
+
+// mkdirOutput creates the output directory and any missing parents, giving
+// those it creates exactly perm, regardless of the umask. Directories that
+// already exist keep their mode.
+func mkdirOutput(out string, perm fs.FileMode) error {
+	var missing []string
+	for p := filepath.Clean(out); ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		missing = append(missing, p)
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(out, perm); err != nil {
+		return err
+	}
+	for _, p := range missing {
+		if err := os.Chmod(p, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func ensureRootDir(root *os.Root, dir string) error {
 	if dir == "" {
 		dir = "."
@@ -138,11 +181,34 @@ func rootEmpty(root *os.Root) (bool, error) {
 func conflictError(target string, claims []Claim) error {
 	owners := make([]string, 0, len(claims))
 	for _, claim := range claims {
-		owners = append(owners, claim.DisplayOwner())
+		owner := claim.DisplayOwner()
+		if claim.Owner != "" && claim.Source != "" {
+			owner = fmt.Sprintf("%s (%s)", claim.Owner, claim.Source)
+		}
+		owners = append(owners, owner)
 	}
 	return fmt.Errorf("%w for %q: claimed by %s", ErrConflicts, target, strings.Join(owners, ", "))
 }
 
+// conflictWinner returns the index of the claim to keep among the claims on
+// one target: the one whose owner comes first in priority. It reports false
+// when no listed owner outranks all the others, which includes every claim
+// being unlisted.
+func conflictWinner(priority []string, claims []Claim) (int, bool) {
+	best, bestRank, tie := -1, len(priority), false
+	for i, claim := range claims {
+		rank := slices.Index(priority, claim.Owner)
+		switch {
+		case rank < 0:
+		case rank < bestRank:
+			best, bestRank, tie = i, rank, false
+		case rank == bestRank:
+			tie = true
+		}
+	}
+	return best, best >= 0 && !tie
+}
+
 // manifestDirs creates a set of directories needed for output files.
 func manifestDirs(m map[string]struct{}) map[string]struct{} {
 	out := make(map[string]struct{})
@@ -163,3 +229,14 @@ func manifestDirs(m map[string]struct{}) map[string]struct{} {
 
 	return out
 }
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
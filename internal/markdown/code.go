@@ -0,0 +1,124 @@
+package markdown
+
+import "strings"
+
+// CodeRange is the byte range [Start, End) of a code block or span in a
+// markdown source.
+type CodeRange struct {
+	Start, End int
+}
+
+// CodeRanges returns, in order, the fenced code blocks and inline code spans
+// of src, whose contents markdown leaves literal. A fence left open runs to
+// the end of src, as CommonMark has it.
+func CodeRanges(src string) []CodeRange {
+	var (
+		ranges    []CodeRange
+		fence     string
+		fenceFrom int
+		textFrom  int
+	)
+	for off := 0; off < len(src); {
+		end := strings.IndexByte(src[off:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += off + 1
+		}
+		line := src[off:end]
+
+		switch {
+		case fence != "":
+			if closesFence(line, fence) {
+				ranges = append(ranges, CodeRange{fenceFrom, end})
+				fence, textFrom = "", end
+			}
+		default:
+			if open := openingFence(line); open != "" {
+				ranges = append(ranges, codeSpans(src, textFrom, off)...)
+				fence, fenceFrom = open, off
+			}
+		}
+		off = end
+	}
+	if fence != "" {
+		return append(ranges, CodeRange{fenceFrom, len(src)})
+	}
+	return append(ranges, codeSpans(src, textFrom, len(src))...)
+}
+
+// InCode reports whether offset falls inside one of ranges.
+func InCode(ranges []CodeRange, offset int) bool {
+	for _, r := range ranges {
+		if offset < r.Start {
+			return false
+		}
+		if offset < r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// openingFence returns the backtick or tilde run that opens a fenced code
+// block on line, or "" when line opens none.
+func openingFence(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || trimmed == "" || trimmed[0] != '`' && trimmed[0] != '~' {
+		return ""
+	}
+	n := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+	if n < 3 {
+		return ""
+	}
+	// A backtick fence's info string may not contain backticks.
+	if trimmed[0] == '`' && strings.Contains(trimmed[n:], "`") {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// closesFence reports whether line closes the block opened by fence: a run of
+// at least as many of its character, with nothing but spaces after.
+func closesFence(line, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	rest := strings.TrimLeft(trimmed, fence[:1])
+	return len(trimmed)-len(rest) >= len(fence) && strings.TrimSpace(rest) == ""
+}
+
+// codeSpans returns the inline code spans in src[from:to]: a run of backticks
+// up to the next run of the same length.
+func codeSpans(src string, from, to int) []CodeRange {
+	var ranges []CodeRange
+	text := src[:to]
+	for i := from; i < to; {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		n := len(text[i:]) - len(strings.TrimLeft(text[i:], "`"))
+		closing := -1
+		for j := i + n; j < to; {
+			if text[j] != '`' {
+				j++
+				continue
+			}
+			m := len(text[j:]) - len(strings.TrimLeft(text[j:], "`"))
+			if m == n {
+				closing = j
+				break
+			}
+			j += m
+		}
+		if closing < 0 {
+			i += n
+			continue
+		}
+		ranges = append(ranges, CodeRange{i, closing + n})
+		i = closing + n
+	}
+	return ranges
+}
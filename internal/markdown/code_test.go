@@ -0,0 +1,29 @@
+package markdown
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCodeRanges(t *testing.T) {
+	src := "a `x` b\n\n```go\ncode\n```\n\n~~~~\nmore\n~~~\n~~~~\n``y``z\n"
+	var got []string
+	for _, r := range CodeRanges(src) {
+		got = append(got, src[r.Start:r.End])
+	}
+	want := []string{"`x`", "```go\ncode\n```\n", "~~~~\nmore\n~~~\n~~~~\n", "``y``"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ranges = %q, want %q", got, want)
+	}
+}
+
+func TestCodeRangesRunsOpenFenceToEnd(t *testing.T) {
+	src := "text\n```\nopen"
+	ranges := CodeRanges(src)
+	if len(ranges) != 1 || src[ranges[0].Start:ranges[0].End] != "```\nopen" {
+		t.Fatalf("ranges = %v, want the open fence to the end", ranges)
+	}
+	if InCode(ranges, 0) || !InCode(ranges, len(src)-1) {
+		t.Fatal("InCode disagrees with the ranges")
+	}
+}
@@ -0,0 +1,47 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/olimci/shizuka/internal/config"
+)
+
+// DefaultHighlightStyle is the style goldmark-highlighting uses when none is
+// configured.
+const DefaultHighlightStyle = "github"
+
+var ErrUnknownHighlightStyle = errors.New("unknown highlighting style")
+
+// HighlightStyle returns the Chroma style cfg names. An unknown name returns
+// Chroma's fallback style, which code is highlighted with, and
+// ErrUnknownHighlightStyle.
+func HighlightStyle(cfg *config.ConfigMarkdownHighlighting) (*chroma.Style, error) {
+	name := cfg.Style
+	if name == "" {
+		name = DefaultHighlightStyle
+	}
+	if style, ok := styles.Registry[strings.ToLower(name)]; ok {
+		return style, nil
+	}
+	return styles.Fallback, fmt.Errorf("%w %q, using %q", ErrUnknownHighlightStyle, name, styles.Fallback.Name)
+}
+
+// HighlightCSS returns the stylesheet for code highlighted with classes.
+func HighlightCSS(cfg *config.ConfigMarkdownHighlighting) (string, error) {
+	style, _ := HighlightStyle(cfg)
+	formatter := chromahtml.New(
+		chromahtml.WithClasses(true),
+		chromahtml.WithLineNumbers(cfg.LineNumbers),
+	)
+
+	var b strings.Builder
+	if err := formatter.WriteCSS(&b, style); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
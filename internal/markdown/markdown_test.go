@@ -1,6 +1,7 @@
 package markdown
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -45,3 +46,25 @@ func TestHighlightingCanEmitClasses(t *testing.T) {
 		t.Fatalf("class-based highlighting included inline color styles:\n%s", doc.Body)
 	}
 }
+
+func TestHighlightStyleReportsUnknownNames(t *testing.T) {
+	if style, err := HighlightStyle(&config.ConfigMarkdownHighlighting{Style: "Monokai"}); err != nil || style.Name != "monokai" {
+		t.Fatalf("HighlightStyle(Monokai) = %v, %v", style.Name, err)
+	}
+	if style, err := HighlightStyle(&config.ConfigMarkdownHighlighting{}); err != nil || style.Name != DefaultHighlightStyle {
+		t.Fatalf("HighlightStyle() = %v, %v, want the default", style.Name, err)
+	}
+	if _, err := HighlightStyle(&config.ConfigMarkdownHighlighting{Style: "no-such-style"}); !errors.Is(err, ErrUnknownHighlightStyle) {
+		t.Fatalf("HighlightStyle(no-such-style) error = %v", err)
+	}
+}
+
+func TestHighlightCSSStylesChromaClasses(t *testing.T) {
+	css, err := HighlightCSS(&config.ConfigMarkdownHighlighting{Style: "monokai", Classes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(css, ".chroma") || !strings.Contains(css, "color:") {
+		t.Fatalf("stylesheet does not style chroma classes:\n%s", css)
+	}
+}
@@ -5,42 +5,165 @@ import (
 	"html/template"
 	"strings"
 
+	"github.com/olimci/shizuka/internal/config"
 	gm "github.com/yuin/goldmark"
 	gmast "github.com/yuin/goldmark/ast"
+	gmparse "github.com/yuin/goldmark/parser"
 	gmtext "github.com/yuin/goldmark/text"
 )
 
+// SummaryDivider is the default marker for the end of a page summary in raw
+// markdown.
+const SummaryDivider = config.DefaultSummaryDivider
+
 type Document struct {
 	Body     template.HTML
+	Summary  template.HTML
 	Sections []template.HTML
 	ToC      []ToCEntry
 }
 
+type RenderOptions struct {
+	// SummaryParagraphs is the number of leading paragraphs used as the summary
+	// when the body has no summary divider.
+	SummaryParagraphs int
+	// SummaryDivider marks the end of the summary. Empty uses SummaryDivider.
+	SummaryDivider string
+	// Placeholders maps text the caller replaces in the rendered HTML to the
+	// text it stands for, which heading IDs and ToC entries use instead.
+	Placeholders map[string]string
+}
+
 type ToCEntry struct {
 	Level int
 	ID    string
 	Text  string
+
+	// Children holds the headings nested under this one. See NestToC.
+	Children []ToCEntry
+}
+
+// NestToC keeps the entries between minLevel and maxLevel and nests each
+// under the nearest preceding entry of a lower level.
+func NestToC(entries []ToCEntry, minLevel, maxLevel int) []ToCEntry {
+	kept := make([]ToCEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Level >= minLevel && entry.Level <= maxLevel {
+			entry.Children = nil
+			kept = append(kept, entry)
+		}
+	}
+	return nestToC(kept)
+}
+
+func nestToC(entries []ToCEntry) []ToCEntry {
+	var out []ToCEntry
+	for i := 0; i < len(entries); {
+		entry := entries[i]
+		end := i + 1
+		for end < len(entries) && entries[end].Level > entry.Level {
+			end++
+		}
+		entry.Children = nestToC(entries[i+1 : end])
+		out = append(out, entry)
+		i = end
+	}
+	return out
 }
 
 func Render(md gm.Markdown, sourcePath, rawBody string) (Document, error) {
+	return RenderWithOptions(md, sourcePath, rawBody, RenderOptions{SummaryParagraphs: 1})
+}
+
+func RenderWithOptions(md gm.Markdown, sourcePath, rawBody string, opts RenderOptions) (Document, error) {
+	summaryRaw, rawBody, hasDivider := SplitSummary(rawBody, opts.SummaryDivider)
+
+	var placeholders *strings.Replacer
+	if len(opts.Placeholders) > 0 {
+		pairs := make([]string, 0, 2*len(opts.Placeholders))
+		for placeholder, text := range opts.Placeholders {
+			pairs = append(pairs, placeholder, text)
+		}
+		placeholders = strings.NewReplacer(pairs...)
+	}
+	parse := func(source []byte) gmast.Node {
+		if placeholders == nil {
+			return md.Parser().Parse(gmtext.NewReader(source))
+		}
+		ids := placeholderIDs{IDs: gmparse.NewContext().IDs(), placeholders: placeholders}
+		return md.Parser().Parse(gmtext.NewReader(source), gmparse.WithContext(gmparse.NewContext(gmparse.WithIDs(ids))))
+	}
+
 	source := []byte(rawBody)
-	doc := md.Parser().Parse(gmtext.NewReader(source))
+	doc := parse(source)
 	toc := collectToC(source, doc)
+	if placeholders != nil {
+		for i := range toc {
+			toc[i].Text = placeholders.Replace(toc[i].Text)
+		}
+	}
+
+	var (
+		summary template.HTML
+		err     error
+	)
+	if hasDivider {
+		summarySource := []byte(summaryRaw)
+		summary, err = renderNode(md, sourcePath, summarySource, parse(summarySource))
+	} else {
+		summary, err = renderParagraphs(md, sourcePath, source, doc, opts.SummaryParagraphs)
+	}
+	if err != nil {
+		return Document{}, err
+	}
+
 	body, err := renderNode(md, sourcePath, source, doc)
 	if err != nil {
 		return Document{}, err
 	}
-	sections, err := renderSections(md, sourcePath, source)
+	sections, err := renderSections(md, sourcePath, source, parse)
 	if err != nil {
 		return Document{}, err
 	}
 	return Document{
 		Body:     body,
+		Summary:  summary,
 		Sections: sections,
 		ToC:      toc,
 	}, nil
 }
 
+// SplitSummary splits raw markdown at the first occurrence of divider, or of
+// SummaryDivider when divider is empty, outside code blocks and spans. The
+// returned body is the full document with the divider removed.
+func SplitSummary(rawBody, divider string) (summary, body string, ok bool) {
+	if divider == "" {
+		divider = SummaryDivider
+	}
+	code := CodeRanges(rawBody)
+	for from := 0; ; {
+		i := strings.Index(rawBody[from:], divider)
+		if i < 0 {
+			return "", rawBody, false
+		}
+		i += from
+		if !InCode(code, i) {
+			return rawBody[:i], rawBody[:i] + rawBody[i+len(divider):], true
+		}
+		from = i + len(divider)
+	}
+}
+
+// placeholderIDs generates heading IDs from the text placeholders stand for.
+type placeholderIDs struct {
+	gmparse.IDs
+	placeholders *strings.Replacer
+}
+
+func (ids placeholderIDs) Generate(value []byte, kind gmast.NodeKind) []byte {
+	return ids.IDs.Generate([]byte(ids.placeholders.Replace(string(value))), kind)
+}
+
 func renderNode(md gm.Markdown, sourcePath string, source []byte, node gmast.Node) (template.HTML, error) {
 	var buf strings.Builder
 	if err := md.Renderer().Render(&buf, source, node); err != nil {
@@ -49,6 +172,21 @@ func renderNode(md gm.Markdown, sourcePath string, source []byte, node gmast.Nod
 	return template.HTML(buf.String()), nil
 }
 
+func renderParagraphs(md gm.Markdown, sourcePath string, source []byte, doc gmast.Node, limit int) (template.HTML, error) {
+	var buf strings.Builder
+	count := 0
+	for node := doc.FirstChild(); node != nil && count < limit; node = node.NextSibling() {
+		if node.Kind() != gmast.KindParagraph {
+			continue
+		}
+		if err := md.Renderer().Render(&buf, source, node); err != nil {
+			return "", fmt.Errorf("render markdown %q: %w", sourcePath, err)
+		}
+		count++
+	}
+	return template.HTML(buf.String()), nil
+}
+
 func collectToC(source []byte, doc gmast.Node) []ToCEntry {
 	var toc []ToCEntry
 	if err := gmast.Walk(doc, func(node gmast.Node, entering bool) (gmast.WalkStatus, error) {
@@ -72,8 +210,8 @@ func collectToC(source []byte, doc gmast.Node) []ToCEntry {
 	return toc
 }
 
-func renderSections(md gm.Markdown, sourcePath string, source []byte) ([]template.HTML, error) {
-	doc := md.Parser().Parse(gmtext.NewReader(source))
+func renderSections(md gm.Markdown, sourcePath string, source []byte, parse func([]byte) gmast.Node) ([]template.HTML, error) {
+	doc := parse(source)
 	var sections []template.HTML
 	section := gmast.NewDocument()
 	for node := doc.FirstChild(); node != nil; {
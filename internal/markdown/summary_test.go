@@ -0,0 +1,85 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+func TestRenderSplitsSummaryAtDivider(t *testing.T) {
+	md := Build(config.ConfigContentMarkdown{}, Options{})
+
+	doc, err := Render(md, "test.md", "First.\n\nSecond.\n\n<!--more-->\n\nThird.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(doc.Summary); got != "<p>First.</p>\n<p>Second.</p>\n" {
+		t.Fatalf("summary = %q, want content before divider", got)
+	}
+	if strings.Contains(string(doc.Body), "more") {
+		t.Fatalf("body still contains divider:\n%s", doc.Body)
+	}
+	if !strings.Contains(string(doc.Body), "<p>Third.</p>") {
+		t.Fatalf("body missing content after divider:\n%s", doc.Body)
+	}
+}
+
+func TestRenderSummaryFallsBackToLeadingParagraphs(t *testing.T) {
+	md := Build(config.ConfigContentMarkdown{}, Options{})
+
+	doc, err := RenderWithOptions(md, "test.md", "# Title\n\nFirst.\n\nSecond.\n\nThird.", RenderOptions{SummaryParagraphs: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(doc.Summary); got != "<p>First.</p>\n<p>Second.</p>\n" {
+		t.Fatalf("summary = %q, want first two paragraphs", got)
+	}
+}
+
+func TestRenderSplitsSummaryAtCustomDivider(t *testing.T) {
+	md := Build(config.ConfigContentMarkdown{}, Options{})
+	opts := RenderOptions{SummaryParagraphs: 1, SummaryDivider: "<!-- excerpt -->"}
+
+	doc, err := RenderWithOptions(md, "test.md", "First.\n\nSecond.\n\n<!-- excerpt -->\n\nThird.", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(doc.Summary); got != "<p>First.</p>\n<p>Second.</p>\n" {
+		t.Fatalf("summary = %q, want content before custom divider", got)
+	}
+	if strings.Contains(string(doc.Body), "excerpt") || !strings.Contains(string(doc.Body), "<p>Third.</p>") {
+		t.Fatalf("body = %q, want full content without divider", doc.Body)
+	}
+
+	doc, err = RenderWithOptions(md, "test.md", "First.\n\n<!--more-->\n\nSecond.", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(doc.Summary); got != "<p>First.</p>\n" {
+		t.Fatalf("summary = %q, want leading paragraph when custom divider is absent", got)
+	}
+	if !strings.Contains(string(doc.Body), "<p>Second.</p>") {
+		t.Fatalf("body = %q, want full content", doc.Body)
+	}
+}
+
+func TestSplitSummarySkipsDividersInCode(t *testing.T) {
+	raw := "Intro with `<!--more-->`.\n\n```html\n<!--more-->\n```\n\nAfter code.\n\n<!--more-->\n\nRest."
+	summary, body, ok := SplitSummary(raw, "")
+	if !ok {
+		t.Fatal("divider after the code block was not found")
+	}
+	if !strings.HasSuffix(summary, "After code.\n\n") {
+		t.Fatalf("summary = %q, want everything before the last divider", summary)
+	}
+	if strings.Count(body, "<!--more-->") != 2 {
+		t.Fatalf("body = %q, want the dividers in code kept", body)
+	}
+
+	if _, _, ok := SplitSummary("```\n<!--more-->\n```\n", ""); ok {
+		t.Fatal("divider inside a fenced block split the summary")
+	}
+}
@@ -1,6 +1,7 @@
 package markdown
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -42,3 +43,31 @@ func assertToCEntry(t *testing.T, entry ToCEntry, level int, id, text string) {
 		t.Fatalf("unexpected ToC entry: got %#v, want level=%d id=%q text=%q", entry, level, id, text)
 	}
 }
+
+func TestNestToC(t *testing.T) {
+	entries := []ToCEntry{
+		{Level: 1, ID: "title"},
+		{Level: 2, ID: "a"},
+		{Level: 3, ID: "a1"},
+		{Level: 5, ID: "deep"},
+		{Level: 4, ID: "a1x"},
+		{Level: 2, ID: "b"},
+		{Level: 4, ID: "b1"},
+		{Level: 3, ID: "b2"},
+	}
+
+	got := NestToC(entries, 2, 4)
+
+	want := []ToCEntry{
+		{Level: 2, ID: "a", Children: []ToCEntry{
+			{Level: 3, ID: "a1", Children: []ToCEntry{{Level: 4, ID: "a1x"}}},
+		}},
+		{Level: 2, ID: "b", Children: []ToCEntry{
+			{Level: 4, ID: "b1"},
+			{Level: 3, ID: "b2"},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("NestToC() = %#v, want %#v", got, want)
+	}
+}
@@ -2,10 +2,14 @@ package options
 
 import (
 	"context"
+	"html/template"
+	"io/fs"
 	"log/slog"
+	"maps"
 	"path/filepath"
 	"runtime"
 	"slices"
+	"time"
 
 	"github.com/olimci/shizuka/internal/registry"
 	"github.com/olimci/shizuka/internal/utils/urlutil"
@@ -91,6 +95,30 @@ func WithMaxWorkers(workers int) Option {
 	}
 }
 
+// WithWriteWorkers limits how many output files are written at once,
+// separately from the workers that run the build steps. Rendering is CPU
+// bound and wants every core, but on a slow disk or network mount a lower
+// write limit avoids queueing more I/O than the disk can serve. Zero or less
+// writes with MaxWorkers.
+func WithWriteWorkers(workers int) Option {
+	return func(o *Options) {
+		o.WriteWorkers = workers
+	}
+}
+
+// WithDeterministic makes byte-identical inputs give byte-identical output
+// and logs, for content-addressed deploys and diffing builds in CI. Steps
+// and output writes run one at a time in a fixed order, and build errors are
+// sorted, so a deterministic build is slower on large sites. Pages still
+// render in parallel, into their fixed places. Dates written into the
+// output come from SOURCE_DATE_EPOCH, or the Unix epoch when it is unset,
+// instead of the clock or file times; scheduling still follows the clock.
+func WithDeterministic(deterministic bool) Option {
+	return func(o *Options) {
+		o.Deterministic = deterministic
+	}
+}
+
 func WithDev(dev bool) Option {
 	return func(o *Options) {
 		o.Dev = dev
@@ -103,12 +131,73 @@ func WithForce(force bool) Option {
 	}
 }
 
+// WithStrict fails the build on warnings, such as oversized images and
+// orphaned static files, as well as on errors.
+func WithStrict(strict bool) Option {
+	return func(o *Options) {
+		o.Strict = strict
+	}
+}
+
+// WithIncludeDrafts builds draft pages even outside dev mode.
+func WithIncludeDrafts(include bool) Option {
+	return func(o *Options) {
+		o.IncludeDrafts = include
+	}
+}
+
+// WithDryRun builds the site without touching the output directory,
+// counting the files a real build would write and remove. A dry run may
+// target an output directory that is not empty.
+func WithDryRun(dryRun bool) Option {
+	return func(o *Options) {
+		o.DryRun = dryRun
+	}
+}
+
+// WithCheck runs the build as a content check: a strict dry run with
+// extra lint passes for problems a build lets through, such as pages
+// without a title and broken internal links.
+func WithCheck(check bool) Option {
+	return func(o *Options) {
+		o.Check = check
+		if check {
+			o.DryRun = true
+			o.Strict = true
+		}
+	}
+}
+
+// WithStepTimeout bounds how long each build step, including the work it
+// hands to the worker pool, may run. Zero means no limit.
+func WithStepTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.StepTimeout = d
+	}
+}
+
 func WithSyncWrites(sync bool) Option {
 	return func(o *Options) {
 		o.SyncWrites = sync
 	}
 }
 
+// WithFileMode sets the permissions of written output files, regardless of
+// the umask.
+func WithFileMode(mode fs.FileMode) Option {
+	return func(o *Options) {
+		o.FileMode = mode.Perm()
+	}
+}
+
+// WithDirMode sets the permissions of output directories the build creates,
+// regardless of the umask.
+func WithDirMode(mode fs.FileMode) Option {
+	return func(o *Options) {
+		o.DirMode = mode.Perm()
+	}
+}
+
 func WithCache(cache *registry.Registry) Option {
 	return func(o *Options) {
 		if o.cacheInternal {
@@ -119,6 +208,64 @@ func WithCache(cache *registry.Registry) Option {
 	}
 }
 
+// WithMaxImageSize enables the oversized image warning with a limit of n
+// bytes, overriding build.image_size in the config.
+func WithMaxImageSize(n int64) Option {
+	return func(o *Options) {
+		o.MaxImageSize = n
+	}
+}
+
+// WithArtefactCache persists artefact fingerprints to path so that later
+// builds can skip artefacts whose inputs are unchanged.
+func WithArtefactCache(path string) Option {
+	return func(o *Options) {
+		if path == "" {
+			o.ArtefactCachePath = ""
+			return
+		}
+		o.ArtefactCachePath = filepath.Clean(path)
+	}
+}
+
+// WithReport writes a JSON report of every output file, with its owner,
+// source and size, and of the files the build removed, to path after a
+// successful build. The path must be outside the output directory, which
+// later builds clean. A dry run writes no report.
+func WithReport(path string) Option {
+	return func(o *Options) {
+		if path == "" {
+			o.ReportPath = ""
+			return
+		}
+		o.ReportPath = filepath.Clean(path)
+	}
+}
+
+// WithConflictPriority lets the manifest settle two claims on one output
+// file, such as a static file and a page both writing index.html, by keeping
+// the claim whose owner comes first in owners and warning about the rest.
+// Listed owners win over unlisted ones; a conflict among unlisted owners is
+// still an error.
+func WithConflictPriority(owners []string) Option {
+	return func(o *Options) {
+		o.ConflictPriority = slices.Clone(owners)
+	}
+}
+
+// WithTemplateFuncs makes funcs available to page, shortcode and markdown
+// component templates, replacing any built-in of the same name. Repeated
+// calls add to the map. Steps render pages in parallel, so the functions
+// must be safe for concurrent use.
+func WithTemplateFuncs(funcs template.FuncMap) Option {
+	return func(o *Options) {
+		if o.TemplateFuncs == nil {
+			o.TemplateFuncs = make(template.FuncMap, len(funcs))
+		}
+		maps.Copy(o.TemplateFuncs, funcs)
+	}
+}
+
 func WithChanges(paths []string) Option {
 	return func(o *Options) {
 		if o.changesInternal {
@@ -188,6 +335,8 @@ func DefaultOptions() *Options {
 		ConfigPath: "shizuka.jsonc",
 		MaxWorkers: runtime.NumCPU(),
 		SyncWrites: true, // should this be true?
+		FileMode:   0o644,
+		DirMode:    0o755,
 		Dev:        false,
 	}
 }
@@ -199,21 +348,50 @@ type Options struct {
 	Logger  *slog.Logger
 
 	// Config overrides
-	ConfigPath string
-	OutputPath string
-	SiteURL    string
+	ConfigPath   string
+	OutputPath   string
+	SiteURL      string
+	MaxImageSize int64
 
 	// Dev-mode stuff
 	Dev bool
 
 	// Runtime options
-	MaxWorkers int
-	SyncWrites bool
-	Force      bool
+	MaxWorkers   int
+	WriteWorkers int
+	SyncWrites   bool
+	FileMode     fs.FileMode
+	DirMode      fs.FileMode
+	Force        bool
+	Strict       bool
+	StepTimeout  time.Duration
+	DryRun       bool
+
+	// Deterministic runs steps and writes serially, in a fixed order, with
+	// errors sorted.
+	Deterministic bool
+
+	// Check adds the lint passes of WithCheck to the build.
+	Check bool
+
+	// IncludeDrafts builds draft pages outside dev mode, where they are
+	// otherwise left out.
+	IncludeDrafts bool
+
+	// ReportPath is where a JSON report of the build output is written.
+	ReportPath string
+
+	// ConflictPriority lists artefact owners, highest first, that settle
+	// two claims on one output file.
+	ConflictPriority []string
+
+	// TemplateFuncs are added to the built-in template functions.
+	TemplateFuncs template.FuncMap
 
 	// Cache Options
-	CacheRegistry *registry.Registry
-	ChangedPaths  []string
+	CacheRegistry     *registry.Registry
+	ChangedPaths      []string
+	ArtefactCachePath string
 
 	// Internal options
 	OutputPathInternal bool
@@ -27,6 +27,15 @@ type Lock struct {
 	cell     *cell
 }
 
+// Key returns the name of the locked key.
+func (l Lock) Key() string { return l.key }
+
+// Writes reports whether the lock grants write access.
+func (l Lock) Writes() bool { return l.write }
+
+// Optional reports whether the key may be missing.
+func (l Lock) Optional() bool { return l.optional }
+
 type Guard struct {
 	locks []Lock
 	s     *Scoped
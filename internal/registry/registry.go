@@ -23,6 +23,11 @@ type Registry struct {
 	values map[string]*cell
 }
 
+// Lock takes the given locks and returns a guard releasing them and a view of
+// the registry scoped to them. Locks are always taken in key order, so guards
+// whose locks cross, one writing a and reading b while another writes b and
+// reads a, wait on each other in turn and can never deadlock. That holds only
+// while each goroutine has at most one guard open.
 func (r *Registry) Lock(locks ...Lock) (*Guard, *Scoped) {
 	scope := make(map[string]*scopedCell)
 
@@ -1,6 +1,9 @@
 package registry
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestRegistryTypedGetSetDelete(t *testing.T) {
 	type payload struct {
@@ -43,6 +46,43 @@ func TestRegistryLockScopesReadAndWriteAccess(t *testing.T) {
 	guard.Close()
 }
 
+func TestRegistryLockWaitsWithoutHoldingLaterKeys(t *testing.T) {
+	a, b := K[int]("a"), K[int]("b")
+	reg := New()
+	Set(reg, a, 0)
+	Set(reg, b, 0)
+
+	holdA, _ := reg.Lock(W(a))
+	crossed := make(chan *Guard)
+	go func() {
+		// Declared b first, but taken in key order: it waits on a while
+		// holding nothing, so b stays free.
+		guard, _ := reg.Lock(W(b), R(a))
+		crossed <- guard
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	locked := make(chan *Guard)
+	go func() {
+		guard, _ := reg.Lock(W(b))
+		locked <- guard
+	}()
+	select {
+	case guard := <-locked:
+		guard.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("b is held by a guard still waiting on a")
+	}
+
+	holdA.Close()
+	select {
+	case guard := <-crossed:
+		guard.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("crossed guard never acquired its locks")
+	}
+}
+
 func TestRegistryOptionalReadOfMissingKey(t *testing.T) {
 	key := K[string]("missing")
 	reg := New()
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuthMiddleware(t *testing.T) {
+	auth, err := ParseBasicAuth("ada:s3cret:with-colon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.User != "ada" || auth.Password != "s3cret:with-colon" {
+		t.Fatalf("auth = %+v, want user ada with the rest as password", auth)
+	}
+	for _, spec := range []string{"", "ada", ":pass", "ada:"} {
+		if _, err := ParseBasicAuth(spec); err == nil {
+			t.Fatalf("ParseBasicAuth(%q) error = nil, want an error", spec)
+		}
+	}
+
+	h := BasicAuthMiddleware(auth, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tests := []struct {
+		user, password string
+		set            bool
+		want           int
+	}{
+		{set: false, want: http.StatusUnauthorized},
+		{user: "ada", password: "wrong", set: true, want: http.StatusUnauthorized},
+		{user: "bob", password: "s3cret:with-colon", set: true, want: http.StatusUnauthorized},
+		{user: "ada", password: "s3cret:with-colon", set: true, want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, reloadPath, nil)
+		if tt.set {
+			req.SetBasicAuth(tt.user, tt.password)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Fatalf("%q/%q: status = %d, want %d", tt.user, tt.password, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("%q/%q: 401 without a WWW-Authenticate challenge", tt.user, tt.password)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchBackend delivers filesystem events for added paths. As with fsnotify,
+// adding a directory reports changes to its direct children.
+type watchBackend interface {
+	Add(name string) error
+	Remove(name string) error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+}
+
+type notifyBackend struct {
+	*fsnotify.Watcher
+}
+
+func (b notifyBackend) Events() <-chan fsnotify.Event { return b.Watcher.Events }
+func (b notifyBackend) Errors() <-chan error          { return b.Watcher.Errors }
+
+// pollBackend detects changes by statting watched paths every interval, for
+// network mounts and containers where inotify events never arrive.
+type pollBackend struct {
+	interval time.Duration
+	events   chan fsnotify.Event
+	errors   chan error
+	done     chan struct{}
+	closed   sync.Once
+
+	mu      sync.Mutex
+	watched map[string]map[string]pollStat // watched path -> snapshot
+}
+
+type pollStat struct {
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func newPollBackend(interval time.Duration) *pollBackend {
+	b := &pollBackend{
+		interval: interval,
+		events:   make(chan fsnotify.Event, 64),
+		errors:   make(chan error, 64),
+		done:     make(chan struct{}),
+		watched:  make(map[string]map[string]pollStat),
+	}
+	go b.loop()
+	return b
+}
+
+func (b *pollBackend) Add(name string) error {
+	snapshot, err := pollSnapshot(name)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.watched[name] = snapshot
+	return nil
+}
+
+func (b *pollBackend) Remove(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.watched, name)
+	return nil
+}
+
+func (b *pollBackend) Events() <-chan fsnotify.Event { return b.events }
+func (b *pollBackend) Errors() <-chan error          { return b.errors }
+
+func (b *pollBackend) Close() error {
+	b.closed.Do(func() { close(b.done) })
+	return nil
+}
+
+func (b *pollBackend) loop() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	defer close(b.events)
+	defer close(b.errors)
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			for _, ev := range b.poll() {
+				select {
+				case b.events <- ev:
+				case <-b.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// poll re-snapshots every watched path and returns the differences as
+// events. A watched path that disappears is reported once and dropped.
+func (b *pollBackend) poll() []fsnotify.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var events []fsnotify.Event
+	for name, before := range b.watched {
+		after, err := pollSnapshot(name)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				lazySend(b.errors, err)
+				continue
+			}
+			events = append(events, fsnotify.Event{Name: name, Op: fsnotify.Remove})
+			delete(b.watched, name)
+			continue
+		}
+		for p, stat := range after {
+			prev, ok := before[p]
+			switch {
+			case !ok:
+				events = append(events, fsnotify.Event{Name: p, Op: fsnotify.Create})
+			case !stat.dir && (prev.size != stat.size || !prev.modTime.Equal(stat.modTime)):
+				events = append(events, fsnotify.Event{Name: p, Op: fsnotify.Write})
+			}
+		}
+		for p := range before {
+			if _, ok := after[p]; !ok {
+				events = append(events, fsnotify.Event{Name: p, Op: fsnotify.Remove})
+			}
+		}
+		b.watched[name] = after
+	}
+	return events
+}
+
+// pollSnapshot stats name and, for a directory, its direct children.
+func pollSnapshot(name string) (map[string]pollStat, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := map[string]pollStat{name: statOf(info)}
+	if !info.IsDir() {
+		return snapshot, nil
+	}
+
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		snapshot[filepath.Join(name, entry.Name())] = statOf(info)
+	}
+	return snapshot, nil
+}
+
+func statOf(info fs.FileInfo) pollStat {
+	return pollStat{size: info.Size(), modTime: info.ModTime(), dir: info.IsDir()}
+}
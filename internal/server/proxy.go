@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ProxyRule forwards requests under Prefix to Target. The prefix is kept on
+// the forwarded path, so /api/users proxied to http://localhost:3000 is
+// requested as http://localhost:3000/api/users.
+type ProxyRule struct {
+	Prefix string
+	Target *url.URL
+}
+
+// ParseProxyRule parses a rule of the form /prefix=http://host:port.
+func ParseProxyRule(spec string) (ProxyRule, error) {
+	prefix, target, ok := strings.Cut(spec, "=")
+	if !ok {
+		return ProxyRule{}, fmt.Errorf("proxy %q: expected /prefix=url", spec)
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		return ProxyRule{}, fmt.Errorf("proxy %q: prefix must start with /", spec)
+	}
+	for _, reserved := range []string{reloadPath, reloadWSPath} {
+		if prefix == reserved || strings.HasPrefix(reserved, strings.TrimSuffix(prefix, "/")+"/") {
+			return ProxyRule{}, fmt.Errorf("proxy %q: prefix would shadow %s", spec, reserved)
+		}
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return ProxyRule{}, fmt.Errorf("proxy %q: %w", spec, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return ProxyRule{}, fmt.Errorf("proxy %q: target must be an absolute http(s) URL", spec)
+	}
+
+	return ProxyRule{Prefix: prefix, Target: u}, nil
+}
+
+func (r ProxyRule) matches(path string) bool {
+	prefix := strings.TrimSuffix(r.Prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+type proxyRoute struct {
+	rule  ProxyRule
+	proxy *httputil.ReverseProxy
+}
+
+// ProxyHandler sends requests matching a proxy rule to its backend and
+// everything else to next. When several prefixes match, the longest wins.
+// Proxied paths never fall through to next, so a backend 404 is not retried
+// against the built site.
+type ProxyHandler struct {
+	routes []proxyRoute
+	next   http.Handler
+}
+
+func NewProxyHandler(rules []ProxyRule, next http.Handler, logger *slog.Logger) *ProxyHandler {
+	routes := make([]proxyRoute, 0, len(rules))
+	for _, rule := range rules {
+		target := rule.Target
+		routes = append(routes, proxyRoute{
+			rule: rule,
+			proxy: &httputil.ReverseProxy{
+				Rewrite: func(pr *httputil.ProxyRequest) {
+					pr.SetURL(target)
+					pr.SetXForwarded()
+				},
+				ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+					logger.Warn("proxy request failed", "path", r.URL.Path, "target", target.String(), "error", err)
+					w.WriteHeader(http.StatusBadGateway)
+				},
+			},
+		})
+	}
+	slices.SortStableFunc(routes, func(a, b proxyRoute) int {
+		return len(b.rule.Prefix) - len(a.rule.Prefix)
+	})
+
+	return &ProxyHandler{routes: routes, next: next}
+}
+
+func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, route := range h.routes {
+		if route.rule.matches(r.URL.Path) {
+			route.proxy.ServeHTTP(w, r)
+			return
+		}
+	}
+	h.next.ServeHTTP(w, r)
+}
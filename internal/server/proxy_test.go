@@ -0,0 +1,67 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProxyHandlerLongestPrefixWinsAndSkipsReload(t *testing.T) {
+	backend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, "<html><body>"+name+" "+r.URL.Path+"</body></html>")
+		}))
+	}
+	api := backend("api")
+	defer api.Close()
+	v2 := backend("v2")
+	defer v2.Close()
+
+	var rules []ProxyRule
+	for _, spec := range []string{"/api=" + api.URL, "/api/v2=" + v2.URL} {
+		rule, err := ParseProxyRule(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+	site := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><body>site</body></html>")
+	})
+	handler := NewProxyHandler(rules, ReloadMiddleware(ReloadSSE, site), slog.New(slog.DiscardHandler))
+
+	tests := []struct {
+		path   string
+		want   string
+		reload bool
+	}{
+		{path: "/api/users", want: "api /api/users"},
+		{path: "/api/v2/users", want: "v2 /api/v2/users"},
+		{path: "/apiary", want: "site", reload: true},
+		{path: "/", want: "site", reload: true},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		body := rec.Body.String()
+		if !strings.Contains(body, tt.want) {
+			t.Fatalf("GET %s body = %q, want %q", tt.path, body, tt.want)
+		}
+		if got := strings.Contains(body, reloadPath); got != tt.reload {
+			t.Fatalf("GET %s reload script injected = %v, want %v", tt.path, got, tt.reload)
+		}
+	}
+}
+
+func TestParseProxyRuleRejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{"/api", "api=http://localhost:3000", "/api=localhost:3000", "/_shizuka=http://localhost:3000"} {
+		if _, err := ParseProxyRule(spec); err == nil {
+			t.Fatalf("ParseProxyRule(%q) = nil error, want error", spec)
+		}
+	}
+}
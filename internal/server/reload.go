@@ -1,19 +1,85 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/felixge/httpsnoop"
+	"github.com/olimci/shizuka/internal/build"
 )
 
+const (
+	reloadFull = "reload"
+	reloadCSS  = "reload:css"
+)
+
+// reloadPingInterval is how often idle reload connections are pinged, so
+// proxies do not time them out.
+const reloadPingInterval = 2 * time.Second
+
+// ReloadTransport is the channel injected pages listen for reloads on.
+type ReloadTransport string
+
+const (
+	// ReloadSSE streams reloads as server-sent events.
+	ReloadSSE ReloadTransport = "sse"
+	// ReloadWebSocket sends reloads over a WebSocket, falling back to
+	// server-sent events in browsers that cannot open one.
+	ReloadWebSocket ReloadTransport = "ws"
+)
+
+// ParseReloadTransport parses a transport name: sse, or ws or websocket.
+func ParseReloadTransport(name string) (ReloadTransport, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "sse":
+		return ReloadSSE, nil
+	case "ws", "websocket":
+		return ReloadWebSocket, nil
+	default:
+		return "", fmt.Errorf("reload transport %q: want sse or ws", name)
+	}
+}
+
+// reloadMessage picks the message to broadcast after a rebuild. Changes that
+// touch only stylesheets are hot-swapped in the page; anything else, or an
+// unknown change set, reloads the page.
+func reloadMessage(changedPaths []string) string {
+	if len(changedPaths) == 0 {
+		return reloadFull
+	}
+	for _, changed := range changedPaths {
+		if !strings.EqualFold(filepath.Ext(changed), ".css") {
+			return reloadFull
+		}
+	}
+	return reloadCSS
+}
+
+// reloadScope picks the message to broadcast after a successful rebuild from
+// the output files it changed, reporting false when the output is unchanged.
+// Without stats, it falls back to the changed source paths.
+func reloadScope(stats *build.BuildStats, changedPaths []string) (string, bool) {
+	if stats == nil {
+		return reloadMessage(changedPaths), true
+	}
+	changed := stats.Changes.Changed()
+	if len(changed) == 0 {
+		return "", false
+	}
+	return reloadMessage(changed), true
+}
+
 func NewReloadHub() *ReloadHub {
 	return &ReloadHub{
 		clients: make(map[*ReloadClient]struct{}),
@@ -24,6 +90,49 @@ type ReloadClient struct {
 	Send chan string
 }
 
+// WSReloadClient is a ReloadClient whose messages are written to a WebSocket
+// rather than an event stream.
+type WSReloadClient struct {
+	*ReloadClient
+
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex // serialises frame writes
+
+	// writeTimeout bounds each frame write, so a client that stops reading
+	// cannot hold the connection open.
+	writeTimeout time.Duration
+}
+
+func (c *WSReloadClient) write(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+		return err
+	}
+	return writeWSFrame(c.rw.Writer, opcode, payload)
+}
+
+// readLoop answers pings and the closing handshake until the connection
+// fails or the client closes it. Clients have nothing else to say.
+func (c *WSReloadClient) readLoop() {
+	for {
+		opcode, payload, err := readWSFrame(c.rw.Reader)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsOpClose:
+			_ = c.write(wsOpClose, payload[:min(len(payload), 2)])
+			return
+		case wsOpPing:
+			if err := c.write(wsOpPong, payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
 type ReloadHub struct {
 	mu      sync.RWMutex
 	clients map[*ReloadClient]struct{}
@@ -55,8 +164,9 @@ func (h *ReloadHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	client := h.subscribe()
 	defer h.unsubscribe(client)
+	flusher.Flush()
 
-	ticker := time.NewTicker(2 * time.Second)
+	ticker := time.NewTicker(reloadPingInterval)
 	defer ticker.Stop()
 
 	for {
@@ -73,7 +183,55 @@ func (h *ReloadHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			flusher.Flush()
-			if msg == "reload" {
+			if msg == reloadFull {
+				return
+			}
+		}
+	}
+}
+
+// ServeWebSocket serves the reload channel over a WebSocket, sending each
+// broadcast as a text frame. Like the event stream, the connection is closed
+// after a full reload.
+func (h *ReloadHub) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, rw, err := upgradeWebSocket(w, r)
+	if errors.Is(err, errCrossOrigin) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	client := &WSReloadClient{ReloadClient: h.subscribe(), conn: conn, rw: rw, writeTimeout: wsWriteTimeout}
+	defer h.unsubscribe(client.ReloadClient)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.readLoop()
+	}()
+
+	ticker := time.NewTicker(reloadPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := client.write(wsOpPing, nil); err != nil {
+				return
+			}
+		case msg := <-client.Send:
+			if err := client.write(wsOpText, []byte(msg)); err != nil {
+				return
+			}
+			if msg == reloadFull {
+				// 1000 is a normal closure.
+				_ = client.write(wsOpClose, []byte{0x03, 0xE8})
 				return
 			}
 		}
@@ -97,13 +255,22 @@ func (h *ReloadHub) unsubscribe(client *ReloadClient) {
 	delete(h.clients, client)
 }
 
-func ReloadMiddleware(next http.Handler) http.Handler {
+// ReloadMiddleware injects a script that listens for reloads over transport
+// into HTML responses from next.
+func ReloadMiddleware(transport ReloadTransport, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !shouldInjectReload(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
+		// The script is injected into the raw body, so precompressed
+		// responses must not be negotiated.
+		if r.Header.Get("Accept-Encoding") != "" {
+			r = r.Clone(r.Context())
+			r.Header.Del("Accept-Encoding")
+		}
+
 		var body bytes.Buffer
 		statusCode := http.StatusOK
 		wroteHeader := false
@@ -139,7 +306,7 @@ func ReloadMiddleware(next http.Handler) http.Handler {
 			}
 		}
 		if strings.Contains(contentType, "text/html") {
-			injected := injectReloadScript(body.String())
+			injected := injectReloadScript(body.String(), transport)
 			w.Header().Set("Content-Length", strconv.Itoa(len(injected)))
 			if wroteHeader {
 				w.WriteHeader(statusCode)
@@ -158,22 +325,68 @@ func ReloadMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func injectReloadScript(html string) string {
-	snippet := `<script>
+// reloadScript is the injected client. Its %s is the call that starts
+// listening: listenSSE() or listenWS().
+const reloadScript = `<script>
 (() => {
-  const es = new EventSource("/_shizuka/reload");
-  es.onmessage = (event) => {
-    if (event.data === "reload") {
-      es.close();
+  let source = null;
+  let reloading = false;
+  const handle = (data) => {
+    if (data === "reload:css") {
+      const stamp = Date.now().toString();
+      document.querySelectorAll('link[rel~="stylesheet"][href]').forEach((link) => {
+        const url = new URL(link.href, window.location.href);
+        if (url.origin !== window.location.origin) {
+          return;
+        }
+        url.searchParams.set("shizuka-reload", stamp);
+        link.href = url.toString();
+      });
+    } else if (data === "reload") {
+      reloading = true;
+      source.close();
       window.location.reload();
     }
   };
+  const listenSSE = () => {
+    source = new EventSource("/_shizuka/reload");
+    source.onmessage = (event) => handle(event.data);
+  };
+  const listenWS = () => {
+    const url = new URL("/_shizuka/ws", window.location.href);
+    url.protocol = url.protocol === "https:" ? "wss:" : "ws:";
+    let ws;
+    try {
+      ws = new WebSocket(url);
+    } catch {
+      listenSSE();
+      return;
+    }
+    source = ws;
+    ws.onmessage = (event) => handle(event.data);
+    ws.onclose = () => {
+      if (!reloading) {
+        listenSSE();
+      }
+    };
+  };
   window.addEventListener("beforeunload", () => {
-    es.close();
+    reloading = true;
+    if (source) {
+      source.close();
+    }
   });
+  %s;
 })();
 </script>`
 
+func injectReloadScript(html string, transport ReloadTransport) string {
+	start := "listenSSE()"
+	if transport == ReloadWebSocket {
+		start = "listenWS()"
+	}
+	snippet := fmt.Sprintf(reloadScript, start)
+
 	lower := strings.ToLower(html)
 	if idx := strings.LastIndex(lower, "</body>"); idx != -1 {
 		return html[:idx] + snippet + html[idx:]
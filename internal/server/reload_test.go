@@ -0,0 +1,44 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/olimci/shizuka/internal/build"
+	"github.com/olimci/shizuka/internal/manifest"
+)
+
+func TestReloadMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{name: "no paths", paths: nil, want: reloadFull},
+		{name: "only css", paths: []string{"/site/static/a.css", "/site/static/b.CSS"}, want: reloadCSS},
+		{name: "mixed", paths: []string{"/site/static/a.css", "/site/content/index.md"}, want: reloadFull},
+		{name: "template", paths: []string{"/site/templates/page.tmpl"}, want: reloadFull},
+	}
+	for _, tt := range tests {
+		if got := reloadMessage(tt.paths); got != tt.want {
+			t.Fatalf("%s: reloadMessage(%v) = %q, want %q", tt.name, tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestReloadScopeUsesOutputChanges(t *testing.T) {
+	sources := []string{"/site/content/index.md"}
+
+	unchanged := &build.BuildStats{Changes: manifest.Changes{Unchanged: []string{"index.html"}}}
+	if msg, ok := reloadScope(unchanged, sources); ok {
+		t.Fatalf("reloadScope() = %q, want no broadcast for an unchanged output", msg)
+	}
+
+	css := &build.BuildStats{Changes: manifest.Changes{Edited: []string{"style.css"}, Unchanged: []string{"index.html"}}}
+	if msg, ok := reloadScope(css, sources); !ok || msg != reloadCSS {
+		t.Fatalf("reloadScope() = %q, %v, want %q", msg, ok, reloadCSS)
+	}
+
+	if msg, ok := reloadScope(nil, sources); !ok || msg != reloadFull {
+		t.Fatalf("reloadScope(nil) = %q, %v, want %q", msg, ok, reloadFull)
+	}
+}
@@ -13,21 +13,35 @@ import (
 	"sync"
 	"time"
 
+	"github.com/olimci/shizuka/internal/build"
 	"github.com/olimci/shizuka/internal/config"
 	"github.com/olimci/shizuka/internal/options"
 	"github.com/olimci/shizuka/internal/registry"
 )
 
-const reloadPath = "/_shizuka/reload"
+const (
+	reloadPath   = "/_shizuka/reload"
+	reloadWSPath = "/_shizuka/ws"
+)
 
-type BuildFunc func(...options.Option) error
+type BuildFunc func(...options.Option) (*build.BuildStats, error)
 
 type Options struct {
 	Addr          string
 	Watch         bool
 	WatchDebounce time.Duration
+	WatchPoll     time.Duration // poll interval; zero uses filesystem notifications
 	Reload        bool
-	Logger        *slog.Logger
+	// ReloadTransport picks the channel reloads are sent on; the zero value
+	// is ReloadSSE. The event stream is served either way, as the fallback.
+	ReloadTransport ReloadTransport
+	Logger          *slog.Logger
+	TLS             *TLSOptions
+	Proxies         []ProxyRule
+	ContentETags    bool
+	// Auth, when set, gates every request, the reload stream included,
+	// behind HTTP Basic credentials.
+	Auth *BasicAuth
 
 	BuildOptions []options.Option
 	Build        BuildFunc
@@ -58,6 +72,7 @@ type Event struct {
 	Addr     string
 	URL      string
 	Duration time.Duration
+	Stats    *build.BuildStats
 	Err      error
 }
 
@@ -75,6 +90,8 @@ type Server struct {
 
 	httpServer *http.Server
 	listener   net.Listener
+	certFile   string
+	keyFile    string
 	watcher    *Watcher
 
 	buildMu sync.Mutex
@@ -127,6 +144,17 @@ func (s *Server) Start(ctx context.Context) error {
 		return err
 	}
 
+	if s.opts.TLS != nil {
+		s.certFile, s.keyFile, err = s.opts.TLS.files()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := validateListenAddr(s.opts.Addr); err != nil {
+		return err
+	}
+
 	listener, err := net.Listen("tcp", s.opts.Addr)
 	if err != nil {
 		return fmt.Errorf("dev server %q: %w", s.opts.Addr, err)
@@ -139,26 +167,43 @@ func (s *Server) Start(ctx context.Context) error {
 	s.static = NewStaticHandler(s.dist, StaticOptions{
 		HeadersFile:   headersFile(cfg),
 		RedirectsFile: redirectsFile(cfg),
+		ContentETags:  s.opts.ContentETags,
+		IndexFile:     cfg.Build.IndexFile,
 	})
 
 	var root http.Handler = s.static
 	mux := http.NewServeMux()
 	if s.opts.Reload {
 		mux.Handle(reloadPath, s.hub)
-		root = ReloadMiddleware(root)
+		if s.opts.ReloadTransport == ReloadWebSocket {
+			mux.HandleFunc(reloadWSPath, s.hub.ServeWebSocket)
+		}
+		root = ReloadMiddleware(s.opts.ReloadTransport, root)
+	}
+	if len(s.opts.Proxies) > 0 {
+		root = NewProxyHandler(s.opts.Proxies, root, s.logger)
 	}
 	mux.Handle("/", root)
 
+	var handler http.Handler = mux
+	if s.opts.Auth != nil {
+		handler = BasicAuthMiddleware(*s.opts.Auth, handler)
+	}
 	s.httpServer = &http.Server{
 		Addr:    s.opts.Addr,
-		Handler: mux,
+		Handler: handler,
 	}
 
 	go s.serve(listener)
 	s.emit(Event{Kind: EventListening, Addr: listener.Addr().String(), URL: s.siteURL})
 
 	if s.opts.Watch {
-		watcher, err := NewWatcher(buildOpts.ConfigPath, s.opts.WatchDebounce)
+		var watcher *Watcher
+		if s.opts.WatchPoll > 0 {
+			watcher, err = NewPollingWatcher(buildOpts.ConfigPath, s.opts.WatchDebounce, s.opts.WatchPoll)
+		} else {
+			watcher, err = NewWatcher(buildOpts.ConfigPath, s.opts.WatchDebounce)
+		}
 		if err != nil {
 			_ = s.Close()
 			return err
@@ -196,7 +241,7 @@ func (s *Server) Rebuild(ctx context.Context, req RebuildRequest) error {
 	s.emit(Event{Kind: EventBuildStarted, Reason: req.Reason, URL: s.siteURL})
 
 	start := time.Now()
-	err := s.opts.Build(s.buildOptions(ctx, req.ChangedPaths)...)
+	stats, err := s.opts.Build(s.buildOptions(ctx, req.ChangedPaths)...)
 	elapsed := time.Since(start).Truncate(time.Millisecond)
 	if err != nil {
 		s.emit(Event{Kind: EventBuildFailed, Reason: req.Reason, URL: s.siteURL, Duration: elapsed, Err: err})
@@ -204,9 +249,11 @@ func (s *Server) Rebuild(ctx context.Context, req RebuildRequest) error {
 	}
 
 	if s.opts.Reload {
-		s.hub.Broadcast("reload")
+		if msg, ok := reloadScope(stats, req.ChangedPaths); ok {
+			s.hub.Broadcast(msg)
+		}
 	}
-	s.emit(Event{Kind: EventBuildSucceeded, Reason: req.Reason, URL: s.siteURL, Duration: elapsed})
+	s.emit(Event{Kind: EventBuildSucceeded, Reason: req.Reason, URL: s.siteURL, Duration: elapsed, Stats: stats})
 	return nil
 }
 
@@ -253,7 +300,12 @@ func (s *Server) Close() error {
 }
 
 func (s *Server) serve(listener net.Listener) {
-	err := s.httpServer.Serve(listener)
+	var err error
+	if s.opts.TLS != nil {
+		err = s.httpServer.ServeTLS(listener, s.certFile, s.keyFile)
+	} else {
+		err = s.httpServer.Serve(listener)
+	}
 	if err == nil || errors.Is(err, http.ErrServerClosed) {
 		return
 	}
@@ -297,12 +349,26 @@ func (s *Server) buildOptions(ctx context.Context, changedPaths []string) []opti
 }
 
 func (s *Server) resolveSiteURL(addr net.Addr) string {
-	host, port, err := net.SplitHostPort(addr.String())
+	scheme := "http"
+	if s.opts.TLS != nil {
+		scheme = "https"
+	}
+	host, _, err := net.SplitHostPort(s.opts.Addr)
+	if err != nil {
+		host = ""
+	}
+	listenHost, port, err := net.SplitHostPort(addr.String())
 	if err != nil {
-		return "http://localhost/"
+		return scheme + "://localhost/"
 	}
-	if host == "" || host == "::" || host == "0.0.0.0" || host == "[::]" {
+	if host == "" {
+		host = listenHost
+	}
+	if isUnspecifiedHost(host) {
 		host = "localhost"
+		if ip := primaryIPv4(); ip != nil {
+			host = ip.String()
+		}
 	}
 	if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
 		host = "[" + host + "]"
@@ -312,7 +378,7 @@ func (s *Server) resolveSiteURL(addr net.Addr) string {
 			port = strconv.Itoa(tcpAddr.Port)
 		}
 	}
-	return fmt.Sprintf("http://%s:%s/", host, port)
+	return fmt.Sprintf("%s://%s:%s/", scheme, host, port)
 }
 
 func (s *Server) emit(ev Event) {
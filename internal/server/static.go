@@ -2,11 +2,15 @@ package server
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,6 +22,13 @@ import (
 type StaticOptions struct {
 	HeadersFile   string
 	RedirectsFile string
+
+	// ContentETags derives ETags from a hash of the file content instead of
+	// its size and modification time.
+	ContentETags bool
+
+	// IndexFile is served for directory requests. It defaults to index.html.
+	IndexFile string
 }
 
 type StaticHandler struct {
@@ -28,6 +39,16 @@ type StaticHandler struct {
 
 	headersCache   cachedHeaders
 	redirectsCache cachedRedirects
+
+	contentETags bool
+	etags        sync.Map // file path -> cachedETag
+	indexFile    string
+}
+
+type cachedETag struct {
+	size    int64
+	modTime time.Time
+	etag    string
 }
 
 type cachedHeaders struct {
@@ -50,9 +71,20 @@ type headerRule struct {
 }
 
 type redirectRule struct {
-	from   string
+	from string
+	// query holds the query parameters a request must carry to match, each
+	// a literal value or a :name placeholder capturing it.
+	query  map[string]string
 	to     string
 	status int
+	// force is set by a status ending in !, as in 301!. A forced rule
+	// applies even when a file exists at the path; other rules only apply
+	// when none does.
+	force bool
+	// conditions holds trailing Key=value,value tokens, such as
+	// Language=en,fr, keyed by the lower-cased key. A rule with conditions
+	// fires only when every one of them matches.
+	conditions map[string][]string
 }
 
 func NewStaticHandler(dist string, opts StaticOptions) *StaticHandler {
@@ -66,10 +98,17 @@ func NewStaticHandler(dist string, opts StaticOptions) *StaticHandler {
 		redirectsFile = "_redirects"
 	}
 
+	indexFile := opts.IndexFile
+	if indexFile == "" {
+		indexFile = "index.html"
+	}
+
 	return &StaticHandler{
 		dist:          dist,
 		headersFile:   headersFile,
 		redirectsFile: redirectsFile,
+		contentETags:  opts.ContentETags,
+		indexFile:     indexFile,
 	}
 }
 
@@ -119,7 +158,10 @@ func (h *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	redirects := h.loadRedirects()
-	if action, ok := matchRedirect(reqPath, redirects); ok {
+	if _, _, exists := h.resolvePath(r.URL.Path); exists {
+		redirects = forcedRedirects(redirects)
+	}
+	if action, ok := matchRedirect(r, redirects); ok {
 		switch action.kind {
 		case redirectActionRewrite:
 			if !pathutil.IsExternalURL(action.target) {
@@ -149,32 +191,205 @@ func (h *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if filePath, redirectPath, ok := h.resolvePath(r.URL.Path); ok {
 		if redirectPath != "" {
 			h.applyHeaders(w, headersPath)
+			if r.URL.RawQuery != "" {
+				redirectPath += "?" + r.URL.RawQuery
+			}
 			http.Redirect(w, r, redirectPath, http.StatusMovedPermanently)
 			return
 		}
 
 		h.applyHeaders(w, headersPath)
-		http.ServeFile(w, r, filePath)
+		servePath := negotiateEncoding(w, r, filePath)
+		if w.Header().Get("ETag") == "" {
+			if etag, err := h.etag(servePath); err == nil {
+				w.Header().Set("ETag", etag)
+			}
+		}
+		if etag := w.Header().Get("ETag"); etag != "" && etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		http.ServeFile(w, r, servePath)
 		return
 	}
 
 	h.serveNotFound(w, r, headersPath, http.StatusNotFound)
 }
 
+// etag returns a strong ETag for filePath, cached until the file's size or
+// modification time changes.
+func (h *StaticHandler) etag(filePath string) (string, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	if cached, ok := h.etags.Load(filePath); ok {
+		if c := cached.(cachedETag); c.size == info.Size() && c.modTime.Equal(info.ModTime()) {
+			return c.etag, nil
+		}
+	}
+
+	var etag string
+	if h.contentETags {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	} else {
+		etag = `"` + strconv.FormatInt(info.Size(), 16) + "-" + strconv.FormatInt(info.ModTime().UnixNano(), 16) + `"`
+	}
+
+	h.etags.Store(filePath, cachedETag{size: info.Size(), modTime: info.ModTime(), etag: etag})
+	return etag, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// precompressed lists sibling file suffixes in order of preference.
+var precompressed = []struct {
+	encoding string
+	suffix   string
+}{
+	{encoding: "br", suffix: ".br"},
+	{encoding: "gzip", suffix: ".gz"},
+}
+
+// negotiateEncoding returns the precompressed sibling of filePath that the
+// client accepts, setting Content-Encoding and the original Content-Type, or
+// filePath itself when there is none. A .br or .gz file requested by its own
+// name is served as-is.
+func negotiateEncoding(w http.ResponseWriter, r *http.Request, filePath string) string {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if ext == ".br" || ext == ".gz" {
+		return filePath
+	}
+
+	accept := r.Header.Get("Accept-Encoding")
+	varied := false
+	for _, candidate := range precompressed {
+		info, err := os.Stat(filePath + candidate.suffix)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if !varied {
+			w.Header().Add("Vary", "Accept-Encoding")
+			varied = true
+		}
+		if !acceptsEncoding(accept, candidate.encoding) {
+			continue
+		}
+
+		if w.Header().Get("Content-Type") == "" {
+			if ctype := mime.TypeByExtension(ext); ctype != "" {
+				w.Header().Set("Content-Type", ctype)
+			}
+		}
+		w.Header().Set("Content-Encoding", candidate.encoding)
+		return filePath + candidate.suffix
+	}
+	return filePath
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows encoding
+// with a non-zero quality.
+func acceptsEncoding(header, encoding string) bool {
+	wildcard := false
+	for part := range strings.SplitSeq(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+		if name == "*" {
+			wildcard = encodingQuality(params) > 0
+			continue
+		}
+		if strings.EqualFold(name, encoding) {
+			return encodingQuality(params) > 0
+		}
+	}
+	return wildcard
+}
+
+func encodingQuality(params string) float64 {
+	q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+	if !ok {
+		return 1
+	}
+	quality, err := strconv.ParseFloat(q, 64)
+	if err != nil {
+		return 0
+	}
+	return quality
+}
+
+// applyHeaders sets the headers of every rule matching reqPath. Where rules
+// set the same header, the more specific rule wins, whatever the file order:
+// see compareHeaderSpecificity.
 func (h *StaticHandler) applyHeaders(w http.ResponseWriter, reqPath string) {
+	var matched []headerRule
 	for _, rule := range h.loadHeaders() {
-		if ok, _ := matchPattern(rule.pattern, reqPath); ok {
-			for key, value := range rule.headers {
-				w.Header().Set(key, value)
-			}
+		if ok, _, _ := matchPattern(rule.pattern, reqPath); ok {
+			matched = append(matched, rule)
+		}
+	}
+	slices.SortStableFunc(matched, func(a, b headerRule) int {
+		return compareHeaderSpecificity(a.pattern, b.pattern)
+	})
+	for _, rule := range matched {
+		for key, value := range rule.headers {
+			w.Header().Set(key, value)
 		}
 	}
 }
 
+// compareHeaderSpecificity orders header patterns from least to most
+// specific: a longer literal prefix, before the first * or :name, is more
+// specific, then fewer wildcards. Equal patterns keep their file order, so
+// the later rule still wins a tie.
+func compareHeaderSpecificity(a, b string) int {
+	prefixA, wildA := headerSpecificity(a)
+	prefixB, wildB := headerSpecificity(b)
+	if prefixA != prefixB {
+		return prefixA - prefixB
+	}
+	return wildB - wildA
+}
+
+func headerSpecificity(pattern string) (prefix, wildcards int) {
+	pattern = normalizePath(pattern)
+	prefix = len(pattern)
+	if i := strings.IndexAny(pattern, "*:"); i >= 0 {
+		prefix = i
+	}
+	wildcards = strings.Count(pattern, "*") + strings.Count(pattern, "/:")
+	return prefix, wildcards
+}
+
 func (h *StaticHandler) serveNotFound(w http.ResponseWriter, r *http.Request, headersPath string, status int) {
 	customPath := filepath.Join(h.dist, "404.html")
 	if info, err := os.Stat(customPath); err == nil && !info.IsDir() {
 		h.applyHeaders(w, headersPath)
+		// The 404 page is not the requested resource, so validators sent
+		// for it must not turn the error into a 304.
+		r = r.Clone(r.Context())
+		r.Header.Del("If-None-Match")
+		r.Header.Del("If-Modified-Since")
 		sw := &statusWriter{ResponseWriter: w}
 		sw.WriteHeader(status)
 		http.ServeFile(sw, r, customPath)
@@ -205,7 +420,7 @@ func (h *StaticHandler) resolvePath(urlPath string) (string, string, bool) {
 	info, err := os.Stat(fullPath)
 	if err == nil {
 		if info.IsDir() {
-			indexPath := filepath.Join(fullPath, "index.html")
+			indexPath := filepath.Join(fullPath, h.indexFile)
 			indexInfo, indexErr := os.Stat(indexPath)
 			if indexErr != nil || indexInfo.IsDir() {
 				return "", "", false
@@ -222,7 +437,7 @@ func (h *StaticHandler) resolvePath(urlPath string) (string, string, bool) {
 		return "", "", false
 	}
 
-	indexPath := filepath.Join(fullPath, "index.html")
+	indexPath := filepath.Join(fullPath, h.indexFile)
 	if info, err := os.Stat(indexPath); err == nil && !info.IsDir() {
 		return indexPath, "", true
 	}
@@ -337,21 +552,40 @@ type redirectAction struct {
 	status int
 }
 
-func matchRedirect(reqPath string, rules []redirectRule) (redirectAction, bool) {
+// matchRedirect returns the action of the first rule matching the request's
+// path, query and the rule's conditions. Redirect and rewrite targets carry
+// the request's query unless the rule matched on it or the target sets its
+// own; a target ending in a bare ? drops it.
+func matchRedirect(r *http.Request, rules []redirectRule) (redirectAction, bool) {
+	reqPath, rawQuery := normalizePath(r.URL.Path), r.URL.RawQuery
+	var query url.Values
 	for _, rule := range rules {
-		matched, splat := matchPattern(rule.from, reqPath)
-		if !matched {
+		matched, splat, params := matchPattern(rule.from, reqPath)
+		if !matched || !matchConditions(rule.conditions, r) {
 			continue
 		}
+		if len(rule.query) > 0 {
+			if query == nil {
+				query, _ = url.ParseQuery(rawQuery)
+			}
+			if params, matched = matchQuery(rule.query, query, params); !matched {
+				continue
+			}
+		}
 
-		target := rule.to
+		target := substituteParams(rule.to, params)
 		if splat != "" {
-			target = strings.ReplaceAll(target, ":splat", splat)
-			target = strings.ReplaceAll(target, "*", splat)
+			target = strings.ReplaceAll(target, ":splat", escapePathValue(splat))
+			target = strings.ReplaceAll(target, "*", escapePathValue(splat))
 		}
 		if target != "" && !strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "?") && !strings.HasPrefix(target, "#") && !pathutil.IsExternalURL(target) {
 			target = "/" + target
 		}
+		if len(rule.query) == 0 {
+			target = forwardQuery(target, rawQuery)
+		} else {
+			target = strings.TrimSuffix(target, "?")
+		}
 
 		status := rule.status
 		if status == 0 {
@@ -372,6 +606,102 @@ func matchRedirect(reqPath string, rules []redirectRule) (redirectAction, bool)
 	return redirectAction{}, false
 }
 
+// forcedRedirects returns the rules that apply to a path where a file
+// exists.
+func forcedRedirects(rules []redirectRule) []redirectRule {
+	var forced []redirectRule
+	for _, rule := range rules {
+		if rule.force {
+			forced = append(forced, rule)
+		}
+	}
+	return forced
+}
+
+// matchConditions reports whether r meets every condition: Language against
+// the languages the request accepts, a primary tag such as en matching any
+// en-* region, and Cookie against the cookies it carries. Country needs the
+// client's location, which a local server does not know, so rules with a
+// Country condition, like those with an unknown condition, never fire.
+func matchConditions(conditions map[string][]string, r *http.Request) bool {
+	for key, values := range conditions {
+		switch key {
+		case "language":
+			if !acceptsLanguage(r.Header.Get("Accept-Language"), values) {
+				return false
+			}
+		case "cookie":
+			if !slices.ContainsFunc(values, func(name string) bool {
+				_, err := r.Cookie(name)
+				return err == nil
+			}) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// acceptsLanguage reports whether an Accept-Language header accepts any of
+// langs.
+func acceptsLanguage(header string, langs []string) bool {
+	for part := range strings.SplitSeq(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" || encodingQuality(params) <= 0 {
+			continue
+		}
+		for _, lang := range langs {
+			if strings.EqualFold(tag, lang) || len(tag) > len(lang) && tag[len(lang)] == '-' && strings.EqualFold(tag[:len(lang)], lang) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchQuery reports whether query carries every parameter in want, adding
+// the values captured by :name placeholders to params.
+func matchQuery(want map[string]string, query url.Values, params map[string]string) (map[string]string, bool) {
+	for key, pattern := range want {
+		if !query.Has(key) {
+			return params, false
+		}
+		value := query.Get(key)
+		if name, ok := strings.CutPrefix(pattern, ":"); ok && name != "" {
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[name] = value
+			continue
+		}
+		if value != pattern {
+			return params, false
+		}
+	}
+	return params, true
+}
+
+// forwardQuery appends rawQuery to target, ahead of any fragment, unless
+// target has a query of its own. A bare trailing ? is dropped, leaving no
+// query.
+func forwardQuery(target, rawQuery string) string {
+	base, fragment, hasFragment := strings.Cut(target, "#")
+	switch {
+	case strings.HasSuffix(base, "?"):
+		base = strings.TrimSuffix(base, "?")
+	case strings.Contains(base, "?") || rawQuery == "":
+		return target
+	default:
+		base += "?" + rawQuery
+	}
+	if hasFragment {
+		return base + "#" + fragment
+	}
+	return base
+}
+
 func normalizePath(raw string) string {
 	clean := path.Clean("/" + raw)
 	if clean == "." {
@@ -383,32 +713,131 @@ func normalizePath(raw string) string {
 	return clean
 }
 
-func matchPattern(pattern, value string) (bool, string) {
+// matchPattern matches value against a redirect pattern. Patterns without
+// named placeholders keep the single-splat form, where * matches any run of
+// characters between a literal prefix and suffix. Patterns with :name
+// segments are matched segment by segment: each :name captures exactly one
+// segment, and a trailing * captures the remaining tail.
+func matchPattern(pattern, value string) (bool, string, map[string]string) {
 	pattern = normalizePath(pattern)
 	value = normalizePath(value)
 
 	if pattern == value {
-		return true, ""
+		return true, "", nil
+	}
+
+	if strings.Contains(pattern, "/:") {
+		return matchSegments(pattern, value)
 	}
 
 	if !strings.Contains(pattern, "*") {
-		return false, ""
+		return false, "", nil
 	}
 
 	parts := strings.Split(pattern, "*")
 	if len(parts) != 2 {
-		return false, ""
+		return false, "", nil
 	}
 
 	prefix := parts[0]
 	suffix := parts[1]
 
 	if !strings.HasPrefix(value, prefix) || !strings.HasSuffix(value, suffix) {
-		return false, ""
+		return false, "", nil
 	}
 
 	splat := strings.TrimSuffix(strings.TrimPrefix(value, prefix), suffix)
-	return true, splat
+	return true, splat, nil
+}
+
+func matchSegments(pattern, value string) (bool, string, map[string]string) {
+	patternSegs := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	valueSegs := strings.Split(strings.TrimPrefix(value, "/"), "/")
+
+	params := make(map[string]string)
+	for i, seg := range patternSegs {
+		if seg == "*" && i == len(patternSegs)-1 {
+			if i > len(valueSegs) {
+				return false, "", nil
+			}
+			splat := strings.Join(valueSegs[i:], "/")
+			params["splat"] = splat
+			return true, splat, params
+		}
+		if i >= len(valueSegs) {
+			return false, "", nil
+		}
+		if name, ok := strings.CutPrefix(seg, ":"); ok && name != "" {
+			if valueSegs[i] == "" {
+				return false, "", nil
+			}
+			params[name] = valueSegs[i]
+			continue
+		}
+		if seg != valueSegs[i] {
+			return false, "", nil
+		}
+	}
+
+	if len(patternSegs) != len(valueSegs) {
+		return false, "", nil
+	}
+	return true, "", params
+}
+
+// substituteParams replaces :name tokens in target with their captured
+// values, escaped for the part of the URL they land in, so that a captured
+// ?, & or / cannot change the target's path or query. Only a splat keeps its
+// slashes. Tokens without a capture are left as-is.
+func substituteParams(target string, params map[string]string) string {
+	if len(params) == 0 {
+		return target
+	}
+
+	var b strings.Builder
+	inQuery, inFragment := false, false
+	for {
+		i := strings.IndexByte(target, ':')
+		if i < 0 {
+			b.WriteString(target)
+			return b.String()
+		}
+		b.WriteString(target[:i])
+		inQuery = inQuery || strings.Contains(target[:i], "?")
+		inFragment = inFragment || strings.Contains(target[:i], "#")
+		end := i + 1
+		for end < len(target) && isParamNameByte(target[end]) {
+			end++
+		}
+		name := target[i+1 : end]
+		if value, ok := params[name]; ok && end > i+1 {
+			switch {
+			case inQuery && !inFragment:
+				b.WriteString(url.QueryEscape(value))
+			case name == "splat" && !inFragment:
+				b.WriteString(escapePathValue(value))
+			default:
+				b.WriteString(url.PathEscape(value))
+			}
+		} else {
+			b.WriteString(target[i:end])
+		}
+		target = target[end:]
+	}
+}
+
+// escapePathValue escapes each segment of a captured path value, keeping the
+// slashes of a splat.
+func escapePathValue(value string) string {
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func isParamNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
 }
 
 func parseHeadersFile(filePath string) ([]headerRule, error) {
@@ -488,23 +917,59 @@ func parseRedirectsFile(filePath string) ([]redirectRule, error) {
 		}
 
 		from := fields[0]
-		if parsed, err := url.Parse(from); err == nil && parsed.Host != "" {
-			from = parsed.Path
+		var query map[string]string
+		if parsed, err := url.Parse(from); err == nil {
+			if parsed.Host != "" || parsed.RawQuery != "" {
+				from = parsed.Path
+			}
+			query = addRedirectQuery(query, parsed.RawQuery)
 		}
 		from = normalizePath(from)
-		to := fields[1]
+
+		// Query conditions may also follow the path as key=value fields, as
+		// in "/store id=:id /items/:id".
+		fields = fields[1:]
+		for len(fields) > 1 && isRedirectQueryField(fields[0]) {
+			query = addRedirectQuery(query, fields[0])
+			fields = fields[1:]
+		}
+
+		to := fields[0]
 		status := 0
+		force := false
 
-		if len(fields) > 2 {
-			if parsed, err := strconv.Atoi(fields[2]); err == nil {
-				status = parsed
+		if len(fields) > 1 {
+			code, forced := strings.CutSuffix(fields[1], "!")
+			if parsed, err := strconv.Atoi(code); err == nil {
+				status, force = parsed, forced
+				fields = fields[1:]
+			}
+		}
+
+		var conditions map[string][]string
+		for _, field := range fields[1:] {
+			key, values, ok := strings.Cut(field, "=")
+			if !ok || key == "" {
+				continue
+			}
+			if conditions == nil {
+				conditions = make(map[string][]string)
+			}
+			key = strings.ToLower(key)
+			for value := range strings.SplitSeq(values, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					conditions[key] = append(conditions[key], value)
+				}
 			}
 		}
 
 		rules = append(rules, redirectRule{
-			from:   from,
-			to:     to,
-			status: status,
+			from:       from,
+			query:      query,
+			to:         to,
+			status:     status,
+			force:      force,
+			conditions: conditions,
 		})
 	}
 
@@ -514,3 +979,31 @@ func parseRedirectsFile(filePath string) ([]redirectRule, error) {
 
 	return rules, nil
 }
+
+// isRedirectQueryField reports whether a _redirects field between the source
+// and the target is a key=value query condition rather than the target.
+func isRedirectQueryField(field string) bool {
+	return strings.Contains(field, "=") && !strings.HasPrefix(field, "/") && !pathutil.IsExternalURL(field)
+}
+
+// addRedirectQuery adds the conditions in a raw key=value query to query,
+// keeping :name placeholders as written.
+func addRedirectQuery(query map[string]string, raw string) map[string]string {
+	for pair := range strings.SplitSeq(raw, "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		if key == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+		if query == nil {
+			query = make(map[string]string)
+		}
+		query[key] = value
+	}
+	return query
+}
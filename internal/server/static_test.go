@@ -0,0 +1,353 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchRedirectNamedPlaceholders(t *testing.T) {
+	rules := []redirectRule{
+		{from: "/blog/:year/:slug", to: "/posts/:slug", status: http.StatusMovedPermanently},
+		{from: "/docs/:version/*", to: "/v/:version/:splat", status: http.StatusFound},
+		{from: "/old/*", to: "/new/*", status: http.StatusFound},
+	}
+
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{path: "/blog/2024/hello", want: "/posts/hello", ok: true},
+		{path: "/blog/2024", ok: false},
+		{path: "/blog/2024/hello/extra", ok: false},
+		{path: "/docs/1.2/guide/intro", want: "/v/1.2/guide/intro", ok: true},
+		{path: "/docs/1.2", want: "/v/1.2/", ok: true},
+		{path: "/old/a/b", want: "/new/a/b", ok: true},
+	}
+	for _, tt := range tests {
+		action, ok := matchRedirect(httptest.NewRequest(http.MethodGet, tt.path, nil), rules)
+		if ok != tt.ok {
+			t.Fatalf("matchRedirect(%q) ok = %v, want %v", tt.path, ok, tt.ok)
+		}
+		if ok && action.target != tt.want {
+			t.Fatalf("matchRedirect(%q) target = %q, want %q", tt.path, action.target, tt.want)
+		}
+	}
+}
+
+func TestMatchRedirectQueryStrings(t *testing.T) {
+	dir := t.TempDir()
+	redirects := filepath.Join(dir, "_redirects")
+	if err := os.WriteFile(redirects, []byte(strings.Join([]string{
+		"/store id=:id      /items/:id     301",
+		"/search?q=:term    /find?query=:term&src=old",
+		"/promo             /sale?          302",
+		"/feed  format=atom /atom.xml       301",
+		"/old/*             /new/*          301",
+	}, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := parseRedirectsFile(redirects)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path, query string
+		want        string
+		ok          bool
+	}{
+		// preserved
+		{path: "/old/a", query: "utm=x&b=1", want: "/new/a?utm=x&b=1", ok: true},
+		{path: "/old/a", want: "/new/a", ok: true},
+		// matched and captured into the target, dropping the query
+		{path: "/store", query: "id=42&ref=home", want: "/items/42", ok: true},
+		{path: "/store", query: "ref=home", ok: false},
+		{path: "/feed", query: "format=atom", want: "/atom.xml", ok: true},
+		{path: "/feed", query: "format=rss", ok: false},
+		// rewritten
+		{path: "/search", query: "q=go&page=2", want: "/find?query=go&src=old", ok: true},
+		// escaped for where the capture lands
+		{path: "/search", query: "q=a%26src%3Devil+b", want: "/find?query=a%26src%3Devil+b&src=old", ok: true},
+		{path: "/store", query: "id=..%2F..%2Fadmin%3Fx", want: "/items/..%2F..%2Fadmin%3Fx", ok: true},
+		{path: "/old/a%3Fb/c", want: "/new/a%3Fb/c", ok: true},
+		// stripped
+		{path: "/promo", query: "utm=x", want: "/sale", ok: true},
+	}
+	for _, tt := range tests {
+		action, ok := matchRedirect(httptest.NewRequest(http.MethodGet, tt.path+"?"+tt.query, nil), rules)
+		if ok != tt.ok {
+			t.Fatalf("matchRedirect(%q, %q) ok = %v, want %v", tt.path, tt.query, ok, tt.ok)
+		}
+		if ok && action.target != tt.want {
+			t.Fatalf("matchRedirect(%q, %q) target = %q, want %q", tt.path, tt.query, action.target, tt.want)
+		}
+	}
+}
+
+func TestMatchRedirectConditionsFallThrough(t *testing.T) {
+	dir := t.TempDir()
+	redirects := filepath.Join(dir, "_redirects")
+	if err := os.WriteFile(redirects, []byte(strings.Join([]string{
+		"/  /fr/    302  Language=fr",
+		"/  /beta/  302  Cookie=beta,preview",
+		"/  /us/    302  Country=us",
+		"/  /ja/    Language=ja",
+		"/  /en/    302",
+	}, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := parseRedirectsFile(redirects)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		language string
+		cookie   string
+		want     string
+	}{
+		{language: "fr-CA, en;q=0.8", want: "/fr/"},
+		{language: "fr;q=0, en", want: "/en/"},
+		{language: "frr", want: "/en/"},
+		{language: "en", cookie: "preview=1", want: "/beta/"},
+		{language: "ja", want: "/ja/"},
+		{want: "/en/"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.language != "" {
+			req.Header.Set("Accept-Language", tt.language)
+		}
+		if tt.cookie != "" {
+			req.Header.Set("Cookie", tt.cookie)
+		}
+		action, ok := matchRedirect(req, rules)
+		if !ok || action.target != tt.want {
+			t.Fatalf("language %q, cookie %q: target = %q (ok %v), want %q", tt.language, tt.cookie, action.target, ok, tt.want)
+		}
+	}
+}
+
+func TestStaticHandlerRedirectKeepsQuery(t *testing.T) {
+	dist := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dist, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"_redirects":      "/old /new 301\n",
+		"docs/index.html": "docs",
+	} {
+		if err := os.WriteFile(filepath.Join(dist, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewStaticHandler(dist, StaticOptions{})
+
+	for path, want := range map[string]string{
+		"/old?a=1":  "/new?a=1",
+		"/docs?a=1": "/docs/?a=1",
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != want {
+			t.Fatalf("GET %s = %d to %q, want 301 to %q", path, rec.Code, rec.Header().Get("Location"), want)
+		}
+	}
+}
+
+func TestStaticHandlerForcedRedirectShadowsFiles(t *testing.T) {
+	dist := t.TempDir()
+	for name, content := range map[string]string{
+		"_redirects": "/kept.html /new 301\n/moved.html /new 301!\n/* /app.html 200\n",
+		"kept.html":  "kept",
+		"moved.html": "moved",
+		"app.html":   "app",
+	} {
+		if err := os.WriteFile(filepath.Join(dist, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewStaticHandler(dist, StaticOptions{})
+
+	// Files win over unforced rules.
+	for path, want := range map[string]string{
+		"/kept.html": "kept",
+		"/missing":   "app",
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != want {
+			t.Fatalf("GET %s = %d %q, want %q", path, rec.Code, rec.Body.String(), want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/moved.html", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/new" {
+		t.Fatalf("GET /moved.html = %d to %q, want 301 to /new", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestSubstituteParamsLeavesUnknownTokens(t *testing.T) {
+	got := substituteParams("https://example.com:8080/:a/:ab/:missing", map[string]string{"a": "1", "ab": "2"})
+	if want := "https://example.com:8080/1/2/:missing"; got != want {
+		t.Fatalf("substituteParams() = %q, want %q", got, want)
+	}
+}
+
+func TestStaticHandlerServesPrecompressedSiblings(t *testing.T) {
+	dist := t.TempDir()
+	for name, content := range map[string]string{
+		"a.css":    "plain",
+		"a.css.br": "brotli",
+		"a.css.gz": "gzip",
+		"b.js":     "plain js",
+	} {
+		if err := os.WriteFile(filepath.Join(dist, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewStaticHandler(dist, StaticOptions{})
+
+	tests := []struct {
+		path     string
+		accept   string
+		body     string
+		encoding string
+	}{
+		{path: "/a.css", accept: "gzip, br", body: "brotli", encoding: "br"},
+		{path: "/a.css", accept: "gzip, br;q=0", body: "gzip", encoding: "gzip"},
+		{path: "/a.css", accept: "", body: "plain"},
+		{path: "/a.css.br", accept: "br", body: "brotli"},
+		{path: "/b.js", accept: "br", body: "plain js"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept-Encoding", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Body.String() != tt.body {
+			t.Fatalf("GET %s (%q) body = %q, want %q", tt.path, tt.accept, rec.Body.String(), tt.body)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Fatalf("GET %s (%q) Content-Encoding = %q, want %q", tt.path, tt.accept, got, tt.encoding)
+		}
+		if tt.encoding != "" && !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") {
+			t.Fatalf("GET %s Content-Type = %q, want text/css", tt.path, rec.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestStaticHandlerETags(t *testing.T) {
+	dist := t.TempDir()
+	for name, content := range map[string]string{
+		"a.css":    "body{}",
+		"404.html": "missing",
+		"_headers": "/a.css\n  Cache-Control: public, max-age=60\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dist, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, contentETags := range []bool{false, true} {
+		h := NewStaticHandler(dist, StaticOptions{ContentETags: contentETags})
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a.css", nil))
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || etag == "" {
+			t.Fatalf("content=%v: GET /a.css = %d, ETag %q; want 200 with an ETag", contentETags, rec.Code, etag)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/a.css", nil)
+		req.Header.Set("If-None-Match", etag)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Fatalf("content=%v: matching If-None-Match = %d with %d body bytes, want empty 304", contentETags, rec.Code, rec.Body.Len())
+		}
+		if got := rec.Header().Get("Cache-Control"); got != "public, max-age=60" {
+			t.Fatalf("content=%v: 304 Cache-Control = %q, want the _headers value", contentETags, got)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/a.css", nil)
+		req.Header.Set("If-None-Match", `"stale"`)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.String() != "body{}" {
+			t.Fatalf("content=%v: stale If-None-Match = %d %q, want 200 with body", contentETags, rec.Code, rec.Body.String())
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/missing", nil)
+		req.Header.Set("If-None-Match", "*")
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotFound || rec.Header().Get("ETag") != "" || rec.Body.String() != "missing" {
+			t.Fatalf("content=%v: missing path = %d %q, ETag %q; want the 404 page without an ETag", contentETags, rec.Code, rec.Body.String(), rec.Header().Get("ETag"))
+		}
+	}
+}
+
+func TestStaticHandlerAppliesMostSpecificHeaderRuleLast(t *testing.T) {
+	dist := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dist, "admin", "users"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	headers := strings.Join([]string{
+		"/admin/users/*",
+		"  X-Robots-Tag: none",
+		"",
+		"/admin/*",
+		"  Cache-Control: no-store",
+		"  X-Robots-Tag: noindex",
+		"",
+		"/*",
+		"  Cache-Control: public, max-age=60",
+		"  X-Frame-Options: DENY",
+		"",
+		"/admin/:section/*",
+		"  X-Section: yes",
+		"  X-Robots-Tag: nofollow",
+	}, "\n")
+	for name, content := range map[string]string{
+		"_headers":              headers,
+		"index.html":            "home",
+		"admin/users/list.html": "users",
+	} {
+		if err := os.WriteFile(filepath.Join(dist, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewStaticHandler(dist, StaticOptions{})
+
+	tests := []struct {
+		path string
+		want map[string]string
+	}{
+		{path: "/", want: map[string]string{"Cache-Control": "public, max-age=60", "X-Frame-Options": "DENY"}},
+		{path: "/admin/users/list.html", want: map[string]string{
+			"Cache-Control":   "no-store",
+			"X-Frame-Options": "DENY",
+			"X-Section":       "yes",
+			"X-Robots-Tag":    "none",
+		}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		for key, want := range tt.want {
+			if got := rec.Header().Get(key); got != want {
+				t.Fatalf("GET %s %s = %q, want %q", tt.path, key, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	selfSignedValidity = 365 * 24 * time.Hour
+	selfSignedRenew    = 24 * time.Hour
+)
+
+// TLSOptions enables HTTPS for the dev server. When CertFile and KeyFile are
+// empty a self-signed certificate for localhost is generated and cached under
+// the OS temp directory.
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+}
+
+func (o *TLSOptions) files() (certFile, keyFile string, err error) {
+	switch {
+	case o.CertFile != "" && o.KeyFile != "":
+		return o.CertFile, o.KeyFile, nil
+	case o.CertFile != "" || o.KeyFile != "":
+		return "", "", errors.New("tls certificate and key must be set together")
+	default:
+		return SelfSignedCert(filepath.Join(os.TempDir(), "shizuka-tls"))
+	}
+}
+
+// SelfSignedCert returns a localhost certificate and key in dir, generating
+// them if they are missing or close to expiry.
+func SelfSignedCert(dir string) (certFile, keyFile string, err error) {
+	certFile = filepath.Join(dir, "localhost.pem")
+	keyFile = filepath.Join(dir, "localhost-key.pem")
+	if certUsable(certFile, keyFile) {
+		return certFile, keyFile, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", fmt.Errorf("tls cache %q: %w", dir, err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost", Organization: []string{"shizuka dev server"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return "", "", err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return "", "", fmt.Errorf("tls key %q: %w", keyFile, err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return "", "", fmt.Errorf("tls certificate %q: %w", certFile, err)
+	}
+	return certFile, keyFile, nil
+}
+
+func certUsable(certFile, keyFile string) bool {
+	if _, err := os.Stat(keyFile); err != nil {
+		return false
+	}
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return false
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	return time.Now().Add(selfSignedRenew).Before(cert.NotAfter)
+}
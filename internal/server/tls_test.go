@@ -0,0 +1,81 @@
+package server
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReloadStreamOverSelfSignedTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, err := SelfSignedCert(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _, err := SelfSignedCert(dir); err != nil || again != certFile {
+		t.Fatalf("SelfSignedCert() = %q, %v; want cached %q", again, err, certFile)
+	}
+	if after, _ := os.Stat(certFile); !after.ModTime().Equal(before.ModTime()) {
+		t.Fatal("cached certificate was regenerated")
+	}
+
+	hub := NewReloadHub()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: hub}
+	go func() { _ = srv.ServeTLS(listener, certFile, keyFile) }()
+	defer srv.Close()
+
+	pem, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(pem)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	resp, err := client.Get("https://localhost:" + port + reloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q, want text/event-stream", ct)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				hub.Broadcast("reload")
+			}
+		}
+	}()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "data: reload" {
+			return
+		}
+	}
+	t.Fatalf("reload event not received: %v", scanner.Err())
+}
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"path/filepath"
 
 	"github.com/olimci/shizuka/internal/config"
@@ -57,3 +59,46 @@ func lazySend[T any](ch chan<- T, value T) {
 	default:
 	}
 }
+
+// validateListenAddr checks that the host in addr is an IP or a name that
+// resolves, so a typo fails with a clear error rather than a bind failure.
+func validateListenAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("dev server address %q: %w", addr, err)
+	}
+	if host == "" || net.ParseIP(host) != nil {
+		return nil
+	}
+	if _, err := net.LookupHost(host); err != nil {
+		return fmt.Errorf("dev server host %q does not resolve: %w", host, err)
+	}
+	return nil
+}
+
+func isUnspecifiedHost(host string) bool {
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// primaryIPv4 returns the first non-loopback IPv4 address of the machine, or
+// nil if there is none.
+func primaryIPv4() net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip := ipNet.IP.To4(); ip != nil && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() {
+			return ip
+		}
+	}
+	return nil
+}
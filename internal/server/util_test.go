@@ -0,0 +1,19 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateListenAddr(t *testing.T) {
+	for _, addr := range []string{":6767", "0.0.0.0:6767", "127.0.0.1:6767", "[::1]:6767", "localhost:6767"} {
+		if err := validateListenAddr(addr); err != nil {
+			t.Fatalf("validateListenAddr(%q) = %v, want nil", addr, err)
+		}
+	}
+
+	err := validateListenAddr("no-such-host.invalid:6767")
+	if err == nil || !strings.Contains(err.Error(), "does not resolve") {
+		t.Fatalf("validateListenAddr(unresolvable) = %v, want resolve error", err)
+	}
+}
@@ -18,25 +18,41 @@ func NewWatcher(configPath string, debounce time.Duration) (*Watcher, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file watcher: %w", err)
 	}
+	return newWatcher(notifyBackend{w}, configPath, debounce), nil
+}
+
+// NewPollingWatcher returns a watcher that stats the watched paths every
+// interval instead of relying on fsnotify, for filesystems that do not
+// deliver change events.
+func NewPollingWatcher(configPath string, debounce, interval time.Duration) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("poll interval must be positive (got %s)", interval)
+	}
+	return newWatcher(newPollBackend(interval), configPath, debounce), nil
+}
 
+func newWatcher(backend watchBackend, configPath string, debounce time.Duration) *Watcher {
 	return &Watcher{
-		watcher:    w,
+		watcher:    backend,
 		debounce:   debounce,
 		configPath: configPath,
 		Events:     make(chan WatchEvent, 64),
 		Errors:     make(chan error, 64),
-	}, nil
+	}
 }
 
 type Watcher struct {
 	Events chan WatchEvent
 	Errors chan error
 
-	watcher  *fsnotify.Watcher
+	watcher  watchBackend
 	debounce time.Duration
 
 	configPath string
 	watched    map[string]struct{}
+
+	// cfg is the most recently loaded config, consulted for ignore patterns.
+	cfg *config.Config
 }
 
 type WatchEvent struct {
@@ -50,6 +66,7 @@ func (w *Watcher) Start(ctx context.Context) error {
 		return fmt.Errorf("config file %q: %w", w.configPath, err)
 	}
 	if cfg, err := config.Load(w.configPath); err == nil {
+		w.cfg = cfg
 		paths, globs, err := cfg.WatchedPaths()
 		if err != nil {
 			lazySend(w.Errors, err)
@@ -118,7 +135,7 @@ func (w *Watcher) loop(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		case ev, ok := <-w.watcher.Events:
+		case ev, ok := <-w.watcher.Events():
 			if !ok {
 				return
 			}
@@ -127,6 +144,8 @@ func (w *Watcher) loop(ctx context.Context) {
 			}
 			if w.isConfigEvent(ev) {
 				w.rebuildWatches()
+			} else if w.ignored(ev.Name) {
+				continue
 			}
 			if ev.Op&fsnotify.Create == fsnotify.Create {
 				w.addDirectoryIfNeeded(ev.Name)
@@ -137,7 +156,7 @@ func (w *Watcher) loop(ctx context.Context) {
 			timer = nil
 			timerCh = nil
 			flush(fmt.Sprintf("file change (%s quiet)", w.debounce))
-		case err, ok := <-w.watcher.Errors:
+		case err, ok := <-w.watcher.Errors():
 			if !ok {
 				return
 			}
@@ -161,6 +180,12 @@ func (w *Watcher) addPath(root string) error {
 		if err != nil {
 			return err
 		}
+		if w.ignored(p) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		return w.addWatch(p)
 	})
 }
@@ -235,6 +260,7 @@ func (w *Watcher) rebuildWatches() {
 		return
 	}
 
+	w.cfg = cfg
 	w.removeAllWatches()
 	if err := w.addPath(w.configPath); err != nil {
 		lazySend(w.Errors, fmt.Errorf("config file %q: %w", w.configPath, err))
@@ -254,6 +280,15 @@ func (w *Watcher) isConfigEvent(ev fsnotify.Event) bool {
 	return filepath.Clean(ev.Name) == filepath.Clean(w.configPath)
 }
 
+// ignored reports whether p matches build.watch.ignore. The config file
+// itself is always watched.
+func (w *Watcher) ignored(p string) bool {
+	if w.cfg == nil || filepath.Clean(p) == filepath.Clean(w.configPath) {
+		return false
+	}
+	return w.cfg.WatchIgnored(p)
+}
+
 func (w *Watcher) addDirectoryIfNeeded(p string) {
 	info, err := os.Stat(p)
 	if err != nil || !info.IsDir() {
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatcherSkipsIgnoredPaths(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"static", "content/drafts", "data", "templates"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configPath := filepath.Join(root, "shizuka.jsonc")
+	if err := os.WriteFile(configPath, []byte(`{"build": {"watch": {"ignore": ["content/drafts/**", "**/*.tmp", "content/scratch"]}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWatcher(configPath, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := w.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	content := filepath.Join(root, "content")
+	if _, ok := w.watched[filepath.Join(content, "drafts")]; ok {
+		t.Fatal("ignored directory content/drafts is watched")
+	}
+	if _, ok := w.watched[content]; !ok {
+		t.Fatal("content directory is not watched")
+	}
+
+	if err := os.Mkdir(filepath.Join(content, "scratch"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(content, "notes.tmp"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	page := filepath.Join(content, "page.md")
+	if err := os.WriteFile(page, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-w.Events:
+		if !slices.Contains(ev.Paths, page) {
+			t.Fatalf("event paths = %v, want %s", ev.Paths, page)
+		}
+		for _, p := range ev.Paths {
+			if p != page {
+				t.Fatalf("event paths = %v, want only %s", ev.Paths, page)
+			}
+		}
+	case err := <-w.Errors:
+		t.Fatalf("watch error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for watch event")
+	}
+	if _, ok := w.watched[filepath.Join(content, "scratch")]; ok {
+		t.Fatal("ignored directory created after start is watched")
+	}
+}
+
+func TestPollingWatcherReportsChanges(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"static", "content", "data", "templates"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configPath := filepath.Join(root, "shizuka.jsonc")
+	if err := os.WriteFile(configPath, []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	page := filepath.Join(root, "content", "page.md")
+	if err := os.WriteFile(page, []byte("one"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewPollingWatcher(configPath, 10*time.Millisecond, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := w.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(want string) {
+		t.Helper()
+		select {
+		case ev := <-w.Events:
+			if !slices.Contains(ev.Paths, want) {
+				t.Fatalf("event paths = %v, want %s", ev.Paths, want)
+			}
+		case err := <-w.Errors:
+			t.Fatalf("watch error: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for an event on %s", want)
+		}
+	}
+
+	if err := os.WriteFile(page, []byte("two, longer"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	next(page)
+
+	created := filepath.Join(root, "content", "new.md")
+	if err := os.WriteFile(created, []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	next(created)
+
+	if err := os.Remove(page); err != nil {
+		t.Fatal(err)
+	}
+	next(page)
+}
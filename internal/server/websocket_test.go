@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebsocketAccept(t *testing.T) {
+	// The worked example from RFC 6455 section 1.3.
+	if got, want := websocketAccept("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Fatalf("websocketAccept = %q, want %q", got, want)
+	}
+}
+
+func TestReloadHubServesWebSocket(t *testing.T) {
+	hub := NewReloadHub()
+	srv := httptest.NewServer(http.HandlerFunc(hub.ServeWebSocket))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, _ = conn.Write([]byte("GET " + reloadWSPath + " HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Origin: http://localhost\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		hub.mu.RLock()
+		n := len(hub.clients)
+		hub.mu.RUnlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client never subscribed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	hub.Broadcast(reloadCSS)
+	hub.Broadcast(reloadFull)
+
+	var got []string
+	for {
+		head := make([]byte, 2)
+		if _, err := io.ReadFull(br, head); err != nil {
+			t.Fatal(err)
+		}
+		if head[1]&0x80 != 0 {
+			t.Fatal("server frame is masked")
+		}
+		payload := make([]byte, head[1]&0x7F)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			t.Fatal(err)
+		}
+		switch head[0] & 0x0F {
+		case wsOpPing:
+			continue
+		case wsOpText:
+			got = append(got, string(payload))
+			continue
+		case wsOpClose:
+		default:
+			t.Fatalf("unexpected opcode %#x", head[0]&0x0F)
+		}
+		break
+	}
+	if strings.Join(got, ",") != reloadCSS+","+reloadFull {
+		t.Fatalf("messages = %q, want %q then %q", got, reloadCSS, reloadFull)
+	}
+}
+
+func TestReloadHubRejectsPlainRequestOnWebSocket(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewReloadHub().ServeWebSocket(rec, httptest.NewRequest(http.MethodGet, reloadWSPath, nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestReloadHubRejectsCrossOriginWebSocket(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, reloadWSPath, nil)
+	req.Host = "localhost:8080"
+	req.Header.Set("Origin", "https://evil.example")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+
+	rec := httptest.NewRecorder()
+	NewReloadHub().ServeWebSocket(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestWSReloadClientWriteTimesOut(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	// Nothing reads from client, so the write can only end at its deadline.
+	ws := &WSReloadClient{conn: server, rw: bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), writeTimeout: 10 * time.Millisecond}
+	done := make(chan error, 1)
+	go func() { done <- ws.write(wsOpText, []byte(reloadFull)) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("write error = %v, want a deadline exceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("write to a client that never reads did not time out")
+	}
+}
+
+func TestInjectReloadScriptTransport(t *testing.T) {
+	sse := injectReloadScript("<body></body>", ReloadSSE)
+	if !strings.Contains(sse, "listenSSE();") || strings.Contains(sse, "listenWS();") {
+		t.Fatalf("sse script does not start the event stream:\n%s", sse)
+	}
+	ws := injectReloadScript("<body></body>", ReloadWebSocket)
+	if !strings.Contains(ws, "listenWS();") || !strings.Contains(ws, reloadWSPath) || !strings.Contains(ws, reloadPath) {
+		t.Fatalf("ws script does not start the websocket with an sse fallback:\n%s", ws)
+	}
+}
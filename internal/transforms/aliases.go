@@ -0,0 +1,65 @@
+package transforms
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+// AliasConflict is an alias that was not redirected because another page
+// already owns its path, either as its own path or as an earlier alias.
+type AliasConflict struct {
+	Alias string
+	Page  *Page
+	Owner *Page
+}
+
+// AliasRedirects returns a permanent redirect from each page alias to the
+// page. Aliases are read as site paths, with a leading slash added when
+// missing, and match page paths whether or not either has a trailing slash.
+// Pages that are not written contribute no aliases.
+func AliasRedirects(pages []*Page) ([]config.Redirect, []AliasConflict) {
+	owners := make(map[string]*Page, len(pages))
+	for _, page := range pages {
+		if page.Error == nil && !page.NoRender {
+			owners[aliasKey(page.Path)] = page
+		}
+	}
+
+	var redirects []config.Redirect
+	var conflicts []AliasConflict
+	for _, page := range pages {
+		if page.Error != nil || page.NoRender {
+			continue
+		}
+		for _, alias := range page.Aliases {
+			if alias == "" {
+				continue
+			}
+			from := "/" + strings.TrimLeft(alias, "/")
+			if owner, ok := owners[aliasKey(from)]; ok {
+				if owner != page {
+					conflicts = append(conflicts, AliasConflict{Alias: from, Page: page, Owner: owner})
+				}
+				continue
+			}
+			owners[aliasKey(from)] = page
+			redirects = append(redirects, config.Redirect{
+				From:   from,
+				To:     page.Path,
+				Status: http.StatusMovedPermanently,
+			})
+		}
+	}
+
+	slices.SortStableFunc(redirects, func(a, b config.Redirect) int {
+		return strings.Compare(a.From, b.From)
+	})
+	return redirects, conflicts
+}
+
+func aliasKey(p string) string {
+	return strings.TrimSuffix(p, "/")
+}
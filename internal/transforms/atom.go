@@ -0,0 +1,199 @@
+package transforms
+
+import (
+	"encoding/xml"
+	"slices"
+	"time"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   AtomPerson  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Summary   string      `xml:"summary,omitempty"`
+	Content   atomContent `xml:"content"`
+	Category  []atomCat   `xml:"category"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomCat struct {
+	Term string `xml:"term,attr"`
+}
+
+// AtomPerson is the feed author, taken from site.Params: either an "author"
+// string naming them, or an "author" map with "name", "email" and "url".
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+	URI   string `xml:"uri,omitempty"`
+}
+
+// AtomEntry is one page in the feed. ID is the page's canonical URL, which
+// stays stable across builds.
+type AtomEntry struct {
+	Title     string
+	ID        string
+	Link      string
+	Summary   string
+	Content   string
+	Tags      []string
+	Updated   time.Time
+	Published time.Time
+}
+
+// AtomTemplate is the Atom feed to render. Updated is the latest entry
+// update, or the build time for an empty feed. Self is the feed's own URL
+// and Alternate the site it describes.
+type AtomTemplate struct {
+	Title     string
+	Subtitle  string
+	ID        string
+	Self      string
+	Alternate string
+	Author    AtomPerson
+	Updated   time.Time
+	Entries   []AtomEntry
+}
+
+// BuildAtom selects feed entries using the same section, draft and limit
+// rules as BuildJSONFeed, newest update first.
+func BuildAtom(pages []*Page, site *Site, cfg *config.ConfigAtom) AtomTemplate {
+	include := feedFilter(cfg.Sections, cfg.IncludeDrafts)
+	entries := make([]AtomEntry, 0, len(pages))
+	for _, page := range pages {
+		if !include(page) {
+			continue
+		}
+
+		link := page.Canon
+		if link == "" {
+			link = page.Path
+		}
+
+		entries = append(entries, AtomEntry{
+			Title:     firstNonzero(page.RSS.Title, page.Title),
+			ID:        firstNonzero(page.RSS.GUID, link),
+			Link:      link,
+			Summary:   firstNonzero(page.RSS.Description, page.Description),
+			Content:   string(page.Body),
+			Tags:      slices.Clone(page.Tags),
+			Updated:   firstNonzero(page.Updated, page.Created, page.PubDate, site.BuildTime),
+			Published: page.Created,
+		})
+	}
+
+	slices.SortFunc(entries, func(a, b AtomEntry) int {
+		return b.Updated.Compare(a.Updated)
+	})
+	entries = limitFeed(entries, cfg.Limit)
+
+	updated := site.BuildTime
+	if len(entries) > 0 {
+		updated = entries[0].Updated
+	}
+
+	return AtomTemplate{
+		Title:     site.Title,
+		Subtitle:  site.Description,
+		ID:        siteAbsURL(site, "/"),
+		Self:      atomSelf(site, cfg),
+		Alternate: site.URL,
+		Author:    atomAuthor(site),
+		Updated:   updated,
+		Entries:   entries,
+	}
+}
+
+func atomSelf(site *Site, cfg *config.ConfigAtom) string {
+	if site.URL == "" || cfg.Path == "" {
+		return ""
+	}
+	return siteAbsURL(site, cfg.Path)
+}
+
+// atomAuthor reads the author from site.Params, falling back to the site
+// title since Atom requires every feed to name an author.
+func atomAuthor(site *Site) AtomPerson {
+	if author, ok := paramsAuthor(site.Params); ok {
+		return author
+	}
+	return AtomPerson{Name: site.Title}
+}
+
+// paramsAuthor reads an "author" param, either a string naming them or a map
+// with "name", "email" and "url".
+func paramsAuthor(params map[string]any) (AtomPerson, bool) {
+	switch author := params["author"].(type) {
+	case string:
+		if author != "" {
+			return AtomPerson{Name: author}, true
+		}
+	case map[string]any:
+		str := func(key string) string {
+			s, _ := author[key].(string)
+			return s
+		}
+		if name := str("name"); name != "" {
+			return AtomPerson{Name: name, Email: str("email"), URI: str("url")}, true
+		}
+	}
+	return AtomPerson{}, false
+}
+
+func RenderAtom(data AtomTemplate) (string, error) {
+	feed := atomFeed{
+		Title:    data.Title,
+		Subtitle: data.Subtitle,
+		ID:       data.ID,
+		Updated:  data.Updated.UTC().Format(time.RFC3339),
+		Author:   data.Author,
+	}
+	if data.Alternate != "" {
+		feed.Links = append(feed.Links, atomLink{Href: data.Alternate, Rel: "alternate", Type: "text/html"})
+	}
+	if data.Self != "" {
+		feed.Links = append(feed.Links, atomLink{Href: data.Self, Rel: "self", Type: "application/atom+xml"})
+	}
+	for _, entry := range data.Entries {
+		e := atomEntry{
+			Title:   entry.Title,
+			ID:      entry.ID,
+			Link:    atomLink{Href: entry.Link, Rel: "alternate", Type: "text/html"},
+			Updated: entry.Updated.UTC().Format(time.RFC3339),
+			Summary: entry.Summary,
+			Content: atomContent{Type: "html", Body: entry.Content},
+		}
+		if !entry.Published.IsZero() {
+			e.Published = entry.Published.UTC().Format(time.RFC3339)
+		}
+		for _, tag := range entry.Tags {
+			e.Category = append(e.Category, atomCat{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+
+	out, err := xml.Marshal(feed)
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(out), nil
+}
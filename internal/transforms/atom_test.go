@@ -0,0 +1,133 @@
+package transforms
+
+import (
+	"encoding/xml"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/olimci/shizuka/internal/config"
+	"github.com/olimci/shizuka/internal/frontmatter"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestRenderAtomMatchesGolden(t *testing.T) {
+	older := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	newer := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	site := &Site{
+		Title:       "Site",
+		Description: "Notes & essays",
+		URL:         "https://example.com",
+		BuildTime:   newer.Add(24 * time.Hour),
+		Params: map[string]any{
+			"author": map[string]any{"name": "Ada", "email": "ada@example.com", "url": "https://example.com/about/"},
+		},
+	}
+	edited := rssPage("Edited", "posts", older, false)
+	edited.Updated = newer.Add(time.Hour)
+	edited.Body = "<p>Fixed a <em>typo</em>.</p>"
+	edited.Tags = []string{"meta"}
+	pages := []*Page{
+		rssPage("Old", "posts", older.Add(-time.Hour), false),
+		edited,
+		rssPage("New", "posts", newer, false),
+		rssPage("Draft", "posts", newer, true),
+		rssPage("Page", "pages", newer, false),
+	}
+
+	data := BuildAtom(pages, site, &config.ConfigAtom{Path: "atom.xml", Sections: []string{"posts"}, Limit: 2})
+	out, err := RenderAtom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "atom.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(out), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != string(want) {
+		t.Fatalf("atom feed =\n%s\nwant\n%s", out, want)
+	}
+
+	validateAtom(t, out)
+}
+
+func TestBuildAtomPublishesCreatedDate(t *testing.T) {
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	updated := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	page := &Page{Path: "/post/"}
+	page.ApplyFrontmatter(frontmatter.Frontmatter{
+		Title:   "Post",
+		Section: "posts",
+		Created: created,
+		Updated: updated,
+		RSS:     frontmatter.RSSMeta{Include: true},
+	})
+
+	data := BuildAtom([]*Page{page}, &Site{URL: "https://example.com"}, &config.ConfigAtom{Sections: []string{"posts"}})
+	if len(data.Entries) != 1 {
+		t.Fatalf("entries = %+v, want one", data.Entries)
+	}
+	if entry := data.Entries[0]; !entry.Published.Equal(created) || !entry.Updated.Equal(updated) {
+		t.Fatalf("published, updated = %v, %v, want %v, %v", entry.Published, entry.Updated, created, updated)
+	}
+}
+
+// validateAtom checks the elements RFC 4287 requires: an id, title and
+// updated date on the feed and on every entry, a feed author and a self link.
+func validateAtom(t *testing.T, doc string) {
+	t.Helper()
+	type link struct {
+		Rel  string `xml:"rel,attr"`
+		Href string `xml:"href,attr"`
+	}
+	var feed struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Title   string   `xml:"title"`
+		Updated string   `xml:"updated"`
+		Author  []string `xml:"author>name"`
+		Links   []link   `xml:"link"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+			Links   []link `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal([]byte(doc), &feed); err != nil {
+		t.Fatalf("atom feed does not parse: %v", err)
+	}
+
+	checkDate := func(what, value string) {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			t.Fatalf("%s updated = %q, want an RFC 3339 date", what, value)
+		}
+	}
+	if feed.ID == "" || feed.Title == "" || len(feed.Author) != 1 || feed.Author[0] == "" {
+		t.Fatalf("feed = %+v, want id, title and author", feed)
+	}
+	checkDate("feed", feed.Updated)
+	rels := make(map[string]bool)
+	for _, l := range feed.Links {
+		rels[l.Rel] = l.Href != ""
+	}
+	if !rels["self"] || !rels["alternate"] {
+		t.Fatalf("feed links = %+v, want self and alternate", feed.Links)
+	}
+	for _, entry := range feed.Entries {
+		if entry.ID == "" || entry.Title == "" || len(entry.Links) == 0 {
+			t.Fatalf("entry = %+v, want id, title and link", entry)
+		}
+		checkDate("entry", entry.Updated)
+	}
+}
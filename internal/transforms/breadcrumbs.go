@@ -0,0 +1,74 @@
+package transforms
+
+import (
+	"path"
+	"slices"
+)
+
+// Crumb is one step of a page's breadcrumb trail. Path is empty for a
+// directory with no rendered index page, which themes should show as plain
+// text rather than a link.
+type Crumb struct {
+	Title string
+	Path  string
+}
+
+// ResolveBreadcrumbs sets each page's Breadcrumbs to the trail from the site
+// root index down to the page itself, through the index page of every
+// directory in between. A directory without an index page, or whose index is
+// not rendered, contributes an unlinked crumb titled from its name. The root
+// index page has an empty trail.
+func ResolveBreadcrumbs(pages []*Page, includeDrafts bool) {
+	indexes := make(map[string]*Page)
+	for _, page := range pages {
+		if page.Error == nil && page.IsIndex() {
+			indexes[path.Dir(page.ContentPath)] = page
+		}
+	}
+
+	crumb := func(dir string) (Crumb, bool) {
+		index := indexes[dir]
+		if index != nil && !index.NoRender && (includeDrafts || !index.Draft) {
+			return Crumb{Title: firstNonzero(index.Title, TitleFromPath(index.ContentPath)), Path: index.Path}, true
+		}
+		if dir == "." {
+			return Crumb{}, false
+		}
+		title := TitleFromPath(path.Join(dir, "index"))
+		if index != nil {
+			title = firstNonzero(index.Title, title)
+		}
+		return Crumb{Title: title}, true
+	}
+
+	for _, page := range pages {
+		if page.Error != nil {
+			continue
+		}
+		dir := path.Dir(page.ContentPath)
+		if page.IsIndex() {
+			if dir == "." {
+				page.Breadcrumbs = nil
+				continue
+			}
+			dir = path.Dir(dir)
+		}
+
+		var dirs []string
+		for ; ; dir = path.Dir(dir) {
+			dirs = append(dirs, dir)
+			if dir == "." {
+				break
+			}
+		}
+		slices.Reverse(dirs)
+
+		crumbs := make([]Crumb, 0, len(dirs)+1)
+		for _, dir := range dirs {
+			if c, ok := crumb(dir); ok {
+				crumbs = append(crumbs, c)
+			}
+		}
+		page.Breadcrumbs = append(crumbs, Crumb{Title: page.Title, Path: page.Path})
+	}
+}
@@ -0,0 +1,134 @@
+package transforms
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"slices"
+	"strings"
+
+	"github.com/tdewolff/parse/v2"
+	htmllex "github.com/tdewolff/parse/v2/html"
+)
+
+// CSPHashes holds the CSP source expressions for a page's inline blocks.
+type CSPHashes struct {
+	Scripts []string
+	Styles  []string
+}
+
+func (h CSPHashes) Empty() bool {
+	return len(h.Scripts) == 0 && len(h.Styles) == 0
+}
+
+// HashInlineBlocks returns 'sha256-...' source expressions for every inline
+// <script> and <style> element in an HTML document. Scripts with a src
+// attribute are external and skipped.
+func HashInlineBlocks(doc []byte) CSPHashes {
+	var hashes CSPHashes
+	lexer := htmllex.NewLexer(parse.NewInputBytes(doc))
+
+	var tag string
+	external := false
+	for {
+		tt, _ := lexer.Next()
+		switch tt {
+		case htmllex.ErrorToken:
+			return hashes
+		case htmllex.StartTagToken:
+			tag = strings.ToLower(string(lexer.Text()))
+			external = false
+		case htmllex.AttributeToken:
+			if tag == "script" && strings.EqualFold(string(lexer.AttrKey()), "src") {
+				external = true
+			}
+		case htmllex.StartTagCloseToken:
+			if tag != "script" && tag != "style" {
+				tag = ""
+				continue
+			}
+			body := []byte{}
+			if next, text := lexer.Next(); next == htmllex.TextToken {
+				body = bytes.Clone(text)
+			}
+			switch {
+			case tag == "style":
+				hashes.Styles = appendHash(hashes.Styles, body)
+			case !external:
+				hashes.Scripts = appendHash(hashes.Scripts, body)
+			}
+			tag = ""
+		}
+	}
+}
+
+func appendHash(hashes []string, body []byte) []string {
+	sum := sha256.Sum256(body)
+	hash := "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+	if slices.Contains(hashes, hash) {
+		return hashes
+	}
+	return append(hashes, hash)
+}
+
+// CSPPolicy appends the hashes to the script-src and style-src directives of
+// base, adding the directives with 'self' when base does not declare them.
+func CSPPolicy(base string, hashes CSPHashes) string {
+	var directives []string
+	for directive := range strings.SplitSeq(base, ";") {
+		if directive = strings.TrimSpace(directive); directive != "" {
+			directives = append(directives, directive)
+		}
+	}
+
+	extend := func(name string, sources []string) {
+		if len(sources) == 0 {
+			return
+		}
+		for i, directive := range directives {
+			if fields := strings.Fields(directive); len(fields) > 0 && strings.EqualFold(fields[0], name) {
+				directives[i] = directive + " " + strings.Join(sources, " ")
+				return
+			}
+		}
+		directives = append(directives, name+" 'self' "+strings.Join(sources, " "))
+	}
+	extend("script-src", hashes.Scripts)
+	extend("style-src", hashes.Styles)
+
+	return strings.Join(directives, "; ")
+}
+
+// ApplyCSP adds a Content-Security-Policy field to the header rule of every
+// path with inline hashes, creating rules for paths that have none.
+func ApplyCSP(rules []HeaderRule, base string, hashes map[string]CSPHashes) []HeaderRule {
+	byPath := make(map[string]int, len(rules))
+	for i, rule := range rules {
+		byPath[rule.Path] = i
+	}
+
+	paths := make([]string, 0, len(hashes))
+	for path, h := range hashes {
+		if !h.Empty() {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		field := HeaderField{Key: "Content-Security-Policy", Value: CSPPolicy(base, hashes[path])}
+		if i, ok := byPath[path]; ok {
+			rules[i].Fields = append(slices.DeleteFunc(rules[i].Fields, func(f HeaderField) bool {
+				return f.Key == field.Key
+			}), field)
+			continue
+		}
+		byPath[path] = len(rules)
+		rules = append(rules, HeaderRule{Path: path, Fields: []HeaderField{field}})
+	}
+
+	slices.SortStableFunc(rules, func(a, b HeaderRule) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return rules
+}
@@ -0,0 +1,112 @@
+package transforms
+
+import (
+	"fmt"
+	"maps"
+	"net/textproto"
+	"slices"
+	"strings"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+// HeaderRule is one path block in a `_headers` file.
+type HeaderRule struct {
+	Path   string
+	Fields []HeaderField
+}
+
+type HeaderField struct {
+	Key   string
+	Value string
+}
+
+// BuildHeaders merges config headers with page frontmatter headers. Rules and
+// fields are sorted so the output is stable between builds.
+func BuildHeaders(pages []*Page, cfg *config.ConfigHeaders, includeDrafts bool) []HeaderRule {
+	merge := make(map[string]struct{}, len(cfg.Merge))
+	for _, key := range cfg.Merge {
+		merge[textproto.CanonicalMIMEHeaderKey(key)] = struct{}{}
+	}
+
+	pageHeaders := make(map[string]map[string]string)
+	for _, page := range pages {
+		noindex := cfg.NoIndex && page.NoIndex
+		if page.Error != nil || (len(page.Headers) == 0 && !noindex) {
+			continue
+		}
+		if page.NoRender || page.Draft && !includeDrafts {
+			continue
+		}
+		headers := page.Headers
+		if noindex {
+			headers = maps.Clone(headers)
+			if headers == nil {
+				headers = make(map[string]string, 1)
+			}
+			headers["X-Robots-Tag"] = "noindex"
+		}
+		pageHeaders[page.Path] = headers
+	}
+
+	paths := make([]string, 0, len(cfg.Values)+len(pageHeaders))
+	for path := range cfg.Values {
+		paths = append(paths, path)
+	}
+	for path := range pageHeaders {
+		if _, ok := cfg.Values[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	rules := make([]HeaderRule, 0, len(paths))
+	for _, path := range paths {
+		low, high := cfg.Values[path], pageHeaders[path]
+		if cfg.Precedence == config.HeadersPrecedenceConfig {
+			low, high = high, low
+		}
+
+		values := make(map[string][]string)
+		for _, kvs := range []map[string]string{low, high} {
+			for key, value := range kvs {
+				key = textproto.CanonicalMIMEHeaderKey(key)
+				if _, ok := merge[key]; ok {
+					if !slices.Contains(values[key], value) {
+						values[key] = append(values[key], value)
+					}
+					continue
+				}
+				values[key] = []string{value}
+			}
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		rule := HeaderRule{Path: path}
+		for _, key := range keys {
+			for _, value := range values[key] {
+				rule.Fields = append(rule.Fields, HeaderField{Key: key, Value: value})
+			}
+		}
+		if len(rule.Fields) > 0 {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func RenderHeaders(rules []HeaderRule) string {
+	var b strings.Builder
+	for _, rule := range rules {
+		fmt.Fprintf(&b, "%s\n", rule.Path)
+		for _, field := range rule.Fields {
+			fmt.Fprintf(&b, "  %s: %s\n", field.Key, field.Value)
+		}
+		fmt.Fprintln(&b)
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package transforms
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+const JSONFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url,omitempty"`
+	Title         string   `json:"title,omitempty"`
+	ContentHTML   string   `json:"content_html"`
+	Summary       string   `json:"summary,omitempty"`
+	DatePublished string   `json:"date_published,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	sortDate      time.Time
+}
+
+// BuildJSONFeed selects feed items using the same section, draft and limit
+// rules as BuildRSS.
+func BuildJSONFeed(pages []*Page, site *Site, cfg *config.ConfigJSONFeed) JSONFeed {
+	include := feedFilter(cfg.Sections, cfg.IncludeDrafts)
+	items := make([]JSONFeedItem, 0, len(pages))
+	for _, page := range pages {
+		if !include(page) {
+			continue
+		}
+
+		pubDate := firstNonzero(page.PubDate, page.Updated, page.Created, site.BuildTime)
+
+		link := page.Canon
+		if link == "" {
+			link = page.Path
+		}
+
+		items = append(items, JSONFeedItem{
+			ID:            link,
+			URL:           link,
+			Title:         firstNonzero(page.RSS.Title, page.Title),
+			ContentHTML:   string(page.Body),
+			Summary:       firstNonzero(page.RSS.Description, page.Description),
+			DatePublished: pubDate.Format(time.RFC3339),
+			Tags:          slices.Clone(page.Tags),
+			sortDate:      pubDate,
+		})
+	}
+
+	slices.SortFunc(items, func(a, b JSONFeedItem) int {
+		return b.sortDate.Compare(a.sortDate)
+	})
+	items = limitFeed(items, cfg.Limit)
+
+	return JSONFeed{
+		Version:     JSONFeedVersion,
+		Title:       site.Title,
+		HomePageURL: site.URL,
+		Description: site.Description,
+		Items:       items,
+	}
+}
+
+func RenderJSONFeed(feed JSONFeed) (string, error) {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
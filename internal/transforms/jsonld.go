@@ -0,0 +1,75 @@
+package transforms
+
+import (
+	"encoding/json"
+	"html/template"
+	"time"
+)
+
+type jsonldPerson struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type jsonldDocument struct {
+	Context       string        `json:"@context"`
+	Type          string        `json:"@type"`
+	Headline      string        `json:"headline,omitempty"`
+	Name          string        `json:"name,omitempty"`
+	Description   string        `json:"description,omitempty"`
+	URL           string        `json:"url,omitempty"`
+	DatePublished string        `json:"datePublished,omitempty"`
+	DateModified  string        `json:"dateModified,omitempty"`
+	Author        *jsonldPerson `json:"author,omitempty"`
+	Keywords      []string      `json:"keywords,omitempty"`
+}
+
+// JSONLD returns a schema.org JSON-LD script block for the page: a WebSite
+// for the site root and an Article elsewhere, which a "jsonld_type" param
+// overrides (for example "BlogPosting"). The author is the page's "author"
+// param, falling back to the site's, read as for the Atom feed.
+func JSONLD(page PageTmpl, site SiteTmpl) template.HTML {
+	doc := jsonldDocument{
+		Context:     "https://schema.org",
+		Type:        "Article",
+		Description: page.Description,
+		URL:         firstNonzero(page.Canon, site.URL),
+	}
+	if page.Path == "/" {
+		doc.Type = "WebSite"
+		doc.Name = firstNonzero(site.Title, page.Title)
+		doc.Description = firstNonzero(page.Description, site.Description)
+	} else {
+		doc.Headline = page.Title
+		doc.DatePublished = jsonldDate(firstNonzero(page.Created, page.PubDate))
+		doc.DateModified = jsonldDate(firstNonzero(page.Updated, page.Created, page.PubDate))
+		doc.Keywords = page.Tags
+
+		author, ok := paramsAuthor(page.Params)
+		if !ok {
+			author, ok = paramsAuthor(site.Params)
+		}
+		if ok {
+			doc.Author = &jsonldPerson{Type: "Person", Name: author.Name, URL: author.URI}
+		}
+	}
+	if kind, ok := page.Params["jsonld_type"].(string); ok && kind != "" {
+		doc.Type = kind
+	}
+
+	// json.Marshal escapes <, > and &, so the block cannot close the script
+	// element early.
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return ""
+	}
+	return template.HTML(`<script type="application/ld+json">` + string(out) + `</script>`)
+}
+
+func jsonldDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
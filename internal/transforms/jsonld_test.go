@@ -0,0 +1,83 @@
+package transforms
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLD(t *testing.T) {
+	site := SiteTmpl{
+		Title:  "Site",
+		URL:    "https://example.com",
+		Params: map[string]any{"author": "Site Author"},
+	}
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		page PageTmpl
+		want map[string]any
+	}{
+		{
+			name: "article",
+			page: PageTmpl{Path: "/post/", Canon: "https://example.com/post/", Title: "Post </script>", Created: created},
+			want: map[string]any{
+				"@type":         "Article",
+				"headline":      "Post </script>",
+				"url":           "https://example.com/post/",
+				"datePublished": "2025-01-02T03:04:05Z",
+				"dateModified":  "2025-01-02T03:04:05Z",
+				"author":        map[string]any{"@type": "Person", "name": "Site Author"},
+			},
+		},
+		{
+			name: "type override and page author",
+			page: PageTmpl{Path: "/post/", Title: "Post", Params: map[string]any{
+				"jsonld_type": "BlogPosting",
+				"author":      map[string]any{"name": "Ada", "url": "https://ada.example"},
+			}},
+			want: map[string]any{
+				"@type":    "BlogPosting",
+				"headline": "Post",
+				"url":      "https://example.com",
+				"author":   map[string]any{"@type": "Person", "name": "Ada", "url": "https://ada.example"},
+			},
+		},
+		{
+			name: "site root",
+			page: PageTmpl{Path: "/", Canon: "https://example.com/", Title: "Home"},
+			want: map[string]any{
+				"@type": "WebSite",
+				"name":  "Site",
+				"url":   "https://example.com/",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := string(JSONLD(tt.page, site))
+			body, ok := strings.CutPrefix(out, `<script type="application/ld+json">`)
+			body, ok2 := strings.CutSuffix(body, `</script>`)
+			if !ok || !ok2 {
+				t.Fatalf("JSONLD() = %q, want a JSON-LD script block", out)
+			}
+			if strings.Contains(body, "</script>") {
+				t.Fatalf("JSONLD() = %q, closes the script early", out)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatal(err)
+			}
+			tt.want["@context"] = "https://schema.org"
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Fatalf("JSONLD() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package transforms
+
+import (
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+// PageLite is a reference to another page, enough for a link to it.
+type PageLite struct {
+	Lang        string
+	Title       string
+	Description string
+	Path        string
+	PubDate     time.Time
+}
+
+// Lite returns a PageLite referring to p.
+func (p *Page) Lite() *PageLite {
+	return &PageLite{Lang: p.Lang, Title: p.Title, Description: p.Description, Path: p.Path, PubDate: p.PubDate}
+}
+
+// PageLanguage returns the language of the page at content path rel, the
+// content path it is placed at and the key its translations share. A
+// language suffix (about.ja.md) or a top-level language directory
+// (ja/about.md) marks the language, and is dropped from the key; pages in a
+// language other than the default are placed under a directory named for
+// it. Without langs, rel is returned unchanged with no language.
+func PageLanguage(rel string, langs *config.ConfigContentLanguages) (lang, contentPath, key string) {
+	if langs == nil {
+		return "", rel, ""
+	}
+
+	dir, base := path.Split(rel)
+	ext := path.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	if suffix := path.Ext(name); suffix != "" && langs.Has(suffix[1:]) {
+		lang = suffix[1:]
+		name = strings.TrimSuffix(name, suffix)
+	}
+
+	dir = strings.TrimSuffix(dir, "/")
+	top, rest, _ := strings.Cut(dir, "/")
+	if top != "" && langs.Has(top) && (lang == "" || lang == top) {
+		lang = top
+		dir = rest
+	}
+	if lang == "" {
+		lang = langs.Default
+	}
+
+	key = path.Join(dir, name)
+	contentPath = path.Join(dir, name+ext)
+	if lang != langs.Default {
+		contentPath = path.Join(lang, contentPath)
+	}
+	return lang, contentPath, key
+}
+
+// ResolveTranslations links each page to the other pages sharing its
+// translation key, ordered as the languages are listed in langs. Pages that
+// are not written are left out, as are drafts unless includeDrafts is set.
+func ResolveTranslations(pages []*Page, langs *config.ConfigContentLanguages, includeDrafts bool) {
+	if langs == nil {
+		return
+	}
+
+	groups := make(map[string][]*Page)
+	for _, page := range pages {
+		if page.Error != nil || page.NoRender || page.Draft && !includeDrafts || page.TranslationKey == "" {
+			continue
+		}
+		groups[page.TranslationKey] = append(groups[page.TranslationKey], page)
+	}
+
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		slices.SortStableFunc(group, func(a, b *Page) int {
+			return slices.Index(langs.Codes, a.Lang) - slices.Index(langs.Codes, b.Lang)
+		})
+		for _, page := range group {
+			page.Translations = make([]*PageLite, 0, len(group)-1)
+			for _, other := range group {
+				if other != page {
+					page.Translations = append(page.Translations, other.Lite())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package transforms
+
+import (
+	"html/template"
+	"slices"
+	"strings"
+)
+
+// OGTags returns Open Graph and Twitter card meta tags for the page. Title,
+// description and image fall back to the site's when the page has none: the
+// description to the page summary, then site.Description, and the image (a
+// "image" param) to the site's "image" param, resolved against site.URL.
+// Regular pages are articles; the root and section index pages are websites.
+func OGTags(page PageTmpl, site SiteTmpl) template.HTML {
+	article := slices.ContainsFunc(site.RegularPages, func(regular PageTmpl) bool {
+		return regular.Path == page.Path
+	})
+	return ogTags(page, site, article)
+}
+
+// OGTagsFunc returns OGTags for use as a template function, looking pages up
+// in a set of the regular page paths among pages built once rather than
+// scanning site.RegularPages on every call.
+func OGTagsFunc(pages []*Page) func(PageTmpl, SiteTmpl) template.HTML {
+	regular := make(map[string]struct{}, len(pages))
+	for _, page := range pages {
+		if !page.IsIndex() {
+			regular[page.Path] = struct{}{}
+		}
+	}
+	return func(page PageTmpl, site SiteTmpl) template.HTML {
+		_, article := regular[page.Path]
+		return ogTags(page, site, article)
+	}
+}
+
+func ogTags(page PageTmpl, site SiteTmpl, article bool) template.HTML {
+	image := paramString(page.Params, "image")
+	if image == "" {
+		image = paramString(site.Params, "image")
+	}
+	if image != "" {
+		image = absURL(site.URL, image)
+	}
+
+	kind := "website"
+	if article {
+		kind = "article"
+	}
+
+	var b strings.Builder
+	meta := func(attr, key, value string) {
+		if value == "" {
+			return
+		}
+		b.WriteString(`<meta ` + attr + `="` + key + `" content="` + template.HTMLEscapeString(value) + `">` + "\n")
+	}
+	meta("property", "og:title", firstNonzero(page.Title, site.Title))
+	meta("property", "og:description", firstNonzero(page.Description, page.SummaryText, site.Description))
+	meta("property", "og:type", kind)
+	meta("property", "og:url", firstNonzero(page.Canon, site.URL))
+	meta("property", "og:site_name", site.Title)
+	meta("property", "og:image", image)
+	if image != "" {
+		meta("name", "twitter:card", "summary_large_image")
+	} else {
+		meta("name", "twitter:card", "summary")
+	}
+	return template.HTML(b.String())
+}
+
+func paramString(params map[string]any, key string) string {
+	s, _ := params[key].(string)
+	return s
+}
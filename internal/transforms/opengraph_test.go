@@ -0,0 +1,70 @@
+package transforms
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOGTags(t *testing.T) {
+	post := PageTmpl{
+		Path:        "/posts/hello/",
+		Canon:       "https://example.com/posts/hello/",
+		Title:       `Hello "world"`,
+		SummaryText: "A summary.",
+		Params:      map[string]any{"image": "/img/hello.png"},
+	}
+	site := SiteTmpl{
+		Title:        "Site",
+		Description:  "Site description.",
+		URL:          "https://example.com",
+		Params:       map[string]any{"image": "/img/default.png"},
+		RegularPages: []PageTmpl{post},
+	}
+
+	got := string(OGTags(post, site))
+	for _, want := range []string{
+		`<meta property="og:title" content="Hello &#34;world&#34;">`,
+		`<meta property="og:description" content="A summary.">`,
+		`<meta property="og:type" content="article">`,
+		`<meta property="og:url" content="https://example.com/posts/hello/">`,
+		`<meta property="og:image" content="https://example.com/img/hello.png">`,
+		`<meta name="twitter:card" content="summary_large_image">`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("OGTags(post) = %q, missing %q", got, want)
+		}
+	}
+
+	got = string(OGTags(PageTmpl{Path: "/", Canon: "https://example.com/"}, site))
+	for _, want := range []string{
+		`<meta property="og:title" content="Site">`,
+		`<meta property="og:description" content="Site description.">`,
+		`<meta property="og:type" content="website">`,
+		`<meta property="og:image" content="https://example.com/img/default.png">`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("OGTags(home) = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestOGTagsFuncMatchesOGTags(t *testing.T) {
+	pages := []*Page{
+		{ContentPath: "index.md", Path: "/"},
+		{ContentPath: "posts/index.md", Path: "/posts/"},
+		{ContentPath: "posts/first.md", Path: "/posts/first/"},
+	}
+	site := &Site{Title: "Site"}
+	site.SetPages(pages)
+	siteTmpl := site.Tmpl()
+
+	ogTags := OGTagsFunc(pages)
+	for _, page := range pages {
+		if got, want := ogTags(page.Tmpl(), siteTmpl), OGTags(page.Tmpl(), siteTmpl); got != want {
+			t.Fatalf("OGTagsFunc(%s) = %q, want %q", page.Path, got, want)
+		}
+	}
+	if got := string(ogTags(pages[2].Tmpl(), siteTmpl)); !strings.Contains(got, `content="article"`) {
+		t.Fatalf("OGTagsFunc(/posts/first/) = %q, want an article", got)
+	}
+}
@@ -7,20 +7,58 @@ import (
 	"io/fs"
 	"path"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/olimci/shizuka/internal/config"
 	"github.com/olimci/shizuka/internal/frontmatter"
 	"github.com/olimci/shizuka/internal/utils/decodeutil"
 )
 
 var ErrUnsupportedContentType = errors.New("unsupported content type")
 
+// ContentHandler returns the handler for a content file extension, looked up
+// in extensions or, when nil, the default set.
+func ContentHandler(ext string, extensions map[string]string) (string, bool) {
+	if extensions == nil {
+		extensions = config.DefaultContentExtensions()
+	}
+	handler, ok := extensions[strings.ToLower(ext)]
+	return handler, ok
+}
+
 type dataPage struct {
 	frontmatter.Frontmatter `toml:",inline" yaml:",inline" json:",inline"`
 	Body                    string `toml:"body" yaml:"body" json:"body"`
 	BodyMarkdown            bool   `toml:"body_markdown" yaml:"body_markdown" json:"body_markdown"`
 }
 
-func BuildPage(sourceFS fs.FS, source string, defaultSection string, defaults frontmatter.Defaults, bySection map[string]frontmatter.Defaults) (*Page, error) {
+// TitleFromPath derives a page title from a content path: the file name, or
+// the directory name for index pages, with separators turned into spaces and
+// the first letter capitalised. The site root index has no derived title.
+func TitleFromPath(contentPath string) string {
+	dir, base := path.Split(path.Clean(contentPath))
+	name := strings.TrimSuffix(base, path.Ext(base))
+	if name == "index" {
+		if dir == "" {
+			return ""
+		}
+		name = path.Base(dir)
+	}
+
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || unicode.IsSpace(r)
+	}), " ")
+	first, size := utf8.DecodeRuneInString(name)
+	if size == 0 {
+		return ""
+	}
+	return string(unicode.ToUpper(first)) + name[size:]
+}
+
+// BuildPage reads a content page from sourceFS. extensions maps file
+// extensions to content handlers; nil uses config.DefaultContentExtensions.
+func BuildPage(sourceFS fs.FS, source string, extensions map[string]string, defaultSection string, defaults frontmatter.Defaults, bySection map[string]frontmatter.Defaults) (*Page, error) {
 	source = path.Clean(source)
 	doc, err := fs.ReadFile(sourceFS, source)
 	if err != nil {
@@ -33,8 +71,9 @@ func BuildPage(sourceFS fs.FS, source string, defaultSection string, defaults fr
 		preprocess string
 	)
 
-	switch ext := strings.ToLower(path.Ext(source)); ext {
-	case ".md", ".html":
+	ext := strings.ToLower(path.Ext(source))
+	switch handler, _ := ContentHandler(ext, extensions); handler {
+	case config.ContentHandlerMarkdown, config.ContentHandlerPassthrough:
 		fm, extractedBody, err := frontmatter.ExtractWithDefaults(doc, defaultSection, defaults, bySection)
 		if err != nil {
 			return nil, err
@@ -42,7 +81,7 @@ func BuildPage(sourceFS fs.FS, source string, defaultSection string, defaults fr
 		meta = *fm
 		body = extractedBody
 
-		if ext == ".md" {
+		if handler == config.ContentHandlerMarkdown {
 			preprocess = "markdown"
 		}
 
@@ -56,11 +95,19 @@ func BuildPage(sourceFS fs.FS, source string, defaultSection string, defaults fr
 		if err != nil {
 			return nil, err
 		}
+		defaultParams, defaultTemplate := base.Params, base.Template
+		base.Params, base.Template = nil, ""
 		dp := dataPage{Frontmatter: base}
 		if err := decodeutil.UnmarshalExt(ext, doc, &dp); err != nil {
 			return nil, err
 		}
 		meta = dp.Frontmatter
+		meta.OwnParams = meta.Params
+		meta.Params = frontmatter.MergeParams(defaultParams, meta.Params)
+		meta.OwnTemplate = meta.Template
+		if meta.Template == "" {
+			meta.Template = defaultTemplate
+		}
 		body = []byte(dp.Body)
 
 		if dp.BodyMarkdown {
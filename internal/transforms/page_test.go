@@ -4,9 +4,12 @@ import (
 	"errors"
 	"html/template"
 	"io/fs"
+	"slices"
 	"testing"
 	"testing/fstest"
+	"time"
 
+	"github.com/olimci/shizuka/internal/config"
 	"github.com/olimci/shizuka/internal/frontmatter"
 )
 
@@ -15,7 +18,7 @@ func TestBuildPageFromMarkdown(t *testing.T) {
 		"posts/hello.md": &fstest.MapFile{
 			Data: []byte("+++\ntitle = \"Hello\"\ntags = [\"go\"]\n+++\n# Body"),
 		},
-	}, "posts/hello.md", "pages", frontmatter.Defaults{Template: "page"}, nil)
+	}, "posts/hello.md", nil, "pages", frontmatter.Defaults{Template: "page"}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -42,7 +45,7 @@ func TestBuildPageFromHTML(t *testing.T) {
 		"about.html": &fstest.MapFile{
 			Data: []byte("---\ntitle: About\n---\n<p>About</p>"),
 		},
-	}, "about.html", "pages", frontmatter.Defaults{}, nil)
+	}, "about.html", nil, "pages", frontmatter.Defaults{}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -68,7 +71,7 @@ func TestBuildPageFromDataFile(t *testing.T) {
 				"body_markdown": true
 			}`),
 		},
-	}, "note.jsonc", "pages", frontmatter.Defaults{}, map[string]frontmatter.Defaults{
+	}, "note.jsonc", nil, "pages", frontmatter.Defaults{}, map[string]frontmatter.Defaults{
 		"notes": {Template: "note"},
 	})
 	if err != nil {
@@ -89,7 +92,7 @@ func TestBuildPageFromDataFile(t *testing.T) {
 func TestBuildPageRejectsUnsupportedContentType(t *testing.T) {
 	_, err := BuildPage(fstest.MapFS{
 		"image.png": &fstest.MapFile{Data: []byte("png")},
-	}, "image.png", "pages", frontmatter.Defaults{}, nil)
+	}, "image.png", nil, "pages", frontmatter.Defaults{}, nil)
 
 	if !errors.Is(err, ErrUnsupportedContentType) {
 		t.Fatalf("err = %v, want ErrUnsupportedContentType", err)
@@ -97,8 +100,129 @@ func TestBuildPageRejectsUnsupportedContentType(t *testing.T) {
 }
 
 func TestBuildPageReturnsReadError(t *testing.T) {
-	_, err := BuildPage(fstest.MapFS{}, "missing.md", "pages", frontmatter.Defaults{}, nil)
+	_, err := BuildPage(fstest.MapFS{}, "missing.md", nil, "pages", frontmatter.Defaults{}, nil)
 	if !errors.Is(err, fs.ErrNotExist) {
 		t.Fatalf("err = %v, want fs.ErrNotExist", err)
 	}
 }
+
+func TestResolveNoIndexMarksDraftsAndFuturePages(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	cfg := config.ConfigContentNoIndex{Drafts: true, Future: true}
+
+	draft := &Page{Draft: true, Created: now.Add(-time.Hour)}
+	draft.ResolveNoIndex(now, cfg)
+	if !draft.Tmpl().NoIndex {
+		t.Fatal("draft page NoIndex = false, want true")
+	}
+
+	future := &Page{Created: now.Add(time.Hour)}
+	future.ResolveNoIndex(now, cfg)
+	if !future.NoIndex {
+		t.Fatal("future page NoIndex = false, want true")
+	}
+
+	published := &Page{Created: now.Add(-time.Hour)}
+	published.ResolveNoIndex(now, cfg)
+	if published.NoIndex {
+		t.Fatal("published page NoIndex = true, want false")
+	}
+
+	draft = &Page{Draft: true}
+	draft.ResolveNoIndex(now, config.ConfigContentNoIndex{})
+	if draft.NoIndex {
+		t.Fatal("draft page NoIndex = true with drafts disabled, want false")
+	}
+}
+
+func TestBuildPageFromConfiguredMarkdownExtension(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Content.Extensions = map[string]string{"markdown": config.ContentHandlerMarkdown}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	sourceFS := fstest.MapFS{
+		"posts/hello.markdown": &fstest.MapFile{Data: []byte("---\ntitle: Hello\n---\n# Body")},
+	}
+	page, err := BuildPage(sourceFS, "posts/hello.markdown", cfg.Content.Extensions, "pages", frontmatter.Defaults{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "Hello" {
+		t.Fatalf("title = %q, want Hello", page.Title)
+	}
+	if page.Preprocess != "markdown" {
+		t.Fatalf("preprocess = %q, want markdown", page.Preprocess)
+	}
+
+	if _, err := BuildPage(sourceFS, "posts/hello.markdown", nil, "pages", frontmatter.Defaults{}, nil); !errors.Is(err, ErrUnsupportedContentType) {
+		t.Fatalf("err without extension config = %v, want ErrUnsupportedContentType", err)
+	}
+}
+
+func TestTitleFromPath(t *testing.T) {
+	tests := map[string]string{
+		"notes/quick-note.md":      "Quick note",
+		"notes/meeting_2025 q1.md": "Meeting 2025 q1",
+		"guides/setup/index.md":    "Setup",
+		"index.md":                 "",
+		"élan.md":                  "Élan",
+	}
+	for input, want := range tests {
+		if got := TitleFromPath(input); got != want {
+			t.Fatalf("TitleFromPath(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestPageLanguage(t *testing.T) {
+	langs := &config.ConfigContentLanguages{Default: "en", Codes: []string{"en", "ja"}}
+	tests := []struct {
+		rel, lang, contentPath, key string
+	}{
+		{"about.md", "en", "about.md", "about"},
+		{"about.en.md", "en", "about.md", "about"},
+		{"about.ja.md", "ja", "ja/about.md", "about"},
+		{"ja/about.md", "ja", "ja/about.md", "about"},
+		{"en/about.md", "en", "about.md", "about"},
+		{"ja/about.ja.md", "ja", "ja/about.md", "about"},
+		{"docs/index.ja.md", "ja", "ja/docs/index.md", "docs/index"},
+		{"notes/v1.2.md", "en", "notes/v1.2.md", "notes/v1.2"},
+	}
+	for _, tt := range tests {
+		lang, contentPath, key := PageLanguage(tt.rel, langs)
+		if lang != tt.lang || contentPath != tt.contentPath || key != tt.key {
+			t.Fatalf("PageLanguage(%q) = %q, %q, %q, want %q, %q, %q", tt.rel, lang, contentPath, key, tt.lang, tt.contentPath, tt.key)
+		}
+	}
+
+	if lang, contentPath, key := PageLanguage("about.ja.md", nil); lang != "" || contentPath != "about.ja.md" || key != "" {
+		t.Fatalf("PageLanguage without languages = %q, %q, %q", lang, contentPath, key)
+	}
+}
+
+func TestResolveTranslationsOrdersByConfiguredLanguages(t *testing.T) {
+	langs := &config.ConfigContentLanguages{Default: "en", Codes: []string{"en", "ja", "fr"}}
+	fr := &Page{Lang: "fr", TranslationKey: "about", Title: "À propos", Path: "/fr/about/"}
+	ja := &Page{Lang: "ja", TranslationKey: "about", Title: "概要", Path: "/ja/about/"}
+	en := &Page{Lang: "en", TranslationKey: "about", Title: "About", Path: "/about/"}
+	draft := &Page{Lang: "ja", TranslationKey: "contact", Draft: true, Path: "/ja/contact/"}
+	contact := &Page{Lang: "en", TranslationKey: "contact", Path: "/contact/"}
+
+	ResolveTranslations([]*Page{fr, ja, en, draft, contact}, langs, false)
+
+	var got []string
+	for _, translation := range en.Translations {
+		got = append(got, translation.Lang+" "+translation.Path)
+	}
+	if want := []string{"ja /ja/about/", "fr /fr/about/"}; !slices.Equal(got, want) {
+		t.Fatalf("en translations = %v, want %v", got, want)
+	}
+	if len(fr.Translations) != 2 || fr.Translations[0].Title != "About" {
+		t.Fatalf("fr translations = %#v, want en first", fr.Translations)
+	}
+	if len(contact.Translations) != 0 {
+		t.Fatalf("contact translations = %#v, want drafts left out", contact.Translations)
+	}
+}
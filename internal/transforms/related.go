@@ -0,0 +1,85 @@
+package transforms
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+// ResolveRelated sets each page's Related to the pages scored highest
+// against it by cfg, ties going to the newer page. Candidates are the
+// written, non-draft leaf pages; a page is never related to itself. Pages are
+// only compared through the tags they share, so the work grows with pages
+// times tags rather than with every pair of pages.
+func ResolveRelated(pages []*Page, cfg *config.ConfigContentRelated) {
+	if cfg == nil || cfg.Limit == 0 {
+		return
+	}
+
+	byTag := make(map[string][]*Page)
+	for _, page := range pages {
+		if page.Error != nil || page.NoRender || page.Draft || page.IsIndex() {
+			continue
+		}
+		for _, tag := range uniqueTags(page.Tags) {
+			byTag[tag] = append(byTag[tag], page)
+		}
+	}
+
+	type scored struct {
+		page  *Page
+		score float64
+	}
+
+	for _, page := range pages {
+		page.Related = nil
+		if page.Error != nil {
+			continue
+		}
+
+		scores := make(map[*Page]float64)
+		for _, tag := range uniqueTags(page.Tags) {
+			for _, other := range byTag[tag] {
+				if other != page {
+					scores[other] += cfg.TagWeight
+				}
+			}
+		}
+		if len(scores) == 0 {
+			continue
+		}
+
+		ranked := make([]scored, 0, len(scores))
+		for other, score := range scores {
+			if cfg.SectionWeight != 0 && page.Section != "" && other.Section == page.Section {
+				score += cfg.SectionWeight
+			}
+			ranked = append(ranked, scored{page: other, score: score})
+		}
+		slices.SortFunc(ranked, func(a, b scored) int {
+			if c := cmp.Compare(b.score, a.score); c != 0 {
+				return c
+			}
+			if c := b.page.PubDate.Compare(a.page.PubDate); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.page.Path, b.page.Path)
+		})
+
+		ranked = ranked[:min(len(ranked), cfg.Limit)]
+		page.Related = make([]*PageLite, 0, len(ranked))
+		for _, r := range ranked {
+			page.Related = append(page.Related, r.page.Lite())
+		}
+	}
+}
+
+func uniqueTags(tags []string) []string {
+	if len(tags) < 2 {
+		return tags
+	}
+	unique := slices.Clone(tags)
+	slices.Sort(unique)
+	return slices.Compact(unique)
+}
@@ -35,7 +35,7 @@ func BuildRobots(pages []*Page, site *Site, cfg *config.ConfigRobots, sitemapCfg
 		if page == nil || page.Error != nil {
 			continue
 		}
-		if !cfg.IncludeDrafts && page.Draft {
+		if page.NoRender || !cfg.IncludeDrafts && page.Draft {
 			continue
 		}
 		if !page.Robots.Disallow {
@@ -95,7 +95,13 @@ func siteAbsURL(site *Site, rel string) string {
 	if site == nil {
 		return rel
 	}
-	base, err := url.Parse(site.URL)
+	return absURL(site.URL, rel)
+}
+
+// absURL resolves rel against siteURL, returning rel unchanged when either
+// does not parse.
+func absURL(siteURL, rel string) string {
+	base, err := url.Parse(siteURL)
 	if err != nil {
 		return rel
 	}
@@ -8,18 +8,28 @@ import (
 	"github.com/olimci/shizuka/internal/config"
 )
 
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
 type rssDocument struct {
 	XMLName xml.Name   `xml:"rss"`
 	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr,omitempty"`
 	Channel rssChannel `xml:"channel"`
 }
 
 type rssChannel struct {
-	Title         string    `xml:"title"`
-	Link          string    `xml:"link"`
-	Description   string    `xml:"description"`
-	LastBuildDate string    `xml:"lastBuildDate"`
-	Items         []RSSItem `xml:"item"`
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	AtomLinks     []atomLink `xml:"atom:link"`
+	Items         []RSSItem  `xml:"item"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
 }
 
 type RSSItem struct {
@@ -31,33 +41,40 @@ type RSSItem struct {
 	sortDate    time.Time
 }
 
+// RSSTemplateData is the feed to render. Self is the feed's own URL and Hub
+// a WebSub hub; when set they are advertised as atom:link elements so
+// subscribers can find the canonical feed and receive pushed updates.
 type RSSTemplateData struct {
 	Title       string
 	Link        string
 	Description string
 	BuildDate   string
+	Self        string
+	Hub         string
 	Items       []RSSItem
 }
 
 func BuildRSS(pages []*Page, site *Site, cfg *config.ConfigRSS) RSSTemplateData {
-	sectionFilter := make(map[string]struct{}, len(cfg.Sections))
-	for _, section := range cfg.Sections {
-		sectionFilter[section] = struct{}{}
+	include := feedFilter(cfg.Sections, cfg.IncludeDrafts)
+	var cutoff time.Time
+	if cfg.MaxAgeDuration > 0 {
+		cutoff = site.BuildTime.Add(-cfg.MaxAgeDuration)
 	}
 	items := make([]RSSItem, 0, len(pages))
 	for _, page := range pages {
-		if !cfg.IncludeDrafts && page.Draft {
+		if !include(page) {
 			continue
 		}
-		if !page.RSS.Include {
-			continue
+
+		pubDate := firstNonzero(page.PubDate, page.Updated, page.Created, site.BuildTime)
+		sortDate := pubDate
+		if cfg.OrderBy == config.RSSOrderDate {
+			sortDate = firstNonzero(page.Created, pubDate)
 		}
-		if _, ok := sectionFilter[page.Section]; !ok {
+		if !cutoff.IsZero() && sortDate.Before(cutoff) {
 			continue
 		}
 
-		pubDate := firstNonzero(page.PubDate, page.Updated, page.Created, time.Now())
-
 		link := page.Canon
 		if link == "" {
 			link = page.Path
@@ -69,7 +86,7 @@ func BuildRSS(pages []*Page, site *Site, cfg *config.ConfigRSS) RSSTemplateData
 			Description: firstNonzero(page.RSS.Description, page.Description),
 			GUID:        firstNonzero(page.RSS.GUID, link),
 			PubDate:     pubDate.Format(time.RFC1123Z),
-			sortDate:    pubDate,
+			sortDate:    sortDate,
 		})
 	}
 
@@ -82,10 +99,19 @@ func BuildRSS(pages []*Page, site *Site, cfg *config.ConfigRSS) RSSTemplateData
 		Link:        site.URL,
 		Description: site.Description,
 		BuildDate:   site.BuildTime.Format(time.RFC1123Z),
+		Self:        rssSelf(site, cfg),
+		Hub:         cfg.Hub,
 		Items:       items,
 	}
 }
 
+func rssSelf(site *Site, cfg *config.ConfigRSS) string {
+	if site.URL == "" || cfg.Path == "" {
+		return ""
+	}
+	return siteAbsURL(site, cfg.Path)
+}
+
 func RenderRSS(data RSSTemplateData) (string, error) {
 	doc := rssDocument{
 		Version: "2.0",
@@ -97,6 +123,15 @@ func RenderRSS(data RSSTemplateData) (string, error) {
 			Items:         data.Items,
 		},
 	}
+	if data.Self != "" {
+		doc.Channel.AtomLinks = append(doc.Channel.AtomLinks, atomLink{Href: data.Self, Rel: "self", Type: "application/rss+xml"})
+	}
+	if data.Hub != "" {
+		doc.Channel.AtomLinks = append(doc.Channel.AtomLinks, atomLink{Href: data.Hub, Rel: "hub"})
+	}
+	if len(doc.Channel.AtomLinks) > 0 {
+		doc.AtomNS = atomNamespace
+	}
 
 	out, err := xml.Marshal(doc)
 	if err != nil {
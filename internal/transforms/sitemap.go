@@ -3,6 +3,7 @@ package transforms
 import (
 	"encoding/xml"
 	"fmt"
+	"path"
 	"slices"
 	"strings"
 	"time"
@@ -27,17 +28,40 @@ type SitemapTemplateData struct {
 	Items []SitemapItem
 }
 
+type sitemapIndexDocument struct {
+	XMLName  xml.Name           `xml:"sitemapindex"`
+	Xmlns    string             `xml:"xmlns,attr"`
+	Sitemaps []SitemapIndexItem `xml:"sitemap"`
+}
+
+// SitemapIndexItem is one child sitemap listed in a sitemap index. LastMod
+// is the latest LastMod of the entries in that sitemap.
+type SitemapIndexItem struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type SitemapIndexTemplateData struct {
+	Sitemaps []SitemapIndexItem
+}
+
+// SitemapPart is one child sitemap of a split sitemap, written to Path.
+type SitemapPart struct {
+	Path string
+	Data SitemapTemplateData
+}
+
 func BuildSitemap(pages []*Page, site *Site, cfg *config.ConfigSitemap) SitemapTemplateData {
 	items := make([]SitemapItem, 0, len(pages))
 	for _, page := range pages {
-		if !cfg.IncludeDrafts && page.Draft {
+		if page.NoRender || !cfg.IncludeDrafts && page.Draft {
 			continue
 		}
 		if !page.Sitemap.Include {
 			continue
 		}
 
-		lastMod := firstNonzero(page.Updated, page.Created, time.Now())
+		lastMod := firstNonzero(page.Updated, page.Created, site.BuildTime)
 
 		loc := page.Canon
 		if loc == "" {
@@ -61,6 +85,60 @@ func BuildSitemap(pages []*Page, site *Site, cfg *config.ConfigSitemap) SitemapT
 	}
 }
 
+// BuildSitemapIndex splits data into child sitemaps of at most cfg.MaxURLs
+// entries, named after cfg.Path with a numeric suffix (sitemap-1.xml,
+// sitemap-2.xml, ...), and returns an index listing each by absolute URL. It
+// reports false when data fits in a single sitemap, which is then written
+// as-is.
+func BuildSitemapIndex(data SitemapTemplateData, site *Site, cfg *config.ConfigSitemap) (SitemapIndexTemplateData, []SitemapPart, bool) {
+	if cfg.MaxURLs <= 0 || len(data.Items) <= cfg.MaxURLs {
+		return SitemapIndexTemplateData{}, nil, false
+	}
+
+	ext := path.Ext(cfg.Path)
+	stem := strings.TrimSuffix(cfg.Path, ext)
+
+	var index SitemapIndexTemplateData
+	var parts []SitemapPart
+	for chunk := range slices.Chunk(data.Items, cfg.MaxURLs) {
+		part := SitemapPart{
+			Path: fmt.Sprintf("%s-%d%s", stem, len(parts)+1, ext),
+			Data: SitemapTemplateData{Items: chunk},
+		}
+		parts = append(parts, part)
+		index.Sitemaps = append(index.Sitemaps, SitemapIndexItem{
+			Loc:     siteAbsURL(site, part.Path),
+			LastMod: latestLastMod(chunk),
+		})
+	}
+	return index, parts, true
+}
+
+func latestLastMod(items []SitemapItem) string {
+	var latest time.Time
+	var out string
+	for _, item := range items {
+		t, err := time.Parse(time.RFC3339, item.LastMod)
+		if err == nil && t.After(latest) {
+			latest, out = t, item.LastMod
+		}
+	}
+	return out
+}
+
+func RenderSitemapIndex(data SitemapIndexTemplateData) (string, error) {
+	doc := sitemapIndexDocument{
+		Xmlns:    "http://www.sitemaps.org/schemas/sitemap/0.9",
+		Sitemaps: data.Sitemaps,
+	}
+
+	out, err := xml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(out), nil
+}
+
 func RenderSitemap(data SitemapTemplateData) (string, error) {
 	doc := sitemapDocument{
 		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
@@ -0,0 +1,93 @@
+package transforms
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/tdewolff/parse/v2"
+	htmllex "github.com/tdewolff/parse/v2/html"
+)
+
+// SummaryTextLength bounds Page.SummaryText, in runes.
+const SummaryTextLength = 200
+
+// blockTags separate words when stripped, so <p>a</p><p>b</p> reads "a b"
+// rather than "ab".
+var blockTags = map[string]struct{}{
+	"address": {}, "article": {}, "aside": {}, "blockquote": {}, "br": {},
+	"dd": {}, "div": {}, "dl": {}, "dt": {}, "figcaption": {}, "figure": {},
+	"footer": {}, "h1": {}, "h2": {}, "h3": {}, "h4": {}, "h5": {}, "h6": {},
+	"header": {}, "hr": {}, "li": {}, "ol": {}, "p": {}, "pre": {},
+	"section": {}, "table": {}, "td": {}, "th": {}, "tr": {}, "ul": {},
+}
+
+// PlainSummary returns a plain-text summary of the page for list templates
+// and feeds, taken from the first of its description, summary and body, with
+// markup stripped and cut to at most limit runes on a word boundary.
+func PlainSummary(p *Page, limit int) string {
+	source := p.Description
+	if source == "" {
+		source = string(firstNonzero(p.Summary, p.Body))
+	}
+	return TruncateWords(PlainText(source), limit)
+}
+
+// PlainText strips markup from an HTML fragment, dropping script and style
+// contents, decoding entities and collapsing whitespace.
+func PlainText(fragment string) string {
+	var b strings.Builder
+	lexer := htmllex.NewLexer(parse.NewInputString(fragment))
+	skip := ""
+	for {
+		tt, data := lexer.Next()
+		switch tt {
+		case htmllex.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case htmllex.StartTagToken:
+			tag := strings.ToLower(string(lexer.Text()))
+			if tag == "script" || tag == "style" {
+				skip = tag
+			}
+			if _, ok := blockTags[tag]; ok {
+				b.WriteByte(' ')
+			}
+		case htmllex.EndTagToken:
+			tag := strings.ToLower(string(lexer.Text()))
+			if tag == skip {
+				skip = ""
+			}
+			if _, ok := blockTags[tag]; ok {
+				b.WriteByte(' ')
+			}
+		case htmllex.TextToken:
+			if skip == "" {
+				b.WriteString(html.UnescapeString(string(data)))
+			}
+		}
+	}
+}
+
+// TruncateWords cuts text to at most limit runes, backing up to the last
+// word boundary and appending an ellipsis. A limit of zero or less disables
+// truncation.
+func TruncateWords(text string, limit int) string {
+	runes := []rune(text)
+	if limit <= 0 || len(runes) <= limit {
+		return text
+	}
+
+	// Leave room for the ellipsis.
+	cut := limit - 1
+	if !unicode.IsSpace(runes[cut]) {
+		for i := cut; i > 0; i-- {
+			if unicode.IsSpace(runes[i-1]) {
+				cut = i - 1
+				break
+			}
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
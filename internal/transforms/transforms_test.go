@@ -2,6 +2,8 @@ package transforms
 
 import (
 	"encoding/xml"
+	"html/template"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -39,6 +41,42 @@ func TestBuildRSSFiltersSectionsDraftsAndSortsNewestFirst(t *testing.T) {
 	}
 }
 
+func TestBuildJSONFeedFiltersSectionsAndAppliesLimit(t *testing.T) {
+	older := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	newer := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	site := &Site{Title: "Site", URL: "https://example.com"}
+	newPage := rssPage("New", "posts", newer, false)
+	newPage.Body = "<p>New body</p>"
+	newPage.Tags = []string{"go"}
+	pages := []*Page{
+		rssPage("Old", "posts", older, false),
+		rssPage("Draft", "posts", newer.Add(time.Hour), true),
+		rssPage("Page", "pages", newer, false),
+		newPage,
+	}
+
+	feed := BuildJSONFeed(pages, site, &config.ConfigJSONFeed{Sections: []string{"posts"}, Limit: 1})
+
+	if feed.Version != JSONFeedVersion {
+		t.Fatalf("version = %q, want %q", feed.Version, JSONFeedVersion)
+	}
+	if len(feed.Items) != 1 {
+		t.Fatalf("items = %#v, want newest post only", feed.Items)
+	}
+	item := feed.Items[0]
+	if item.ID != "https://example.com/new/" || item.ContentHTML != "<p>New body</p>" || item.DatePublished != newer.Format(time.RFC3339) {
+		t.Fatalf("item = %#v, want canonical id, rendered body and date", item)
+	}
+
+	out, err := RenderJSONFeed(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"content_html": "<p>New body</p>"`) || !strings.Contains(out, `"tags": [`) {
+		t.Fatalf("unexpected json feed:\n%s", out)
+	}
+}
+
 func TestRenderRSSProducesXML(t *testing.T) {
 	out, err := RenderRSS(RSSTemplateData{
 		Title:       "Site",
@@ -84,6 +122,47 @@ func TestBuildSitemapFiltersAndSorts(t *testing.T) {
 	}
 }
 
+func TestBuildSitemapIndexSplitsLargeSitemaps(t *testing.T) {
+	early := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	data := SitemapTemplateData{Items: []SitemapItem{
+		{Loc: "https://example.com/a/", LastMod: early.Format(time.RFC3339)},
+		{Loc: "https://example.com/b/", LastMod: late.Format(time.RFC3339)},
+		{Loc: "https://example.com/c/", LastMod: early.Format(time.RFC3339)},
+	}}
+	site := &Site{URL: "https://example.com"}
+
+	if _, _, split := BuildSitemapIndex(data, site, &config.ConfigSitemap{Path: "sitemap.xml", MaxURLs: 3}); split {
+		t.Fatal("split a sitemap within MaxURLs")
+	}
+
+	index, parts, split := BuildSitemapIndex(data, site, &config.ConfigSitemap{Path: "sitemap.xml", MaxURLs: 2})
+	if !split {
+		t.Fatal("did not split a sitemap over MaxURLs")
+	}
+	if len(parts) != 2 || parts[0].Path != "sitemap-1.xml" || parts[1].Path != "sitemap-2.xml" {
+		t.Fatalf("parts = %#v, want sitemap-1.xml and sitemap-2.xml", parts)
+	}
+	if len(parts[0].Data.Items) != 2 || len(parts[1].Data.Items) != 1 {
+		t.Fatalf("parts = %#v, want 2 and 1 entries", parts)
+	}
+	want := []SitemapIndexItem{
+		{Loc: "https://example.com/sitemap-1.xml", LastMod: late.Format(time.RFC3339)},
+		{Loc: "https://example.com/sitemap-2.xml", LastMod: early.Format(time.RFC3339)},
+	}
+	if !slices.Equal(index.Sitemaps, want) {
+		t.Fatalf("index = %#v, want %#v", index.Sitemaps, want)
+	}
+
+	out, err := RenderSitemapIndex(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`) || !strings.Contains(out, "<loc>https://example.com/sitemap-2.xml</loc>") {
+		t.Fatalf("sitemap index missing expected XML:\n%s", out)
+	}
+}
+
 func TestBuildRobotsCombinesConfiguredGroupsPageDisallowsAndSitemap(t *testing.T) {
 	data := BuildRobots(
 		[]*Page{
@@ -109,6 +188,31 @@ func TestBuildRobotsCombinesConfiguredGroupsPageDisallowsAndSitemap(t *testing.T
 	}
 }
 
+func TestPageVariantsEmitsEnabledVariantUnderPagePath(t *testing.T) {
+	page := &Page{
+		Path:     "/post/",
+		Template: "post",
+		Variants: map[string]string{"amp": "post-amp", "print": "post-print"},
+	}
+
+	variants := PageVariants(page, map[string]config.ConfigContentVariant{"amp": {}})
+	if len(variants) != 1 {
+		t.Fatalf("variants = %#v, want only the enabled amp variant", variants)
+	}
+	if variants[0].Path != "/post/amp/" || variants[0].Template != "post-amp" {
+		t.Fatalf("variant = %#v, want post-amp at /post/amp/", variants[0])
+	}
+
+	variants = PageVariants(&Page{Path: "/"}, map[string]config.ConfigContentVariant{"amp": {Template: "amp"}})
+	if len(variants) != 1 || variants[0].Path != "/amp/" || variants[0].Template != "amp" {
+		t.Fatalf("variants = %#v, want configured default template at /amp/", variants)
+	}
+
+	if variants := PageVariants(page, nil); len(variants) != 0 {
+		t.Fatalf("variants = %#v, want none when no variants are enabled", variants)
+	}
+}
+
 func rssPage(title, section string, created time.Time, draft bool) *Page {
 	return &Page{
 		Title:       title,
@@ -136,3 +240,212 @@ func sitemapPage(canon string, created time.Time, draft, include bool) *Page {
 		Draft: draft,
 	}
 }
+
+func TestAliasRedirectsSkipsConflicts(t *testing.T) {
+	about := &Page{Path: "/about/", Aliases: []string{"old-about", "/blog/"}}
+	blog := &Page{Path: "/blog/", Aliases: []string{"/old-about/"}}
+	hidden := &Page{Path: "/data/", NoRender: true, Aliases: []string{"/gone/"}}
+
+	redirects, conflicts := AliasRedirects([]*Page{about, blog, hidden})
+
+	want := []config.Redirect{{From: "/old-about", To: "/about/", Status: 301}}
+	if !slices.Equal(redirects, want) {
+		t.Fatalf("redirects = %#v, want %#v", redirects, want)
+	}
+	if len(conflicts) != 2 || conflicts[0].Owner != blog || conflicts[1].Owner != about {
+		t.Fatalf("conflicts = %#v, want /blog/ and /old-about/", conflicts)
+	}
+}
+
+func TestBuildHeadersPrecedenceAndMerge(t *testing.T) {
+	pages := []*Page{{
+		Path: "/post/",
+		Headers: map[string]string{
+			"cache-control": "no-store",
+			"Link":          "</page.css>; rel=preload",
+		},
+	}}
+	values := map[string]map[string]string{
+		"/post/": {
+			"Cache-Control": "max-age=60",
+			"Link":          "</site.css>; rel=preload",
+		},
+	}
+
+	tests := []struct {
+		precedence string
+		want       string
+	}{
+		{
+			precedence: config.HeadersPrecedencePage,
+			want:       "/post/\n  Cache-Control: no-store\n  Link: </site.css>; rel=preload\n  Link: </page.css>; rel=preload\n\n",
+		},
+		{
+			precedence: config.HeadersPrecedenceConfig,
+			want:       "/post/\n  Cache-Control: max-age=60\n  Link: </page.css>; rel=preload\n  Link: </site.css>; rel=preload\n\n",
+		},
+	}
+	for _, tt := range tests {
+		cfg := &config.ConfigHeaders{Values: values, Precedence: tt.precedence, Merge: []string{"link"}}
+		if got := RenderHeaders(BuildHeaders(pages, cfg, false)); got != tt.want {
+			t.Fatalf("precedence %q headers =\n%s\nwant\n%s", tt.precedence, got, tt.want)
+		}
+	}
+}
+
+func TestApplyCSPHashesInlineScript(t *testing.T) {
+	hashes := HashInlineBlocks([]byte(`<script src="/app.js"></script><script>alert(1)</script><style>body{color:red}</style>`))
+
+	rules := ApplyCSP(nil, "default-src 'self'; script-src 'self'", map[string]CSPHashes{"/post/": hashes})
+	got := RenderHeaders(rules)
+
+	want := "/post/\n  Content-Security-Policy: default-src 'self'; " +
+		"script-src 'self' 'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='; " +
+		"style-src 'self' 'sha256-FcQqt3aNlV7AZnGV4zkQRVeCeJOxbMPnQSx258L803E='\n\n"
+	if got != want {
+		t.Fatalf("headers =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSiteRegularPagesExcludesIndexPages(t *testing.T) {
+	pages := []*Page{
+		{ContentPath: "index.md", Path: "/"},
+		{ContentPath: "posts/index.md", Path: "/posts/"},
+		{ContentPath: "posts/first.md", Path: "/posts/first/"},
+		{ContentPath: "about.toml", Path: "/about/"},
+	}
+	site := &Site{}
+	site.SetPages(pages)
+
+	tmpl := site.Tmpl()
+	if len(tmpl.Pages) != 4 {
+		t.Fatalf("len(Pages) = %d, want 4", len(tmpl.Pages))
+	}
+	var got []string
+	for _, page := range tmpl.RegularPages {
+		got = append(got, page.Path)
+	}
+	if want := []string{"/posts/first/", "/about/"}; !slices.Equal(got, want) {
+		t.Fatalf("RegularPages = %v, want %v", got, want)
+	}
+}
+
+func TestBuildRSSOrderByAndMaxAge(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	site := &Site{URL: "https://example.com", BuildTime: now}
+
+	old := rssPage("Old", "posts", now.AddDate(0, -6, 0), false)
+	edited := rssPage("Edited", "posts", now.AddDate(0, 0, -20), false)
+	edited.Updated = now.AddDate(0, 0, -1)
+	recent := rssPage("Recent", "posts", now.AddDate(0, 0, -5), false)
+	pages := []*Page{old, edited, recent}
+
+	titles := func(data RSSTemplateData) []string {
+		var out []string
+		for _, item := range data.Items {
+			out = append(out, item.Title)
+		}
+		return out
+	}
+
+	data := BuildRSS(pages, site, &config.ConfigRSS{Sections: []string{"posts"}, OrderBy: config.RSSOrderUpdated})
+	if got, want := titles(data), []string{"Edited", "Recent", "Old"}; !slices.Equal(got, want) {
+		t.Fatalf("order_by updated = %v, want %v", got, want)
+	}
+
+	data = BuildRSS(pages, site, &config.ConfigRSS{Sections: []string{"posts"}, OrderBy: config.RSSOrderDate})
+	if got, want := titles(data), []string{"Recent", "Edited", "Old"}; !slices.Equal(got, want) {
+		t.Fatalf("order_by date = %v, want %v", got, want)
+	}
+
+	data = BuildRSS(pages, site, &config.ConfigRSS{Sections: []string{"posts"}, OrderBy: config.RSSOrderDate, MaxAgeDuration: 30 * 24 * time.Hour})
+	if got, want := titles(data), []string{"Recent", "Edited"}; !slices.Equal(got, want) {
+		t.Fatalf("max_age = %v, want %v", got, want)
+	}
+}
+
+func TestPlainSummary(t *testing.T) {
+	page := &Page{
+		Body: template.HTML(`<p>Fish &amp; <em>chips</em> are great.</p><script>alert(1)</script><p>Second paragraph with more words in it.</p>`),
+	}
+	if got, want := PlainSummary(page, 200), "Fish & chips are great. Second paragraph with more words in it."; got != want {
+		t.Fatalf("PlainSummary() = %q, want %q", got, want)
+	}
+
+	got := PlainSummary(page, 30)
+	if got != "Fish & chips are great…" {
+		t.Fatalf("PlainSummary(30) = %q, want a cut on a word boundary", got)
+	}
+	if n := len([]rune(got)); n > 30 {
+		t.Fatalf("PlainSummary(30) has %d runes, want at most 30", n)
+	}
+
+	page.Description = "Plain description"
+	if got := PlainSummary(page, 200); got != "Plain description" {
+		t.Fatalf("PlainSummary() = %q, want description", got)
+	}
+}
+
+func TestRenderRSSAdvertisesWebSubHub(t *testing.T) {
+	site := &Site{Title: "Site", URL: "https://example.com"}
+	cfg := &config.ConfigRSS{Path: "rss.xml", Hub: "https://hub.example.com/"}
+
+	out, err := RenderRSS(BuildRSS(nil, site, cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`xmlns:atom="http://www.w3.org/2005/Atom"`,
+		`<atom:link href="https://example.com/rss.xml" rel="self" type="application/rss+xml"></atom:link>`,
+		`<atom:link href="https://hub.example.com/" rel="hub"></atom:link>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("rss missing %s:\n%s", want, out)
+		}
+	}
+	if err := xml.Unmarshal([]byte(out), new(struct{})); err != nil {
+		t.Fatalf("rss is not well-formed: %v", err)
+	}
+
+	cfg.Hub = ""
+	out, err = RenderRSS(BuildRSS(nil, site, cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, `rel="hub"`) {
+		t.Fatalf("rss without a hub advertised one:\n%s", out)
+	}
+}
+
+func TestResolveRelatedScoresSharedTagsAndBreaksTiesByRecency(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	post := &Page{ContentPath: "posts/go.md", Path: "/posts/go/", Section: "posts", Tags: []string{"go", "web"}, PubDate: day(1)}
+	both := &Page{ContentPath: "notes/both.md", Path: "/notes/both/", Section: "notes", Tags: []string{"go", "web"}, PubDate: day(2)}
+	older := &Page{ContentPath: "posts/older.md", Path: "/posts/older/", Section: "posts", Tags: []string{"go"}, PubDate: day(3)}
+	newer := &Page{ContentPath: "notes/newer.md", Path: "/notes/newer/", Section: "notes", Tags: []string{"web"}, PubDate: day(4)}
+	draft := &Page{ContentPath: "posts/draft.md", Path: "/posts/draft/", Tags: []string{"go", "web"}, Draft: true}
+	index := &Page{ContentPath: "posts/index.md", Path: "/posts/", Tags: []string{"go"}}
+	unrelated := &Page{ContentPath: "posts/other.md", Path: "/posts/other/", Tags: []string{"rust"}}
+	pages := []*Page{post, both, older, newer, draft, index, unrelated}
+
+	paths := func(related []*PageLite) []string {
+		var out []string
+		for _, page := range related {
+			out = append(out, page.Path)
+		}
+		return out
+	}
+
+	ResolveRelated(pages, &config.ConfigContentRelated{Limit: 3, TagWeight: 1})
+	if got, want := paths(post.Related), []string{"/notes/both/", "/notes/newer/", "/posts/older/"}; !slices.Equal(got, want) {
+		t.Fatalf("related = %v, want %v", got, want)
+	}
+	if len(unrelated.Related) != 0 {
+		t.Fatalf("unrelated related = %v, want none", paths(unrelated.Related))
+	}
+
+	ResolveRelated(pages, &config.ConfigContentRelated{Limit: 2, TagWeight: 1, SectionWeight: 0.5})
+	if got, want := paths(post.Related), []string{"/notes/both/", "/posts/older/"}; !slices.Equal(got, want) {
+		t.Fatalf("related with section weight = %v, want %v", got, want)
+	}
+}
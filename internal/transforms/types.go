@@ -3,9 +3,12 @@ package transforms
 import (
 	"html/template"
 	"maps"
+	"path"
 	"slices"
+	"strings"
 	"time"
 
+	"github.com/olimci/shizuka/internal/config"
 	"github.com/olimci/shizuka/internal/frontmatter"
 	"github.com/olimci/shizuka/internal/markdown"
 )
@@ -17,9 +20,29 @@ type Page struct {
 	// TODO: we should be able to reduce this somewhat, since sourcePath includes contentPath. also notice how these fields are a subset of claim- could just put a claim struct here.
 	SourcePath  string
 	ContentPath string
-	Path        string
-	OutputPath  string
-	Template    string
+	// Collection names the content source the page was read from.
+	Collection string
+	Path       string
+	OutputPath string
+	// URL is the frontmatter url override, already applied to Path.
+	URL      string
+	Template string
+	// Type and OwnTemplate are the frontmatter type and template; see
+	// frontmatter.Frontmatter. Template already includes defaults.
+	Type        string
+	OwnTemplate string
+
+	// Lang is the page's language when content.languages is set, and
+	// Translations are the pages sharing its TranslationKey in other
+	// languages. See PageLanguage and ResolveTranslations.
+	Lang           string
+	TranslationKey string
+	Translations   []*PageLite
+
+	// Related are the pages most like this one by shared tags. See
+	// ResolveRelated.
+	Related  []*PageLite
+	Variants map[string]string
 
 	Error error
 
@@ -32,52 +55,110 @@ type Page struct {
 	Slug        string
 	Tags        []string
 
+	// Aliases are old paths that redirect to the page. See AliasRedirects.
+	Aliases []string
+
 	RSS     frontmatter.RSSMeta
 	Sitemap frontmatter.SitemapMeta
 	Robots  frontmatter.RobotsMeta
 
-	Created time.Time
-	Updated time.Time
-	PubDate time.Time
+	Created    time.Time
+	Updated    time.Time
+	PubDate    time.Time
+	ExpiryDate time.Time
 
-	Params map[string]any
+	Params  map[string]any
+	Headers map[string]string
+
+	// OwnParams holds the params set in the page's own frontmatter, so
+	// cascaded params never override them.
+	OwnParams map[string]any
+
+	// Cascade holds the params an index page passes down to the pages
+	// below it. See the cascade in pages:resolve.
+	Cascade map[string]any
 
 	Preprocess string
 	RawBody    string
 	Body       template.HTML
+	Summary    template.HTML
 	Sections   []template.HTML
 	ToC        []markdown.ToCEntry
 
+	// ToCTree is ToC nested by heading level, for the levels set in
+	// content.markdown.toc. It is empty when that is unset or the page
+	// opts out with `toc: false`.
+	ToCTree []markdown.ToCEntry
+	NoToC   bool
+
+	// Breadcrumbs is the trail from the site root to the page. See
+	// ResolveBreadcrumbs.
+	Breadcrumbs []Crumb
+
+	// SummaryText is a plain-text summary for list templates and feeds. See
+	// PlainSummary.
+	SummaryText string
+
+	// A page is in one of three publication states beyond the default:
+	// Draft pages render only in dev builds, NoIndex pages render but ask
+	// crawlers to skip them, and NoRender pages are never written but stay
+	// queryable so other pages can reference them as data.
 	Featured bool
 	Draft    bool
+	NoIndex  bool
+	NoRender bool
 }
 
 func (p *Page) CloneShallow() *Page {
 	cloned := *p
 	cloned.Tags = slices.Clone(p.Tags)
+	cloned.Aliases = slices.Clone(p.Aliases)
 	cloned.Params = maps.Clone(p.Params)
+	cloned.OwnParams = maps.Clone(p.OwnParams)
+	cloned.Cascade = maps.Clone(p.Cascade)
+	cloned.Variants = maps.Clone(p.Variants)
+	cloned.Headers = maps.Clone(p.Headers)
 	cloned.Sections = slices.Clone(p.Sections)
 	cloned.ToC = slices.Clone(p.ToC)
+	cloned.ToCTree = slices.Clone(p.ToCTree)
+	cloned.Breadcrumbs = slices.Clone(p.Breadcrumbs)
+	cloned.Translations = slices.Clone(p.Translations)
+	cloned.Related = slices.Clone(p.Related)
 	return &cloned
 }
 
 func (p *Page) ApplyFrontmatter(meta frontmatter.Frontmatter) {
 	p.Template = meta.Template
+	p.Type = meta.Type
+	p.OwnTemplate = meta.OwnTemplate
+	p.Variants = maps.Clone(meta.Variants)
 	p.Weight = meta.Weight
 	p.Title = meta.Title
 	p.Description = meta.Description
 	p.Section = meta.Section
 	p.Slug = meta.Slug
+	p.URL = meta.URL
 	p.Tags = slices.Clone(meta.Tags)
+	p.Aliases = slices.Clone(meta.Aliases)
+	p.Canon = meta.Canonical
 	p.Created = meta.Created
 	p.Updated = meta.Updated
-	p.PubDate = firstNonzero(meta.Updated, meta.Created, time.Now())
+	p.ExpiryDate = meta.ExpiryDate
+	// Undated pages are dated with the build time once the build resolves
+	// them.
+	p.PubDate = firstNonzero(meta.Updated, meta.Created)
 	p.Params = maps.Clone(meta.Params)
+	p.OwnParams = maps.Clone(meta.OwnParams)
+	p.Cascade = maps.Clone(meta.Cascade)
+	p.Headers = maps.Clone(meta.Headers)
 	p.RSS = meta.RSS
 	p.Sitemap = meta.Sitemap
 	p.Robots = meta.Robots
 	p.Featured = meta.Featured
 	p.Draft = meta.Draft
+	p.NoIndex = meta.NoIndex
+	p.NoRender = meta.Render != nil && !*meta.Render
+	p.NoToC = meta.ToC != nil && !*meta.ToC
 }
 
 type Site struct {
@@ -90,6 +171,25 @@ type Site struct {
 	Dev       bool
 	Git       SiteGitMeta
 	BuildTime time.Time
+	// Version is the version of shizuka that built the site. The commit it
+	// was built from is in Git when content.git is set.
+	Version string
+
+	// Pages holds every page in the site; RegularPages holds only leaf
+	// content pages, leaving out section and index pages.
+	Pages        []*Page
+	RegularPages []*Page
+}
+
+// SetPages fills Pages and RegularPages from pages.
+func (s *Site) SetPages(pages []*Page) {
+	s.Pages = pages
+	s.RegularPages = make([]*Page, 0, len(pages))
+	for _, page := range pages {
+		if !page.IsIndex() {
+			s.RegularPages = append(s.RegularPages, page)
+		}
+	}
 }
 
 type PageTemplate struct {
@@ -120,29 +220,52 @@ type SiteTmpl struct {
 	Dev       bool
 	Git       SiteGitMeta
 	BuildTime time.Time
+	Version   string
+
+	Pages        []PageTmpl
+	RegularPages []PageTmpl
 }
 
+// Tmpl returns the template view of the site. It converts every page, so it
+// must not be called before page bodies have been rendered; the build does
+// so once and shares the result through its registry.
 func (s *Site) Tmpl() SiteTmpl {
 	if s == nil {
 		return SiteTmpl{}
 	}
 
 	return SiteTmpl{
-		Title:       s.Title,
-		Description: s.Description,
-		URL:         s.URL,
-		Params:      s.Params,
-		Dev:         s.Dev,
-		Git:         s.Git,
-		BuildTime:   s.BuildTime,
+		Title:        s.Title,
+		Description:  s.Description,
+		URL:          s.URL,
+		Params:       s.Params,
+		Dev:          s.Dev,
+		Git:          s.Git,
+		BuildTime:    s.BuildTime,
+		Version:      s.Version,
+		Pages:        pageTmpls(s.Pages),
+		RegularPages: pageTmpls(s.RegularPages),
 	}
 }
 
+func pageTmpls(pages []*Page) []PageTmpl {
+	tmpls := make([]PageTmpl, len(pages))
+	for i, page := range pages {
+		tmpls[i] = page.Tmpl()
+	}
+	return tmpls
+}
+
 type PageTmpl struct {
 	Git  PageGitMeta
 	File PageFileMeta
 
-	Path string
+	Path       string
+	Collection string
+
+	Lang         string
+	Translations []*PageLite
+	Related      []*PageLite
 
 	Canon  string
 	Weight int
@@ -153,18 +276,52 @@ type PageTmpl struct {
 	Slug        string
 	Tags        []string
 
-	Created time.Time
-	Updated time.Time
-	PubDate time.Time
+	Created    time.Time
+	Updated    time.Time
+	PubDate    time.Time
+	ExpiryDate time.Time
 
 	Params map[string]any
 
-	Body     template.HTML
-	Sections []template.HTML
-	ToC      []markdown.ToCEntry
+	Body        template.HTML
+	Summary     template.HTML
+	SummaryText string
+	Sections    []template.HTML
+	ToC         []markdown.ToCEntry
+	ToCTree     []markdown.ToCEntry
+	Breadcrumbs []Crumb
 
 	Featured bool
 	Draft    bool
+	NoIndex  bool
+	NoRender bool
+}
+
+// IsIndex reports whether the page is a section or index page, i.e. one
+// built from an index file rather than a leaf content file.
+func (p *Page) IsIndex() bool {
+	base := path.Base(p.ContentPath)
+	return strings.TrimSuffix(base, path.Ext(base)) == "index"
+}
+
+// Published reports whether the page is live at now: created no later than
+// now and not yet past its expiry date.
+func (p *Page) Published(now time.Time) bool {
+	if p.Created.After(now) {
+		return false
+	}
+	return p.ExpiryDate.IsZero() || p.ExpiryDate.After(now)
+}
+
+// ResolveNoIndex marks the page noindex if it is a draft or scheduled after
+// now, as selected by cfg. A noindex set in frontmatter is always kept.
+func (p *Page) ResolveNoIndex(now time.Time, cfg config.ConfigContentNoIndex) {
+	switch {
+	case cfg.Drafts && p.Draft:
+		p.NoIndex = true
+	case cfg.Future && p.Created.After(now):
+		p.NoIndex = true
+	}
 }
 
 func (p *Page) Tmpl() PageTmpl {
@@ -173,24 +330,35 @@ func (p *Page) Tmpl() PageTmpl {
 	}
 
 	return PageTmpl{
-		Git:         p.Git,
-		File:        p.File,
-		Path:        p.Path,
-		Canon:       p.Canon,
-		Weight:      p.Weight,
-		Title:       p.Title,
-		Description: p.Description,
-		Section:     p.Section,
-		Slug:        p.Slug,
-		Tags:        p.Tags,
-		Created:     p.Created,
-		Updated:     p.Updated,
-		PubDate:     p.PubDate,
-		Params:      p.Params,
-		Body:        p.Body,
-		Sections:    p.Sections,
-		ToC:         p.ToC,
-		Featured:    p.Featured,
-		Draft:       p.Draft,
+		Git:          p.Git,
+		File:         p.File,
+		Path:         p.Path,
+		Collection:   p.Collection,
+		Lang:         p.Lang,
+		Translations: p.Translations,
+		Related:      p.Related,
+		Canon:        p.Canon,
+		Weight:       p.Weight,
+		Title:        p.Title,
+		Description:  p.Description,
+		Section:      p.Section,
+		Slug:         p.Slug,
+		Tags:         p.Tags,
+		Created:      p.Created,
+		Updated:      p.Updated,
+		PubDate:      p.PubDate,
+		ExpiryDate:   p.ExpiryDate,
+		Params:       p.Params,
+		Body:         p.Body,
+		Summary:      p.Summary,
+		SummaryText:  p.SummaryText,
+		Sections:     p.Sections,
+		ToC:          p.ToC,
+		ToCTree:      p.ToCTree,
+		Breadcrumbs:  p.Breadcrumbs,
+		Featured:     p.Featured,
+		Draft:        p.Draft,
+		NoIndex:      p.NoIndex,
+		NoRender:     p.NoRender,
 	}
 }
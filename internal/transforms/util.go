@@ -9,3 +9,31 @@ func firstNonzero[T comparable](values ...T) T {
 	}
 	return zero
 }
+
+// feedFilter returns a predicate selecting the pages a feed lists: rendered,
+// opted into feeds, in one of sections and, unless includeDrafts, published.
+func feedFilter(sections []string, includeDrafts bool) func(*Page) bool {
+	sectionFilter := make(map[string]struct{}, len(sections))
+	for _, section := range sections {
+		sectionFilter[section] = struct{}{}
+	}
+	return func(page *Page) bool {
+		if page.NoRender || !includeDrafts && page.Draft {
+			return false
+		}
+		if !page.RSS.Include {
+			return false
+		}
+		_, ok := sectionFilter[page.Section]
+		return ok
+	}
+}
+
+// limitFeed keeps the first limit items. A limit of zero or less keeps them
+// all.
+func limitFeed[T any](items []T, limit int) []T {
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
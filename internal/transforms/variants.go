@@ -0,0 +1,53 @@
+package transforms
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/olimci/shizuka/internal/config"
+)
+
+// PageVariant is an alternate rendering of a page, such as an AMP or print
+// version.
+type PageVariant struct {
+	Name     string
+	Template string
+	Path     string
+}
+
+// PageVariants resolves the enabled variants for a page. Templates declared in
+// the page's frontmatter take precedence over the configured default; variants
+// with no template are skipped.
+func PageVariants(page *Page, variants map[string]config.ConfigContentVariant) []PageVariant {
+	if page == nil || len(variants) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(variants))
+	for name := range variants {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	out := make([]PageVariant, 0, len(names))
+	for _, name := range names {
+		tmpl := firstNonzero(page.Variants[name], variants[name].Template)
+		if tmpl == "" {
+			continue
+		}
+		out = append(out, PageVariant{
+			Name:     name,
+			Template: tmpl,
+			Path:     variantPath(page.Path, name),
+		})
+	}
+	return out
+}
+
+func variantPath(pagePath, name string) string {
+	base := strings.Trim(pagePath, "/")
+	if base == "" {
+		return "/" + name + "/"
+	}
+	return "/" + base + "/" + name + "/"
+}
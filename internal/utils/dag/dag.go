@@ -66,6 +66,64 @@ func (g *Graph[T]) AddDeps(id string, deps []string) error {
 	return nil
 }
 
+// Nodes returns the IDs of the graph's nodes, sorted.
+func (g *Graph[T]) Nodes() []string {
+	ids := make([]string, 0, len(g.nodes))
+	for id := range g.nodes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Value returns the value registered for node id.
+func (g *Graph[T]) Value(id string) (T, bool) {
+	value, ok := g.nodes[id]
+	return value, ok
+}
+
+// Deps returns the IDs node id depends on, sorted, including any that are
+// not in the graph.
+func (g *Graph[T]) Deps(id string) []string {
+	deps := make([]string, 0, len(g.edges[id]))
+	for dep := range g.edges[id] {
+		deps = append(deps, dep)
+	}
+	slices.Sort(deps)
+	return deps
+}
+
+// Validate reports the error Run would stop with for the shape of the graph,
+// an unresolved dependency or a cycle, without running any node.
+func (g *Graph[T]) Validate() error {
+	adj, deg, err := g.compile()
+	if err != nil {
+		return err
+	}
+
+	ready := make([]string, 0, len(deg))
+	for id, d := range deg {
+		if d == 0 {
+			ready = append(ready, id)
+		}
+	}
+	for len(ready) > 0 {
+		id := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		for _, next := range adj[id] {
+			deg[next]--
+			if deg[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+	if stuck := stuck(deg); len(stuck) > 0 {
+		slices.Sort(stuck)
+		return fmt.Errorf("%w: %v", ErrCircularDependency, stuck)
+	}
+	return nil
+}
+
 func (g *Graph[T]) addDeps(id string, deps []string) {
 	edges := g.edges[id]
 	if edges == nil {
@@ -101,6 +159,10 @@ func (g *Graph[T]) compile() (map[string][]string, map[string]int, error) {
 			adj[dep] = append(adj[dep], id)
 		}
 	}
+	// Sorted successors make a single-worker run visit nodes in one order.
+	for _, next := range adj {
+		slices.Sort(next)
+	}
 
 	return adj, deg, nil
 }
@@ -133,6 +195,7 @@ func (g *Graph[T]) Run(ctx context.Context, workers int, fn NodeFn[T]) error {
 	if len(ready) == 0 {
 		return fmt.Errorf("%w: %v", ErrCircularDependency, stuck(deg))
 	}
+	slices.Sort(ready)
 
 	if workers <= 0 || workers > len(g.nodes) {
 		workers = len(g.nodes)
@@ -227,5 +290,6 @@ func stuck(deg map[string]int) []string {
 			stuck = append(stuck, id)
 		}
 	}
+	slices.Sort(stuck)
 	return stuck
 }
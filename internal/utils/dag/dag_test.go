@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"slices"
+	"strings"
 	"testing"
 )
 
@@ -36,6 +37,33 @@ func TestGraphRunHonoursDependencies(t *testing.T) {
 	assertBefore(t, order, "build", "emit")
 }
 
+func TestGraphRunWithOneWorkerIsOrdered(t *testing.T) {
+	graph := New[string]()
+	for _, id := range []string{"d", "b", "a", "c"} {
+		if err := graph.Add(id, nil, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"z", "y", "x"} {
+		if err := graph.Add(id, []string{"a"}, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for range 10 {
+		var order []string
+		if err := graph.Run(context.Background(), 1, func(ctx context.Context, value string) error {
+			order = append(order, value)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"a", "b", "c", "d", "x", "y", "z"}; !slices.Equal(order, want) {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
 func TestGraphRunReportsUnresolvedDependency(t *testing.T) {
 	graph := New[string]()
 	if err := graph.Add("build", []string{"parse"}, "build"); err != nil {
@@ -67,6 +95,30 @@ func TestGraphRunReportsCircularDependency(t *testing.T) {
 	}
 }
 
+func TestGraphValidateFindsCycleBehindReadyNodes(t *testing.T) {
+	graph := New[string]()
+	if err := graph.Add("root", nil, "root"); err != nil {
+		t.Fatal(err)
+	}
+	if err := graph.Add("a", []string{"root", "b"}, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := graph.Add("b", []string{"a"}, "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := graph.Validate()
+	if !errors.Is(err, ErrCircularDependency) || !strings.Contains(err.Error(), "[a b]") {
+		t.Fatalf("err = %v, want a cycle through a and b", err)
+	}
+	if deps := graph.Deps("a"); !slices.Equal(deps, []string{"b", "root"}) {
+		t.Fatalf("Deps(a) = %v, want [b root]", deps)
+	}
+	if nodes := graph.Nodes(); !slices.Equal(nodes, []string{"a", "b", "root"}) {
+		t.Fatalf("Nodes() = %v, want [a b root]", nodes)
+	}
+}
+
 func TestGraphAddRejectsDuplicateAndSelfDependency(t *testing.T) {
 	graph := New[string]()
 	if err := graph.Add("a", nil, "a"); err != nil {
@@ -43,8 +43,10 @@ func UnmarshalExt(ext string, data []byte, v any) error {
 	return Unmarshal(format, data, v)
 }
 
+// Unmarshal decodes data into v. Errors carry a PositionError when the
+// decoder reports where they occurred.
 func Unmarshal(format Format, data []byte, v any) error {
-	return Decode(format, bytes.NewReader(data), v)
+	return withPosition(format, data, Decode(format, bytes.NewReader(data), v))
 }
 
 func DecodeExt(ext string, r io.Reader, v any) error {
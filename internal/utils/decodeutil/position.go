@@ -0,0 +1,89 @@
+package decodeutil
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+	"github.com/olimci/roundtrip/json"
+)
+
+// PositionError is a decode error annotated with where in the input it
+// occurred. Line and Column are 1-based; Column is 0 when the decoder only
+// reports the line.
+type PositionError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *PositionError) Error() string { return e.Err.Error() }
+func (e *PositionError) Unwrap() error { return e.Err }
+
+// OffsetLines shifts the position of a PositionError by n lines, for input
+// that was decoded from partway through a file. Other errors are returned
+// unchanged.
+func OffsetLines(err error, n int) error {
+	perr, ok := err.(*PositionError)
+	if !ok || n == 0 {
+		return err
+	}
+	shifted := *perr
+	shifted.Line += n
+	return &shifted
+}
+
+// yaml.v3 and the TOML decoder's type errors only carry the line in their
+// messages.
+var errorLinePattern = regexp.MustCompile(`\bline (\d+)\b`)
+
+// withPosition wraps err in a PositionError when the decoder for format
+// reports where in data it occurred.
+func withPosition(format Format, data []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+	line, column := errorPosition(format, data, err)
+	if line <= 0 {
+		return err
+	}
+	return &PositionError{Line: line, Column: column, Err: err}
+}
+
+func errorPosition(format Format, data []byte, err error) (line, column int) {
+	switch format {
+	case FormatJSON, FormatJSONC:
+		if perr, ok := errors.AsType[json.ParseError](err); ok {
+			return perr.Token.Position.Line, perr.Token.Position.Column
+		}
+		if cerr, ok := errors.AsType[json.CommentError](err); ok {
+			return cerr.Token.Position.Line, cerr.Token.Position.Column
+		}
+		if serr, ok := errors.AsType[*json.SyntaxError](err); ok {
+			return offsetPosition(data, serr.Offset)
+		}
+		if terr, ok := errors.AsType[*json.UnmarshalTypeError](err); ok {
+			return offsetPosition(data, terr.Offset)
+		}
+		return 0, 0
+	case FormatTOML:
+		if perr, ok := errors.AsType[toml.ParseError](err); ok {
+			return perr.Position.Line, perr.Position.Col
+		}
+	}
+	if m := errorLinePattern.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line, 0
+	}
+	return 0, 0
+}
+
+func offsetPosition(data []byte, offset int64) (line, column int) {
+	if offset < 0 || offset > int64(len(data)) {
+		return 0, 0
+	}
+	before := data[:offset]
+	return bytes.Count(before, []byte("\n")) + 1, int(offset) - bytes.LastIndexByte(before, '\n')
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"slices"
@@ -14,6 +15,9 @@ import (
 type AtomicOptions struct {
 	Sync            bool
 	CompareExisting bool
+	// Mode is the permissions the file ends up with, regardless of the
+	// umask. Zero leaves the temporary file's 0600.
+	Mode fs.FileMode
 }
 
 func AtomicWrite(root *os.Root, path string, gen func(w io.Writer) error, opts AtomicOptions) (bool, error) {
@@ -30,6 +34,11 @@ func AtomicWrite(root *os.Root, path string, gen func(w io.Writer) error, opts A
 	if err := gen(tmp); err != nil {
 		return false, err
 	}
+	if opts.Mode != 0 {
+		if err := tmp.Chmod(opts.Mode); err != nil {
+			return false, err
+		}
+	}
 	if opts.Sync {
 		if err := tmp.Sync(); err != nil {
 			return false, err
@@ -43,7 +52,7 @@ func AtomicWrite(root *os.Root, path string, gen func(w io.Writer) error, opts A
 		if eq, err := cmp(root, tmpRel, path); err != nil {
 			return false, err
 		} else if eq {
-			return false, nil
+			return false, chmodIfDiffers(root, path, opts.Mode)
 		}
 	}
 
@@ -63,6 +72,47 @@ func AtomicWrite(root *os.Root, path string, gen func(w io.Writer) error, opts A
 	return true, nil
 }
 
+// chmodIfDiffers gives an existing file mode, unless mode is zero.
+func chmodIfDiffers(root *os.Root, path string, mode fs.FileMode) error {
+	if mode == 0 {
+		return nil
+	}
+	info, err := root.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm() == mode {
+		return nil
+	}
+	return root.Chmod(path, mode)
+}
+
+// MkdirAll creates dir and any missing parents under root, giving those it
+// creates exactly perm, regardless of the umask.
+func MkdirAll(root *os.Root, dir string, perm fs.FileMode) error {
+	var missing []string
+	for p := filepath.Clean(dir); p != "." && p != string(filepath.Separator); p = filepath.Dir(p) {
+		if _, err := root.Lstat(p); err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		missing = append(missing, p)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err := root.MkdirAll(dir, perm); err != nil {
+		return err
+	}
+	for _, p := range missing {
+		if err := root.Chmod(p, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func temp(root *os.Root, dir, prefix string) (string, *os.File, error) {
 	for range 100 {
 		var b [16]byte
@@ -222,6 +222,26 @@ func ValidateRoutePath(raw string) (string, error) {
 	return raw, nil
 }
 
+// RoutePathForURL turns a site path written by hand, such as a frontmatter
+// url, into a route path. Leading and trailing slashes are optional, but the
+// path between them must be clean and may not climb out of the site root.
+func RoutePathForURL(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", fmt.Errorf("url is empty")
+	}
+	trimmed := strings.Trim(raw, "/")
+	if trimmed == "" {
+		return "/", nil
+	}
+	if EscapesRoot(trimmed) {
+		return "", fmt.Errorf("url %q escapes site root", raw)
+	}
+	if cleaned := path.Clean(trimmed); cleaned != trimmed {
+		return "", fmt.Errorf("url must be clean (got %q, want /%s/)", raw, cleaned)
+	}
+	return ValidateRoutePath("/" + trimmed + "/")
+}
+
 func RelPathWithin(root, source string) (string, error) {
 	root = path.Clean(root)
 	source = path.Clean(source)
@@ -235,12 +255,12 @@ func RelPathWithin(root, source string) (string, error) {
 	return strings.TrimPrefix(source, prefix), nil
 }
 
-func OutputPathForRoutePath(routePath string) string {
+func OutputPathForRoutePath(routePath, indexFile string) string {
 	routePath = strings.Trim(routePath, "/")
 	if routePath == "" {
-		return "index.html"
+		return indexFile
 	}
-	return path.Join(routePath, "index.html")
+	return path.Join(routePath, indexFile)
 }
 
 func routePathDir(dir string) (string, error) {
@@ -85,6 +85,31 @@ func TestValidateRoutePath(t *testing.T) {
 	}
 }
 
+func TestRoutePathForURL(t *testing.T) {
+	valid := map[string]string{
+		"/":              "/",
+		"landing":        "/landing/",
+		"/landing":       "/landing/",
+		"/docs/landing/": "/docs/landing/",
+	}
+	for raw, want := range valid {
+		got, err := RoutePathForURL(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", raw, err)
+		}
+		if got != want {
+			t.Fatalf("%s: route = %q, want %q", raw, got, want)
+		}
+	}
+
+	invalid := []string{"", " ", "../up/", "/docs/../landing/", "/docs//landing/", "/has space/"}
+	for _, raw := range invalid {
+		if _, err := RoutePathForURL(raw); err == nil {
+			t.Fatalf("%s: expected validation error", raw)
+		}
+	}
+}
+
 func TestCanonicalAndOutputPaths(t *testing.T) {
 	canon, err := CanonicalPageURL("https://example.com/blog", "/posts/hello/")
 	if err != nil {
@@ -94,10 +119,10 @@ func TestCanonicalAndOutputPaths(t *testing.T) {
 		t.Fatalf("canon = %q, want https://example.com/blog/posts/hello/", canon)
 	}
 
-	if got := OutputPathForRoutePath("/posts/hello/"); got != "posts/hello/index.html" {
+	if got := OutputPathForRoutePath("/posts/hello/", "index.html"); got != "posts/hello/index.html" {
 		t.Fatalf("output = %q, want posts/hello/index.html", got)
 	}
-	if got := OutputPathForRoutePath("/"); got != "index.html" {
+	if got := OutputPathForRoutePath("/", "index.html"); got != "index.html" {
 		t.Fatalf("output = %q, want index.html", got)
 	}
 }
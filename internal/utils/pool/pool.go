@@ -15,6 +15,14 @@ var (
 
 // Pool runs submitted work on a bounded set of goroutines.
 type Pool struct {
+	*core
+
+	// scope, when set, also cancels jobs submitted through this view. See
+	// WithScope.
+	scope context.Context
+}
+
+type core struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 	group  *errgroup.Group
@@ -40,13 +48,13 @@ func New(ctx context.Context, workers int) *Pool {
 
 	poolCtx, cancel := context.WithCancel(ctx)
 	group, groupCtx := errgroup.WithContext(poolCtx)
-	p := &Pool{
+	p := &Pool{core: &core{
 		ctx:    groupCtx,
 		cancel: cancel,
 		group:  group,
 		jobs:   make(chan task, workers*4),
 		done:   make(chan struct{}),
-	}
+	}}
 
 	p.group.SetLimit(workers)
 	go p.orchestrate()
@@ -54,6 +62,13 @@ func New(ctx context.Context, workers int) *Pool {
 	return p
 }
 
+// WithScope returns a view of the pool whose jobs also stop when ctx is done:
+// each runs with a context cancelled by either the pool or ctx. Jobs still
+// share the pool's workers, and Wait and Close act on the whole pool.
+func (p *Pool) WithScope(ctx context.Context) *Pool {
+	return &Pool{core: p.core, scope: ctx}
+}
+
 func (p *Pool) orchestrate() {
 	defer close(p.done)
 
@@ -85,6 +100,12 @@ func (p *Pool) Go(fn func(context.Context) error) error {
 	if err := p.ctx.Err(); err != nil {
 		return err
 	}
+	if p.scope != nil {
+		if err := p.scope.Err(); err != nil {
+			return err
+		}
+		fn = scopedJob(p.scope, fn)
+	}
 
 	select {
 	case p.jobs <- task(fn):
@@ -94,6 +115,20 @@ func (p *Pool) Go(fn func(context.Context) error) error {
 	}
 }
 
+// scopedJob wraps fn so that its context is also cancelled, with the same
+// cause, when scope is done.
+func scopedJob(scope context.Context, fn func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		stop := context.AfterFunc(scope, func() {
+			cancel(context.Cause(scope))
+		})
+		defer stop()
+		return fn(ctx)
+	}
+}
+
 // Close stops accepting work. Already accepted work is drained before Wait
 // returns.
 func (p *Pool) Close() {
@@ -11,7 +11,11 @@ import (
 func DefaultFuncs() template.FuncMap {
 	return template.FuncMap{
 		"discard":    discard,
-		"datefmt":    datefmt,
+		"datefmt":    dateFormat,
+		"dateFormat": dateFormat,
+		"dateISO":    dateISO,
+		"now":        time.Now,
+		"timeAgo":    timeAgo,
 		"uniq":       unique,
 		"dict":       dict,
 		"merge":      merge,
@@ -26,14 +30,83 @@ func discard() (string, error) {
 	return "", Discard()
 }
 
-// datefmt formats a time using Go's time layout.
-func datefmt(layout string, t time.Time) string {
+// dateLayouts are friendly names accepted by dateFormat in place of a Go
+// reference layout.
+var dateLayouts = map[string]string{
+	"date":     time.DateOnly,
+	"datetime": "2006-01-02 15:04",
+	"short":    "Jan 2, 2006",
+	"long":     "January 2, 2006",
+	"year":     "2006",
+	"month":    "January 2006",
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123Z,
+}
+
+// dateFormat formats a time using a Go reference layout, such as
+// "Jan 2, 2006", or one of the dateLayouts names. The zero time formats as "".
+func dateFormat(layout string, t time.Time) string {
 	if t.IsZero() {
 		return ""
 	}
+	if named, ok := dateLayouts[layout]; ok {
+		layout = named
+	}
 	return t.Format(layout)
 }
 
+// dateISO formats a time as RFC 3339, or "" for the zero time.
+func dateISO(t time.Time) string {
+	return dateFormat(time.RFC3339, t)
+}
+
+// timeAgo describes how long ago t was in the largest whole unit, such as
+// "3 days ago", or "in 3 days" for a future time. The zero time gives "".
+func timeAgo(t time.Time) string {
+	return relativeTime(t, time.Now())
+}
+
+func relativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < time.Minute {
+		return "just now"
+	}
+
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"week", 7 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	for _, unit := range units {
+		n := int(d / unit.size)
+		if n < 1 {
+			continue
+		}
+		phrase := fmt.Sprintf("%d %s", n, unit.name)
+		if n > 1 {
+			phrase += "s"
+		}
+		if future {
+			return "in " + phrase
+		}
+		return phrase + " ago"
+	}
+	return "just now"
+}
+
 // unique deduplicates strings while preserving order.
 func unique(values []string) []string {
 	seen := make(map[string]struct{}, len(values))
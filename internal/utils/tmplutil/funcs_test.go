@@ -0,0 +1,48 @@
+package tmplutil
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateFuncs(t *testing.T) {
+	date := time.Date(2025, 3, 4, 15, 30, 0, 0, time.UTC)
+	tmpl := template.Must(template.New("dates").Funcs(DefaultFuncs()).Parse(
+		`{{ dateFormat "2006" .T }}|{{ dateFormat "Jan 2, 2006" .T }}|{{ dateFormat "long" .T }}|{{ dateISO .T }}|` +
+			`{{ dateFormat "2006" .Zero }}|{{ dateISO .Zero }}|{{ timeAgo .Zero }}|{{ datefmt "date" .T }}`,
+	))
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, map[string]any{"T": date, "Zero": time.Time{}}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := "2025|Mar 4, 2025|March 4, 2025|2025-03-04T15:30:00Z||||2025-03-04"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{t: time.Time{}, want: ""},
+		{t: now.Add(-30 * time.Second), want: "just now"},
+		{t: now.Add(-time.Minute), want: "1 minute ago"},
+		{t: now.Add(-5 * time.Hour), want: "5 hours ago"},
+		{t: now.AddDate(0, 0, -3), want: "3 days ago"},
+		{t: now.AddDate(0, 0, -15), want: "2 weeks ago"},
+		{t: now.AddDate(0, -2, 0), want: "1 month ago"},
+		{t: now.AddDate(-3, 0, 0), want: "3 years ago"},
+		{t: now.AddDate(0, 0, 2), want: "in 2 days"},
+	}
+	for _, tt := range tests {
+		if got := relativeTime(tt.t, now); got != tt.want {
+			t.Fatalf("relativeTime(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}